@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// The formats of the access log, see Config.AccessLogFormat.
+const (
+	textLogFormat = "text" // textLogFormat formats each entry as a line of key=value pairs.
+	jsonLogFormat = "json" // jsonLogFormat formats each entry as a JSON object.
+)
+
+// accessLogger logs the requests served, see withAccessLog.
+var accessLogger *slog.Logger
+
+// accessLogWriter is an http.ResponseWriter recording the status code and the size of the response, for the access log
+// and the traces (see withTracing).
+type accessLogWriter struct {
+	http.ResponseWriter
+	statusCode int   // statusCode is the status code of the response, 0 until it is sent.
+	bytes      int64 // bytes is the number of bytes of the body of the response written so far.
+}
+
+// WriteHeader records the status code of the response and sends it.
+func (aw *accessLogWriter) WriteHeader(statusCode int) {
+	if aw.statusCode == 0 {
+		aw.statusCode = statusCode
+	}
+	aw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the bytes of the body of the response and writes them, the status code being 200 OK when it has not
+// been sent.
+func (aw *accessLogWriter) Write(data []byte) (int, error) {
+	if aw.statusCode == 0 {
+		aw.statusCode = http.StatusOK
+	}
+	written, err := aw.ResponseWriter.Write(data)
+	aw.bytes += int64(written)
+	return written, err
+}
+
+// Flush sends the response written so far to the client, the status code being 200 OK when it has not been sent.
+func (aw *accessLogWriter) Flush() {
+	if aw.statusCode == 0 {
+		aw.statusCode = http.StatusOK
+	}
+	_ = http.NewResponseController(aw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (aw *accessLogWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+// Hijack lets the handler take over the connection (e.g. for WebSocket), through the underlying http.ResponseWriter,
+// the request being logged with the 101 Switching Protocols status code.
+func (aw *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, readWriter, err := http.NewResponseController(aw.ResponseWriter).Hijack()
+	if err == nil && aw.statusCode == 0 {
+		aw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, readWriter, err
+}
+
+// initializeAccessLog creates the accessLogger writing to the standard output in Config.AccessLogFormat when
+// Config.AccessLog is enabled.
+func initializeAccessLog() {
+	if !config.AccessLog {
+		return
+	}
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, nil)
+	if config.AccessLogFormat == jsonLogFormat {
+		handler = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	accessLogger = slog.New(requestIDLogHandler{handler})
+}
+
+// withAccessLog logs each request once it has been served, with its method, path, status code, latency, response
+// size, client (see clientIP) and user agent, along with its ID (see withRequestID).
+func withAccessLog(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accessLogger == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		aw := &accessLogWriter{ResponseWriter: w}
+		handler.ServeHTTP(aw, r)
+		accessLogger.InfoContext(r.Context(), "HTTP request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", aw.statusCode,
+			"latency", time.Since(start),
+			"bytes", aw.bytes,
+			"client", clientIP(r),
+			"userAgent", r.UserAgent(),
+		)
+	})
+}
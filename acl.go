@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// errArticleForbidden is returned by the article operations when the actor is not allowed to change the article.
+var errArticleForbidden = errors.New("forbidden")
+
+// ArticleACL represents who can change an article: its owner, its editors and the admins (see Config.Admins).
+type ArticleACL struct {
+	Owner   string   `json:"owner" validate:"required"`                  // Owner is the actor owning the article.
+	Editors []string `json:"editors" validate:"omitempty,dive,required"` // Editors lists the actors the owner allows to change the article.
+}
+
+// The roles of the actors, each of them allowing the routes of the previous ones as well (see requiredRole).
+const (
+	roleReader = "reader" // roleReader allows the routes reading the articles (GET, HEAD and OPTIONS) and the ones of readerRoutes.
+	roleEditor = "editor" // roleEditor allows the routes changing the articles.
+	roleAdmin  = "admin"  // roleAdmin allows the /admin routes, and changing any article and its ACL whoever its owner.
+)
+
+// roles lists the roles the actors can be granted, the least privileged first.
+var roles = []string{roleReader, roleEditor, roleAdmin}
+
+// routeRoles maps the patterns of the routes of router which don't require the role of their method (see requiredRole)
+// to the role they require: the readers view, comment, flag, like and bookmark the articles, and send GraphQL requests,
+// whose mutations require the editor role (see checkGraphQLMutation).
+var routeRoles = map[string]string{
+	"POST /article/{id}/view":                      roleReader,
+	"POST /article/{id}/comments":                  roleReader,
+	"POST /article/{id}/comments/{commentId}/flag": roleReader,
+	"PUT /users/{user}/likes/{id}":                 roleReader,
+	"DELETE /users/{user}/likes/{id}":              roleReader,
+	"PUT /users/{user}/bookmarks/{id}":             roleReader,
+	"DELETE /users/{user}/bookmarks/{id}":          roleReader,
+	"POST /graphql":                                roleReader,
+}
+
+// isReadMethod reports whether method only reads: GET, HEAD or OPTIONS.
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requiredRole returns the role an actor needs to call the route of r when Config.RBAC is enabled: admin for the /admin
+// routes, the one of routeRoles for its routes, reader for the other GET, HEAD and OPTIONS routes, and editor for the
+// others.
+func requiredRole(r *http.Request) string {
+	if r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/") {
+		return roleAdmin
+	}
+	if router != nil {
+		if _, pattern := router.Handler(r); routeRoles[pattern] != "" {
+			return routeRoles[pattern]
+		}
+	}
+	if isReadMethod(r.Method) {
+		return roleReader
+	}
+	return roleEditor
+}
+
+// grantedRoles returns the roles of actor: the ones ctx is granted by its authentication (see authenticatedRoles), the
+// admin role for Config.Admins, and Config.DefaultRole for an authenticated actor granted none of them.
+func grantedRoles(ctx context.Context, actor string) []string {
+	granted := authenticatedRoles(ctx)
+	if slices.Contains(config.Admins, actor) {
+		granted = append(slices.Clip(granted), roleAdmin)
+	}
+	if len(granted) == 0 && authenticatedSubject(ctx) != "" {
+		granted = []string{config.DefaultRole}
+	}
+	return granted
+}
+
+// hasRole reports whether actor is granted role or a more privileged one, see grantedRoles.
+func hasRole(ctx context.Context, actor string, role string) bool {
+	required := slices.Index(roles, role)
+	return slices.ContainsFunc(grantedRoles(ctx, actor), func(granted string) bool { return slices.Index(roles, granted) >= required })
+}
+
+// isAdmin reports whether actor is granted the admin role, see grantedRoles.
+func isAdmin(ctx context.Context, actor string) bool {
+	return hasRole(ctx, actor, roleAdmin)
+}
+
+// withRoles refuses the requests of the actors lacking the role of their route (see requiredRole) with an HTTP 403
+// Forbidden error when Config.RBAC is enabled. The requests which are not authenticated are left to
+// withAuthentication, which only lets the ones reading the articles through.
+func withRoles(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := authenticatedSubject(r.Context())
+		if role := requiredRole(r); config.RBAC && actor != "" && !hasRole(r.Context(), actor, role) {
+			handleError(w, "Forbidden", fmt.Errorf("%s lacks the %s role", actor, role), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// canChangeArticle reports whether actor is allowed to update or delete an article: its owner, one of its editors or an
+// admin. An article without owner, as stored before the owners were recorded, can be changed by anyone.
+func canChangeArticle(ctx context.Context, actor string, article *Article) bool {
+	return article.Owner == "" || article.Owner == actor || slices.Contains(article.Editors, actor) || isAdmin(ctx, actor)
+}
+
+// articleForbidden responds with an HTTP 403 Forbidden error for the article with the given ID, actor not being
+// allowed to change it.
+func articleForbidden(w http.ResponseWriter, actor string, id string) {
+	handleError(w, "Forbidden", fmt.Errorf("%s is not allowed to change article with ID %s", actor, id), http.StatusForbidden)
+}
+
+// updateArticleACL handles PUT /article/{id}/acl, transferring the ownership of the article with the provided ID and
+// setting its editors. Only the owner of the article or an admin can change its ACL, an editor being answered with an
+// HTTP 403 Forbidden error. The ACL is written as a new version of the article, which is returned.
+func updateArticleACL(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id, actor := r.PathValue("id"), requestActor(r)
+
+	var acl ArticleACL
+	if err := json.NewDecoder(r.Body).Decode(&acl); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	acl.Owner = strings.TrimSpace(acl.Owner)
+	if err := validateStruct(acl); err != nil {
+		handleError(w, "Validation failed for ACL", err, http.StatusBadRequest)
+		return
+	}
+
+	key := tenantKey(ctx, keysPrefix+id)
+	storedArticle, err := getStoredArticle(ctx, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if storedArticle == nil {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	if storedArticle.Owner != "" && storedArticle.Owner != actor && !isAdmin(ctx, actor) {
+		handleError(w, "Forbidden", fmt.Errorf("only the owner of article with ID %s can change its ACL", id), http.StatusForbidden)
+		return
+	}
+
+	editors := slices.Clone(acl.Editors)
+	slices.Sort(editors)
+	article := *storedArticle
+	article.Owner, article.Editors = acl.Owner, slices.Compact(editors)
+	article.Version = storedArticle.Version + 1
+	article.UpdatedAt = time.Now().Unix()
+	if err := setArticleIfVersion(ctx, key, storedArticle, article); err != nil {
+		handleVersionedWriteError(w, err)
+		return
+	}
+	articleChanged(ctx, actor, storedArticle, &article)
+	responseArticleJSON(w, r, article, nil, http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequiredRole(t *testing.T) {
+	router = http.NewServeMux()
+	t.Cleanup(func() { router = nil })
+	for _, pattern := range []string{"GET /article/{id}", "PUT /article/{id}", "POST /article/{id}/comments", "PUT /users/{user}/likes/{id}", "POST /graphql", "GET /admin/apikeys"} {
+		router.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	}
+	tests := []struct {
+		method string
+		path   string
+		role   string
+		scope  string
+	}{
+		{http.MethodGet, "/article/1", roleReader, apiKeyScopeRead},
+		{http.MethodHead, "/article/1", roleReader, apiKeyScopeRead},
+		{http.MethodPut, "/article/1", roleEditor, apiKeyScopeWrite},
+		{http.MethodPost, "/article/1/comments", roleReader, apiKeyScopeRead},
+		{http.MethodPut, "/users/alice/likes/1", roleReader, apiKeyScopeRead},
+		{http.MethodPost, "/graphql", roleReader, apiKeyScopeRead},
+		{http.MethodGet, "/admin/apikeys", roleAdmin, apiKeyScopeAdmin},
+		{http.MethodPost, "/unknown", roleEditor, apiKeyScopeWrite},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if role := requiredRole(r); role != test.role {
+			t.Errorf("requiredRole(%s %s) = %s, expected %s", test.method, test.path, role, test.role)
+		}
+		if scope := requiredAPIKeyScope(r); scope != test.scope {
+			t.Errorf("requiredAPIKeyScope(%s %s) = %s, expected %s", test.method, test.path, scope, test.scope)
+		}
+	}
+}
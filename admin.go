@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+)
+
+// getIndexInfo returns the information about the articles search index, as reported by FT.INFO.
+func getIndexInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	info, err := db.IndexInfo(ctx, databaseClient, tenantIndexName(ctx, searchIndexName))
+	if err != nil {
+		handleError(w, "Failed to retrieve search index information", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, info, http.StatusOK)
+}
+
+// dropIndex drops the articles search index, the articles themselves are kept.
+// Searches fail until the index is created again, see recreateIndex.
+func dropIndex(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	indexName := tenantIndexName(ctx, searchIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err != nil {
+		handleError(w, "Error checking if search index exists", err, http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		handleError(w, "Search index not found", withErrorCode(ErrorCodeIndexNotFound, fmt.Errorf("no search index named %s", indexName)), http.StatusNotFound)
+		return
+	}
+
+	if err := db.DropIndex(ctx, databaseClient, indexName, false); err != nil {
+		handleError(w, "Failed to drop search index", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("search index %s successfully dropped", indexName)}, http.StatusOK)
+}
+
+// recreateIndex drops the articles search index (if any) and creates it again with the current schema.
+// The articles already stored are indexed again by the database in the background.
+func recreateIndex(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	indexName := tenantIndexName(ctx, searchIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err != nil {
+		handleError(w, "Error checking if search index exists", err, http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		if err := db.DropIndex(ctx, databaseClient, indexName, false); err != nil {
+			handleError(w, "Failed to drop search index", err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := db.CreateIndex(ctx, databaseClient, indexName, articlesIndexSchema(ctx)); err != nil {
+		handleError(w, "Failed to create search index", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("search index %s successfully created", indexName)}, http.StatusCreated)
+}
+
+// alterIndex adds to the articles search index the fields of the current schema it is missing (e.g. after
+// new Article fields have been added) using FT.ALTER, and returns the alias of the fields added.
+// Changes to fields that already exist can't be applied this way and require the index to be recreated.
+func alterIndex(w http.ResponseWriter, r *http.Request) {
+	addedFields, err := addMissingIndexFields(requestContext(r))
+	if err != nil {
+		handleError(w, "Failed to add the missing fields to the search index", err, http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON(w, struct {
+		AddedFields []string `json:"addedFields"`
+	}{AddedFields: addedFields}, http.StatusOK)
+}
+
+// reindexBatchSize is the number of articles processed at once by a reindex job.
+const reindexBatchSize = 100
+
+// startReindex submits a background job rebuilding the articles search index from the stored articles
+// and responds with an HTTP 202 Accepted along with the job, whose progress is reported by getJob.
+// If a reindex job is already queued or running, it responds with an HTTP 409 Conflict along with that job (see submitJob).
+func startReindex(w http.ResponseWriter, r *http.Request) {
+	submitJob(requestContext(r), w, "reindex", true, reindex)
+}
+
+// reindex rebuilds the articles search index of the tenant ctx is scoped to for the job with the given ID:
+// the index is recreated with the current schema, then the data derived from the stored articles is refreshed,
+// the job progress being updated along the way.
+func reindex(ctx context.Context, jobId string) error {
+	keys, err := db.GetAllKeys(ctx, databaseClient, tenantKey(ctx, keysPrefix))
+	if err != nil {
+		return fmt.Errorf("unable to list articles: %v", err)
+	}
+	jobs.update(jobId, func(job *Job) { job.Total = len(keys) })
+
+	indexName := tenantIndexName(ctx, searchIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := db.DropIndex(ctx, databaseClient, indexName, false); err != nil {
+			return fmt.Errorf("unable to drop search index: %v", err)
+		}
+	}
+	if err := db.CreateIndex(ctx, databaseClient, indexName, articlesIndexSchema(ctx)); err != nil {
+		return fmt.Errorf("unable to create search index: %v", err)
+	}
+
+	return refreshArticles(ctx, keys, func(processed int) {
+		jobs.update(jobId, func(job *Job) { job.Processed += processed })
+	})
+}
+
+// refreshArticles refreshes the data derived from the articles stored at the given keys (e.g. embeddings, suggestions),
+// by batches of reindexBatchSize articles, calling progress with the number of keys processed after each batch.
+func refreshArticles(ctx context.Context, keys []string, progress func(processed int)) error {
+	for start := 0; start < len(keys); start += reindexBatchSize {
+		batch := keys[start:min(start+reindexBatchSize, len(keys))]
+		articles, err := fetchArticles(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve articles: %v", err)
+		}
+		for _, article := range articles {
+			refreshDerivedData(ctx, nil, &article)
+		}
+		progress(len(batch))
+	}
+	return nil
+}
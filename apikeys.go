@@ -0,0 +1,244 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeysKey is the key of the hash holding the API keys by ID, shared by all the tenants, each key belonging to
+	// one of them (see APIKey.Tenant).
+	apiKeysKey = "apikeys"
+	// apiKeyHeader is the header of the requests holding their API key.
+	apiKeyHeader = "X-API-Key"
+	// apiKeyPrefix prefixes the API keys, followed by the ID of the key, a dot and its secret.
+	apiKeyPrefix = "ask_"
+	// bootstrapAPIKeyName is the name of Config.BootstrapAPIKey, the actor of the requests made with it.
+	bootstrapAPIKeyName = "bootstrap"
+)
+
+// The scopes of the API keys, each of them allowing a key to call some of the routes (see requiredAPIKeyScope).
+const (
+	apiKeyScopeRead  = "read"  // apiKeyScopeRead allows the routes reading the articles (GET, HEAD and OPTIONS).
+	apiKeyScopeWrite = "write" // apiKeyScopeWrite allows the routes changing the articles.
+	apiKeyScopeAdmin = "admin" // apiKeyScopeAdmin allows the /admin routes, whatever their method.
+)
+
+// APIKey represents an API key authenticating the requests as an actor, allowed to call the routes of its scopes.
+type APIKey struct {
+	Id        string        `json:"id"`                                                           // Id is the unique identifier of the key.
+	Name      string        `json:"name" validate:"required"`                                     // Name is the actor of the requests made with the key, see requestActor.
+	Scopes    []string      `json:"scopes" validate:"required,min=1,dive,oneof=read write admin"` // Scopes lists the scopes of the key, see requiredAPIKeyScope.
+	Quotas    *APIKeyQuotas `json:"quotas,omitempty"`                                             // Quotas holds the quotas of the key, the ones of the configuration applying when missing.
+	Tenant    string        `json:"tenant,omitempty"`                                             // Tenant is the only tenant the key can act on, the one it was created for, empty for the default tenant.
+	CreatedAt int64         `json:"createdAt"`                                                    // CreatedAt is the time the key was created, as a Unix timestamp in seconds.
+}
+
+// CreatedAPIKey represents an API key along with its value, which is only returned when the key is created.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"` // Key is the value of the key, sent as X-API-Key header.
+}
+
+// storedAPIKey is an API key as stored in the Database, along with the SHA-256 hash of its value.
+type storedAPIKey struct {
+	APIKey
+	Hash string `json:"hash"` // Hash is the hexadecimal SHA-256 hash of the value of the key.
+}
+
+// apiKeyContextKey is the context key of the API key authenticating a request, see withAuthentication.
+type apiKeyContextKey struct{}
+
+// contextWithAPIKey returns a copy of parent authenticated by apiKey.
+func contextWithAPIKey(parent context.Context, apiKey APIKey) context.Context {
+	return context.WithValue(parent, apiKeyContextKey{}, apiKey)
+}
+
+// authenticatedAPIKey returns the API key ctx is authenticated by, if any.
+func authenticatedAPIKey(ctx context.Context) (APIKey, bool) {
+	apiKey, found := ctx.Value(apiKeyContextKey{}).(APIKey)
+	return apiKey, found
+}
+
+// clientId returns the identifier of the API key as a client of the rate limiter and of the quotas: its ID, or its name
+// for the bootstrap key.
+func (apiKey APIKey) clientId() string {
+	return cmp.Or(apiKey.Id, apiKey.Name)
+}
+
+// isBootstrap reports whether the API key is Config.BootstrapAPIKey, the only key without ID, which may act on any
+// tenant.
+func (apiKey APIKey) isBootstrap() bool {
+	return apiKey.Id == ""
+}
+
+// hashAPIKey returns the hexadecimal SHA-256 hash of the value of an API key.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// apiKeyScopeRoles maps the scopes of the API keys to the roles they grant, see requiredRole.
+var apiKeyScopeRoles = map[string]string{apiKeyScopeRead: roleReader, apiKeyScopeWrite: roleEditor, apiKeyScopeAdmin: roleAdmin}
+
+// requiredAPIKeyScope returns the scope an API key needs to call the route of r, the one granting the role of the route
+// (see requiredRole): admin for the /admin routes, read for the other GET, HEAD and OPTIONS routes and the routes open to
+// the readers (see routeRoles), and write for the others.
+func requiredAPIKeyScope(r *http.Request) string {
+	switch requiredRole(r) {
+	case roleAdmin:
+		return apiKeyScopeAdmin
+	case roleReader:
+		return apiKeyScopeRead
+	}
+	return apiKeyScopeWrite
+}
+
+// roles returns the roles granted by the scopes of the API key, see apiKeyScopeRoles.
+func (apiKey APIKey) roles() []string {
+	granted := make([]string, 0, len(apiKey.Scopes))
+	for _, scope := range apiKey.Scopes {
+		granted = append(granted, apiKeyScopeRoles[scope])
+	}
+	return granted
+}
+
+// authenticateAPIKey returns the API key with the given value, or an error when it does not exist or has been revoked.
+// Config.BootstrapAPIKey is a key named bootstrapAPIKeyName holding all the scopes, to create the first keys.
+func authenticateAPIKey(ctx context.Context, key string) (APIKey, error) {
+	if config.BootstrapAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(config.BootstrapAPIKey)) == 1 {
+		return APIKey{Name: bootstrapAPIKeyName, Scopes: []string{apiKeyScopeRead, apiKeyScopeWrite, apiKeyScopeAdmin}}, nil
+	}
+	id, _, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), ".")
+	if !strings.HasPrefix(key, apiKeyPrefix) || !found {
+		return APIKey{}, errors.New("the API key is malformed")
+	}
+	record, err := db.HashGet(ctx, databaseClient, apiKeysKey, id)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("unable to retrieve the API key: %v", err)
+	}
+	var stored storedAPIKey
+	if record == "" || json.Unmarshal([]byte(record), &stored) != nil ||
+		subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(stored.Hash)) != 1 {
+		return APIKey{}, errors.New("the API key is unknown or has been revoked")
+	}
+	return stored.APIKey, nil
+}
+
+// createAPIKey handles POST /admin/apikeys, creating an API key for the tenant of the request, responding with an HTTP
+// 201 Created along with the key and its value, which is not returned afterward: only its hash is stored.
+func createAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	var apiKey APIKey
+	if err := json.NewDecoder(r.Body).Decode(&apiKey); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	apiKey.Name = strings.TrimSpace(apiKey.Name)
+	if err := validateStruct(apiKey); err != nil {
+		handleError(w, "Validation failed for API key", err, http.StatusBadRequest)
+		return
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		handleError(w, "Failed to generate API key", err, http.StatusInternalServerError)
+		return
+	}
+	slices.Sort(apiKey.Scopes)
+	apiKey.Scopes = slices.Compact(apiKey.Scopes)
+	apiKey.Id = uuid.New().String()
+	apiKey.Tenant = tenantOf(ctx)
+	apiKey.CreatedAt = time.Now().Unix()
+	key := apiKeyPrefix + apiKey.Id + "." + hex.EncodeToString(secret)
+
+	record, err := json.Marshal(storedAPIKey{APIKey: apiKey, Hash: hashAPIKey(key)})
+	if err != nil {
+		handleError(w, "Failed to encode API key", err, http.StatusInternalServerError)
+		return
+	}
+	if err := db.HashSet(ctx, databaseClient, apiKeysKey, apiKey.Id, record); err != nil {
+		handleError(w, "Failed to store API key in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CreatedAPIKey{APIKey: apiKey, Key: key}, http.StatusCreated)
+}
+
+// getAPIKeys handles GET /admin/apikeys, returning the API keys of the tenant of the request, the oldest first, without
+// their value.
+func getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	records, err := db.HashGetAll(ctx, databaseClient, apiKeysKey)
+	if err != nil {
+		handleError(w, "Failed to retrieve API keys from Database", err, http.StatusInternalServerError)
+		return
+	}
+	apiKeys := make([]APIKey, 0, len(records))
+	for id, record := range records {
+		var stored storedAPIKey
+		if err := json.Unmarshal([]byte(record), &stored); err != nil {
+			handleError(w, "Failed to parse API key data", fmt.Errorf("unable to validate the structure of stored APIKey %s: %v", id, err), http.StatusInternalServerError)
+			return
+		}
+		if stored.Tenant == tenantOf(ctx) {
+			apiKeys = append(apiKeys, stored.APIKey)
+		}
+	}
+	sort.Slice(apiKeys, func(i, j int) bool {
+		if apiKeys[i].CreatedAt != apiKeys[j].CreatedAt {
+			return apiKeys[i].CreatedAt < apiKeys[j].CreatedAt
+		}
+		return apiKeys[i].Id < apiKeys[j].Id
+	})
+	responseJSON(w, apiKeys, http.StatusOK)
+}
+
+// revokeAPIKey handles DELETE /admin/apikeys/{id}, revoking the API key with the provided ID, the requests made with it
+// being refused right away. If there is no such key in the tenant of the request, it returns an HTTP 404 Not Found
+// response.
+func revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	record, err := db.HashGet(ctx, databaseClient, apiKeysKey, id)
+	if err != nil {
+		handleError(w, "Failed to retrieve API key from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if record == "" {
+		handleError(w, "API key not found", withErrorCode(ErrorCodeAPIKeyNotFound, fmt.Errorf("no API key found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	// An API key whose record can't be parsed is not revoked, as it may belong to another tenant
+	var stored storedAPIKey
+	if err := json.Unmarshal([]byte(record), &stored); err != nil {
+		handleError(w, "Failed to parse API key data", fmt.Errorf("unable to validate the structure of stored APIKey %s: %v", id, err), http.StatusInternalServerError)
+		return
+	}
+	if stored.Tenant != tenantOf(ctx) {
+		handleError(w, "API key not found", withErrorCode(ErrorCodeAPIKeyNotFound, fmt.Errorf("no API key found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	deleted, err := db.HashDel(ctx, databaseClient, apiKeysKey, id)
+	if err != nil {
+		handleError(w, "Failed to delete API key from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		handleError(w, "API key not found", withErrorCode(ErrorCodeAPIKeyNotFound, fmt.Errorf("no API key found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("API key %s successfully revoked", id)}, http.StatusOK)
+}
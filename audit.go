@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// auditStream is the key of the Redis Stream the audit entries are appended to.
+	auditStream = "audit"
+	// auditScanBatch is the number of entries of the audit stream read at once when filtering them.
+	auditScanBatch = 500
+)
+
+// auditCursorPattern is the pattern of the cursor of a page of the audit log, the ID of the last entry of the previous
+// page.
+var auditCursorPattern = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// AuditEntry represents a change made to an article, as recorded in the audit log.
+type AuditEntry struct {
+	Id         string           `json:"id"`               // Id is the ID of the entry in the audit stream, ordered by time.
+	ArticleId  string           `json:"articleId"`        // ArticleId is the ID of the changed article.
+	Operation  ArticleEventType `json:"operation"`        // Operation is the kind of change made to the article.
+	Actor      string           `json:"actor"`            // Actor is who made the change, see requestActor.
+	Before     *Article         `json:"before,omitempty"` // Before is the article before the change, missing when it has been created.
+	After      *Article         `json:"after,omitempty"`  // After is the article after the change, missing when it has been deleted.
+	OccurredAt int64            `json:"occurredAt"`       // OccurredAt is the time of the change, as a Unix timestamp in seconds.
+}
+
+// AuditPage represents a page of the audit log, the latest entries first.
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`               // Entries holds the entries of the current page.
+	Limit      int          `json:"limit"`                 // Limit is the requested number of entries per page.
+	NextCursor string       `json:"next_cursor,omitempty"` // NextCursor is the token to use to get the next page, empty on the last page.
+}
+
+// recordAudit appends the change of an article to the audit stream of the tenant and namespace ctx is scoped to, along
+// with the article before and after it, previous and current being the same as for articleChanged. The stream is only
+// appended to (and trimmed to about Config.AuditStreamMaxLen entries when set), a failure being logged.
+func recordAudit(ctx context.Context, actor string, previous *Article, current *Article) {
+	operation, article := ArticleUpdated, current
+	switch {
+	case previous == nil:
+		operation = ArticleCreated
+	case current == nil:
+		operation, article = ArticleDeleted, previous
+	}
+	values := map[string]any{
+		"articleId":  article.Id,
+		"operation":  string(operation),
+		"actor":      actor,
+		"occurredAt": time.Now().Unix(),
+	}
+	for field, snapshot := range map[string]*Article{"before": previous, "after": current} {
+		if snapshot == nil {
+			continue
+		}
+		encoded, err := json.Marshal(snapshot)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to encode the article of the audit entry", "articleId", article.Id, "Error:", err)
+			return
+		}
+		values[field] = string(encoded)
+	}
+	if _, err := db.StreamAdd(ctx, databaseClient, tenantKey(ctx, auditStream), int64(config.AuditStreamMaxLen), values); err != nil {
+		slog.ErrorContext(ctx, "Unable to append the change to the audit log", "articleId", article.Id, "operation", operation, "Error:", err)
+	}
+}
+
+// parseAuditEntry returns the audit entry of an entry of the audit stream.
+func parseAuditEntry(entry db.StreamEntry) (AuditEntry, error) {
+	auditEntry := AuditEntry{
+		Id:        entry.Id,
+		ArticleId: entry.Values["articleId"],
+		Operation: ArticleEventType(entry.Values["operation"]),
+		Actor:     entry.Values["actor"],
+	}
+	auditEntry.OccurredAt, _ = strconv.ParseInt(entry.Values["occurredAt"], 10, 64)
+	for field, snapshot := range map[string]**Article{"before": &auditEntry.Before, "after": &auditEntry.After} {
+		if encoded := entry.Values[field]; encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), snapshot); err != nil {
+				return auditEntry, fmt.Errorf("unable to parse the %s article of audit entry %s: %v", field, entry.Id, err)
+			}
+		}
+	}
+	return auditEntry, nil
+}
+
+// getAuditLog handles GET /admin/audit, returning the changes made to the articles, the latest first.
+// The changes can be filtered by article (articleId query parameter), by actor (actor query parameter) and by time
+// (from and to query parameters, as Unix timestamps in seconds, both included). The number of entries returned is
+// controlled by the limit query parameter (defaultPageLimit by default, up to maxPageLimit), and the next entries are
+// returned by providing the next_cursor of the page as cursor query parameter.
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	invalidAuditError := "invalid audit log parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"articleId", "actor", "from", "to", "limit", "cursor"}); err != nil {
+		handleError(w, invalidAuditError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, _, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	start, end := "-", "+"
+	for name, bound := range map[string]*string{"from": &start, "to": &end} {
+		if !queryParams.Has(name) {
+			continue
+		}
+		seconds, err := strconv.ParseInt(queryParams.Get(name), 10, 64)
+		if err != nil || seconds < 0 {
+			handleError(w, invalidAuditError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("%s must be a Unix timestamp in seconds", name)), http.StatusBadRequest)
+			return
+		}
+		if name == "from" {
+			*bound = strconv.FormatInt(seconds*1000, 10)
+		} else {
+			*bound = strconv.FormatInt(seconds*1000+999, 10)
+		}
+	}
+	if cursor := queryParams.Get("cursor"); cursor != "" {
+		if !auditCursorPattern.MatchString(cursor) {
+			handleError(w, invalidAuditError, withErrorCode(ErrorCodeInvalidParameter, errors.New("cursor is not a valid token")), http.StatusBadRequest)
+			return
+		}
+		end = "(" + cursor
+	}
+
+	articleId, actor := queryParams.Get("articleId"), queryParams.Get("actor")
+	page := AuditPage{Entries: []AuditEntry{}, Limit: limit}
+	for {
+		entries, err := db.StreamRevRange(ctx, databaseClient, tenantKey(ctx, auditStream), end, start, auditScanBatch)
+		if err != nil {
+			handleError(w, "Failed to retrieve the audit log from Database", err, http.StatusInternalServerError)
+			return
+		}
+		for _, entry := range entries {
+			if (articleId != "" && entry.Values["articleId"] != articleId) || (actor != "" && entry.Values["actor"] != actor) {
+				continue
+			}
+			auditEntry, err := parseAuditEntry(entry)
+			if err != nil {
+				handleError(w, "Failed to parse the audit log", err, http.StatusInternalServerError)
+				return
+			}
+			page.Entries = append(page.Entries, auditEntry)
+			if len(page.Entries) == limit {
+				page.NextCursor = entry.Id
+				responseJSON(w, page, http.StatusOK)
+				return
+			}
+		}
+		if len(entries) < auditScanBatch {
+			break
+		}
+		end = "(" + entries[len(entries)-1].Id
+	}
+	responseJSON(w, page, http.StatusOK)
+}
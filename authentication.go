@@ -0,0 +1,390 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers the hash of the 256 algorithms
+	_ "crypto/sha512" // registers the hash of the 384 and 512 algorithms
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwtLeeway                 = time.Minute      // jwtLeeway is the clock skew tolerated when checking the validity period of a token.
+	jwksRefreshInterval       = time.Hour        // jwksRefreshInterval is how often the keys of the JWKS URL are fetched again.
+	jwksUnknownKeyMinInterval = time.Minute      // jwksUnknownKeyMinInterval is the minimum time between two fetches caused by an unknown key ID.
+	jwksTimeout               = 10 * time.Second // jwksTimeout is the maximum time to fetch the keys of the JWKS URL.
+)
+
+// subjectContextKey is the context key of the subject of an authenticated request, see withAuthentication.
+type subjectContextKey struct{}
+
+// rolesContextKey is the context key of the roles of an authenticated request, see withAuthentication.
+type rolesContextKey struct{}
+
+// jwtPrincipal is the principal authenticated by a JSON Web Token, see jwtAuthenticator.authenticate.
+type jwtPrincipal struct {
+	subject string   // subject is the sub claim of the token, the actor of the request.
+	roles   []string // roles holds the roles mapped from the groups of the token.
+	tenant  string   // tenant is the only tenant the requests of the token may be scoped to, empty for the default tenant.
+}
+
+// jwtKey is a key verifying the signature of the tokens: an *rsa.PublicKey, an *ecdsa.PublicKey or an HMAC secret.
+type jwtKey struct {
+	id  string // id is the ID of the key, matched against the kid header of the tokens when both are set.
+	key any    // key is the key itself.
+}
+
+// jwtAuthenticator verifies the bearer tokens of the requests against the configured keys and the ones published at
+// the JWKS URL, which are cached and fetched again every jwksRefreshInterval or when a token names an unknown key.
+type jwtAuthenticator struct {
+	staticKeys    []jwtKey           // staticKeys holds the keys of Config.JWTSecret and Config.JWTKeyFiles.
+	jwksURL       string             // jwksURL is the JWKS URL of the configuration or of the provider, no keys being fetched when empty.
+	issuer        string             // issuer is the issuer of the configuration or of the provider, any issuer being accepted when empty.
+	httpClient    *http.Client       // httpClient fetches the keys of the JWKS URL.
+	jwksFetches   singleflight.Group // jwksFetches shares a fetch of the keys of the JWKS URL among the requests waiting for it.
+	mu            sync.RWMutex       // mu guards the following fields, never held while the keys are fetched.
+	jwksKeys      []jwtKey           // jwksKeys holds the keys last fetched from the JWKS URL.
+	jwksFetchedAt time.Time          // jwksFetchedAt is the last time the keys have been fetched from the JWKS URL.
+}
+
+// authenticator verifies the bearer tokens of the requests, nil when the authentication is disabled (see
+// initializeAuthentication).
+var authenticator *jwtAuthenticator
+
+// initializeAuthentication enables the JWT authentication when Config.JWTSecret, Config.JWTKeyFiles,
+// Config.JWTJWKSURL or Config.OIDCIssuerURL is set, loading the keys of the files and fetching the discovery document
+// of the OpenID Connect provider, whose issuer and JWKS URL are used unless JWTIssuer and JWTJWKSURL are set. The keys
+// of the JWKS URL are fetched by the first request.
+func initializeAuthentication() error {
+	if config.JWTSecret == "" && len(config.JWTKeyFiles) == 0 && config.JWTJWKSURL == "" && config.OIDCIssuerURL == "" {
+		return nil
+	}
+	jwtAuth := &jwtAuthenticator{jwksURL: config.JWTJWKSURL, issuer: config.JWTIssuer, httpClient: &http.Client{Timeout: jwksTimeout}}
+	if config.OIDCIssuerURL != "" {
+		discovery, err := discoverOIDC(jwtAuth.httpClient)
+		if err != nil {
+			return fmt.Errorf("unable to discover the OpenID Connect provider %s: %v", config.OIDCIssuerURL, err)
+		}
+		jwtAuth.jwksURL = cmp.Or(jwtAuth.jwksURL, discovery.JWKSURL)
+		jwtAuth.issuer = cmp.Or(jwtAuth.issuer, discovery.Issuer)
+	}
+	if config.JWTSecret != "" {
+		jwtAuth.staticKeys = append(jwtAuth.staticKeys, jwtKey{key: []byte(config.JWTSecret)})
+	}
+	for _, keyFile := range config.JWTKeyFiles {
+		keys, err := readPEMPublicKeys(keyFile)
+		if err != nil {
+			return fmt.Errorf("unable to read the keys of %s: %v", keyFile, err)
+		}
+		jwtAuth.staticKeys = append(jwtAuth.staticKeys, keys...)
+	}
+	authenticator = jwtAuth
+	return nil
+}
+
+// checkRolesAuthentication returns an error when Config.RBAC is enabled without authentication, the roles being granted
+// to the authenticated actors only.
+func checkRolesAuthentication() error {
+	if config.RBAC && !authenticationEnabled() {
+		return errors.New("the role-based access control requires the authentication, with bearer tokens or API keys")
+	}
+	return nil
+}
+
+// readPEMPublicKeys returns the public keys held by a PEM file, as PUBLIC KEY or CERTIFICATE blocks.
+func readPEMPublicKeys(path string) ([]jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []jwtKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var key any
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var certificate *x509.Certificate
+			if certificate, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = certificate.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwtKey{key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PUBLIC KEY nor CERTIFICATE block found")
+	}
+	return keys, nil
+}
+
+// contextWithSubject returns a copy of parent authenticated as subject.
+func contextWithSubject(parent context.Context, subject string) context.Context {
+	return context.WithValue(parent, subjectContextKey{}, subject)
+}
+
+// authenticatedSubject returns the subject ctx is authenticated as, empty when it is not authenticated.
+func authenticatedSubject(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectContextKey{}).(string)
+	return subject
+}
+
+// contextWithRoles returns a copy of parent granted the given roles.
+func contextWithRoles(parent context.Context, roles []string) context.Context {
+	return context.WithValue(parent, rolesContextKey{}, roles)
+}
+
+// authenticatedRoles returns the roles ctx is granted by its authentication, see Config.OIDCGroupRoles.
+func authenticatedRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesContextKey{}).([]string)
+	return roles
+}
+
+// authenticationEnabled reports whether the requests changing anything and the /admin requests must be authenticated,
+// either with a bearer token (see initializeAuthentication), with an API key (see Config.APIKeys) or with a client
+// certificate (see clientCertificatesEnabled).
+func authenticationEnabled() bool {
+	return authenticator != nil || config.APIKeys || clientCertificatesEnabled()
+}
+
+// withAuthentication authenticates the requests with their bearer token (Authorization: Bearer <JWT>), their API key
+// (X-API-Key header, see authenticateAPIKey) or the certificate of their TLS connection (see clientCertificateSubject)
+// when the authentication is enabled, the subject of the token, the name of the key or the identity of the certificate
+// being the actor of the request (see requestActor), granted the roles mapped from the groups of the token or the ones
+// of the scopes of the key. An actor authenticated by a certificate is granted the roles of grantedRoles.
+// The tokens belong to the tenant of their Config.JWTTenantClaim claim, the API keys to the tenant they were created for,
+// the bootstrap key acting on any tenant, and the certificates to the default tenant, see resolveTenant.
+// The requests changing anything (any method but GET, HEAD and OPTIONS) and the /admin requests must be authenticated,
+// and an invalid token or key is refused whatever the request, both being answered with an HTTP 401 Unauthorized
+// error. A request made with an API key lacking the scope of its route (see requiredAPIKeyScope) is answered with an
+// HTTP 403 Forbidden error.
+func withAuthentication(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authenticationEnabled() {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if key := r.Header.Get(apiKeyHeader); config.APIKeys && key != "" {
+			apiKey, err := authenticateAPIKey(r.Context(), key)
+			if err != nil {
+				handleError(w, "Invalid API key", err, http.StatusUnauthorized)
+				return
+			}
+			if scope := requiredAPIKeyScope(r); !slices.Contains(apiKey.Scopes, scope) {
+				handleError(w, "Forbidden", fmt.Errorf("the API key lacks the %s scope", scope), http.StatusForbidden)
+				return
+			}
+			authenticatedCtx := contextWithRoles(contextWithSubject(contextWithAPIKey(r.Context(), apiKey), apiKey.Name), apiKey.roles())
+			if !apiKey.isBootstrap() {
+				authenticatedCtx = contextWithPrincipalTenant(authenticatedCtx, apiKey.Tenant)
+			}
+			handler.ServeHTTP(w, r.WithContext(authenticatedCtx))
+			return
+		}
+		if subject := clientCertificateSubject(r); subject != "" && r.Header.Get("Authorization") == "" {
+			handler.ServeHTTP(w, r.WithContext(contextWithPrincipalTenant(contextWithSubject(r.Context(), subject), "")))
+			return
+		}
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if authenticator == nil || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			if requiredRole(r) == roleReader && isReadMethod(r.Method) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			if authenticator != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			handleError(w, "Authentication required", errors.New("a bearer token, an API key or a client certificate is required"), http.StatusUnauthorized)
+			return
+		}
+		principal, err := authenticator.authenticate(strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			handleError(w, "Invalid bearer token", err, http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r.WithContext(contextWithPrincipalTenant(contextWithRoles(contextWithSubject(r.Context(), principal.subject), principal.roles), principal.tenant)))
+	})
+}
+
+// jwtAlgorithms are the algorithms the tokens may be signed with, any other one (e.g. none) being refused.
+var jwtAlgorithms = []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// authenticate verifies the signature and the claims of a JSON Web Token and returns its principal: its subject, the
+// roles mapped from its groups (see groupRoles) and its tenant (see claimTenant). The token must be signed with one of
+// jwtAlgorithms by one of the keys of the authenticator (see verificationKeys), must expire, and be issued by the issuer
+// of the authenticator to Config.JWTAudience when they are set.
+func (jwtAuth *jwtAuthenticator) authenticate(token string) (jwtPrincipal, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods(jwtAlgorithms), jwt.WithLeeway(jwtLeeway), jwt.WithExpirationRequired()}
+	if jwtAuth.issuer != "" {
+		options = append(options, jwt.WithIssuer(jwtAuth.issuer))
+	}
+	if config.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.JWTAudience))
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, jwtAuth.verificationKeys, options...); err != nil {
+		return jwtPrincipal{}, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || strings.TrimSpace(subject) == "" {
+		return jwtPrincipal{}, errors.New("the token has no sub claim")
+	}
+	tenant, err := claimTenant(claims)
+	if err != nil {
+		return jwtPrincipal{}, err
+	}
+	return jwtPrincipal{subject: strings.TrimSpace(subject), roles: groupRoles(claims), tenant: tenant}, nil
+}
+
+// verificationKeys returns the keys the signature of a token may be verified with, see keys: the ones of the type of
+// its algorithm (an HMAC secret, an RSA key or an EC key), the keys with an ID having to be the one of its kid header.
+func (jwtAuth *jwtAuthenticator) verificationKeys(token *jwt.Token) (any, error) {
+	keyId, _ := token.Header["kid"].(string)
+	keys, err := jwtAuth.keys(keyId)
+	if err != nil {
+		return nil, err
+	}
+	var verificationKeys jwt.VerificationKeySet
+	for _, key := range keys {
+		if key.id != "" && keyId != "" && key.id != keyId {
+			continue
+		}
+		var matching bool
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			_, matching = key.key.([]byte)
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			_, matching = key.key.(*rsa.PublicKey)
+		case *jwt.SigningMethodECDSA:
+			_, matching = key.key.(*ecdsa.PublicKey)
+		}
+		if matching {
+			verificationKeys.Keys = append(verificationKeys.Keys, key.key)
+		}
+	}
+	if len(verificationKeys.Keys) == 0 {
+		return nil, fmt.Errorf("no key can verify a token signed with the %s algorithm", token.Method.Alg())
+	}
+	return verificationKeys, nil
+}
+
+// keys returns the keys verifying a token signed with the key of the given ID, fetching the keys of the JWKS URL when
+// they are stale or when none of them has the ID. The keys last fetched are used when they can't be fetched again.
+// The keys are fetched without holding mu, the requests needing them meanwhile sharing the same fetch, so that a slow
+// JWKS URL only delays the requests waiting for its keys.
+func (jwtAuth *jwtAuthenticator) keys(keyId string) ([]jwtKey, error) {
+	if jwtAuth.jwksURL == "" {
+		return jwtAuth.staticKeys, nil
+	}
+	jwtAuth.mu.RLock()
+	jwksKeys, jwksFetchedAt := jwtAuth.jwksKeys, jwtAuth.jwksFetchedAt
+	jwtAuth.mu.RUnlock()
+	sinceFetched := time.Since(jwksFetchedAt)
+	unknownKey := keyId != "" && !slices.ContainsFunc(jwksKeys, func(key jwtKey) bool { return key.id == keyId })
+	if sinceFetched > jwksRefreshInterval || (unknownKey && sinceFetched > jwksUnknownKeyMinInterval) {
+		fetched, err, _ := jwtAuth.jwksFetches.Do(jwtAuth.jwksURL, func() (any, error) {
+			// The keys may have been fetched since they were found stale, by a fetch which has just completed.
+			jwtAuth.mu.RLock()
+			refetched, keys := !jwtAuth.jwksFetchedAt.Equal(jwksFetchedAt), jwtAuth.jwksKeys
+			jwtAuth.mu.RUnlock()
+			if refetched {
+				return keys, nil
+			}
+			keys, err := jwtAuth.fetchJWKS()
+			if err != nil {
+				return nil, err
+			}
+			jwtAuth.mu.Lock()
+			jwtAuth.jwksKeys, jwtAuth.jwksFetchedAt = keys, time.Now()
+			jwtAuth.mu.Unlock()
+			return keys, nil
+		})
+		switch {
+		case err != nil && jwksFetchedAt.IsZero():
+			return nil, fmt.Errorf("unable to fetch the keys verifying the token: %v", err)
+		case err != nil:
+			slog.Warn("Unable to fetch the JWKS keys, the keys last fetched are used", "url", jwtAuth.jwksURL, "Error:", err)
+		default:
+			jwksKeys = fetched.([]jwtKey)
+		}
+	}
+	return slices.Concat(jwtAuth.staticKeys, jwksKeys), nil
+}
+
+// fetchJWKS fetches the RSA and EC keys of the JWK Set (RFC 7517) published at the JWKS URL, the encryption keys and
+// the keys of other types being left out.
+func (jwtAuth *jwtAuthenticator) fetchJWKS() ([]jwtKey, error) {
+	resp, err := jwtAuth.httpClient.Get(jwtAuth.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyId   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWK Set: %v", err)
+	}
+
+	var keys []jwtKey
+	for _, jwk := range jwks.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("invalid RSA key %s", jwk.KeyId)
+			}
+			exponent := new(big.Int).SetBytes(e).Int64()
+			keys = append(keys, jwtKey{id: jwk.KeyId, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent)}})
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			curve, found := curves[jwk.Curve]
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if !found || errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid EC key %s", jwk.KeyId)
+			}
+			keys = append(keys, jwtKey{id: jwk.KeyId, key: &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}})
+		}
+	}
+	return keys, nil
+}
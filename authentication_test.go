@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rfc7515HS256Token is the token of RFC 7515, Appendix A.1, signed with HS256 by rfc7515HS256Key, which expired in 2011.
+const rfc7515HS256Token = "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+	".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+	".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+// rfc7515HS256Key is the base64url encoded HMAC key of RFC 7515, Appendix A.1.
+const rfc7515HS256Key = "AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("a secret of at least 256 bits for HS256")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfcKey, err := base64.RawURLEncoding.DecodeString(rfc7515HS256Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicKey})
+
+	allKeys := &jwtAuthenticator{staticKeys: []jwtKey{{key: secret}, {id: "rsa", key: &rsaKey.PublicKey}, {id: "ec", key: &ecKey.PublicKey}}}
+	rsaOnly := &jwtAuthenticator{staticKeys: []jwtKey{{key: &rsaKey.PublicKey}}}
+	rfcOnly := &jwtAuthenticator{staticKeys: []jwtKey{{key: rfcKey}}}
+
+	now := time.Now()
+	valid := jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix()}
+	sign := func(method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	unsigned := func(header string, claims string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+	}
+	resign := func(token string, change func(signingInput string, signature []byte) (string, []byte)) string {
+		dot := strings.LastIndex(token, ".")
+		signature, _ := base64.RawURLEncoding.DecodeString(token[dot+1:])
+		signingInput, signature := change(token[:dot], signature)
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	truncated := func(signingInput string, signature []byte) (string, []byte) {
+		return signingInput, signature[:len(signature)-1]
+	}
+	padded := func(signingInput string, signature []byte) (string, []byte) {
+		return signingInput, append([]byte{0}, signature...)
+	}
+	tampered := func(signingInput string, signature []byte) (string, []byte) {
+		header, _, _ := strings.Cut(signingInput, ".")
+		return header + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","exp":4102444800}`)), signature
+	}
+
+	tests := []struct {
+		name          string
+		authenticator *jwtAuthenticator
+		token         string
+		err           error // err is the error expected, nil when the token is valid.
+	}{
+		{"HS256", allKeys, sign(jwt.SigningMethodHS256, secret, "", valid), nil},
+		{"HS512", allKeys, sign(jwt.SigningMethodHS512, secret, "", valid), nil},
+		{"RS256", allKeys, sign(jwt.SigningMethodRS256, rsaKey, "rsa", valid), nil},
+		{"RS256 without kid", allKeys, sign(jwt.SigningMethodRS256, rsaKey, "", valid), nil},
+		{"PS384", allKeys, sign(jwt.SigningMethodPS384, rsaKey, "rsa", valid), nil},
+		{"ES256", allKeys, sign(jwt.SigningMethodES256, ecKey, "ec", valid), nil},
+		{"HS256 with another secret", allKeys, sign(jwt.SigningMethodHS256, []byte("another secret"), "", valid), jwt.ErrTokenSignatureInvalid},
+		{"alg none", allKeys, unsigned(`{"alg":"none","typ":"JWT"}`, `{"sub":"alice","exp":4102444800}`), jwt.ErrTokenSignatureInvalid},
+		{"alg None", allKeys, unsigned(`{"alg":"None","typ":"JWT"}`, `{"sub":"alice","exp":4102444800}`), jwt.ErrTokenUnverifiable},
+		{"HS256 signed with the RSA public key", rsaOnly, sign(jwt.SigningMethodHS256, rsaPublicKeyPEM, "", valid), jwt.ErrTokenUnverifiable},
+		{"HS256 signed with the RSA public key among secrets", allKeys, sign(jwt.SigningMethodHS256, rsaPublicKeyPEM, "", valid), jwt.ErrTokenSignatureInvalid},
+		{"RS256 with a wrong kid", allKeys, sign(jwt.SigningMethodRS256, rsaKey, "unknown", valid), jwt.ErrTokenUnverifiable},
+		{"RS256 with the kid of the EC key", allKeys, sign(jwt.SigningMethodRS256, rsaKey, "ec", valid), jwt.ErrTokenUnverifiable},
+		{"ES256 with a truncated signature", allKeys, resign(sign(jwt.SigningMethodES256, ecKey, "ec", valid), truncated), jwt.ErrTokenSignatureInvalid},
+		{"ES256 with a padded signature", allKeys, resign(sign(jwt.SigningMethodES256, ecKey, "ec", valid), padded), jwt.ErrTokenSignatureInvalid},
+		{"HS256 with tampered claims", allKeys, resign(sign(jwt.SigningMethodHS256, secret, "", valid), tampered), jwt.ErrTokenSignatureInvalid},
+		{"RS256 with tampered claims", allKeys, resign(sign(jwt.SigningMethodRS256, rsaKey, "rsa", valid), tampered), jwt.ErrTokenSignatureInvalid},
+		{"expired", allKeys, sign(jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "exp": now.Add(-2 * jwtLeeway).Unix()}), jwt.ErrTokenExpired},
+		{"expired within the leeway", allKeys, sign(jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "exp": now.Add(-jwtLeeway / 2).Unix()}), nil},
+		{"not valid yet", allKeys, sign(jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(2 * jwtLeeway).Unix()}), jwt.ErrTokenNotValidYet},
+		{"without exp", allKeys, sign(jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "alice"}), jwt.ErrTokenRequiredClaimMissing},
+		{"not a JWT", allKeys, "not.a.jwt", jwt.ErrTokenMalformed},
+		{"RFC 7515 A.1", rfcOnly, rfc7515HS256Token, jwt.ErrTokenExpired},
+		{"RFC 7515 A.1 with another key", &jwtAuthenticator{staticKeys: []jwtKey{{key: secret}}}, rfc7515HS256Token, jwt.ErrTokenSignatureInvalid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := test.authenticator.authenticate(test.token)
+			switch {
+			case test.err == nil && err != nil:
+				t.Errorf("authenticate() failed: %v", err)
+			case test.err == nil && principal.subject != "alice":
+				t.Errorf("authenticate() = %q, expected alice", principal.subject)
+			case test.err != nil && !errors.Is(err, test.err):
+				t.Errorf("authenticate() error = %v, expected %v", err, test.err)
+			}
+		})
+	}
+
+	t.Run("without sub", func(t *testing.T) {
+		if _, err := allKeys.authenticate(sign(jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"exp": now.Add(time.Hour).Unix()})); err == nil {
+			t.Error("authenticate() accepted a token without sub claim")
+		}
+	})
+}
+
+func TestJWKSKeysFetchedOnce(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write([]byte(`{"keys": [{"kty": "EC", "kid": "k1", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	}))
+	defer server.Close()
+	jwtAuth := &jwtAuthenticator{jwksURL: server.URL, httpClient: server.Client()}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys, err := jwtAuth.keys("k1")
+			if err == nil && len(keys) != 1 {
+				t.Errorf("keys() returned %d keys, expected 1", len(keys))
+			}
+			errs <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for fetches.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// The cached keys can be read while they are fetched.
+	if !jwtAuth.mu.TryRLock() {
+		t.Error("the keys are locked while they are fetched")
+	} else {
+		jwtAuth.mu.RUnlock()
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("keys() failed: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("the JWKS URL has been fetched %d times, expected 1", fetches.Load())
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"strings"
+)
+
+// AuthorProfile represents the optional profile of an author, stored apart from the articles.
+type AuthorProfile struct {
+	Name    string `json:"name" validate:"required"`                   // Name is the name of the author, as set on the articles.
+	Bio     string `json:"bio,omitempty" validate:"omitempty"`         // Bio is a short biography of the author.
+	Website string `json:"website,omitempty" validate:"omitempty,url"` // Website is the URL of the author website.
+}
+
+// AuthorCount represents an author along with the number of articles written.
+type AuthorCount struct {
+	Author string `json:"author"` // Author is the name of the author.
+	Count  int64  `json:"count"`  // Count is the number of articles written by the author.
+}
+
+// authorsKeysPrefix is the prefix of the Database keys holding the author profiles.
+var authorsKeysPrefix = "author:"
+
+// getAllAuthors returns all the authors along with their number of articles, the most prolific first.
+// The counts are computed by the database using FT.AGGREGATE, through db.Facets, up to maxStatsValues authors.
+func getAllAuthors(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	if err := isQueryParamsExpected(r.URL.Query(), nil); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	facetCounts, err := db.Facets(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), nil, db.SearchOptions{Required: visibleArticlesFilters(ctx)}, []string{"author"}, maxStatsValues)
+	if err != nil {
+		handleError(w, "Database Error while counting authors", err, http.StatusInternalServerError)
+		return
+	}
+
+	authors := make([]AuthorCount, len(facetCounts["author"]))
+	for i, count := range facetCounts["author"] {
+		authors[i] = AuthorCount{Author: count.Value, Count: count.Count}
+	}
+	responseJSON(w, authors, http.StatusOK)
+}
+
+// getAuthorArticles returns a page of the articles written by the author with the provided name.
+// Articles are searched with an exact phrase query on the author field and then filtered on the exact name.
+// The page is controlled by the limit and offset query parameters, see parsePaginationParams.
+func getAuthorArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	name := r.PathValue("name")
+	queryParams := r.URL.Query()
+
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	searchParameters := []db.SearchParams{{Param: "author", Type: db.StringType, Value: []string{name}, ExactPhrase: true}}
+	searchOptions := db.SearchOptions{SortBy: "title", Offset: offset, Limit: limit, Required: visibleArticlesFilters(ctx)}
+	searchResult, err := db.Search[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions)
+	if err != nil {
+		handleError(w, fmt.Sprintf("Database Error while searching articles of %s", name), err, http.StatusInternalServerError)
+		return
+	}
+
+	page := ArticlesPage{
+		Articles: []Article{},
+		Total:    int(searchResult.Total),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, searchHit := range searchResult.Hits {
+		// The phrase query also matches longer names containing the requested one
+		if strings.EqualFold(searchHit.Item.Author, name) {
+			page.Articles = append(page.Articles, searchHit.Item)
+		}
+	}
+	responseJSON(w, page, http.StatusOK)
+}
+
+// getAuthorProfile returns the profile of the author with the provided name.
+// If no profile has been stored for this author, it returns an HTTP 404 Not Found response.
+func getAuthorProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	name := r.PathValue("name")
+
+	result, err := db.JSONGet(ctx, databaseClient, tenantKey(ctx, authorsKeysPrefix+name))
+	if err != nil {
+		handleError(w, "Failed to retrieve author profile from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if result == "" {
+		handleError(w, "Author profile not found", withErrorCode(ErrorCodeAuthorNotFound, fmt.Errorf("no profile found for author %s", name)), http.StatusNotFound)
+		return
+	}
+
+	var profile AuthorProfile
+	if err := json.Unmarshal([]byte(result), &profile); err != nil {
+		handleError(w, "Failed to parse author profile data", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, profile, http.StatusOK)
+}
+
+// updateAuthorProfile creates or replaces the profile of the author with the provided name.
+// The name of the profile is always the one of the path, the profile is validated and stored using JSONSet.
+func updateAuthorProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	name := r.PathValue("name")
+
+	var profile AuthorProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	profile.Name = name
+
+	if err := validateStruct(profile); err != nil {
+		handleError(w, "Validation failed for author profile", err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.JSONSet(ctx, databaseClient, tenantKey(ctx, authorsKeysPrefix+name), "$", profile); err != nil {
+		handleError(w, "Failed to store author profile in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, profile, http.StatusOK)
+}
+
+// deleteAuthorProfile deletes the profile of the author with the provided name, the articles are left untouched.
+// If no profile has been stored for this author, it returns an HTTP 404 Not Found response.
+func deleteAuthorProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	name := r.PathValue("name")
+
+	deleted, err := db.Del(ctx, databaseClient, tenantKey(ctx, authorsKeysPrefix+name))
+	if err != nil {
+		handleError(w, "Failed to delete author profile from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		handleError(w, "Author profile not found", withErrorCode(ErrorCodeAuthorNotFound, fmt.Errorf("no profile found for author %s", name)), http.StatusNotFound)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("profile of author %s successfully deleted", name)}, http.StatusOK)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// backupFormatVersion is the version of the layout of the backup archives, increased on incompatible changes.
+	backupFormatVersion = 1
+	// The files of a backup archive.
+	backupManifestFile = "manifest.json"
+	backupIndexFile    = "index.json"
+	backupArticlesFile = "articles.ndjson"
+	backupTrashFile    = "trash.ndjson"
+)
+
+// BackupManifest describes the content of a backup archive.
+type BackupManifest struct {
+	Version         int   `json:"version"`         // Version is the version of the layout of the archive, see backupFormatVersion.
+	CreatedAt       int64 `json:"createdAt"`       // CreatedAt is the time the backup started, as a Unix timestamp in seconds.
+	Articles        int   `json:"articles"`        // Articles is the number of articles of the archive.
+	TrashedArticles int   `json:"trashedArticles"` // TrashedArticles is the number of articles of the archive that are in the trash.
+}
+
+// BackupIndex holds the metadata of the articles search index at the time of a backup.
+type BackupIndex struct {
+	Name string         `json:"name"` // Name is the name of the search index.
+	Info map[string]any `json:"info"` // Info is the information about the search index (e.g. its attributes), as reported by FT.INFO.
+}
+
+// backupArticles streams a complete dump of the articles as a ZIP archive suitable for backup, holding:
+//   - articles.ndjson and trash.ndjson, the articles and the articles in the trash as newline delimited JSON
+//   - index.json, the metadata of the search index (see BackupIndex)
+//   - manifest.json, written last, describing the archive (see BackupManifest)
+//
+// Articles are retrieved in batches as their keys are scanned (see scanArticles) and written to the archive as they come,
+// so that the whole collection is never held in memory. An archive without manifest is incomplete.
+func backupArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	info, err := db.IndexInfo(ctx, databaseClient, tenantIndexName(ctx, searchIndexName))
+	if err != nil {
+		handleError(w, "Failed to retrieve search index information", err, http.StatusInternalServerError)
+		return
+	}
+	manifest := BackupManifest{Version: backupFormatVersion, CreatedAt: time.Now().Unix()}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="articles-backup-%s.zip"`, time.Unix(manifest.CreatedAt, 0).UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(w)
+	err = writeBackupJSON(archive, backupIndexFile, BackupIndex{Name: tenantIndexName(ctx, searchIndexName), Info: info})
+	if err == nil {
+		manifest.Articles, err = writeBackupArticles(ctx, archive, backupArticlesFile, keysPrefix)
+	}
+	if err == nil {
+		manifest.TrashedArticles, err = writeBackupArticles(ctx, archive, backupTrashFile, trashKeysPrefix)
+	}
+	if err == nil {
+		err = writeBackupJSON(archive, backupManifestFile, manifest)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to write backup, the archive is incomplete", "Error:", err)
+	}
+}
+
+// writeBackupJSON writes v as a JSON file of the archive.
+func writeBackupJSON(archive *zip.Writer, name string, v any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeBackupArticles writes the articles of the tenant ctx is scoped to stored at keys with the given prefix as a
+// newline delimited JSON file of the archive, and returns the number of articles written.
+func writeBackupArticles(ctx context.Context, archive *zip.Writer, name string, prefix string) (int, error) {
+	file, err := archive.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	encoder := json.NewEncoder(file)
+	err = scanArticles(ctx, prefix, nil, func(articles []Article) error {
+		count += len(articles)
+		return writeNDJSONArticles(encoder, articles, nil)
+	})
+	return count, err
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// categoriesKey is the key of the hash holding the categories, by ID.
+const categoriesKey = "categories"
+
+// Category represents a category of articles, the categories forming a tree through their parent.
+type Category struct {
+	Id     string `json:"id"`                       // Id is the unique identifier of the category, as set on the articles.
+	Name   string `json:"name" validate:"required"` // Name is the name of the category, as displayed.
+	Parent string `json:"parent,omitempty"`         // Parent is the ID of the parent category, empty for a top level category.
+}
+
+// CategoryNode represents a category along with its subcategories.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"` // Children holds the subcategories, sorted by name.
+}
+
+// fetchCategories returns all the categories of the tenant ctx is scoped to, by ID.
+func fetchCategories(ctx context.Context) (map[string]Category, error) {
+	records, err := db.HashGetAll(ctx, databaseClient, tenantKey(ctx, categoriesKey))
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[string]Category, len(records))
+	for id, record := range records {
+		var category Category
+		if err := json.Unmarshal([]byte(record), &category); err != nil {
+			return nil, fmt.Errorf("unable to validate the structure of stored Category %s: %v", id, err)
+		}
+		categories[id] = category
+	}
+	return categories, nil
+}
+
+// categoryTree returns the node of the category with the given ID along with all its descendants.
+func categoryTree(categories map[string]Category, id string) CategoryNode {
+	node := CategoryNode{Category: categories[id], Children: []CategoryNode{}}
+	for _, category := range categories {
+		if category.Parent == id && category.Id != id {
+			node.Children = append(node.Children, categoryTree(categories, category.Id))
+		}
+	}
+	sortCategoryNodes(node.Children)
+	return node
+}
+
+// sortCategoryNodes sorts categories by name, then by ID.
+func sortCategoryNodes(nodes []CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].Id < nodes[j].Id
+	})
+}
+
+// categoryDescendants returns the IDs of the category with the given ID and of all its descendants.
+func categoryDescendants(categories map[string]Category, id string) []string {
+	ids := []string{id}
+	for _, category := range categories {
+		if category.Parent == id && category.Id != id {
+			ids = append(ids, categoryDescendants(categories, category.Id)...)
+		}
+	}
+	return ids
+}
+
+// categoryNotFound responds with an HTTP 404 Not Found error for the category with the given ID.
+func categoryNotFound(w http.ResponseWriter, id string) {
+	handleError(w, "Category not found", withErrorCode(ErrorCodeCategoryNotFound, fmt.Errorf("no category found with ID %s", id)), http.StatusNotFound)
+}
+
+// getAllCategories handles GET /categories, returning the tree of the categories, the top level categories first.
+func getAllCategories(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	if err := isQueryParamsExpected(r.URL.Query(), nil); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	categories, err := fetchCategories(ctx)
+	if err != nil {
+		handleError(w, "Failed to retrieve categories from Database", err, http.StatusInternalServerError)
+		return
+	}
+
+	roots := []CategoryNode{}
+	for _, category := range categories {
+		// A category whose parent has been deleted is shown at the top level
+		if _, found := categories[category.Parent]; category.Parent == "" || !found {
+			roots = append(roots, categoryTree(categories, category.Id))
+		}
+	}
+	sortCategoryNodes(roots)
+	responseJSON(w, roots, http.StatusOK)
+}
+
+// getCategory handles GET /category/{id}, returning the category with the provided ID along with its subcategories.
+func getCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	categories, err := fetchCategories(ctx)
+	if err != nil {
+		handleError(w, "Failed to retrieve categories from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if _, found := categories[id]; !found {
+		categoryNotFound(w, id)
+		return
+	}
+	responseJSON(w, categoryTree(categories, id), http.StatusOK)
+}
+
+// updateCategory handles PUT /category/{id}, creating or replacing the category with the provided ID.
+// The parent must be an existing category which is neither the category itself nor one of its descendants.
+func updateCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+
+	var category Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	category.Id = id
+	if err := validateStruct(category); err != nil {
+		handleError(w, "Validation failed for category", err, http.StatusBadRequest)
+		return
+	}
+
+	if category.Parent != "" {
+		categories, err := fetchCategories(ctx)
+		if err != nil {
+			handleError(w, "Failed to retrieve categories from Database", err, http.StatusInternalServerError)
+			return
+		}
+		if _, found := categories[category.Parent]; !found {
+			handleError(w, "Validation failed for category", fmt.Errorf("no parent category found with ID %s", category.Parent), http.StatusBadRequest)
+			return
+		}
+		for _, descendant := range categoryDescendants(categories, id) {
+			if descendant == category.Parent {
+				handleError(w, "Validation failed for category", errors.New("a category can't be a descendant of itself"), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	record, err := json.Marshal(category)
+	if err != nil {
+		handleError(w, "Failed to encode category", err, http.StatusInternalServerError)
+		return
+	}
+	if err := db.HashSet(ctx, databaseClient, tenantKey(ctx, categoriesKey), id, record); err != nil {
+		handleError(w, "Failed to store category in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, category, http.StatusOK)
+}
+
+// deleteCategory handles DELETE /category/{id}, deleting the category with the provided ID, its articles being left
+// untouched. A category holding subcategories can't be deleted, it responds with an HTTP 409 Conflict error instead.
+func deleteCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	categories, err := fetchCategories(ctx)
+	if err != nil {
+		handleError(w, "Failed to retrieve categories from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if _, found := categories[id]; !found {
+		categoryNotFound(w, id)
+		return
+	}
+	if children := categoryTree(categories, id).Children; len(children) > 0 {
+		handleError(w, "Failed to delete category", fmt.Errorf("category %s holds %d subcategories", id, len(children)), http.StatusConflict)
+		return
+	}
+
+	if _, err := db.HashDel(ctx, databaseClient, tenantKey(ctx, categoriesKey), id); err != nil {
+		handleError(w, "Failed to delete category from Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("category with ID %s successfully deleted", id)}, http.StatusOK)
+}
+
+// getCategoryArticles handles GET /category/{id}/articles, returning a page of the articles of the category with the
+// provided ID or of any of its descendants, sorted by title. The page is controlled by the limit and offset query
+// parameters, see parsePaginationParams.
+func getCategoryArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	queryParams := r.URL.Query()
+
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	categories, err := fetchCategories(ctx)
+	if err != nil {
+		handleError(w, "Failed to retrieve categories from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if _, found := categories[id]; !found {
+		categoryNotFound(w, id)
+		return
+	}
+
+	searchParameters := []db.SearchParams{{Param: "category", Type: db.TagType, Value: []string{strings.Join(categoryDescendants(categories, id), "|")}}}
+	searchOptions := db.SearchOptions{SortBy: "title", Offset: offset, Limit: limit, Required: visibleArticlesFilters(ctx)}
+	searchResult, err := db.Search[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions)
+	if err != nil {
+		handleError(w, fmt.Sprintf("Database Error while searching articles of category %s", id), err, http.StatusInternalServerError)
+		return
+	}
+
+	page := ArticlesPage{Articles: []Article{}, Total: int(searchResult.Total), Limit: limit, Offset: offset}
+	for _, searchHit := range searchResult.Hits {
+		page.Articles = append(page.Articles, searchHit.Item)
+	}
+	responseJSON(w, page, http.StatusOK)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// collectionsKeysPrefix is the prefix of the Database keys holding the collections.
+const collectionsKeysPrefix = "collection:"
+
+// Collection represents an ordered collection of articles, such as the parts of a series.
+type Collection struct {
+	Id          string   `json:"id"`                                                    // Id is the unique identifier of the collection, generated on creation when empty.
+	Title       string   `json:"title" validate:"required,maxTitleLength"`              // Title is the title of the collection.
+	Description string   `json:"description,omitempty"`                                 // Description describes the collection.
+	ArticleIds  []string `json:"articleIds" validate:"omitempty,unique,dive,validUuid"` // ArticleIds holds the IDs of the articles of the collection, in order.
+	CreatedAt   int64    `json:"createdAt,omitempty"`                                   // CreatedAt is the time the collection was created, as a Unix timestamp in seconds.
+	UpdatedAt   int64    `json:"updatedAt,omitempty"`                                   // UpdatedAt is the last time the collection was written, as a Unix timestamp in seconds.
+}
+
+// CollectionArticles represents a collection along with its articles.
+type CollectionArticles struct {
+	Collection
+	// Articles holds the articles of the collection in order, the articles deleted since they were added being left out.
+	Articles []Article `json:"articles"`
+}
+
+// getStoredCollection returns the collection with the given ID, nil when there is no such collection.
+func getStoredCollection(ctx context.Context, id string) (*Collection, error) {
+	result, err := db.JSONGet(ctx, databaseClient, tenantKey(ctx, collectionsKeysPrefix+id))
+	if err != nil || result == "" {
+		return nil, err
+	}
+	var collection Collection
+	if err := json.Unmarshal([]byte(result), &collection); err != nil {
+		return nil, fmt.Errorf("unable to parse collection data: %v", err)
+	}
+	return &collection, nil
+}
+
+// collectionNotFound responds with an HTTP 404 Not Found error for the collection with the given ID.
+func collectionNotFound(w http.ResponseWriter, id string) {
+	handleError(w, "Collection not found", withErrorCode(ErrorCodeCollectionNotFound, fmt.Errorf("no collection found with ID %s", id)), http.StatusNotFound)
+}
+
+// fetchCollectionArticles retrieves the articles with the given IDs, in the same order, leaving out the ones which do
+// not exist.
+func fetchCollectionArticles(ctx context.Context, ids []string) ([]Article, error) {
+	if len(ids) == 0 {
+		return []Article{}, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = tenantKey(ctx, keysPrefix+id)
+	}
+	return fetchArticles(ctx, keys)
+}
+
+// decodeCollection decodes and validates the collection of a request, responding with an HTTP 400 Bad Request error
+// when it is invalid or refers to articles which do not exist. It reports whether the collection is valid.
+func decodeCollection(w http.ResponseWriter, r *http.Request, collection *Collection) bool {
+	ctx := requestContext(r)
+	if err := json.NewDecoder(r.Body).Decode(collection); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return false
+	}
+	if collection.ArticleIds == nil {
+		collection.ArticleIds = []string{}
+	}
+	if err := validateStruct(*collection); err != nil {
+		handleError(w, "Validation failed for collection", err, http.StatusBadRequest)
+		return false
+	}
+
+	articles, err := fetchCollectionArticles(ctx, collection.ArticleIds)
+	if err != nil {
+		handleError(w, "Failed to retrieve articles from Database", err, http.StatusInternalServerError)
+		return false
+	}
+	if len(articles) != len(collection.ArticleIds) {
+		for _, id := range collection.ArticleIds {
+			if !slices.ContainsFunc(articles, func(article Article) bool { return article.Id == id }) {
+				handleError(w, "Validation failed for collection", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusBadRequest)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// createCollection handles POST /collections, creating a collection of articles, a unique ID being generated when
+// none is provided. If a collection with the same ID already exists, it responds with an HTTP 409 Conflict error.
+func createCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	var collection Collection
+	if !decodeCollection(w, r, &collection) {
+		return
+	}
+	if collection.Id == "" {
+		collection.Id = uuid.New().String()
+	}
+	collection.CreatedAt = time.Now().Unix()
+	collection.UpdatedAt = collection.CreatedAt
+
+	exists, err := db.Exists(ctx, databaseClient, tenantKey(ctx, collectionsKeysPrefix+collection.Id))
+	if err != nil {
+		handleError(w, "Error checking if collection exists", err, http.StatusInternalServerError)
+		return
+	}
+	if exists != 0 {
+		handleError(w, "Failed to create collection", withErrorCode(ErrorCodeDuplicateId, fmt.Errorf("a collection with ID %s already exists", collection.Id)), http.StatusConflict)
+		return
+	}
+	if _, err := db.JSONSet(ctx, databaseClient, tenantKey(ctx, collectionsKeysPrefix+collection.Id), "$", collection); err != nil {
+		handleError(w, "Failed to store collection in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, collection, http.StatusCreated)
+}
+
+// getCollection handles GET /collection/{id}, returning the collection with the provided ID along with its articles,
+// in the order of the collection.
+func getCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	collection, err := getStoredCollection(ctx, id)
+	if err != nil {
+		handleError(w, "Failed to retrieve collection from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if collection == nil {
+		collectionNotFound(w, id)
+		return
+	}
+
+	articles, err := fetchCollectionArticles(ctx, collection.ArticleIds)
+	if err != nil {
+		handleError(w, "Failed to retrieve articles from Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CollectionArticles{Collection: *collection, Articles: articles}, http.StatusOK)
+}
+
+// updateCollection handles PUT /collection/{id}, replacing the collection with the provided ID, e.g. to add the next
+// part of a series. The ID of the collection is always the one of the path.
+func updateCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	var collection Collection
+	if !decodeCollection(w, r, &collection) {
+		return
+	}
+	storedCollection, err := getStoredCollection(ctx, id)
+	if err != nil {
+		handleError(w, "Failed to retrieve collection from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if storedCollection == nil {
+		collectionNotFound(w, id)
+		return
+	}
+	collection.Id = id
+	collection.CreatedAt = storedCollection.CreatedAt
+	collection.UpdatedAt = time.Now().Unix()
+
+	if _, err := db.JSONSet(ctx, databaseClient, tenantKey(ctx, collectionsKeysPrefix+id), "$", collection); err != nil {
+		handleError(w, "Failed to store collection in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, collection, http.StatusOK)
+}
+
+// deleteCollection handles DELETE /collection/{id}, deleting the collection with the provided ID, its articles being
+// left untouched.
+func deleteCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	deleted, err := db.Del(ctx, databaseClient, tenantKey(ctx, collectionsKeysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to delete collection from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		collectionNotFound(w, id)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("collection with ID %s successfully deleted", id)}, http.StatusOK)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	commentsKeysPrefix     = "comment:"       // commentsKeysPrefix prefixes the keys of the comments.
+	commentFlagsKeysPrefix = "flags:comment:" // commentFlagsKeysPrefix prefixes the keys of the sets of the actors who flagged a comment.
+	commentsIndexName      = "idx_comments"   // commentsIndexName is the search index of the comments.
+
+	// visibleCommentStatus, pendingCommentStatus and hiddenCommentStatus are the moderation statuses of a comment,
+	// only the visible comments being listed along with an article.
+	visibleCommentStatus = "visible"
+	pendingCommentStatus = "pending"
+	hiddenCommentStatus  = "hidden"
+)
+
+// Comment represents a comment written on an article, stored apart from the article.
+type Comment struct {
+	Id        string `json:"id"`                                         // Id is the unique identifier of the comment, set by the server.
+	ArticleId string `json:"articleId"`                                  // ArticleId is the ID of the article commented.
+	Author    string `json:"author" validate:"required"`                 // Author is the actor who wrote the comment, set by the server (see requestActor).
+	Content   string `json:"content" validate:"required,maxContentSize"` // Content is the content of the comment, sanitized like the articles.
+	CreatedAt int64  `json:"createdAt"`                                  // CreatedAt is the time the comment was written, as a Unix timestamp in seconds.
+	Status    string `json:"status"`                                     // Status is the moderation status of the comment, see visibleCommentStatus.
+	Flags     int64  `json:"flags"`                                      // Flags is the number of readers who flagged the comment.
+}
+
+// CommentsPage represents a single page of comments along with the paging metadata.
+type CommentsPage struct {
+	Comments []Comment `json:"comments"` // Comments holds the comments of the current page.
+	Total    int64     `json:"total"`    // Total is the number of comments available across all pages.
+	Limit    int       `json:"limit"`    // Limit is the maximum number of comments returned in a page.
+	Offset   int       `json:"offset"`   // Offset is the position of the first comment of the page.
+}
+
+// CommentModeration is the moderation of a comment, as submitted to PATCH /admin/comments/{commentId}.
+type CommentModeration struct {
+	Status string `json:"status" validate:"required,oneof=visible pending hidden"` // Status is the new moderation status of the comment.
+}
+
+// commentsIndexSchema returns the schema of the comments search index of the tenant ctx is scoped to.
+func commentsIndexSchema(ctx context.Context) db.IndexSchema {
+	return db.IndexSchema{
+		Prefixes: []string{tenantKey(ctx, commentsKeysPrefix)},
+		Fields: []db.IndexField{
+			{Path: "$.articleId", Alias: "articleId", Type: db.TagField},
+			{Path: "$.status", Alias: "status", Type: db.TagField},
+			{Path: "$.flags", Alias: "flags", Type: db.NumericField, Sortable: true},
+			{Path: "$.createdAt", Alias: "createdAt", Type: db.NumericField, Sortable: true},
+		},
+	}
+}
+
+// initializeCommentsIndex creates the comments search index of the tenant ctx is scoped to when it does not exist yet,
+// an existing index is left untouched.
+func initializeCommentsIndex(ctx context.Context) error {
+	indexName := tenantIndexName(ctx, commentsIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err != nil || exists {
+		return err
+	}
+	slog.InfoContext(ctx, "Creating the search index", "index", indexName)
+	return db.CreateIndex(ctx, databaseClient, indexName, commentsIndexSchema(ctx))
+}
+
+// getStoredComment returns the comment stored under the given key, nil when there is no such comment.
+func getStoredComment(ctx context.Context, key string) (*Comment, error) {
+	result, err := db.JSONGet(ctx, databaseClient, key)
+	if err != nil || result == "" {
+		return nil, err
+	}
+	var comment Comment
+	if err := json.Unmarshal([]byte(result), &comment); err != nil {
+		return nil, fmt.Errorf("unable to parse comment data: %v", err)
+	}
+	return &comment, nil
+}
+
+// commentNotFound responds with an HTTP 404 Not Found error for the comment with the given ID.
+func commentNotFound(w http.ResponseWriter, commentId string) {
+	handleError(w, "Comment not found", withErrorCode(ErrorCodeCommentNotFound, fmt.Errorf("no comment found with ID %s", commentId)), http.StatusNotFound)
+}
+
+// createComment handles POST /article/{id}/comments, adding a comment to the article with the provided ID.
+// The comment is pending until approved by a moderator when config.CommentsModeration is enabled, visible otherwise.
+// Its author is the actor of the request (see requestActor), whatever the author sent.
+func createComment(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	if !ensureArticleExists(ctx, w, id) {
+		return
+	}
+
+	var comment Comment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	comment.Id = uuid.New().String()
+	comment.ArticleId = id
+	comment.Author = requestActor(r)
+	comment.CreatedAt = time.Now().Unix()
+	comment.Flags = 0
+	comment.Status = visibleCommentStatus
+	if config.CommentsModeration {
+		comment.Status = pendingCommentStatus
+	}
+	if config.HTMLSanitization {
+		comment.Content = sanitizeHTML(comment.Content)
+	}
+
+	if err := validateStruct(comment); err != nil {
+		handleError(w, "Validation failed for comment", err, http.StatusBadRequest)
+		return
+	}
+	if _, err := db.JSONSet(ctx, databaseClient, tenantKey(ctx, commentsKeysPrefix+comment.Id), "$", comment); err != nil {
+		handleError(w, "Failed to store comment in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, comment, http.StatusCreated)
+}
+
+// getArticleComments handles GET /article/{id}/comments, returning a page of the visible comments of the article with
+// the provided ID, the oldest first. The page is controlled by the limit and offset query parameters,
+// see parsePaginationParams.
+func getArticleComments(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	queryParams := r.URL.Query()
+
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	if !ensureArticleExists(ctx, w, id) {
+		return
+	}
+
+	searchParameters := []db.SearchParams{
+		{Param: "articleId", Type: db.TagType, Value: []string{id}},
+		{Param: "status", Type: db.TagType, Value: []string{visibleCommentStatus}},
+	}
+	searchOptions := db.SearchOptions{SortBy: "createdAt", Offset: offset, Limit: limit}
+	respondCommentsPage(ctx, w, searchParameters, searchOptions)
+}
+
+// getModeratedComments handles GET /admin/comments, returning a page of the comments to moderate, the most flagged
+// first. The comments can be restricted to a moderation status with the status query parameter and to the flagged
+// ones with flagged=true. The page is controlled by the limit and offset query parameters, see parsePaginationParams.
+func getModeratedComments(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	invalidModerationError := "invalid comments moderation parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"status", "flagged", "limit", "offset"}); err != nil {
+		handleError(w, invalidModerationError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	var searchParameters []db.SearchParams
+	if queryParams.Has("status") {
+		status := queryParams.Get("status")
+		if status != visibleCommentStatus && status != pendingCommentStatus && status != hiddenCommentStatus {
+			err := fmt.Errorf("status must be one of %s, %s or %s", visibleCommentStatus, pendingCommentStatus, hiddenCommentStatus)
+			handleError(w, invalidModerationError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+			return
+		}
+		searchParameters = append(searchParameters, db.SearchParams{Param: "status", Type: db.TagType, Value: []string{status}})
+	}
+	if queryParams.Has("flagged") {
+		flagged, err := strconv.ParseBool(queryParams.Get("flagged"))
+		if err != nil {
+			handleError(w, invalidModerationError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("flagged must be a boolean")), http.StatusBadRequest)
+			return
+		}
+		if flagged {
+			searchParameters = append(searchParameters, db.SearchParams{Param: "flags", Type: db.NumberType, Ranges: []db.NumberRange{{Min: 1, Max: math.Inf(1)}}})
+		} else {
+			searchParameters = append(searchParameters, db.SearchParams{Param: "flags", Type: db.NumberType, Ranges: []db.NumberRange{{Min: 0, Max: 0}}})
+		}
+	}
+	searchOptions := db.SearchOptions{SortBy: "flags", SortDescending: true, Offset: offset, Limit: limit}
+	respondCommentsPage(ctx, w, searchParameters, searchOptions)
+}
+
+// respondCommentsPage searches the comments and responds with the page of comments found.
+func respondCommentsPage(ctx context.Context, w http.ResponseWriter, searchParameters []db.SearchParams, searchOptions db.SearchOptions) {
+	searchResult, err := db.Search[Comment](ctx, databaseClient, tenantIndexName(ctx, commentsIndexName), searchParameters, searchOptions)
+	if err != nil {
+		handleError(w, "Database Error while searching comments", err, http.StatusInternalServerError)
+		return
+	}
+	page := CommentsPage{Comments: []Comment{}, Total: searchResult.Total, Limit: searchOptions.Limit, Offset: searchOptions.Offset}
+	for _, searchHit := range searchResult.Hits {
+		page.Comments = append(page.Comments, searchHit.Item)
+	}
+	responseJSON(w, page, http.StatusOK)
+}
+
+// flagComment handles POST /article/{id}/comments/{commentId}/flag, a reader reporting a comment of the article with
+// the provided ID. The actors who flagged a comment are recorded, so that a comment is flagged once by each of them, and
+// its flags are the number of them (see db.SetUpdateAndCount). A visible comment is set back to pending once it has
+// been flagged by config.CommentsFlagsThreshold actors, so that it is reviewed by a moderator.
+func flagComment(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id, commentId := r.PathValue("id"), r.PathValue("commentId")
+	key := tenantKey(ctx, commentsKeysPrefix+commentId)
+
+	comment, err := getStoredComment(ctx, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve comment from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if comment == nil || comment.ArticleId != id {
+		commentNotFound(w, commentId)
+		return
+	}
+
+	flags, err := db.SetUpdateAndCount(ctx, databaseClient, tenantKey(ctx, commentFlagsKeysPrefix+commentId), requestActor(r), true, key, "$.flags")
+	if errors.Is(err, db.ErrNotFound) {
+		commentNotFound(w, commentId)
+		return
+	}
+	if err != nil {
+		handleError(w, "Failed to flag comment", err, http.StatusInternalServerError)
+		return
+	}
+	comment.Flags = flags
+	if config.CommentsFlagsThreshold > 0 && comment.Flags >= int64(config.CommentsFlagsThreshold) && comment.Status == visibleCommentStatus {
+		if _, err := db.JSONSet(ctx, databaseClient, key, "$.status", pendingCommentStatus); err != nil {
+			handleError(w, "Failed to store comment status", err, http.StatusInternalServerError)
+			return
+		}
+		comment.Status = pendingCommentStatus
+	}
+	responseJSON(w, comment, http.StatusOK)
+}
+
+// moderateComment handles PATCH /admin/comments/{commentId}, setting the moderation status of a comment.
+// Making a comment visible again clears its flags, as it has been reviewed.
+func moderateComment(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	commentId := r.PathValue("commentId")
+	key := tenantKey(ctx, commentsKeysPrefix+commentId)
+
+	var moderation CommentModeration
+	if err := json.NewDecoder(r.Body).Decode(&moderation); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	if err := validateStruct(moderation); err != nil {
+		handleError(w, "Validation failed for comment moderation", err, http.StatusBadRequest)
+		return
+	}
+
+	comment, err := getStoredComment(ctx, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve comment from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if comment == nil {
+		commentNotFound(w, commentId)
+		return
+	}
+	comment.Status = moderation.Status
+	if comment.Status == visibleCommentStatus {
+		comment.Flags = 0
+		if _, err := db.Del(ctx, databaseClient, tenantKey(ctx, commentFlagsKeysPrefix+commentId)); err != nil {
+			handleError(w, "Failed to clear comment flags", err, http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := db.JSONSet(ctx, databaseClient, key, "$", comment); err != nil {
+		handleError(w, "Failed to store comment in Database", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, comment, http.StatusOK)
+}
+
+// deleteComment handles DELETE /admin/comments/{commentId}, permanently deleting a comment along with its flags.
+func deleteComment(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	commentId := r.PathValue("commentId")
+
+	deleted, err := db.Del(ctx, databaseClient, tenantKey(ctx, commentsKeysPrefix+commentId), tenantKey(ctx, commentFlagsKeysPrefix+commentId))
+	if err != nil {
+		handleError(w, "Failed to delete comment from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		commentNotFound(w, commentId)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("comment with ID %s successfully deleted", commentId)}, http.StatusOK)
+}
+
+// deleteArticlesComments permanently deletes the comments of the articles of the tenant ctx is scoped to with the
+// given IDs along with their flags, by batches of streamBatchSize comments as they are found.
+func deleteArticlesComments(ctx context.Context, ids []string) error {
+	searchParameters := []db.SearchParams{{Param: "articleId", Type: db.TagType, Value: []string{strings.Join(ids, "|")}}}
+	searchOptions := db.SearchOptions{Limit: streamBatchSize, Paths: []string{"$.id"}}
+	for {
+		searchResult, err := db.Search[Comment](ctx, databaseClient, tenantIndexName(ctx, commentsIndexName), searchParameters, searchOptions)
+		if err != nil {
+			return fmt.Errorf("unable to search comments: %v", err)
+		}
+		if len(searchResult.Hits) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, 2*len(searchResult.Hits))
+		for _, searchHit := range searchResult.Hits {
+			keys = append(keys, searchHit.Key, tenantKey(ctx, commentFlagsKeysPrefix+searchHit.Item.Id))
+		}
+		if _, err := db.Del(ctx, databaseClient, keys...); err != nil {
+			return fmt.Errorf("unable to delete comments: %v", err)
+		}
+	}
+}
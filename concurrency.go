@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionPath is the JSON path of the version of the stored articles.
+const versionPath = "$.version"
+
+// errVersionRequired is returned when an article is updated without telling which version of it is updated.
+var errVersionRequired = errors.New("the version of the article being updated must be provided, either as version field or as If-Match header (version or ETag)")
+
+// checkExpectedVersion checks that the stored article is the version a client expects to update: the one designated
+// by the If-Match header when provided, either a version (e.g. "3") or the ETag of the article, the given payload version
+// otherwise. It responds with an HTTP 428 Precondition Required when the client provided none of them, or an HTTP 409
+// Conflict when the stored article is not the expected version, and returns false when the write must not go on.
+func checkExpectedVersion(w http.ResponseWriter, r *http.Request, payloadVersion int64, storedArticle *Article) bool {
+	expected := payloadVersion
+	if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch != "" {
+		etag, err := computeETag(*storedArticle)
+		if err != nil {
+			handleError(w, "Failed to compute ETag", err, http.StatusInternalServerError)
+			return false
+		}
+		if etagMatches(ifMatch, etag) {
+			return true
+		}
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+		if err != nil {
+			handleError(w, "Version conflict", withErrorCode(ErrorCodeVersionConflict, fmt.Errorf("article with ID %s does not match %s", storedArticle.Id, ifMatch)), http.StatusConflict)
+			return false
+		}
+		expected = version
+	}
+	if expected == 0 {
+		handleError(w, "Version required", errVersionRequired, http.StatusPreconditionRequired)
+		return false
+	}
+	if expected != storedArticle.Version {
+		handleError(w, "Version conflict", withErrorCode(ErrorCodeVersionConflict,
+			fmt.Errorf("article with ID %s is at version %d, not %d", storedArticle.Id, storedArticle.Version, expected)), http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// versionedArticleSet returns the write of an article at key with db.JSONMSetIfVersion, provided that the stored
+// article is still previous (nil when the article is created). The previous version of an updated article is recorded
+// as its latest revision (see ArticleRevision) by the write itself, so that an update is never left without its revision,
+// and the key of the article expires at its expiresAt time, or is made persistent when it has none, along with the write.
+func versionedArticleSet(ctx context.Context, key string, previous *Article, article Article) db.VersionedJSONSet {
+	set := db.VersionedJSONSet{Key: key, Value: article, ExpireAt: article.ExpiresAt}
+	if previous != nil {
+		set.Version = previous.Version
+		set.HistoryKey = tenantKey(ctx, revisionsKeysPrefix+previous.Id)
+		set.History = ArticleRevision{RevisedAt: time.Now().Unix(), Article: *previous}
+	}
+	return set
+}
+
+// setArticleIfVersion writes an article at key provided that the stored article is still previous (nil when the
+// article is created), see versionedArticleSet.
+func setArticleIfVersion(ctx context.Context, key string, previous *Article, article Article) error {
+	return db.JSONMSetIfVersion(ctx, databaseClient, versionPath, []db.VersionedJSONSet{versionedArticleSet(ctx, key, previous, article)})
+}
+
+// handleVersionedWriteError handles the failure of a write made with db.JSONMSetIfVersion, which fails with an
+// HTTP 409 Conflict when an article has been changed (or created or deleted) concurrently.
+func handleVersionedWriteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrVersionMismatch) || errors.Is(err, db.ErrNotFound) {
+		handleError(w, "Version conflict, the article has been changed concurrently", withErrorCode(ErrorCodeVersionConflict, err), http.StatusConflict)
+		return
+	}
+	handleError(w, "Failed to update article in Database", err, http.StatusInternalServerError)
+}
+
+// patchVersion returns the version of the article a patch expects to update, 0 when the patch does not tell:
+// the version member of a merge patch, or the value of a test operation on /version of a JSON Patch.
+func patchVersion(mergePatchDocument any, jsonPatchDocument []jsonPatchOperation) int64 {
+	if document, isObject := mergePatchDocument.(map[string]any); isObject {
+		if version, isNumber := document["version"].(float64); isNumber {
+			return int64(version)
+		}
+	}
+	for _, operation := range jsonPatchDocument {
+		var version int64
+		if operation.Op == "test" && operation.Path == "/version" && json.Unmarshal(operation.Value, &version) == nil {
+			return version
+		}
+	}
+	return 0
+}
@@ -0,0 +1,686 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of the service that can be tuned through environment variables.
+type Config struct {
+	// SearchWeights holds the weight of each full-text field when ranking search results, from AS_SEARCH_WEIGHTS
+	// formatted as a comma separated list of field=weight (e.g. title=5,content=1).
+	SearchWeights map[string]float64
+	// Embedder is the kind of embedder computing the articles vector, from AS_EMBEDDER: hashing (default) or http.
+	Embedder string
+	// EmbedderURL is the URL of the embeddings API used by the http embedder, from AS_EMBEDDER_URL.
+	EmbedderURL string
+	// EmbedderModel is the model requested from the embeddings API, from AS_EMBEDDER_MODEL.
+	EmbedderModel string
+	// EmbedderAPIKey is the key sent to the embeddings API, from AS_EMBEDDER_API_KEY.
+	EmbedderAPIKey string
+	// EmbeddingDimensions is the number of dimensions of the articles vector, from AS_EMBEDDING_DIMENSIONS.
+	// It must match the DIM of the embedding field of the search index.
+	EmbeddingDimensions int
+	// IndexStopwords replaces the default stopwords of the search index, from AS_INDEX_STOPWORDS
+	// formatted as a space separated list. Setting AS_INDEX_STOPWORDS to none disables stopwords (IndexStopwordsDisabled).
+	IndexStopwords         []string
+	IndexStopwordsDisabled bool
+	// IndexNoStemFields lists the text fields indexed without stemming, from AS_INDEX_NOSTEM_FIELDS
+	// formatted as a space separated list (e.g. title author).
+	IndexNoStemFields []string
+	// IdempotencyTTL is how long the response to a request sent with an Idempotency-Key is kept to be replayed,
+	// from AS_IDEMPOTENCY_TTL formatted as a duration (e.g. 24h).
+	IdempotencyTTL time.Duration
+	// JobWorkers is the number of background jobs (e.g. reindex, import) run at once, from AS_JOB_WORKERS.
+	JobWorkers int
+	// EventsStreamMaxLen is the approximate number of article events kept by the Redis Stream of the changes,
+	// from AS_EVENTS_STREAM_MAXLEN.
+	EventsStreamMaxLen int
+	// WebhookPrivateTargets reports whether the webhooks may be notified on loopback, private or link-local addresses,
+	// from AS_WEBHOOK_PRIVATE_TARGETS. They are refused by default, so that the webhooks can't reach the internal
+	// services of the network of the service.
+	WebhookPrivateTargets bool
+	// AuditStreamMaxLen is the approximate number of entries kept by the Redis Stream of the audit log, from
+	// AS_AUDIT_STREAM_MAXLEN. The audit log is never trimmed when 0.
+	AuditStreamMaxLen int
+	// KafkaBrokers lists the addresses of the Kafka brokers the article events are published to, from AS_KAFKA_BROKERS
+	// formatted as a comma separated list of host:port. The events are not published to Kafka when empty.
+	KafkaBrokers []string
+	// KafkaTopic is the Kafka topic the article events are published to, from AS_KAFKA_TOPIC.
+	KafkaTopic string
+	// GRPCAddress is the address the gRPC ArticleService listens on, alongside the REST API, from AS_GRPC_ADDRESS.
+	GRPCAddress string
+	// SwaggerUI reports whether the Swagger UI exploring the OpenAPI document is served at /docs, from AS_SWAGGER_UI.
+	SwaggerUI bool
+	// Pprof reports whether the profiles of the service are served under /admin/debug/pprof/ (see registerPprof), from
+	// AS_PPROF. It requires the authentication.
+	Pprof bool
+	// ErrorFormat is the format of the error responses, from AS_ERROR_FORMAT: problem for RFC 7807 Problem Details,
+	// or legacy for the CustomOutput sent by the previous versions.
+	ErrorFormat string
+	// MaxTitleLength is the maximum number of characters of the title of an article, from AS_MAX_TITLE_LENGTH.
+	// The title length is not limited when zero.
+	MaxTitleLength int
+	// MaxContentSize is the maximum size of the content of an article in bytes, from AS_MAX_CONTENT_SIZE.
+	// The content size is not limited when zero.
+	MaxContentSize int
+	// MaxTags is the maximum number of tags of an article, from AS_MAX_TAGS. The number of tags is not limited when zero.
+	MaxTags int
+	// TagPattern is the regular expression every tag of an article must fully match, from AS_TAG_PATTERN
+	// (e.g. [a-z0-9-]+ for lowercase tags). The tags are not restricted when nil.
+	TagPattern *regexp.Regexp
+	// HTMLSanitization reports whether the HTML markup of the content of the articles is sanitized when they are
+	// written (see sanitizeContent), from AS_HTML_SANITIZATION.
+	HTMLSanitization bool
+	// HTMLAllowedTags lists the HTML elements kept by the sanitization, from AS_HTML_ALLOWED_TAGS formatted as a space
+	// separated list (e.g. p a em). Setting AS_HTML_ALLOWED_TAGS to none removes all the elements.
+	HTMLAllowedTags []string
+	// HTMLAllowedAttributes lists the HTML attributes kept by the sanitization, from AS_HTML_ALLOWED_ATTRIBUTES
+	// formatted as a space separated list of element:attribute, * standing for any element (e.g. a:href *:title).
+	HTMLAllowedAttributes []string
+	// ReadingWordsPerMinute is the number of words read per minute, computing the reading time of the articles,
+	// from AS_READING_WORDS_PER_MINUTE.
+	ReadingWordsPerMinute int
+	// ViewsRetentionDays is the number of days the daily views of the articles are kept, the longest window of
+	// GET /articles/popular, from AS_VIEWS_RETENTION_DAYS.
+	ViewsRetentionDays int
+	// CommentsModeration reports whether the new comments are pending until approved by a moderator, instead of being
+	// visible right away, from AS_COMMENTS_MODERATION.
+	CommentsModeration bool
+	// CommentsFlagsThreshold is the number of flags setting a visible comment back to pending, from
+	// AS_COMMENTS_FLAGS_THRESHOLD. The flagged comments stay visible when zero.
+	CommentsFlagsThreshold int
+	// MultiTenancy reports whether the requests are scoped to the tenant named by their TenantHeader, each tenant
+	// having its own articles and search indexes, from AS_MULTI_TENANCY. It requires the authentication, the tenant of a
+	// request being the one of its principal.
+	MultiTenancy bool
+	// TenantHeader is the header naming the tenant of a request, from AS_TENANT_HEADER. The requests without it are
+	// served from the default tenant, or from the tenant of their principal when authenticated (see resolveTenant).
+	TenantHeader string
+	// Tenants lists the tenants which may be served besides the ones already recorded, from AS_TENANTS formatted as a
+	// comma separated list. The search indexes of a tenant are only created for the tenants of the list, see
+	// initializeTenant.
+	Tenants []string
+	// Namespaces lists the namespaces a request may select with its NamespaceHeader, each namespace having its own
+	// articles and search indexes within the tenant (e.g. staging and production), from AS_NAMESPACES formatted as a
+	// comma separated list. The NamespaceHeader is ignored when empty.
+	Namespaces []string
+	// NamespaceHeader is the header naming the namespace of a request, from AS_NAMESPACE_HEADER. The requests without
+	// it are served from the default namespace.
+	NamespaceHeader string
+	// Admins lists the actors allowed to change any article and its ACL, whoever its owner (see canChangeArticle),
+	// from AS_ADMINS formatted as a comma separated list. The actors granted the admin role are allowed as well.
+	Admins []string
+	// JWTSecret is the HMAC secret verifying the bearer tokens signed with the HS256, HS384 or HS512 algorithms, from
+	// AS_JWT_SECRET. Setting it, JWTKeyFiles or JWTJWKSURL enables the authentication (see withAuthentication).
+	JWTSecret string
+	// JWTKeyFiles lists the PEM files holding the public keys (or certificates) verifying the bearer tokens, from
+	// AS_JWT_KEY_FILES formatted as a comma separated list.
+	JWTKeyFiles []string
+	// JWTJWKSURL is the URL of the JWK Set publishing the public keys verifying the bearer tokens, from AS_JWT_JWKS_URL
+	// (e.g. https://issuer/.well-known/jwks.json).
+	JWTJWKSURL string
+	// JWTIssuer is the issuer (iss claim) the bearer tokens must be issued by, from AS_JWT_ISSUER. Any issuer is
+	// accepted when empty, unless OIDCIssuerURL is set.
+	JWTIssuer string
+	// JWTAudience is the audience (aud claim) the bearer tokens must be issued to, from AS_JWT_AUDIENCE. Any audience is
+	// accepted when empty.
+	JWTAudience string
+	// JWTTenantClaim is the claim of the bearer tokens holding the tenant of their subject, the only tenant their
+	// requests may be scoped to (see resolveTenant), from AS_JWT_TENANT_CLAIM. A dotted path reads a nested claim. The
+	// subjects belong to the default tenant when it is empty or when their token lacks the claim.
+	JWTTenantClaim string
+	// APIKeys reports whether the requests are authenticated by their X-API-Key header, holding one of the API keys
+	// created by POST /admin/apikeys, from AS_API_KEYS. It enables the authentication, like the JWT settings.
+	APIKeys bool
+	// BootstrapAPIKey is an API key holding all the scopes, to create the first API keys, from AS_BOOTSTRAP_API_KEY.
+	BootstrapAPIKey string
+	// OIDCIssuerURL is the URL of the OpenID Connect provider issuing the bearer tokens (e.g. a Keycloak realm), from
+	// AS_OIDC_ISSUER_URL. Its discovery document provides the issuer and the JWKS URL, and JWTAudience must be set.
+	OIDCIssuerURL string
+	// OIDCGroupsClaim is the claim of the bearer tokens listing the groups of their subject, from AS_OIDC_GROUPS_CLAIM.
+	// A dotted path reads a nested claim, e.g. realm_access.roles.
+	OIDCGroupsClaim string
+	// OIDCGroupRoles maps the groups of the bearer tokens to the roles of their subject (see roles), from
+	// AS_OIDC_GROUP_ROLES formatted as a comma separated list of group=role.
+	OIDCGroupRoles map[string]string
+	// RBAC reports whether the actors must be granted the role of the routes they call (see requiredRole), from
+	// AS_RBAC. It requires the authentication.
+	RBAC bool
+	// DefaultRole is the role of the authenticated actors granted no role by their token, key or Config.Admins, from
+	// AS_DEFAULT_ROLE.
+	DefaultRole string
+	// RateLimit is the number of requests each client can make per RateLimitWindow (see withRateLimit), from
+	// AS_RATE_LIMIT. The requests are not limited when 0.
+	RateLimit int
+	// RateLimitWindow is the window of RateLimit, from AS_RATE_LIMIT_WINDOW.
+	RateLimitWindow time.Duration
+	// QuotaDailyRequests is the number of requests each API key can make per day (in UTC), from
+	// AS_QUOTA_DAILY_REQUESTS. The API keys with their own quotas override it, and no quota applies when 0.
+	QuotaDailyRequests int
+	// QuotaMonthlyRequests is the number of requests each API key can make per month, from AS_QUOTA_MONTHLY_REQUESTS.
+	QuotaMonthlyRequests int
+	// QuotaDailyWrites is the number of requests changing anything each API key can make per day, from
+	// AS_QUOTA_DAILY_WRITES.
+	QuotaDailyWrites int
+	// QuotaMonthlyWrites is the number of requests changing anything each API key can make per month, from
+	// AS_QUOTA_MONTHLY_WRITES.
+	QuotaMonthlyWrites int
+	// TrustedProxies lists the networks of the proxies whose X-Forwarded-For header names the client of the requests
+	// (see clientIP), from AS_TRUSTED_PROXIES formatted as a comma separated list of CIDRs or addresses.
+	TrustedProxies []netip.Prefix
+	// AllowedIPs lists the networks of the clients allowed to call the service (see withIPFilter), from AS_ALLOWED_IPS
+	// formatted like TrustedProxies. All the clients are allowed when empty.
+	AllowedIPs []netip.Prefix
+	// DeniedIPs lists the networks of the clients denied to call the service, from AS_DENIED_IPS formatted like
+	// TrustedProxies.
+	DeniedIPs []netip.Prefix
+	// AdminAllowedIPs lists the networks of the clients allowed to call the /admin routes, from AS_ADMIN_ALLOWED_IPS
+	// formatted like TrustedProxies. All the allowed clients can call them when empty.
+	AdminAllowedIPs []netip.Prefix
+	// AccessLog reports whether the requests served are logged to the standard output (see withAccessLog), from
+	// AS_ACCESS_LOG.
+	AccessLog bool
+	// AccessLogFormat is the format of the access log, from AS_ACCESS_LOG_FORMAT: text (default) or json.
+	AccessLogFormat string
+	// LogLevel is the minimum level of the logs, from AS_LOG_LEVEL: debug, info (default), warn or error.
+	LogLevel string
+	// LogFormat is the format of the logs, from AS_LOG_FORMAT: text (default) or json.
+	LogFormat string
+	// LogOutput is the destination of the logs, from AS_LOG_OUTPUT: stderr (default), stdout or the path of a file.
+	LogOutput string
+	// Tracing reports whether the requests are traced, the traces being exported over OTLP (see initializeTracing):
+	// when OTLPEndpoint, OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set, unless
+	// OTEL_SDK_DISABLED is true or OTEL_TRACES_EXPORTER is none.
+	Tracing bool
+	// OTLPEndpoint is the URL of the OpenTelemetry collector receiving the traces (e.g. http://localhost:4318 over
+	// OTLP/HTTP, http://localhost:4317 over OTLP/gRPC), from AS_OTLP_ENDPOINT. The OTEL_EXPORTER_OTLP_ENDPOINT and
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT standard variables are used when it is empty.
+	OTLPEndpoint string
+	// OTLPProtocol is the protocol the traces are exported with, http/protobuf (default) or grpc, from
+	// AS_OTLP_PROTOCOL, OTEL_EXPORTER_OTLP_TRACES_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL.
+	OTLPProtocol string
+	// HealthTimeout is the time each component checked by GET /healthz is given to answer, from AS_HEALTH_TIMEOUT.
+	HealthTimeout time.Duration
+	// ShutdownTimeout is the time given to the requests in flight to complete once the service is asked to stop, from
+	// AS_SHUTDOWN_TIMEOUT. The requests still running after it are dropped.
+	ShutdownTimeout time.Duration
+	// ListenAddress is the address the REST API listens on, as host:port (e.g. 127.0.0.1:8080, or :8080 for all the
+	// interfaces), from AS_LISTEN_ADDRESS.
+	ListenAddress string
+	// ReadHeaderTimeout is the time given to a client to send the headers of a request, from AS_READ_HEADER_TIMEOUT.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is the time given to a client to send a whole request, body included, from AS_READ_TIMEOUT. There is
+	// no limit when empty.
+	ReadTimeout time.Duration
+	// WriteTimeout is the time given to the service to send a response once the headers of its request are read, from
+	// AS_WRITE_TIMEOUT. There is no limit when empty. The Server-Sent Events streams are not limited by it.
+	WriteTimeout time.Duration
+	// IdleTimeout is the time a keep-alive connection is kept open waiting for the next request, from AS_IDLE_TIMEOUT.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the maximum size of the headers of a request, in bytes, from AS_MAX_HEADER_BYTES.
+	MaxHeaderBytes int
+	// TLSCertFile is the path of the PEM certificate chain the REST API is served with over HTTPS, from
+	// AS_TLS_CERT_FILE. The REST API is served over plain HTTP when it is empty, see configureTLS.
+	TLSCertFile string
+	// TLSKeyFile is the path of the PEM private key of TLSCertFile, from AS_TLS_KEY_FILE.
+	TLSKeyFile string
+	// TLSReloadInterval is the interval at which TLSCertFile and TLSKeyFile are checked for a rotated certificate to
+	// load, from AS_TLS_RELOAD_INTERVAL. The certificate is only loaded at startup when empty.
+	TLSReloadInterval time.Duration
+	// TLSClientCAFile is the path of the PEM bundle of the CAs the certificates of the clients are verified against,
+	// from AS_TLS_CLIENT_CA_FILE. The clients authenticate with their certificate when set (see
+	// clientCertificateSubject), which requires HTTPS.
+	TLSClientCAFile string
+	// TLSClientAuth tells whether the clients must send a certificate, from AS_TLS_CLIENT_AUTH: require (default) to
+	// refuse the connections without one, or optional to let the clients authenticate otherwise.
+	TLSClientAuth string
+	// HTTP2 reports whether the REST API is served over HTTP/2 to the clients supporting it over HTTPS, from AS_HTTP2.
+	HTTP2 bool
+	// H2C reports whether the REST API is served over HTTP/2 without TLS (h2c) when it is served over plain HTTP, from
+	// AS_H2C, for the internal clients. It requires HTTP2.
+	H2C bool
+	// TracingServiceName is the name of the service reported by the traces, from AS_TRACING_SERVICE_NAME or
+	// OTEL_SERVICE_NAME.
+	TracingServiceName string
+	// TracingSampleRatio is the ratio of the traces started by the service which are recorded, from
+	// AS_TRACING_SAMPLE_RATIO, between 0 and 1 (default). The traces continued from a caller follow its decision. It is
+	// ignored when OTEL_TRACES_SAMPLER is set, the sampler it names being used.
+	TracingSampleRatio float64
+}
+
+// config holds the settings of the service, loaded at startup by loadConfig.
+var config = defaultConfig()
+
+// defaultConfig returns the settings used when no environment variable overrides them.
+func defaultConfig() Config {
+	return Config{
+		SearchWeights:         map[string]float64{"title": 5, "author": 2, "content": 1},
+		Embedder:              "hashing",
+		EmbeddingDimensions:   256,
+		IdempotencyTTL:        24 * time.Hour,
+		JobWorkers:            2,
+		EventsStreamMaxLen:    100000,
+		KafkaTopic:            "articles.events",
+		GRPCAddress:           ":9090",
+		ErrorFormat:           problemErrorFormat,
+		HTMLSanitization:      true,
+		HTMLAllowedTags:       defaultHTMLAllowedTags,
+		HTMLAllowedAttributes: defaultHTMLAllowedAttributes,
+		ReadingWordsPerMinute: 200,
+		ViewsRetentionDays:    30,
+		TenantHeader:          "X-Tenant-ID",
+		NamespaceHeader:       "X-Namespace",
+		OIDCGroupsClaim:       "groups",
+		DefaultRole:           roleReader,
+		RateLimitWindow:       time.Minute,
+		AccessLog:             true,
+		AccessLogFormat:       textLogFormat,
+		LogLevel:              "info",
+		LogFormat:             textLogFormat,
+		LogOutput:             stderrLogOutput,
+		OTLPProtocol:          otlpProtocolHTTP,
+		TracingServiceName:    "articles-search",
+		TracingSampleRatio:    1,
+		HealthTimeout:         2 * time.Second,
+		ShutdownTimeout:       30 * time.Second,
+		ListenAddress:         ":8080",
+		ReadHeaderTimeout:     10 * time.Second,
+		IdleTimeout:           2 * time.Minute,
+		MaxHeaderBytes:        http.DefaultMaxHeaderBytes,
+		TLSClientAuth:         clientAuthRequire,
+		HTTP2:                 true,
+	}
+}
+
+// loadConfig loads the settings of the service from the environment variables, on top of defaultConfig.
+func loadConfig() (Config, error) {
+	loadedConfig := defaultConfig()
+
+	if searchWeights := os.Getenv("AS_SEARCH_WEIGHTS"); searchWeights != "" {
+		weights, err := parseSearchWeights(searchWeights)
+		if err != nil {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_SEARCH_WEIGHTS: %v", err)
+		}
+		loadedConfig.SearchWeights = weights
+	}
+
+	lookupEnvString("AS_EMBEDDER", &loadedConfig.Embedder)
+	lookupEnvString("AS_EMBEDDER_URL", &loadedConfig.EmbedderURL)
+	lookupEnvString("AS_EMBEDDER_MODEL", &loadedConfig.EmbedderModel)
+	lookupEnvString("AS_EMBEDDER_API_KEY", &loadedConfig.EmbedderAPIKey)
+	if stopwords := os.Getenv("AS_INDEX_STOPWORDS"); stopwords == "none" {
+		loadedConfig.IndexStopwordsDisabled = true
+	} else {
+		loadedConfig.IndexStopwords = strings.Fields(stopwords)
+	}
+	loadedConfig.IndexNoStemFields = strings.Fields(os.Getenv("AS_INDEX_NOSTEM_FIELDS"))
+	for _, field := range loadedConfig.IndexNoStemFields {
+		if !slices.Contains(fullTextSearchFields, field) {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_INDEX_NOSTEM_FIELDS: %s is not one of the following fields: %v", field, fullTextSearchFields)
+		}
+	}
+	if dimensions := os.Getenv("AS_EMBEDDING_DIMENSIONS"); dimensions != "" {
+		var err error
+		if loadedConfig.EmbeddingDimensions, err = parseEmbeddingDimensions(dimensions); err != nil {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_EMBEDDING_DIMENSIONS: %v", err)
+		}
+	}
+
+	if err := lookupEnvDuration("AS_IDEMPOTENCY_TTL", &loadedConfig.IdempotencyTTL); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_JOB_WORKERS", &loadedConfig.JobWorkers); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_EVENTS_STREAM_MAXLEN", &loadedConfig.EventsStreamMaxLen); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_WEBHOOK_PRIVATE_TARGETS", &loadedConfig.WebhookPrivateTargets); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_AUDIT_STREAM_MAXLEN", &loadedConfig.AuditStreamMaxLen); err != nil {
+		return loadedConfig, err
+	}
+	for _, broker := range strings.Split(os.Getenv("AS_KAFKA_BROKERS"), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			loadedConfig.KafkaBrokers = append(loadedConfig.KafkaBrokers, broker)
+		}
+	}
+	lookupEnvString("AS_KAFKA_TOPIC", &loadedConfig.KafkaTopic)
+	lookupEnvString("AS_GRPC_ADDRESS", &loadedConfig.GRPCAddress)
+	if err := lookupEnvBool("AS_SWAGGER_UI", &loadedConfig.SwaggerUI); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_PPROF", &loadedConfig.Pprof); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_ERROR_FORMAT", &loadedConfig.ErrorFormat)
+	if loadedConfig.ErrorFormat != problemErrorFormat && loadedConfig.ErrorFormat != legacyErrorFormat {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_ERROR_FORMAT: %q is neither %s nor %s", loadedConfig.ErrorFormat, problemErrorFormat, legacyErrorFormat)
+	}
+	if err := lookupEnvPositiveInt("AS_MAX_TITLE_LENGTH", &loadedConfig.MaxTitleLength); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_MAX_CONTENT_SIZE", &loadedConfig.MaxContentSize); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_MAX_TAGS", &loadedConfig.MaxTags); err != nil {
+		return loadedConfig, err
+	}
+	if tagPattern := os.Getenv("AS_TAG_PATTERN"); tagPattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + tagPattern + `)$`)
+		if err != nil {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_TAG_PATTERN: %v", err)
+		}
+		loadedConfig.TagPattern = pattern
+	}
+	if err := lookupEnvBool("AS_HTML_SANITIZATION", &loadedConfig.HTMLSanitization); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_READING_WORDS_PER_MINUTE", &loadedConfig.ReadingWordsPerMinute); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_VIEWS_RETENTION_DAYS", &loadedConfig.ViewsRetentionDays); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_COMMENTS_MODERATION", &loadedConfig.CommentsModeration); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_COMMENTS_FLAGS_THRESHOLD", &loadedConfig.CommentsFlagsThreshold); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_MULTI_TENANCY", &loadedConfig.MultiTenancy); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_TENANT_HEADER", &loadedConfig.TenantHeader)
+	for _, tenant := range strings.Split(os.Getenv("AS_TENANTS"), ",") {
+		if tenant = strings.TrimSpace(tenant); tenant == "" {
+			continue
+		}
+		if !tenantPattern.MatchString(tenant) {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_TENANTS: %q must only hold letters, digits, - and _, up to 64 of them", tenant)
+		}
+		loadedConfig.Tenants = append(loadedConfig.Tenants, tenant)
+	}
+	for _, namespace := range strings.Split(os.Getenv("AS_NAMESPACES"), ",") {
+		if namespace = strings.TrimSpace(namespace); namespace == "" {
+			continue
+		}
+		if !tenantPattern.MatchString(namespace) {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_NAMESPACES: %q must only hold letters, digits, - and _, up to 64 of them", namespace)
+		}
+		loadedConfig.Namespaces = append(loadedConfig.Namespaces, namespace)
+	}
+	lookupEnvString("AS_NAMESPACE_HEADER", &loadedConfig.NamespaceHeader)
+	for _, admin := range strings.Split(os.Getenv("AS_ADMINS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" {
+			loadedConfig.Admins = append(loadedConfig.Admins, admin)
+		}
+	}
+	lookupEnvString("AS_JWT_SECRET", &loadedConfig.JWTSecret)
+	for _, keyFile := range strings.Split(os.Getenv("AS_JWT_KEY_FILES"), ",") {
+		if keyFile = strings.TrimSpace(keyFile); keyFile != "" {
+			loadedConfig.JWTKeyFiles = append(loadedConfig.JWTKeyFiles, keyFile)
+		}
+	}
+	lookupEnvString("AS_JWT_JWKS_URL", &loadedConfig.JWTJWKSURL)
+	lookupEnvString("AS_JWT_ISSUER", &loadedConfig.JWTIssuer)
+	lookupEnvString("AS_JWT_AUDIENCE", &loadedConfig.JWTAudience)
+	lookupEnvString("AS_JWT_TENANT_CLAIM", &loadedConfig.JWTTenantClaim)
+	if err := lookupEnvBool("AS_API_KEYS", &loadedConfig.APIKeys); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_BOOTSTRAP_API_KEY", &loadedConfig.BootstrapAPIKey)
+	lookupEnvString("AS_OIDC_ISSUER_URL", &loadedConfig.OIDCIssuerURL)
+	if loadedConfig.OIDCIssuerURL != "" && loadedConfig.JWTAudience == "" {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_JWT_AUDIENCE: it must be set along with AS_OIDC_ISSUER_URL")
+	}
+	lookupEnvString("AS_OIDC_GROUPS_CLAIM", &loadedConfig.OIDCGroupsClaim)
+	if err := lookupEnvBool("AS_RBAC", &loadedConfig.RBAC); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_DEFAULT_ROLE", &loadedConfig.DefaultRole)
+	if !slices.Contains(roles, loadedConfig.DefaultRole) {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_DEFAULT_ROLE: %s is not one of the following roles: %v", loadedConfig.DefaultRole, roles)
+	}
+	if err := lookupEnvPositiveInt("AS_RATE_LIMIT", &loadedConfig.RateLimit); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvDuration("AS_RATE_LIMIT_WINDOW", &loadedConfig.RateLimitWindow); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_QUOTA_DAILY_REQUESTS", &loadedConfig.QuotaDailyRequests); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_QUOTA_MONTHLY_REQUESTS", &loadedConfig.QuotaMonthlyRequests); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_QUOTA_DAILY_WRITES", &loadedConfig.QuotaDailyWrites); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvPositiveInt("AS_QUOTA_MONTHLY_WRITES", &loadedConfig.QuotaMonthlyWrites); err != nil {
+		return loadedConfig, err
+	}
+	for name, target := range map[string]*[]netip.Prefix{
+		"AS_TRUSTED_PROXIES":   &loadedConfig.TrustedProxies,
+		"AS_ALLOWED_IPS":       &loadedConfig.AllowedIPs,
+		"AS_DENIED_IPS":        &loadedConfig.DeniedIPs,
+		"AS_ADMIN_ALLOWED_IPS": &loadedConfig.AdminAllowedIPs,
+	} {
+		prefixes, err := parseNetworks(os.Getenv(name))
+		if err != nil {
+			return loadedConfig, fmt.Errorf("invalid environment variable %s: %v", name, err)
+		}
+		*target = prefixes
+	}
+	if err := lookupEnvBool("AS_ACCESS_LOG", &loadedConfig.AccessLog); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_ACCESS_LOG_FORMAT", &loadedConfig.AccessLogFormat)
+	lookupEnvString("AS_LOG_LEVEL", &loadedConfig.LogLevel)
+	lookupEnvString("AS_LOG_FORMAT", &loadedConfig.LogFormat)
+	lookupEnvString("AS_LOG_OUTPUT", &loadedConfig.LogOutput)
+	if loadedConfig.AccessLogFormat != textLogFormat && loadedConfig.AccessLogFormat != jsonLogFormat {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_ACCESS_LOG_FORMAT: %q is neither %s nor %s", loadedConfig.AccessLogFormat, textLogFormat, jsonLogFormat)
+	}
+	lookupEnvString("AS_OTLP_ENDPOINT", &loadedConfig.OTLPEndpoint)
+	lookupEnvString("OTEL_EXPORTER_OTLP_PROTOCOL", &loadedConfig.OTLPProtocol)
+	lookupEnvString("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", &loadedConfig.OTLPProtocol)
+	lookupEnvString("AS_OTLP_PROTOCOL", &loadedConfig.OTLPProtocol)
+	if loadedConfig.OTLPProtocol != otlpProtocolHTTP && loadedConfig.OTLPProtocol != otlpProtocolGRPC {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_OTLP_PROTOCOL: %q is neither %s nor %s", loadedConfig.OTLPProtocol, otlpProtocolHTTP, otlpProtocolGRPC)
+	}
+	loadedConfig.Tracing = (loadedConfig.OTLPEndpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") &&
+		!strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") && os.Getenv("OTEL_TRACES_EXPORTER") != "none"
+	if err := lookupEnvDuration("AS_HEALTH_TIMEOUT", &loadedConfig.HealthTimeout); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvDuration("AS_SHUTDOWN_TIMEOUT", &loadedConfig.ShutdownTimeout); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_LISTEN_ADDRESS", &loadedConfig.ListenAddress)
+	if _, _, err := net.SplitHostPort(loadedConfig.ListenAddress); err != nil {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_LISTEN_ADDRESS: %v", err)
+	}
+	for name, target := range map[string]*time.Duration{
+		"AS_READ_HEADER_TIMEOUT": &loadedConfig.ReadHeaderTimeout,
+		"AS_READ_TIMEOUT":        &loadedConfig.ReadTimeout,
+		"AS_WRITE_TIMEOUT":       &loadedConfig.WriteTimeout,
+		"AS_IDLE_TIMEOUT":        &loadedConfig.IdleTimeout,
+	} {
+		if err := lookupEnvDuration(name, target); err != nil {
+			return loadedConfig, err
+		}
+	}
+	if err := lookupEnvPositiveInt("AS_MAX_HEADER_BYTES", &loadedConfig.MaxHeaderBytes); err != nil {
+		return loadedConfig, err
+	}
+	lookupEnvString("AS_TLS_CERT_FILE", &loadedConfig.TLSCertFile)
+	lookupEnvString("AS_TLS_KEY_FILE", &loadedConfig.TLSKeyFile)
+	if err := lookupEnvDuration("AS_TLS_RELOAD_INTERVAL", &loadedConfig.TLSReloadInterval); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_HTTP2", &loadedConfig.HTTP2); err != nil {
+		return loadedConfig, err
+	}
+	if err := lookupEnvBool("AS_H2C", &loadedConfig.H2C); err != nil {
+		return loadedConfig, err
+	}
+	if loadedConfig.H2C && !loadedConfig.HTTP2 {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_H2C: h2c requires AS_HTTP2")
+	}
+	lookupEnvString("AS_TLS_CLIENT_CA_FILE", &loadedConfig.TLSClientCAFile)
+	lookupEnvString("AS_TLS_CLIENT_AUTH", &loadedConfig.TLSClientAuth)
+	if loadedConfig.TLSClientAuth != clientAuthRequire && loadedConfig.TLSClientAuth != clientAuthOptional {
+		return loadedConfig, fmt.Errorf("invalid environment variable AS_TLS_CLIENT_AUTH: %q is neither %s nor %s", loadedConfig.TLSClientAuth, clientAuthRequire, clientAuthOptional)
+	}
+	lookupEnvString("OTEL_SERVICE_NAME", &loadedConfig.TracingServiceName)
+	lookupEnvString("AS_TRACING_SERVICE_NAME", &loadedConfig.TracingServiceName)
+	if sampleRatio := os.Getenv("AS_TRACING_SAMPLE_RATIO"); sampleRatio != "" {
+		ratio, err := strconv.ParseFloat(sampleRatio, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_TRACING_SAMPLE_RATIO: %q is not a number between 0 and 1", sampleRatio)
+		}
+		loadedConfig.TracingSampleRatio = ratio
+	}
+	if groupRoles := os.Getenv("AS_OIDC_GROUP_ROLES"); groupRoles != "" {
+		parsedGroupRoles, err := parseGroupRoles(groupRoles)
+		if err != nil {
+			return loadedConfig, fmt.Errorf("invalid environment variable AS_OIDC_GROUP_ROLES: %v", err)
+		}
+		loadedConfig.OIDCGroupRoles = parsedGroupRoles
+	}
+	if allowedTags := os.Getenv("AS_HTML_ALLOWED_TAGS"); allowedTags == "none" {
+		loadedConfig.HTMLAllowedTags = nil
+	} else if allowedTags != "" {
+		loadedConfig.HTMLAllowedTags = strings.Fields(strings.ToLower(allowedTags))
+	}
+	if allowedAttributes := os.Getenv("AS_HTML_ALLOWED_ATTRIBUTES"); allowedAttributes != "" {
+		loadedConfig.HTMLAllowedAttributes = strings.Fields(strings.ToLower(allowedAttributes))
+		for _, allowedAttribute := range loadedConfig.HTMLAllowedAttributes {
+			if tag, attribute, found := strings.Cut(allowedAttribute, ":"); !found || tag == "" || attribute == "" {
+				return loadedConfig, fmt.Errorf("invalid environment variable AS_HTML_ALLOWED_ATTRIBUTES: %q is not formatted as element:attribute", allowedAttribute)
+			}
+		}
+	}
+
+	return loadedConfig, nil
+}
+
+// parseNetworks parses a comma separated list of networks, as CIDRs (e.g. 10.0.0.0/8) or addresses (e.g. ::1).
+func parseNetworks(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, network := range strings.Split(value, ",") {
+		if network = strings.TrimSpace(network); network == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(network)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q is neither a CIDR nor an address", network)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseGroupRoles parses a comma separated list of group=role into a map, each role being one of the roles.
+func parseGroupRoles(value string) (map[string]string, error) {
+	groupRoles := make(map[string]string)
+	for _, groupRole := range strings.Split(value, ",") {
+		group, role, found := strings.Cut(strings.TrimSpace(groupRole), "=")
+		if !found || group == "" {
+			return nil, fmt.Errorf("%q is not formatted as group=role", groupRole)
+		}
+		if !slices.Contains(roles, role) {
+			return nil, fmt.Errorf("%s is not one of the following roles: %v", role, roles)
+		}
+		groupRoles[group] = role
+	}
+	return groupRoles, nil
+}
+
+// parseSearchWeights parses a comma separated list of field=weight into a map.
+// Each field must be one of the fullTextSearchFields and each weight a positive number.
+func parseSearchWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, fieldWeight := range strings.Split(value, ",") {
+		field, weight, found := strings.Cut(strings.TrimSpace(fieldWeight), "=")
+		if !found {
+			return nil, fmt.Errorf("%q is not formatted as field=weight", fieldWeight)
+		}
+		if !slices.Contains(fullTextSearchFields, field) {
+			return nil, fmt.Errorf("%s is not one of the following fields: %v", field, fullTextSearchFields)
+		}
+		weightFloat, err := strconv.ParseFloat(weight, 64)
+		if err != nil || weightFloat <= 0 {
+			return nil, fmt.Errorf("weight of %s must be a positive number", field)
+		}
+		weights[field] = weightFloat
+	}
+	return weights, nil
+}
+
+// lookupEnvString sets target to the value of the environment variable name, when it is set and not empty.
+func lookupEnvString(name string, target *string) {
+	if value := os.Getenv(name); value != "" {
+		*target = value
+	}
+}
+
+// lookupEnvBool sets target to the value of the environment variable name, when it is set and not empty.
+// The value must be a boolean (e.g. true or 1).
+func lookupEnvBool(name string, target *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	boolean, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid environment variable %s: %q is not a boolean", name, value)
+	}
+	*target = boolean
+	return nil
+}
+
+// lookupEnvPositiveInt sets target to the value of the environment variable name, when it is set and not empty.
+// The value must be a positive integer.
+func lookupEnvPositiveInt(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	integer, err := strconv.Atoi(value)
+	if err != nil || integer <= 0 {
+		return fmt.Errorf("invalid environment variable %s: %q is not a positive integer", name, value)
+	}
+	*target = integer
+	return nil
+}
+
+// lookupEnvDuration sets target to the value of the environment variable name, when it is set and not empty.
+// The value must be a positive duration (e.g. 30s, 24h).
+func lookupEnvDuration(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid environment variable %s: %q is not a positive duration", name, value)
+	}
+	*target = duration
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// currentRevision designates the current version of an article when comparing revisions.
+const currentRevision = "current"
+
+// ArticleDiff represents the differences between two versions of an article.
+type ArticleDiff struct {
+	From        string                 `json:"from"`                  // From is the revision compared, or current.
+	To          string                 `json:"to"`                    // To is the revision From is compared to, or current.
+	Changes     map[string]FieldChange `json:"changes"`               // Changes holds the changed fields, by JSON name, except tags and content.
+	TagsAdded   []string               `json:"tagsAdded,omitempty"`   // TagsAdded lists the tags of To missing from From.
+	TagsRemoved []string               `json:"tagsRemoved,omitempty"` // TagsRemoved lists the tags of From missing from To.
+	ContentDiff []LineChange           `json:"contentDiff,omitempty"` // ContentDiff is the line by line diff of the content, when changed.
+}
+
+// FieldChange represents the change of the value of a field between two versions of an article.
+type FieldChange struct {
+	From any `json:"from"` // From is the value of the field in the first version.
+	To   any `json:"to"`   // To is the value of the field in the second version.
+}
+
+// LineChange represents a line of a line by line diff.
+type LineChange struct {
+	Op   string `json:"op"`   // Op is either equal, insert (line of the second version only) or delete (line of the first version only).
+	Line string `json:"line"` // Line is the content of the line.
+}
+
+// diffArticleRevisions returns the differences between two versions of the article with the provided ID.
+// Each version is either a revision number or current for the current version of the article.
+// If the article or one of the revisions does not exist, it responds with an HTTP 404 Not Found error.
+func diffArticleRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+
+	storedArticle, err := getStoredArticle(ctx, tenantKey(ctx, keysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if storedArticle == nil || isHiddenDraft(ctx, *storedArticle) {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+
+	var versions []Article
+	for _, name := range []string{r.PathValue("a"), r.PathValue("b")} {
+		if name == currentRevision {
+			versions = append(versions, *storedArticle)
+			continue
+		}
+		number, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			handleError(w, "Invalid revision", withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("revision must be an integer or %s, got %s", currentRevision, name)), http.StatusBadRequest)
+			return
+		}
+		revision, err := getRevision(ctx, id, number)
+		if err != nil {
+			handleError(w, "Failed to retrieve article revision from Database", err, http.StatusInternalServerError)
+			return
+		}
+		if revision == nil {
+			handleError(w, "Revision not found", withErrorCode(ErrorCodeRevisionNotFound, fmt.Errorf("no revision %d found for article with ID %s", number, id)), http.StatusNotFound)
+			return
+		}
+		versions = append(versions, revision.Article)
+	}
+
+	articleDiff := diffArticles(versions[0], versions[1])
+	articleDiff.From, articleDiff.To = r.PathValue("a"), r.PathValue("b")
+	responseJSON(w, articleDiff, http.StatusOK)
+}
+
+// diffArticles returns the differences between two versions of an article.
+func diffArticles(from Article, to Article) ArticleDiff {
+	articleDiff := ArticleDiff{Changes: map[string]FieldChange{}}
+
+	fromValue, toValue := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < fromValue.NumField(); i++ {
+		name, _, _ := strings.Cut(fromValue.Type().Field(i).Tag.Get("json"), ",")
+		if name == "tags" || name == "content" {
+			continue
+		}
+		if !reflect.DeepEqual(fromValue.Field(i).Interface(), toValue.Field(i).Interface()) {
+			articleDiff.Changes[name] = FieldChange{From: fromValue.Field(i).Interface(), To: toValue.Field(i).Interface()}
+		}
+	}
+
+	for _, tag := range to.Tags {
+		if !slices.Contains(from.Tags, tag) {
+			articleDiff.TagsAdded = append(articleDiff.TagsAdded, tag)
+		}
+	}
+	for _, tag := range from.Tags {
+		if !slices.Contains(to.Tags, tag) {
+			articleDiff.TagsRemoved = append(articleDiff.TagsRemoved, tag)
+		}
+	}
+
+	if from.Content != to.Content {
+		articleDiff.ContentDiff = diffLines(strings.Split(from.Content, "\n"), strings.Split(to.Content, "\n"))
+	}
+	return articleDiff
+}
+
+// diffLines returns the line by line diff turning the from lines into the to lines,
+// based on their longest common subsequence.
+func diffLines(from []string, to []string) []LineChange {
+	// common[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	common := make([][]int, len(from)+1)
+	for i := range common {
+		common[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var changes []LineChange
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			changes = append(changes, LineChange{Op: "equal", Line: from[i]})
+			i, j = i+1, j+1
+		case common[i+1][j] >= common[i][j+1]:
+			changes = append(changes, LineChange{Op: "delete", Line: from[i]})
+			i++
+		default:
+			changes = append(changes, LineChange{Op: "insert", Line: to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		changes = append(changes, LineChange{Op: "delete", Line: from[i]})
+	}
+	for ; j < len(to); j++ {
+		changes = append(changes, LineChange{Op: "insert", Line: to[j]})
+	}
+	return changes
+}
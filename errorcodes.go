@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ErrorCode is the stable machine-readable code of an error response, so that clients can branch on it instead of
+// matching the messages. It is sent along with every error by handleError.
+type ErrorCode string
+
+// The codes of the errors specific to a resource or a situation.
+const (
+	ErrorCodeArticleNotFound        ErrorCode = "ARTICLE_NOT_FOUND"
+	ErrorCodeTrashedArticleNotFound ErrorCode = "TRASHED_ARTICLE_NOT_FOUND"
+	ErrorCodeRevisionNotFound       ErrorCode = "REVISION_NOT_FOUND"
+	ErrorCodeAuthorNotFound         ErrorCode = "AUTHOR_NOT_FOUND"
+	ErrorCodeJobNotFound            ErrorCode = "JOB_NOT_FOUND"
+	ErrorCodeWebhookNotFound        ErrorCode = "WEBHOOK_NOT_FOUND"
+	ErrorCodeAPIKeyNotFound         ErrorCode = "API_KEY_NOT_FOUND"
+	ErrorCodeCommentNotFound        ErrorCode = "COMMENT_NOT_FOUND"
+	ErrorCodeCategoryNotFound       ErrorCode = "CATEGORY_NOT_FOUND"
+	ErrorCodeCollectionNotFound     ErrorCode = "COLLECTION_NOT_FOUND"
+	ErrorCodeIndexNotFound          ErrorCode = "INDEX_NOT_FOUND"
+	ErrorCodeDuplicateId            ErrorCode = "DUPLICATE_ID"
+	ErrorCodeVersionConflict        ErrorCode = "VERSION_CONFLICT"
+	ErrorCodeVersionRequired        ErrorCode = "VERSION_REQUIRED"
+	ErrorCodeValidationFailed       ErrorCode = "VALIDATION_FAILED"
+	ErrorCodeInvalidParameter       ErrorCode = "INVALID_PARAMETER"
+	ErrorCodeInvalidBody            ErrorCode = "INVALID_BODY"
+	ErrorCodeInvalidTenant          ErrorCode = "INVALID_TENANT"
+	ErrorCodeInvalidNamespace       ErrorCode = "INVALID_NAMESPACE"
+	ErrorCodePatchTestFailed        ErrorCode = "PATCH_TEST_FAILED"
+	ErrorCodeIdempotencyKeyInUse    ErrorCode = "IDEMPOTENCY_KEY_IN_USE"
+	ErrorCodeIdempotencyKeyReused   ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrorCodeQuotaExceeded          ErrorCode = "QUOTA_EXCEEDED"
+	ErrorCodeDatabaseUnavailable    ErrorCode = "DB_UNAVAILABLE"
+)
+
+// The codes of the errors only described by their status code, see statusErrorCodes.
+const (
+	ErrorCodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	ErrorCodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden            ErrorCode = "FORBIDDEN"
+	ErrorCodeNotFound             ErrorCode = "NOT_FOUND"
+	ErrorCodeConflict             ErrorCode = "CONFLICT"
+	ErrorCodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrorCodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeUnprocessableRequest ErrorCode = "UNPROCESSABLE_REQUEST"
+	ErrorCodePreconditionRequired ErrorCode = "PRECONDITION_REQUIRED"
+	ErrorCodeRateLimited          ErrorCode = "RATE_LIMITED"
+	ErrorCodeServiceUnavailable   ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrorCodeInternalError        ErrorCode = "INTERNAL_ERROR"
+)
+
+// statusErrorCodes holds the code of the errors without a more specific one, by status code.
+// The errors with another status code are INTERNAL_ERROR.
+var statusErrorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            ErrorCodeInvalidRequest,
+	http.StatusUnauthorized:          ErrorCodeUnauthorized,
+	http.StatusForbidden:             ErrorCodeForbidden,
+	http.StatusNotFound:              ErrorCodeNotFound,
+	http.StatusConflict:              ErrorCodeConflict,
+	http.StatusRequestEntityTooLarge: ErrorCodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  ErrorCodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   ErrorCodeUnprocessableRequest,
+	http.StatusPreconditionRequired:  ErrorCodePreconditionRequired,
+	http.StatusTooManyRequests:       ErrorCodeRateLimited,
+	http.StatusServiceUnavailable:    ErrorCodeServiceUnavailable,
+}
+
+// codedError is an error along with the ErrorCode of the responses reporting it, see withErrorCode.
+type codedError struct {
+	code ErrorCode
+	err  error
+}
+
+// Error returns the message of the underlying error.
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withErrorCode returns err along with the ErrorCode of the responses reporting it.
+func withErrorCode(code ErrorCode, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// errorCodeOf returns the ErrorCode of an error reported with the given status code: the one it has been given
+// by withErrorCode, the one of its kind (e.g. VALIDATION_FAILED for the validation errors, DB_UNAVAILABLE for
+// the failures to reach the database), or the one of the status code.
+func errorCodeOf(err error, statusCode int) ErrorCode {
+	var coded *codedError
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.As(err, &validationErrors):
+		return ErrorCodeValidationFailed
+	case errors.Is(err, errVersionRequired):
+		return ErrorCodeVersionRequired
+	case errors.Is(err, errArticleNotFound):
+		return ErrorCodeArticleNotFound
+	case errors.Is(err, errArticleExists):
+		return ErrorCodeDuplicateId
+	case errors.Is(err, errArticleVersion):
+		return ErrorCodeVersionConflict
+	case errors.Is(err, errPatchTestFailed):
+		return ErrorCodePatchTestFailed
+	case statusCode >= http.StatusInternalServerError && isDatabaseUnavailable(err):
+		return ErrorCodeDatabaseUnavailable
+	}
+	if code, found := statusErrorCodes[statusCode]; found {
+		return code
+	}
+	return ErrorCodeInternalError
+}
+
+// isDatabaseUnavailable reports whether an error is a failure to reach the database, rather than an error
+// returned by the database.
+func isDatabaseUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, redis.ErrClosed) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
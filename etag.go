@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// computeETag returns the strong ETag of a representation: the quoted SHA-256 hash of its JSON encoding.
+func computeETag(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(hash[:]) + `"`, nil
+}
+
+// etagMatches reports whether a If-Match or If-None-Match header value, a list of ETags or *, holds the given ETag.
+// ETags are compared weakly, a W/ prefix being ignored.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// responseJSONWithETag sends v as JSON like responseJSON along with its ETag. A GET request whose If-None-Match header
+// matches the ETag gets an HTTP 304 Not Modified without body instead, the client already holding the representation.
+func responseJSONWithETag(w http.ResponseWriter, r *http.Request, v any, statusCode int) {
+	etag, err := computeETag(v)
+	if err != nil {
+		handleError(w, "Failed to compute ETag", err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && statusCode == http.StatusOK {
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	responseJSON(w, v, statusCode)
+}
+
+// responseArticleJSON sends an article as JSON like responseProjectedJSON, along with its update time as Last-Modified.
+// A GET request whose If-Modified-Since header is not older than the update time gets an HTTP 304 Not Modified instead,
+// If-Modified-Since being ignored when If-None-Match is provided.
+func responseArticleJSON(w http.ResponseWriter, r *http.Request, article Article, fields []string, statusCode int) {
+	if article.UpdatedAt != 0 {
+		lastModified := time.Unix(article.UpdatedAt, 0)
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && statusCode == http.StatusOK && r.Header.Get("If-None-Match") == "" {
+			if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	responseProjectedJSON(w, r, article, fields, statusCode)
+}
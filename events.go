@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ArticleEventType represents the kind of change made to an article.
+type ArticleEventType string
+
+const (
+	ArticleCreated ArticleEventType = "article.created"
+	ArticleUpdated ArticleEventType = "article.updated"
+	ArticleDeleted ArticleEventType = "article.deleted"
+)
+
+// articleEventTypes lists the kinds of change made to an article.
+var articleEventTypes = []ArticleEventType{ArticleCreated, ArticleUpdated, ArticleDeleted}
+
+// ArticleEvent represents a change made to an article, as notified to the subscribers of the event bus.
+type ArticleEvent struct {
+	Id         string           `json:"id"`                  // Id is the unique identifier of the event.
+	Type       ArticleEventType `json:"type"`                // Type is the kind of change made to the article.
+	ArticleId  string           `json:"articleId"`           // ArticleId is the ID of the changed article.
+	Tenant     string           `json:"tenant,omitempty"`    // Tenant is the tenant of the changed article, empty for the default tenant.
+	Namespace  string           `json:"namespace,omitempty"` // Namespace is the namespace of the changed article, empty for the default namespace.
+	Actor      string           `json:"actor"`               // Actor is who made the change, see requestActor.
+	Article    *Article         `json:"article"`             // Article is the article after the change, or before it when it has been deleted.
+	OccurredAt int64            `json:"occurredAt"`          // OccurredAt is the time of the change, as a Unix timestamp in seconds.
+}
+
+const (
+	// anonymousActor is the actor of the changes requested without authentication.
+	anonymousActor = "anonymous"
+	// schedulerActor is the actor of the changes made by the publication scheduler.
+	schedulerActor = "scheduler"
+)
+
+// articleEventBufferSize is the number of events a subscriber can lag behind before the next events are dropped for it.
+const articleEventBufferSize = 1000
+
+// eventBus notifies the changes made to the articles to its subscribers (e.g. webhooks).
+// A subscriber lagging behind by more than articleEventBufferSize events misses the next ones, so that a slow
+// subscriber never holds back the writes.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan ArticleEvent]struct{}
+}
+
+// articleEvents is the event bus of the changes made to the articles by this instance of the service, for the
+// subscribers handling each event once across the instances (e.g. webhooks or the stream of startEventStreamWriter).
+var articleEvents = &eventBus{subscribers: make(map[chan ArticleEvent]struct{})}
+
+// clusterArticleEvents is the event bus of the changes made to the articles by all the instances of the service, as
+// relayed by startEventRelay, for the subscribers streaming them to the clients connected to this instance (e.g. SSE).
+var clusterArticleEvents = &eventBus{subscribers: make(map[chan ArticleEvent]struct{})}
+
+// articleEventsChannel is the Redis pub/sub channel relaying the article events between the instances of the service.
+const articleEventsChannel = "articles:events:relay"
+
+// subscribe returns a channel receiving the next events, along with the function ending the subscription.
+func (bus *eventBus) subscribe() (<-chan ArticleEvent, func()) {
+	events := make(chan ArticleEvent, articleEventBufferSize)
+	bus.mu.Lock()
+	bus.subscribers[events] = struct{}{}
+	bus.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			delete(bus.subscribers, events)
+			bus.mu.Unlock()
+			close(events)
+		})
+	}
+}
+
+// publish sends an event to all the subscribers, without waiting for any of them.
+func (bus *eventBus) publish(event ArticleEvent) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	for events := range bus.subscribers {
+		select {
+		case events <- event:
+		default:
+			slog.Warn("Event bus subscriber lagging behind, event dropped", "event", event.Id, "type", event.Type)
+		}
+	}
+}
+
+// requestActor returns who makes the changes requested by r: the subject it is authenticated as (see
+// withAuthentication), anonymousActor when it is not authenticated, so that an actor can't be impersonated.
+func requestActor(r *http.Request) string {
+	if subject := authenticatedSubject(r.Context()); subject != "" {
+		return subject
+	}
+	return anonymousActor
+}
+
+// publishArticleEvent publishes the event matching the change of an article made by actor, previous being the article
+// before the change (nil when it has been created) and current the article after the change (nil when it has been deleted).
+// The event is published on behalf of the tenant and namespace ctx is scoped to.
+func publishArticleEvent(ctx context.Context, actor string, previous *Article, current *Article) {
+	event := ArticleEvent{Id: uuid.New().String(), Tenant: tenantOf(ctx), Namespace: namespaceOf(ctx), Actor: actor, OccurredAt: time.Now().Unix()}
+	switch {
+	case previous == nil && current == nil:
+		return
+	case previous == nil:
+		event.Type, event.Article = ArticleCreated, current
+	case current == nil:
+		event.Type, event.Article = ArticleDeleted, previous
+	default:
+		event.Type, event.Article = ArticleUpdated, current
+	}
+	event.ArticleId = event.Article.Id
+	articleEvents.publish(event)
+}
+
+// startEventRelay starts relaying the events of articleEvents to all the instances of the service through
+// articleEventsChannel, and the events received from there, this instance's included, to clusterArticleEvents.
+// Redis pub/sub delivers each event at most once: the events relayed while an instance is disconnected from Redis are
+// missed by its subscribers, the durable change feed being the stream of startEventStreamWriter.
+func startEventRelay() error {
+	messages, _, err := db.Subscribe(ctx, databaseClient, articleEventsChannel)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for message := range messages {
+			var event ArticleEvent
+			if err := json.Unmarshal([]byte(message), &event); err != nil {
+				slog.Error("Unable to decode relayed event", "channel", articleEventsChannel, "Error:", err)
+				continue
+			}
+			clusterArticleEvents.publish(event)
+		}
+	}()
+
+	events, _ := articleEvents.subscribe()
+	go func() {
+		for event := range events {
+			message, err := json.Marshal(event)
+			if err != nil {
+				slog.Error("Unable to encode event", "event", event.Id, "Error:", err)
+				continue
+			}
+			if _, err := db.Publish(event.context(ctx), databaseClient, articleEventsChannel, message); err != nil {
+				slog.Error("Unable to relay event to the instances", "event", event.Id, "channel", articleEventsChannel, "Error:", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// scopedTo reports whether the event is about an article of the tenant and namespace ctx is scoped to.
+func (event ArticleEvent) scopedTo(ctx context.Context) bool {
+	return event.Tenant == tenantOf(ctx) && event.Namespace == namespaceOf(ctx)
+}
+
+// context returns a copy of parent scoped to the tenant and namespace of the changed article.
+func (event ArticleEvent) context(parent context.Context) context.Context {
+	return contextWithNamespace(contextWithTenant(parent, event.Tenant), event.Namespace)
+}
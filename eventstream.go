@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+)
+
+// articleEventsStream is the key of the Redis Stream the article events are appended to.
+const articleEventsStream = "articles:events"
+
+// startEventStreamWriter starts the background goroutine appending the events of the event bus to the articleEventsStream
+// of the tenant and namespace of the changed article (see tenantKey), in order, giving consumers a durable change feed they can read
+// with consumer groups (XREADGROUP).
+// Each entry holds the following fields:
+//   - event, the ID of the event
+//   - articleId, the ID of the changed article
+//   - operation, the kind of change (article.created, article.updated or article.deleted)
+//   - actor, who made the change (see requestActor)
+//   - occurredAt, the time of the change as a Unix timestamp in seconds
+//
+// The stream is trimmed to about config.EventsStreamMaxLen entries, the oldest ones being evicted.
+func startEventStreamWriter() {
+	events, _ := articleEvents.subscribe()
+	go func() {
+		for event := range events {
+			values := map[string]any{
+				"event":      event.Id,
+				"articleId":  event.ArticleId,
+				"operation":  string(event.Type),
+				"actor":      event.Actor,
+				"occurredAt": event.OccurredAt,
+			}
+			ctx := event.context(ctx)
+			if _, err := db.StreamAdd(ctx, databaseClient, tenantKey(ctx, articleEventsStream), int64(config.EventsStreamMaxLen), values); err != nil {
+				slog.ErrorContext(ctx, "Unable to append event to the stream", "event", event.Id, "stream", articleEventsStream, "Error:", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/stivesso/articles-search/pkg/db"
+	"math"
+	"time"
+)
+
+// futureTimestampValidation validates if a given field is a Unix timestamp, in seconds, in the future.
+func futureTimestampValidation(fl validator.FieldLevel) bool {
+	return fl.Field().Int() > time.Now().Unix()
+}
+
+// notExpiredFilters returns the filters excluding the expired articles from a search, as an expired article
+// can remain in the search index for a short while, until Redis actually deletes its key.
+func notExpiredFilters() []db.SearchParams {
+	return []db.SearchParams{{
+		Param:   "expiresAt",
+		Type:    db.NumberType,
+		Ranges:  []db.NumberRange{{Min: math.Inf(-1), Max: float64(time.Now().Unix())}},
+		Exclude: true,
+	}}
+}
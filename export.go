@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// csvTagsSeparator separates the tags of an article within its CSV tags column.
+	csvTagsSeparator = "|"
+	// csvMediaType is the media type of the CSV exports.
+	csvMediaType = "text/csv; charset=utf-8"
+)
+
+// exportFormats lists the formats articles can be exported to.
+var exportFormats = []string{"csv"}
+
+// csvExportFields lists the Article fields exported as CSV columns, in order.
+var csvExportFields = []string{"id", "title", "author", "tags", "createdAt", "updatedAt"}
+
+// exportArticles exports all the articles in the format given by the format query parameter, CSV being the only
+// format supported so far (and the default): one row per article with its id, title, author, tags (joined with |)
+// and its creation and update times as RFC 3339 timestamps. Articles are streamed as they are retrieved, see streamArticles.
+func exportArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"format"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	if format := queryParams.Get("format"); format != "" && format != "csv" {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("format must be one of %v", exportFormats)), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="articles.csv"`)
+	csvWriter := csv.NewWriter(w)
+	streamArticles(ctx, w, csvMediaType, csvExportFields,
+		func() error {
+			csvWriter.Write(csvExportFields)
+			csvWriter.Flush()
+			return csvWriter.Error()
+		},
+		func(articles []Article) error {
+			for _, article := range articles {
+				csvWriter.Write([]string{
+					article.Id,
+					csvSafeCell(article.Title),
+					csvSafeCell(article.Author),
+					csvSafeCell(strings.Join(article.Tags, csvTagsSeparator)),
+					csvTimestamp(article.CreatedAt),
+					csvTimestamp(article.UpdatedAt),
+				})
+			}
+			csvWriter.Flush()
+			return csvWriter.Error()
+		},
+	)
+}
+
+// csvTimestamp formats a Unix timestamp in seconds as RFC 3339, an unset timestamp being left empty.
+func csvTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+// csvSafeCell prevents a cell from being evaluated as a formula once opened in a spreadsheet,
+// by prefixing the values starting with a formula character with a single quote.
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -21,8 +21,9 @@ until redis-cli ping &>/dev/null; do
     sleep 1
 done
 
-# Add logic for creating Redisearch indexes
-redis-cli FT.CREATE idx_articles ON JSON PREFIX 1 "article" SCHEMA $.id AS id TEXT $.title AS title TEXT $.content AS content TEXT $.author AS author TEXT $.tags AS tags TAG
+# The Redisearch index (idx_articles) is created by the articles-search service at startup when missing,
+# its schema is tuned through the AS_INDEX_STOPWORDS, AS_INDEX_NOSTEM_FIELDS and AS_EMBEDDING_DIMENSIONS
+# environment variables of the service.
 
 # Wait for the background process to finish ,and returns its exit code
 wait
\ No newline at end of file
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// serverManagedFieldNames lists the Article fields set by the server, which are not part of the GraphQL ArticleInput.
+var serverManagedFieldNames = []string{"id", "createdAt", "updatedAt", "version", "deletedAt", "wordCount", "readingTimeMinutes", "likes", "owner", "editors"}
+
+// GraphQLRequest represents a GraphQL request, as posted to /graphql.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`                   // Query is the GraphQL document to execute.
+	OperationName string         `json:"operationName,omitempty"` // OperationName selects the operation to execute, when the document holds several.
+	Variables     map[string]any `json:"variables,omitempty"`     // Variables holds the values of the variables of the operation.
+}
+
+// graphQLActorKey is the context key of the actor of a GraphQL request, see requestActor.
+type graphQLActorKey struct{}
+
+// graphQLSchema is the GraphQL schema of the articles, built at startup by initializeGraphQLSchema.
+var graphQLSchema graphql.Schema
+
+// initializeGraphQLSchema builds the GraphQL schema served by executeGraphQL:
+//
+//	type Query {
+//	  article(id: ID!): Article
+//	  articles(limit: Int, offset: Int): ArticlesPage!
+//	  search(q: String, filters: [SearchFilter!], operator: String, sortBy: String, order: String,
+//	         fuzzy: Int, match: String, createdAfter: String, createdBefore: String, minWords: Int, maxWords: Int,
+//	         limit: Int, offset: Int): ArticlesSearchPage!
+//	}
+//	type Mutation {
+//	  createArticle(id: ID, article: ArticleInput!): Article!
+//	  updateArticle(id: ID!, version: Int!, article: ArticleInput!): Article!
+//	  deleteArticle(id: ID!): ID!
+//	}
+//
+// The Article type holds the fields of the Article struct, and ArticleInput the ones that are not server managed.
+func initializeGraphQLSchema() error {
+	articleFields, articleInputFields := graphql.Fields{}, graphql.InputObjectConfigFieldMap{}
+	for name, fieldType := range articleGraphQLFieldTypes(false) {
+		articleFields[name] = &graphql.Field{Type: fieldType}
+	}
+	for name, fieldType := range articleGraphQLFieldTypes(true) {
+		articleInputFields[name] = &graphql.InputObjectFieldConfig{Type: fieldType}
+	}
+	articleType := graphql.NewObject(graphql.ObjectConfig{Name: "Article", Fields: articleFields})
+	articleInputType := graphql.NewInputObject(graphql.InputObjectConfig{Name: "ArticleInput", Fields: articleInputFields})
+	articlesPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticlesPage",
+		Fields: graphql.Fields{
+			"articles": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleType)))},
+			"total":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"limit":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"offset":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	searchHitType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleSearchHit",
+		Fields: graphql.Fields{
+			"article": &graphql.Field{Type: graphql.NewNonNull(articleType), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(ArticleSearchHit).Article, nil
+			}},
+			"score": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+	searchPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticlesSearchPage",
+		Fields: graphql.Fields{
+			"hits": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(searchHitType))), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(ArticlesSearchPage).Articles, nil
+			}},
+			"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"limit":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"offset": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	searchFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "SearchFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"article": &graphql.Field{
+				Type:    articleType,
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: resolveArticle,
+			},
+			"articles": &graphql.Field{
+				Type:    graphql.NewNonNull(articlesPageType),
+				Args:    graphql.FieldConfigArgument{"limit": {Type: graphql.Int}, "offset": {Type: graphql.Int}},
+				Resolve: resolveArticles,
+			},
+			"search": &graphql.Field{
+				Type: graphql.NewNonNull(searchPageType),
+				Args: graphql.FieldConfigArgument{
+					fullTextSearchParam: {Type: graphql.String},
+					"filters":           {Type: graphql.NewList(graphql.NewNonNull(searchFilterType))},
+					"operator":          {Type: graphql.String},
+					"sortBy":            {Type: graphql.String},
+					"order":             {Type: graphql.String},
+					"fuzzy":             {Type: graphql.Int},
+					"match":             {Type: graphql.String},
+					"createdAfter":      {Type: graphql.String},
+					"createdBefore":     {Type: graphql.String},
+					"minWords":          {Type: graphql.Int},
+					"maxWords":          {Type: graphql.Int},
+					"limit":             {Type: graphql.Int},
+					"offset":            {Type: graphql.Int},
+				},
+				Resolve: resolveSearch,
+			},
+		},
+	})
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createArticle": &graphql.Field{
+				Type:    graphql.NewNonNull(articleType),
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.ID}, "article": {Type: graphql.NewNonNull(articleInputType)}},
+				Resolve: resolveCreateArticle,
+			},
+			"updateArticle": &graphql.Field{
+				Type: graphql.NewNonNull(articleType),
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"version": {Type: graphql.NewNonNull(graphql.Int)},
+					"article": {Type: graphql.NewNonNull(articleInputType)},
+				},
+				Resolve: resolveUpdateArticle,
+			},
+			"deleteArticle": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.ID),
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: resolveDeleteArticle,
+			},
+		},
+	})
+
+	var err error
+	graphQLSchema, err = graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	return err
+}
+
+// articleGraphQLFieldTypes returns the GraphQL types of the fields of the Article struct, by the name of their JSON tag,
+// the server managed fields being left out of the input fields.
+func articleGraphQLFieldTypes(input bool) map[string]graphql.Type {
+	fields := make(map[string]graphql.Type)
+	articleType := reflect.TypeOf(Article{})
+	for i := 0; i < articleType.NumField(); i++ {
+		field := articleType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if input && slices.Contains(serverManagedFieldNames, name) {
+			continue
+		}
+		var fieldType graphql.Type
+		switch field.Type.Kind() {
+		case reflect.Slice:
+			fieldType = graphql.NewList(graphql.NewNonNull(graphql.String))
+		case reflect.Int, reflect.Int64:
+			fieldType = graphql.Int
+		case reflect.Float64:
+			fieldType = graphql.Float
+		case reflect.Bool:
+			fieldType = graphql.Boolean
+		default:
+			fieldType = graphql.String
+		}
+		if name == "id" {
+			fieldType = graphql.NewNonNull(graphql.ID)
+		}
+		if input && field.Tag.Get("validate") == "required" {
+			fieldType = graphql.NewNonNull(fieldType)
+		}
+		fields[name] = fieldType
+	}
+	return fields
+}
+
+// executeGraphQL executes the GraphQL request posted to /graphql (see GraphQLRequest) against the schema described
+// by initializeGraphQLSchema, and responds with its result, the errors being reported in the result as usual with GraphQL.
+// The changes made by the mutations are made on behalf of the actor of the request (see requestActor).
+func executeGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request GraphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		handleError(w, "Invalid GraphQL request", withErrorCode(ErrorCodeInvalidBody, errors.New("query is required")), http.StatusBadRequest)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphQLSchema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        context.WithValue(requestContext(r), graphQLActorKey{}, requestActor(r)),
+	})
+	responseJSON(w, result, http.StatusOK)
+}
+
+// resolveArticle resolves the article query, returning the article with the given ID, null when there is no such article
+// or it is a draft the actor can't read (see isHiddenDraft).
+func resolveArticle(p graphql.ResolveParams) (any, error) {
+	article, err := getStoredArticle(p.Context, tenantKey(p.Context, keysPrefix+p.Args["id"].(string)))
+	if err != nil || article == nil || isHiddenDraft(p.Context, *article) {
+		return nil, err
+	}
+	return *article, nil
+}
+
+// resolveArticles resolves the articles query, returning a page of articles like GET /articles.
+func resolveArticles(p graphql.ResolveParams) (any, error) {
+	return listArticles(p.Context, graphQLArgsValues(p.Args))
+}
+
+// resolveSearch resolves the search query, running the same search as GET /articles/search with the given arguments,
+// each filter searching an Article field like its query parameter (e.g. {field: "tags", value: "go|redis"}).
+func resolveSearch(p graphql.ResolveParams) (any, error) {
+	providedParams := graphQLArgsValues(p.Args)
+	filters, _ := p.Args["filters"].([]any)
+	for _, filter := range filters {
+		filterArgs := filter.(map[string]any)
+		field := filterArgs["field"].(string)
+		if !slices.Contains(structFieldsJsonTags(Article{}), field) {
+			return nil, fmt.Errorf("filter field must be one of the following fields: %v", structFieldsJsonTags(Article{}))
+		}
+		providedParams.Add(field, filterArgs["value"].(string))
+	}
+	return searchArticlesPage(p.Context, providedParams)
+}
+
+// resolveCreateArticle resolves the createArticle mutation, creating an article like POST /articles.
+// A unique ID is generated when none is provided.
+func resolveCreateArticle(p graphql.ResolveParams) (any, error) {
+	if err := checkGraphQLMutation(p); err != nil {
+		return nil, err
+	}
+	article, err := graphQLArticleInput(p.Args["article"])
+	if err != nil {
+		return nil, err
+	}
+	article.Id, _ = p.Args["id"].(string)
+	return createSingleArticle(p.Context, graphQLActor(p), article)
+}
+
+// resolveUpdateArticle resolves the updateArticle mutation, replacing an article like PUT /article/{id}.
+// The version of the stored article must be provided, so that concurrent updates are detected.
+func resolveUpdateArticle(p graphql.ResolveParams) (any, error) {
+	if err := checkGraphQLMutation(p); err != nil {
+		return nil, err
+	}
+	article, err := graphQLArticleInput(p.Args["article"])
+	if err != nil {
+		return nil, err
+	}
+	article.Id = p.Args["id"].(string)
+	article.Version = int64(p.Args["version"].(int))
+	return replaceArticle(p.Context, graphQLActor(p), article)
+}
+
+// resolveDeleteArticle resolves the deleteArticle mutation, moving an article to the trash like DELETE /article/{id},
+// and returns its ID.
+func resolveDeleteArticle(p graphql.ResolveParams) (any, error) {
+	if err := checkGraphQLMutation(p); err != nil {
+		return nil, err
+	}
+	id := p.Args["id"].(string)
+	if err := trashArticle(p.Context, graphQLActor(p), id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// checkGraphQLMutation returns an error when the actor of a mutation lacks the editor role, like on the routes changing
+// the articles (see requiredRole), POST /graphql being open to the readers: when Config.RBAC is enabled, or when the
+// request is authenticated by an API key lacking the write scope.
+func checkGraphQLMutation(p graphql.ResolveParams) error {
+	actor := authenticatedSubject(p.Context)
+	_, byAPIKey := authenticatedAPIKey(p.Context)
+	if (config.RBAC || byAPIKey) && actor != "" && !hasRole(p.Context, actor, roleEditor) {
+		return fmt.Errorf("%s lacks the %s role", actor, roleEditor)
+	}
+	return nil
+}
+
+// graphQLArticleInput converts an ArticleInput argument to an Article.
+func graphQLArticleInput(input any) (Article, error) {
+	var article Article
+	data, err := json.Marshal(input)
+	if err == nil {
+		err = json.Unmarshal(data, &article)
+	}
+	return article, err
+}
+
+// graphQLArgsValues converts the scalar arguments of a field to query parameters, so that they are parsed and validated
+// like the query parameters of the matching REST endpoint.
+func graphQLArgsValues(args map[string]any) url.Values {
+	values := url.Values{}
+	for name, value := range args {
+		switch value := value.(type) {
+		case string:
+			values.Set(name, value)
+		case int:
+			values.Set(name, strconv.Itoa(value))
+		}
+	}
+	return values
+}
+
+// graphQLActor returns the actor of the GraphQL request being executed.
+func graphQLActor(p graphql.ResolveParams) string {
+	actor, _ := p.Context.Value(graphQLActorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/articlespb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// grpcWriteMethods lists the gRPC methods changing the articles, requiring the write scope of an API key.
+var grpcWriteMethods = []string{
+	articlespb.ArticleService_Create_FullMethodName,
+	articlespb.ArticleService_Update_FullMethodName,
+	articlespb.ArticleService_Delete_FullMethodName,
+}
+
+// articleServiceServer implements the gRPC ArticleService (see proto/articles.proto) on top of the article operations
+// shared with the other APIs.
+type articleServiceServer struct {
+	articlespb.UnimplementedArticleServiceServer
+}
+
+// grpcServer serves the gRPC ArticleService, see startGRPCServer.
+var grpcServer *grpc.Server
+
+// startGRPCServer starts serving the gRPC ArticleService on config.GRPCAddress, alongside the REST API. The calls are
+// traced with otelgrpc when the tracing is enabled, like the HTTP requests (see withTracing).
+func startGRPCServer() {
+	listener, err := net.Listen("tcp", config.GRPCAddress)
+	if err != nil {
+		fatal(fmt.Sprintf("Failed to listen for gRPC on address %s", config.GRPCAddress), err)
+	}
+	var options []grpc.ServerOption
+	if tracerProvider != nil {
+		options = append(options, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+	grpcServer = grpc.NewServer(options...)
+	articlespb.RegisterArticleServiceServer(grpcServer, &articleServiceServer{})
+	go func() {
+		slog.Info("Starting gRPC Server", "address", config.GRPCAddress)
+		if err := grpcServer.Serve(listener); err != nil {
+			fatal("Failed to serve gRPC", err)
+		}
+	}()
+}
+
+// stopGRPCServer stops the gRPC server from accepting calls and waits for the calls in flight to complete, until ctx is
+// done, after which they are canceled.
+func stopGRPCServer(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Unable to complete all the gRPC calls in flight, canceling them")
+		grpcServer.Stop()
+	}
+}
+
+// Get returns the article with the given ID.
+func (server *articleServiceServer) Get(ctx context.Context, request *articlespb.GetArticleRequest) (*articlespb.Article, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	article, err := getStoredArticle(ctx, tenantKey(ctx, keysPrefix+request.GetId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if article == nil || isHiddenDraft(ctx, *article) {
+		return nil, status.Errorf(codes.NotFound, "no article found with ID %s", request.GetId())
+	}
+	return articleToProto(*article), nil
+}
+
+// List returns a page of articles, see listArticles.
+func (server *articleServiceServer) List(ctx context.Context, request *articlespb.ListArticlesRequest) (*articlespb.ListArticlesResponse, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queryParams := url.Values{}
+	setIntParam(queryParams, "limit", request.GetLimit())
+	setIntParam(queryParams, "offset", request.GetOffset())
+	page, err := listArticles(ctx, queryParams)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &articlespb.ListArticlesResponse{Total: int64(page.Total), Limit: int32(page.Limit), Offset: int32(page.Offset)}
+	for _, article := range page.Articles {
+		response.Articles = append(response.Articles, articleToProto(article))
+	}
+	return response, nil
+}
+
+// Search returns a page of the articles matching a search, see searchArticlesPage.
+func (server *articleServiceServer) Search(ctx context.Context, request *articlespb.SearchArticlesRequest) (*articlespb.SearchArticlesResponse, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providedParams := url.Values{}
+	for name, value := range map[string]string{
+		fullTextSearchParam: request.GetQ(),
+		"operator":          request.GetOperator(),
+		"sortBy":            request.GetSortBy(),
+		"order":             request.GetOrder(),
+		"match":             request.GetMatch(),
+		"createdAfter":      request.GetCreatedAfter(),
+		"createdBefore":     request.GetCreatedBefore(),
+	} {
+		if value != "" {
+			providedParams.Set(name, value)
+		}
+	}
+	setIntParam(providedParams, "fuzzy", request.GetFuzzy())
+	setIntParam(providedParams, "minWords", request.GetMinWords())
+	setIntParam(providedParams, "maxWords", request.GetMaxWords())
+	setIntParam(providedParams, "limit", request.GetLimit())
+	setIntParam(providedParams, "offset", request.GetOffset())
+	for _, filter := range request.GetFilters() {
+		if !slices.Contains(structFieldsJsonTags(Article{}), filter.GetField()) {
+			return nil, status.Errorf(codes.InvalidArgument, "filter field must be one of the following fields: %v", structFieldsJsonTags(Article{}))
+		}
+		providedParams.Add(filter.GetField(), filter.GetValue())
+	}
+
+	page, err := searchArticlesPage(ctx, providedParams)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &articlespb.SearchArticlesResponse{Total: page.Total, Limit: int32(page.Limit), Offset: int32(page.Offset)}
+	for _, hit := range page.Articles {
+		response.Hits = append(response.Hits, &articlespb.ArticleSearchHit{Article: articleToProto(hit.Article), Score: hit.Score})
+	}
+	return response, nil
+}
+
+// Create creates an article, see createSingleArticle.
+func (server *articleServiceServer) Create(ctx context.Context, request *articlespb.CreateArticleRequest) (*articlespb.Article, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := grpcAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	article, err := createSingleArticle(ctx, grpcActor(ctx), articleFromProto(request.GetArticle()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return articleToProto(article), nil
+}
+
+// Update replaces an article, see replaceArticle.
+func (server *articleServiceServer) Update(ctx context.Context, request *articlespb.UpdateArticleRequest) (*articlespb.Article, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := grpcAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	article := articleFromProto(request.GetArticle())
+	article.Version = request.GetArticle().GetVersion()
+	article, err = replaceArticle(ctx, grpcActor(ctx), article)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return articleToProto(article), nil
+}
+
+// Delete moves an article to the trash, see trashArticle.
+func (server *articleServiceServer) Delete(ctx context.Context, request *articlespb.DeleteArticleRequest) (*articlespb.DeleteArticleResponse, error) {
+	ctx, err := grpcContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := grpcAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	if err := trashArticle(ctx, grpcActor(ctx), request.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &articlespb.DeleteArticleResponse{}, nil
+}
+
+// Watch streams the events of the event bus matching the request until the client cancels the call, only the events of
+// the articles of the tenant and namespace of the call being sent.
+func (server *articleServiceServer) Watch(request *articlespb.WatchArticlesRequest, stream articlespb.ArticleService_WatchServer) error {
+	ctx, err := grpcContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	types, err := parseArticleEventTypes(strings.Join(request.GetTypes(), ","))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	subscription := &articleSubscription{}
+	subscription.apply(SubscriptionMessage{Action: "subscribe", Tags: request.GetTags(), Authors: request.GetAuthors()})
+
+	events, unsubscribe := clusterArticleEvents.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if !event.scopedTo(ctx) || (len(types) > 0 && !slices.Contains(types, event.Type)) || !subscription.matches(event) {
+				continue
+			}
+			err := stream.Send(&articlespb.ArticleEvent{
+				Id:         event.Id,
+				Type:       string(event.Type),
+				ArticleId:  event.ArticleId,
+				Actor:      event.Actor,
+				Article:    articleToProto(*event.Article),
+				OccurredAt: event.OccurredAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcActor returns who makes the changes of a gRPC call: the subject it is authenticated as (see grpcContext),
+// anonymousActor when it is not authenticated, like requestActor.
+func grpcActor(ctx context.Context) string {
+	if subject := authenticatedSubject(ctx); subject != "" {
+		return subject
+	}
+	return anonymousActor
+}
+
+// grpcContext returns the context of the Database operations made to serve a gRPC call, scoped to its tenant (the one
+// of its principal, or the one named by its metadata named after config.TenantHeader, e.g. x-tenant-id, see
+// resolveTenant) when config.MultiTenancy is enabled and to the
+// namespace named by its metadata named after config.NamespaceHeader when it is one of config.Namespaces, like
+// withTenancy. The call is authenticated by the bearer token of its authorization metadata or by the API key of its
+// x-api-key metadata when the authentication is enabled, like withAuthentication, an invalid token or key being refused.
+// The call is tagged with the ID of its x-request-id metadata, or a new ID, sent back as x-request-id header, like
+// withRequestID.
+func grpcContext(ctx context.Context) (context.Context, error) {
+	ctx = context.WithoutCancel(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	var sentRequestID string
+	if values := md.Get(strings.ToLower(requestIDHeader)); len(values) > 0 {
+		sentRequestID = values[0]
+	}
+	requestID := newRequestID(sentRequestID)
+	ctx = contextWithRequestID(ctx, requestID)
+	grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(requestIDHeader), requestID))
+	if values := md.Get("authorization"); authenticator != nil && len(values) > 0 {
+		scheme, token, _ := strings.Cut(values[0], " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return nil, status.Error(codes.Unauthenticated, "the authorization metadata must hold a bearer token")
+		}
+		principal, err := authenticator.authenticate(strings.TrimSpace(token))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		ctx = contextWithPrincipalTenant(contextWithRoles(contextWithSubject(ctx, principal.subject), principal.roles), principal.tenant)
+	}
+	if values := md.Get(strings.ToLower(apiKeyHeader)); config.APIKeys && len(values) > 0 {
+		apiKey, err := authenticateAPIKey(ctx, values[0])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		scope := apiKeyScopeRead
+		if method, _ := grpc.Method(ctx); slices.Contains(grpcWriteMethods, method) {
+			scope = apiKeyScopeWrite
+		}
+		if !slices.Contains(apiKey.Scopes, scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "the API key lacks the %s scope", scope)
+		}
+		ctx = contextWithRoles(contextWithSubject(ctx, apiKey.Name), apiKey.roles())
+		if !apiKey.isBootstrap() {
+			ctx = contextWithPrincipalTenant(ctx, apiKey.Tenant)
+		}
+	}
+	var sentTenant, namespace string
+	if values := md.Get(config.TenantHeader); len(values) > 0 {
+		sentTenant = values[0]
+	}
+	tenant, err := resolveTenant(ctx, sentTenant)
+	switch {
+	case errors.Is(err, errTenantUnauthenticated):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if values := md.Get(config.NamespaceHeader); len(config.Namespaces) > 0 && len(values) > 0 {
+		namespace = values[0]
+	}
+	if tenant == "" && namespace == "" {
+		return ctx, nil
+	}
+	if tenant != "" && !tenantPattern.MatchString(tenant) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s must only hold letters, digits, - and _, up to 64 of them", strings.ToLower(config.TenantHeader))
+	}
+	if namespace != "" && !slices.Contains(config.Namespaces, namespace) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s must be one of the following namespaces: %v", strings.ToLower(config.NamespaceHeader), config.Namespaces)
+	}
+	ctx = contextWithNamespace(contextWithTenant(ctx, tenant), namespace)
+	if err := initializeTenant(ctx); errors.Is(err, errUnknownTenant) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return ctx, nil
+}
+
+// grpcAuthenticated returns an Unauthenticated error when the authentication is enabled and the call is not
+// authenticated, the calls changing the articles having to be, and a PermissionDenied error when Config.RBAC is enabled
+// and its actor lacks the editor role.
+func grpcAuthenticated(ctx context.Context) error {
+	subject := authenticatedSubject(ctx)
+	if authenticationEnabled() && subject == "" {
+		return status.Error(codes.Unauthenticated, "a bearer token or an API key is required")
+	}
+	if config.RBAC && subject != "" && !hasRole(ctx, subject, roleEditor) {
+		return status.Errorf(codes.PermissionDenied, "%s lacks the %s role", subject, roleEditor)
+	}
+	return nil
+}
+
+// grpcError converts the error of an article operation to a gRPC status error.
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, errInvalidArticleRequest):
+		code = codes.InvalidArgument
+	case errors.Is(err, errArticleNotFound):
+		code = codes.NotFound
+	case errors.Is(err, errArticleExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, errArticleVersion):
+		code = codes.Aborted
+	case errors.Is(err, errArticleForbidden):
+		code = codes.PermissionDenied
+	}
+	return status.Error(code, err.Error())
+}
+
+// setIntParam sets a query parameter to a number, unless it is 0 so that the default value applies.
+func setIntParam(queryParams url.Values, name string, value int32) {
+	if value != 0 {
+		queryParams.Set(name, strconv.Itoa(int(value)))
+	}
+}
+
+// articleToProto converts an article to its gRPC message.
+func articleToProto(article Article) *articlespb.Article {
+	return &articlespb.Article{
+		Id:                 article.Id,
+		Title:              article.Title,
+		Content:            article.Content,
+		Author:             article.Author,
+		Tags:               article.Tags,
+		Language:           article.Language,
+		CreatedAt:          article.CreatedAt,
+		UpdatedAt:          article.UpdatedAt,
+		Status:             article.Status,
+		PublishAt:          article.PublishAt,
+		ExpiresAt:          article.ExpiresAt,
+		Version:            article.Version,
+		ContentFormat:      article.ContentFormat,
+		WordCount:          int32(article.WordCount),
+		ReadingTimeMinutes: int32(article.ReadingTimeMinutes),
+		Likes:              article.Likes,
+		Category:           article.Category,
+		Owner:              article.Owner,
+		Editors:            article.Editors,
+	}
+}
+
+// articleFromProto converts the gRPC message of an article to an article, without its server managed fields.
+func articleFromProto(message *articlespb.Article) Article {
+	return Article{
+		Id:            message.GetId(),
+		Title:         message.GetTitle(),
+		Content:       message.GetContent(),
+		Author:        message.GetAuthor(),
+		Tags:          message.GetTags(),
+		Language:      message.GetLanguage(),
+		Status:        message.GetStatus(),
+		PublishAt:     message.GetPublishAt(),
+		ExpiresAt:     message.GetExpiresAt(),
+		ContentFormat: message.GetContentFormat(),
+		Category:      message.GetCategory(),
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"time"
+)
+
+// The statuses of the service and of its components, see getHealth.
+const (
+	healthStatusOK       = "ok"       // healthStatusOK is the status of a service whose components are all up.
+	healthStatusDegraded = "degraded" // healthStatusDegraded is the status of a service with a component down.
+	componentStatusUp    = "up"       // componentStatusUp is the status of a component working as expected.
+	componentStatusDown  = "down"     // componentStatusDown is the status of a failing component.
+)
+
+// ComponentHealth is the status of a component the service depends on.
+type ComponentHealth struct {
+	Name      string  `json:"name"`            // Name is the name of the component, e.g. database.
+	Status    string  `json:"status"`          // Status is either up or down.
+	LatencyMs float64 `json:"latencyMs"`       // LatencyMs is the time it took to check the component, in milliseconds.
+	Error     string  `json:"error,omitempty"` // Error is the reason the component is down.
+}
+
+// Health is the status of the service along with the one of each of its components.
+type Health struct {
+	Status     string            `json:"status"`     // Status is ok when all the components are up, degraded otherwise.
+	Components []ComponentHealth `json:"components"` // Components holds the status of each component.
+}
+
+// healthCheck checks a component of the service, returning an error when it is down.
+type healthCheck struct {
+	name  string                          // name is the name of the component.
+	check func(ctx context.Context) error // check returns the reason the component is down, nil when it is up.
+}
+
+// databaseHealthCheck checks that the Database answers a PING.
+var databaseHealthCheck = healthCheck{name: "database", check: func(ctx context.Context) error {
+	return db.Ping(ctx, databaseClient)
+}}
+
+// indexHealthCheck checks that the articles search index of the tenant ctx is scoped to exists.
+var indexHealthCheck = healthCheck{name: "index", check: func(ctx context.Context) error {
+	indexName := tenantIndexName(ctx, searchIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err == nil && !exists {
+		err = fmt.Errorf("search index %s does not exist", indexName)
+	}
+	return err
+}}
+
+// migrationsHealthCheck checks that all the migrations of the Database have been applied, see runMigrations.
+var migrationsHealthCheck = healthCheck{name: "migrations", check: func(ctx context.Context) error {
+	pending, err := db.PendingMigrations(ctx, databaseClient, migrationsKey, migrations)
+	if err == nil && len(pending) > 0 {
+		err = fmt.Errorf("migrations %v have not been applied", pending)
+	}
+	return err
+}}
+
+// checkHealth runs the checks of the components, each of them within Config.HealthTimeout, and returns the status of
+// the service.
+func checkHealth(ctx context.Context, checks []healthCheck) Health {
+	health := Health{Status: healthStatusOK, Components: make([]ComponentHealth, 0, len(checks))}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, config.HealthTimeout)
+		start := time.Now()
+		err := check.check(checkCtx)
+		cancel()
+		component := ComponentHealth{Name: check.name, Status: componentStatusUp, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			component.Status, component.Error = componentStatusDown, err.Error()
+			health.Status = healthStatusDegraded
+		}
+		health.Components = append(health.Components, component)
+	}
+	return health
+}
+
+// responseHealth responds with the status of the service, as an HTTP 503 Service Unavailable response when it is
+// degraded.
+func responseHealth(w http.ResponseWriter, health Health) {
+	w.Header().Set("Cache-Control", "no-store")
+	statusCode := http.StatusOK
+	if health.Status != healthStatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+	responseJSON(w, health, statusCode)
+}
+
+// getHealth handles GET /healthz, checking that the Database answers and that the search index exists, for the load
+// balancers and the uptime checks. It responds with the status of each of them, as an HTTP 503 Service Unavailable
+// response when one of them is down.
+func getHealth(w http.ResponseWriter, r *http.Request) {
+	responseHealth(w, checkHealth(r.Context(), []healthCheck{databaseHealthCheck, indexHealthCheck}))
+}
+
+// getLiveness handles GET /livez, responding as long as the process serves requests, for the liveness probes which
+// restart the instances that are stuck. It checks no dependency, an unavailable Database being no reason to restart.
+func getLiveness(w http.ResponseWriter, r *http.Request) {
+	responseHealth(w, Health{Status: healthStatusOK, Components: []ComponentHealth{}})
+}
+
+// getReadiness handles GET /readyz, checking that the Database answers, that the search index exists and that the
+// migrations have been applied, for the readiness probes which stop routing the requests to the instances that can't
+// serve them. An instance shutting down is not ready. It responds like GET /healthz.
+func getReadiness(w http.ResponseWriter, r *http.Request) {
+	responseHealth(w, checkHealth(r.Context(), []healthCheck{stoppingHealthCheck, databaseHealthCheck, indexHealthCheck, migrationsHealthCheck}))
+}
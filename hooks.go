@@ -0,0 +1,29 @@
+package main
+
+import "context"
+
+// articleChanged keeps the data derived from the articles in sync once an article has been written to the Database,
+// and publishes the change made by actor on the event bus (see publishArticleEvent).
+// previous is the article before the change (nil when it has been created) and current is the article after
+// the change (nil when it has been deleted). ctx is scoped to the tenant of the article.
+func articleChanged(ctx context.Context, actor string, previous *Article, current *Article) {
+	refreshDerivedData(ctx, previous, current)
+	recordAudit(ctx, actor, previous, current)
+	publishArticleEvent(ctx, actor, previous, current)
+}
+
+// refreshDerivedData keeps the data derived from the articles in sync once an article has been written to the Database,
+// without publishing any event, e.g. when the derived data of unchanged articles are rebuilt.
+// previous and current are the same as for articleChanged.
+func refreshDerivedData(ctx context.Context, previous *Article, current *Article) {
+	id, previousTitle, title := "", "", ""
+	if previous != nil {
+		id, previousTitle = previous.Id, previous.Title
+	}
+	if current != nil {
+		id, title = current.Id, current.Title
+		scheduleEmbedding(ctx, *current)
+	}
+	refreshTitleSuggestion(ctx, previousTitle, title)
+	refreshPublicationSchedule(ctx, id, current)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net/http"
+)
+
+// configureHTTP2 makes server serve HTTP/2 over TLS, negotiated with ALPN, along with HTTP/1.1, unless Config.HTTP2
+// is disabled. Without TLS, it serves HTTP/2 over cleartext (h2c) when Config.H2C is enabled, either upgraded from
+// HTTP/1.1 or with prior knowledge, for the internal clients multiplexing their requests. The HTTP/2 connections are
+// closed gracefully with the others when the service shuts down. It must be called once the TLS configuration and the
+// handler of server are set.
+func configureHTTP2(server *http.Server) error {
+	if !config.HTTP2 {
+		// A non-nil TLSNextProto turns off the HTTP/2 support of net/http.
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		return nil
+	}
+	// http2.ConfigureServer creates a TLS configuration, which would make the server serve HTTPS, see serveUntilSignaled
+	tlsConfig := server.TLSConfig
+	http2Server := &http2.Server{IdleTimeout: config.IdleTimeout}
+	if err := http2.ConfigureServer(server, http2Server); err != nil {
+		return fmt.Errorf("unable to configure HTTP/2: %v", err)
+	}
+	if tlsConfig == nil {
+		server.TLSConfig = nil
+		if config.H2C {
+			server.Handler = h2c.NewHandler(server.Handler, http2Server)
+		}
+	}
+	return nil
+}
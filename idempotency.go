@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// idempotencyKeysPrefix is the prefix of the keys holding the responses of the requests sent with an Idempotency-Key.
+	idempotencyKeysPrefix = "idempotency:"
+	// idempotencyPending is the value held by an Idempotency-Key while its request is being processed.
+	idempotencyPending = "pending"
+	// maxIdempotencyKeyLength is the maximum length of an Idempotency-Key.
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentResponse is the response to a request sent with an Idempotency-Key, kept to be replayed.
+type idempotentResponse struct {
+	RequestHash string `json:"requestHash"` // RequestHash is the hash of the request body, a key can't be reused for another request.
+	StatusCode  int    `json:"statusCode"`  // StatusCode is the status code of the response.
+	ContentType string `json:"contentType"` // ContentType is the content type of the response.
+	Body        []byte `json:"body"`        // Body is the body of the response.
+}
+
+// responseRecorder is an http.ResponseWriter writing through to another one while recording the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader records the status code of the response before sending it.
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body of the response before sending it.
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// withIdempotency makes a handler idempotent for the requests sent with an Idempotency-Key header: the response to the
+// first request is kept for config.IdempotencyTTL and replayed to the retries of the request, which are not processed again.
+// A retry arriving while the first request is still processed gets an HTTP 409 Conflict, and reusing a key for a request
+// with another body gets an HTTP 422 Unprocessable Entity. Responses with a 5xx status code are not kept, so that they can be retried.
+func withIdempotency(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := requestContext(r)
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" {
+			handler(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			handleError(w, "Invalid Idempotency-Key", fmt.Errorf("Idempotency-Key can't be longer than %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, "Failed to read request body", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+
+		key := tenantKey(ctx, idempotencyKeysPrefix+r.Method+":"+r.URL.Path+":"+idempotencyKey)
+		reserved, err := db.SetNX(ctx, databaseClient, key, idempotencyPending, config.IdempotencyTTL)
+		if err != nil {
+			handleError(w, "Failed to check Idempotency-Key", err, http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			replayIdempotentResponse(ctx, w, key, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		handler(recorder, r)
+
+		if recorder.statusCode >= http.StatusInternalServerError {
+			if _, err := db.Del(ctx, databaseClient, key); err != nil {
+				slog.WarnContext(ctx, "Unable to release Idempotency-Key", "key", idempotencyKey, "Error:", err)
+			}
+			return
+		}
+		response, err := json.Marshal(idempotentResponse{
+			RequestHash: requestHash,
+			StatusCode:  recorder.statusCode,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err == nil {
+			err = db.Set(ctx, databaseClient, key, response, config.IdempotencyTTL)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Unable to keep the response of Idempotency-Key", "key", idempotencyKey, "Error:", err)
+		}
+	}
+}
+
+// replayIdempotentResponse sends again the response kept at key, to the retry of a request whose body has the given hash.
+func replayIdempotentResponse(ctx context.Context, w http.ResponseWriter, key string, requestHash string) {
+	value, err := db.Get(ctx, databaseClient, key)
+	if err != nil {
+		handleError(w, "Failed to check Idempotency-Key", err, http.StatusInternalServerError)
+		return
+	}
+	if value == idempotencyPending {
+		handleError(w, "Request in progress", withErrorCode(ErrorCodeIdempotencyKeyInUse, errors.New("a request with the same Idempotency-Key is being processed")), http.StatusConflict)
+		return
+	}
+	var response idempotentResponse
+	if value == "" || json.Unmarshal([]byte(value), &response) != nil {
+		handleError(w, "Request in progress", withErrorCode(ErrorCodeIdempotencyKeyInUse, errors.New("a request with the same Idempotency-Key has just been processed, retry")), http.StatusConflict)
+		return
+	}
+	if response.RequestHash != requestHash {
+		handleError(w, "Idempotency-Key reused", withErrorCode(ErrorCodeIdempotencyKeyReused, errors.New("the Idempotency-Key has already been used for another request")), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(response.StatusCode)
+	if _, err := w.Write(response.Body); err != nil {
+		slog.ErrorContext(ctx, "Unable to write the replayed response", "Error:", err)
+	}
+}
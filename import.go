@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// importBatchSize is the number of articles written at once by an import job.
+	importBatchSize = 100
+	// maxImportSize is the maximum size of an uploaded import file, in bytes.
+	maxImportSize = 64 << 20
+)
+
+// importMediaTypes lists the media types of the files that can be imported.
+var importMediaTypes = []string{ndjsonMediaType, "text/csv"}
+
+// importRow is an article read from an import file, or the reason why it could not be read.
+type importRow struct {
+	article Article
+	err     error
+}
+
+// importArticles starts a background job creating the articles of the uploaded file, either newline delimited JSON
+// (one article per line) or CSV (a header row naming the Article fields of the columns, tags being joined with |
+// and times being Unix timestamps, RFC 3339 or dates, like the CSV export), according to the Content-Type.
+// It responds with an HTTP 202 Accepted along with the job, whose progress is reported by getJob.
+// Each article is validated and created on its own, like with POST /articles, the articles failing being
+// reported by the job along with their position in the file instead of failing the whole import.
+func importArticles(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !slices.Contains(importMediaTypes, mediaType) {
+		handleError(w, "Unsupported import format",
+			fmt.Errorf("content type %s is not supported, use one of %v", mediaType, importMediaTypes), http.StatusUnsupportedMediaType)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		handleError(w, "Failed to read request body", err, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	actor := requestActor(r)
+	submitJob(requestContext(r), w, "import", false, func(ctx context.Context, jobId string) error {
+		return runImport(ctx, jobId, actor, mediaType, data)
+	})
+}
+
+// runImport creates the articles of an import file in the tenant ctx is scoped to for the job with the given ID, on
+// behalf of actor, by batches of importBatchSize articles, the job progress and the failed articles being updated
+// along the way.
+func runImport(ctx context.Context, jobId string, actor string, mediaType string, data []byte) error {
+	var rows []importRow
+	var err error
+	if mediaType == ndjsonMediaType {
+		rows, err = readNDJSONImport(data)
+	} else {
+		rows, err = readCSVImport(data)
+	}
+	if err != nil {
+		return err
+	}
+	jobs.update(jobId, func(job *Job) { job.Total = len(rows) })
+
+	importedIds := make(map[string]bool)
+	for start := 0; start < len(rows); start += importBatchSize {
+		var articles []*Article
+		var setArgs []db.JSONSetArgs
+		for index := start; index < min(start+importBatchSize, len(rows)); index++ {
+			article, setArg, err := prepareImportedArticle(ctx, actor, rows[index], importedIds)
+			if err != nil {
+				jobs.fail(jobId, ArticleBulkError{Index: index, Id: rows[index].article.Id, Error: err.Error(), Fields: fieldErrorsOf(err)})
+				continue
+			}
+			importedIds[article.Id] = true
+			articles = append(articles, article)
+			setArgs = append(setArgs, setArg)
+		}
+
+		if len(setArgs) > 0 {
+			expireAt := make(map[string]int64, len(articles))
+			for _, article := range articles {
+				expireAt[tenantKey(ctx, keysPrefix+article.Id)] = article.ExpiresAt
+			}
+			if err := db.JSONMSetArgsExpireAt(ctx, databaseClient, setArgs, expireAt); err != nil {
+				return fmt.Errorf("unable to create articles: %v", err)
+			}
+		}
+		for _, article := range articles {
+			articleChanged(ctx, actor, nil, article)
+		}
+		jobs.update(jobId, func(job *Job) { job.Processed = min(start+importBatchSize, len(rows)) })
+	}
+	return nil
+}
+
+// prepareImportedArticle validates an article imported by actor and returns it along with the arguments to create it,
+// or the reason why it can't be created. importedIds holds the IDs of the articles already imported from the same file.
+func prepareImportedArticle(ctx context.Context, actor string, row importRow, importedIds map[string]bool) (*Article, db.JSONSetArgs, error) {
+	if row.err != nil {
+		return nil, db.JSONSetArgs{}, row.err
+	}
+	article := row.article
+	if article.Id == "" {
+		article.Id = uuid.New().String()
+	}
+	if err := validateStruct(article); err != nil {
+		return nil, db.JSONSetArgs{}, err
+	}
+	key := tenantKey(ctx, keysPrefix+article.Id)
+	exists, err := db.Exists(ctx, databaseClient, key)
+	if err != nil {
+		return nil, db.JSONSetArgs{}, fmt.Errorf("unable to check if the article exists: %v", err)
+	}
+	if exists != 0 || importedIds[article.Id] {
+		return nil, db.JSONSetArgs{}, fmt.Errorf("article with ID %s already exists", article.Id)
+	}
+	setServerManagedFields(&article, nil, actor)
+
+	articleByte, err := json.Marshal(article)
+	if err != nil {
+		return nil, db.JSONSetArgs{}, err
+	}
+	return &article, db.JSONSetArgs{Key: key, Path: "$", Value: articleByte}, nil
+}
+
+// readNDJSONImport reads the articles of a newline delimited JSON import file, one per line, blank lines being ignored.
+func readNDJSONImport(data []byte) ([]importRow, error) {
+	var rows []importRow
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var row importRow
+			if decodeErr := json.Unmarshal(line, &row.article); decodeErr != nil {
+				row.err = fmt.Errorf("invalid JSON: %v", decodeErr)
+			}
+			rows = append(rows, row)
+		}
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readCSVImport reads the articles of a CSV import file, whose header row names the Article field of each column.
+func readCSVImport(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	names := articleFieldNames()
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if !slices.Contains(names, header[i]) {
+			return nil, fmt.Errorf("invalid CSV header, column %s is not one of the following fields: %v", column, names)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		var row importRow
+		if err != nil {
+			row.err = fmt.Errorf("invalid CSV: %v", err)
+		} else {
+			row.article, row.err = csvRecordArticle(header, record)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// csvRecordArticle builds an article from a CSV record, header naming the Article field of each column.
+func csvRecordArticle(header []string, record []string) (Article, error) {
+	var article Article
+	if len(record) != len(header) {
+		return article, fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+	}
+
+	document := make(map[string]any, len(header))
+	articleType := reflect.TypeOf(article)
+	for i := 0; i < articleType.NumField(); i++ {
+		field := articleType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		column := slices.Index(header, name)
+		if column < 0 || record[column] == "" {
+			continue
+		}
+		value := record[column]
+		switch field.Type.Kind() {
+		case reflect.Slice:
+			document[name] = strings.Split(value, csvTagsSeparator)
+		case reflect.Int64:
+			timestamp, err := parseTimestamp(value)
+			if err != nil {
+				return article, fmt.Errorf("%s must be a Unix timestamp, an RFC 3339 time or a date", name)
+			}
+			document[name] = timestamp
+		default:
+			document[name] = value
+		}
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return article, err
+	}
+	if err := json.Unmarshal(data, &article); err != nil {
+		return article, errors.New("the values of the record don't match the Article fields")
+	}
+	return article, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"slices"
+)
+
+// articlesIndexSchema returns the schema of the articles search index of the tenant ctx is scoped to, according to
+// the configuration.
+func articlesIndexSchema(ctx context.Context) db.IndexSchema {
+	textField := func(alias string, sortable bool) db.IndexField {
+		return db.IndexField{
+			Path:     "$." + alias,
+			Alias:    alias,
+			Type:     db.TextField,
+			Sortable: sortable,
+			NoStem:   slices.Contains(config.IndexNoStemFields, alias),
+		}
+	}
+
+	return db.IndexSchema{
+		Prefixes:         []string{tenantKey(ctx, keysPrefix)},
+		LanguageField:    "$.language",
+		Stopwords:        config.IndexStopwords,
+		DisableStopwords: config.IndexStopwordsDisabled,
+		Fields: []db.IndexField{
+			textField("id", true),
+			textField("title", true),
+			textField("content", false),
+			textField("author", true),
+			{Path: "$.tags", Alias: "tags", Type: db.TagField},
+			{Path: "$.language", Alias: "language", Type: db.TagField},
+			{Path: "$.status", Alias: "status", Type: db.TagField},
+			{Path: "$.category", Alias: "category", Type: db.TagField},
+			{Path: "$.createdAt", Alias: "createdAt", Type: db.NumericField, Sortable: true},
+			{Path: "$.updatedAt", Alias: "updatedAt", Type: db.NumericField, Sortable: true},
+			{Path: "$.expiresAt", Alias: "expiresAt", Type: db.NumericField},
+			{Path: "$.wordCount", Alias: "wordCount", Type: db.NumericField, Sortable: true},
+			{Path: "$." + embeddingField, Alias: embeddingField, Type: db.VectorField, VectorDimensions: config.EmbeddingDimensions},
+		},
+	}
+}
+
+// initializeSearchIndex creates the articles search index of the tenant ctx is scoped to when it does not exist yet,
+// so that a fresh Database works out of the box. An existing index is left untouched.
+func initializeSearchIndex(ctx context.Context) error {
+	indexName := tenantIndexName(ctx, searchIndexName)
+	exists, err := db.IndexExists(ctx, databaseClient, indexName)
+	if err != nil || exists {
+		return err
+	}
+	slog.InfoContext(ctx, "Creating the search index", "index", indexName)
+	return db.CreateIndex(ctx, databaseClient, indexName, articlesIndexSchema(ctx))
+}
+
+// addMissingIndexFields adds to the articles search index of the tenant ctx is scoped to the fields of the current
+// schema it is missing using FT.ALTER, and returns the alias of the fields added.
+func addMissingIndexFields(ctx context.Context) ([]string, error) {
+	indexName := tenantIndexName(ctx, searchIndexName)
+	attributes, err := db.IndexAttributes(ctx, databaseClient, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve search index information: %v", err)
+	}
+
+	addedFields := []string{}
+	for _, field := range articlesIndexSchema(ctx).Fields {
+		if slices.Contains(attributes, field.Alias) {
+			continue
+		}
+		if err := db.AlterIndex(ctx, databaseClient, indexName, field); err != nil {
+			return addedFields, fmt.Errorf("unable to add field %s to search index: %v", field.Alias, err)
+		}
+		addedFields = append(addedFields, field.Alias)
+	}
+	return addedFields, nil
+}
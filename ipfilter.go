@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// clientIP returns the IP address of the client of r: the host of its remote address, or, when the remote address is
+// one of Config.TrustedProxies, the last address of its X-Forwarded-For header which is not one of them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remoteAddr, err := netip.ParseAddr(host)
+	if err != nil || !containsAddr(config.TrustedProxies, remoteAddr) {
+		return host
+	}
+	forwardedFor := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		forwarded, err := netip.ParseAddr(strings.TrimSpace(forwardedFor[i]))
+		if err != nil {
+			break
+		}
+		if !containsAddr(config.TrustedProxies, forwarded) {
+			return forwarded.String()
+		}
+		remoteAddr = forwarded
+	}
+	return remoteAddr.String()
+}
+
+// containsAddr reports whether addr belongs to one of prefixes.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return slices.ContainsFunc(prefixes, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+// withIPFilter refuses the requests of the clients (see clientIP) belonging to Config.DeniedIPs, or not belonging to
+// Config.AllowedIPs when it is set, with an HTTP 403 Forbidden error. The /admin requests must belong to
+// Config.AdminAllowedIPs as well when it is set, e.g. to restrict them to the internal networks.
+func withIPFilter(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(config.DeniedIPs) == 0 && len(config.AllowedIPs) == 0 && len(config.AdminAllowedIPs) == 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r)
+		addr, err := netip.ParseAddr(ip)
+		switch {
+		case err != nil:
+			handleError(w, "Forbidden", fmt.Errorf("the address of the client %s is invalid", ip), http.StatusForbidden)
+		case containsAddr(config.DeniedIPs, addr):
+			handleError(w, "Forbidden", fmt.Errorf("the address %s is denied", ip), http.StatusForbidden)
+		case len(config.AllowedIPs) > 0 && !containsAddr(config.AllowedIPs, addr):
+			handleError(w, "Forbidden", fmt.Errorf("the address %s is not allowed", ip), http.StatusForbidden)
+		case requiredRole(r) == roleAdmin && len(config.AdminAllowedIPs) > 0 && !containsAddr(config.AdminAllowedIPs, addr):
+			handleError(w, "Forbidden", fmt.Errorf("the address %s is not allowed to call the admin routes", ip), http.StatusForbidden)
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+// parsePrefixes returns the networks of the given CIDR notations.
+func parsePrefixes(networks ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(networks))
+	for i, network := range networks {
+		prefixes[i] = netip.MustParsePrefix(network)
+	}
+	return prefixes
+}
+
+func TestContainsAddr(t *testing.T) {
+	prefixes := parsePrefixes("10.0.0.0/8", "192.168.1.0/24", "203.0.113.7/32", "2001:db8::/32")
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"10.0.0.1", true},
+		{"10.255.255.255", true},
+		{"11.0.0.1", false},
+		{"192.168.1.42", true},
+		{"192.168.2.1", false},
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"::ffff:10.1.2.3", true},
+		{"::ffff:11.1.2.3", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"::1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.addr, func(t *testing.T) {
+			if contained := containsAddr(prefixes, netip.MustParseAddr(test.addr)); contained != test.expected {
+				t.Errorf("containsAddr(%v, %s) = %v, expected %v", prefixes, test.addr, contained, test.expected)
+			}
+		})
+	}
+	if containsAddr(nil, netip.MustParseAddr("10.0.0.1")) {
+		t.Errorf("containsAddr(nil, 10.0.0.1) = true, expected false")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.TrustedProxies = parsePrefixes("10.0.0.0/8")
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expected     string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted proxy", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed forwarded address", "10.0.0.1:1234", []string{"192.0.2.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"}, "198.51.100.1"},
+		{"only trusted proxies", "10.0.0.1:1234", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"invalid forwarded address", "10.0.0.1:1234", []string{"198.51.100.1, unknown"}, "10.0.0.1"},
+		{"trusted proxy without header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"IPv6 client", "[2001:db8::1]:1234", nil, "2001:db8::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			r.RemoteAddr = test.remoteAddr
+			for _, forwardedFor := range test.forwardedFor {
+				r.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+			if ip := clientIP(r); ip != test.expected {
+				t.Errorf("clientIP() = %s, expected %s", ip, test.expected)
+			}
+		})
+	}
+}
+
+func TestWithIPFilter(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.TrustedProxies = nil
+	config.AllowedIPs = parsePrefixes("10.0.0.0/8", "192.168.0.0/16")
+	config.DeniedIPs = parsePrefixes("10.0.0.66/32")
+	config.AdminAllowedIPs = parsePrefixes("192.168.1.0/24")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		path       string
+		status     int
+	}{
+		{"allowed", "10.1.2.3:1234", "/articles", http.StatusOK},
+		{"not allowed", "203.0.113.7:1234", "/articles", http.StatusForbidden},
+		{"denied", "10.0.0.66:1234", "/articles", http.StatusForbidden},
+		{"IPv4-mapped denied", "[::ffff:10.0.0.66]:1234", "/articles", http.StatusForbidden},
+		{"invalid address", "unknown", "/articles", http.StatusForbidden},
+		{"admin allowed", "192.168.1.10:1234", "/admin/apikeys", http.StatusOK},
+		{"admin not allowed", "10.1.2.3:1234", "/admin/apikeys", http.StatusForbidden},
+	}
+	handler := withIPFilter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.RemoteAddr = test.remoteAddr
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, r)
+			if recorder.Code != test.status {
+				t.Errorf("withIPFilter() status = %d, expected %d", recorder.Code, test.status)
+			}
+		})
+	}
+}
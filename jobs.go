@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// JobStatus represents the status of a background job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job represents a background job and its progress.
+type Job struct {
+	Id         string     `json:"id"`                   // Id is the unique identifier of the job.
+	Type       string     `json:"type"`                 // Type is the kind of work done by the job, e.g. reindex.
+	Status     JobStatus  `json:"status"`               // Status is the current status of the job.
+	Step       string     `json:"step,omitempty"`       // Step is the current step of a job made of several, e.g. reindex.
+	Total      int        `json:"total"`                // Total is the number of items to process, when known.
+	Processed  int        `json:"processed"`            // Processed is the number of items processed so far.
+	Failed     int        `json:"failed,omitempty"`     // Failed is the number of processed items that failed, for jobs processing items independently.
+	Error      string     `json:"error,omitempty"`      // Error is the reason of the failure of a failed job.
+	CreatedAt  time.Time  `json:"createdAt"`            // CreatedAt is when the job was submitted.
+	StartedAt  *time.Time `json:"startedAt,omitempty"`  // StartedAt is when a worker started the job.
+	FinishedAt *time.Time `json:"finishedAt,omitempty"` // FinishedAt is when the job completed or failed.
+	// Errors describes why items failed, for jobs processing items independently, up to maxJobErrors of them.
+	Errors []ArticleBulkError `json:"errors,omitempty"`
+	// exclusive reports whether the job holds the lock of its type while it is queued or running.
+	exclusive bool
+	// tenant and namespace are the tenant and namespace the job works for, its record and lock being scoped to them
+	// (see tenantKey).
+	tenant, namespace string
+}
+
+const (
+	// maxJobErrors is the maximum number of item failures described by a job.
+	maxJobErrors = 1000
+	// jobsKeysPrefix is the prefix of the keys holding the job records.
+	jobsKeysPrefix = "job:"
+	// jobsLocksPrefix is the prefix of the keys locking a job type while one of its exclusive jobs is queued or running.
+	jobsLocksPrefix = "jobs:lock:"
+	// jobRecordTTL is how long a job record is kept after its last update.
+	jobRecordTTL = 7 * 24 * time.Hour
+	// jobLockTTL bounds the time a job type stays locked without any progress, should the instance running the job die.
+	jobLockTTL = 10 * time.Minute
+	// jobQueueSize is the maximum number of jobs waiting for a worker.
+	jobQueueSize = 100
+)
+
+// errJobQueueFull is returned when a job is submitted while jobQueueSize jobs are already waiting for a worker.
+var errJobQueueFull = errors.New("too many jobs are waiting, retry later")
+
+// queuedJob is a job waiting for a worker, along with the work to do and the context it is done in.
+type queuedJob struct {
+	id  string
+	ctx context.Context
+	run func(ctx context.Context, jobId string) error
+}
+
+// jobRegistry runs the background jobs of the service on a pool of workers (see startJobWorkers) and keeps track of them.
+// The job records are stored in the database, so that any instance of the service can report any job,
+// while the jobs queued or running on this instance are also held in memory.
+type jobRegistry struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	queue chan queuedJob
+}
+
+// jobs holds the background jobs of the service.
+var jobs = &jobRegistry{jobs: make(map[string]*Job), queue: make(chan queuedJob, jobQueueSize)}
+
+// startJobWorkers starts the given number of goroutines running the submitted jobs, in order.
+func startJobWorkers(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for queued := range jobs.queue {
+				jobs.runJob(queued)
+			}
+		}()
+	}
+}
+
+// submit queues a job of the given type doing run, which is given ctx along with the job ID to report its progress
+// with update and fail. An exclusive job is refused when a job of the same type is already queued or running for the
+// tenant and namespace ctx is scoped to, on any instance, in which case that job is returned along with false.
+func (registry *jobRegistry) submit(ctx context.Context, jobType string, exclusive bool, run func(ctx context.Context, jobId string) error) (Job, bool, error) {
+	job := &Job{Id: uuid.New().String(), Type: jobType, Status: JobQueued, CreatedAt: time.Now(), exclusive: exclusive, tenant: tenantOf(ctx), namespace: namespaceOf(ctx)}
+	if exclusive {
+		locked, err := db.SetNX(ctx, databaseClient, tenantKey(ctx, jobsLocksPrefix+jobType), job.Id, jobLockTTL)
+		if err != nil {
+			return Job{}, false, fmt.Errorf("unable to lock %s jobs: %v", jobType, err)
+		}
+		if !locked {
+			runningId, err := db.Get(ctx, databaseClient, tenantKey(ctx, jobsLocksPrefix+jobType))
+			if err != nil {
+				return Job{}, false, err
+			}
+			running, found, err := registry.get(ctx, runningId)
+			if err != nil || !found {
+				running = Job{Id: runningId, Type: jobType, Status: JobRunning}
+			}
+			return running, false, nil
+		}
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	select {
+	case registry.queue <- queuedJob{id: job.Id, ctx: ctx, run: run}:
+	default:
+		registry.unlock(*job)
+		return Job{}, false, errJobQueueFull
+	}
+	registry.jobs[job.Id] = job
+	registry.save(job)
+	return *job, true, nil
+}
+
+// runJob runs a queued job and records its outcome.
+func (registry *jobRegistry) runJob(queued queuedJob) {
+	registry.update(queued.id, func(job *Job) {
+		startedAt := time.Now()
+		job.StartedAt = &startedAt
+		job.Status = JobRunning
+	})
+	err := queued.run(queued.ctx, queued.id)
+	if err != nil {
+		slog.Error("Job failed", "job", queued.id, "Error:", err)
+	}
+	registry.finish(queued.id, err)
+
+	// The job is only known from its record from now on
+	registry.mu.Lock()
+	job := registry.jobs[queued.id]
+	delete(registry.jobs, queued.id)
+	registry.mu.Unlock()
+	registry.unlock(*job)
+}
+
+// unlock releases the lock of the type of an exclusive job, if still held by the job.
+func (registry *jobRegistry) unlock(job Job) {
+	if !job.exclusive {
+		return
+	}
+	ctx := contextWithNamespace(contextWithTenant(ctx, job.tenant), job.namespace)
+	if _, err := db.DelIfEquals(ctx, databaseClient, tenantKey(ctx, jobsLocksPrefix+job.Type), job.Id); err != nil {
+		slog.WarnContext(ctx, "Unable to release job lock", "job", job.Id, "Error:", err)
+	}
+}
+
+// get returns a copy of the job with the given ID working for the tenant and namespace ctx is scoped to, the second
+// value reports whether the job exists.
+func (registry *jobRegistry) get(ctx context.Context, id string) (Job, bool, error) {
+	registry.mu.Lock()
+	job, found := registry.jobs[id]
+	if found && job.tenant == tenantOf(ctx) && job.namespace == namespaceOf(ctx) {
+		copied := *job
+		copied.Errors = slices.Clone(job.Errors)
+		registry.mu.Unlock()
+		return copied, true, nil
+	}
+	registry.mu.Unlock()
+
+	record, err := db.Get(ctx, databaseClient, tenantKey(ctx, jobsKeysPrefix+id))
+	if err != nil || record == "" {
+		return Job{}, false, err
+	}
+	var stored Job
+	if err := json.Unmarshal([]byte(record), &stored); err != nil {
+		return Job{}, false, fmt.Errorf("unable to validate the structure of stored Job: %v", err)
+	}
+	return stored, true, nil
+}
+
+// update applies the given change to the job with the given ID, queued or running on this instance, and records it.
+func (registry *jobRegistry) update(id string, change func(job *Job)) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if job, found := registry.jobs[id]; found {
+		change(job)
+		registry.save(job)
+	}
+}
+
+// save records a job in the database, registry.mu being held. The lock of its type, if it holds it, is kept alive.
+// A failure is logged, the job going on anyway.
+func (registry *jobRegistry) save(job *Job) {
+	ctx := contextWithNamespace(contextWithTenant(ctx, job.tenant), job.namespace)
+	record, err := json.Marshal(job)
+	if err == nil {
+		err = db.Set(ctx, databaseClient, tenantKey(ctx, jobsKeysPrefix+job.Id), record, jobRecordTTL)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to record job", "job", job.Id, "Error:", err)
+	}
+	if job.exclusive && job.FinishedAt == nil {
+		if _, err := db.ExpireAt(ctx, databaseClient, tenantKey(ctx, jobsLocksPrefix+job.Type), time.Now().Add(jobLockTTL)); err != nil {
+			slog.WarnContext(ctx, "Unable to extend job lock", "job", job.Id, "Error:", err)
+		}
+	}
+}
+
+// fail records the failure of an item processed by the job with the given ID.
+func (registry *jobRegistry) fail(id string, itemError ArticleBulkError) {
+	registry.update(id, func(job *Job) {
+		job.Failed++
+		if len(job.Errors) < maxJobErrors {
+			job.Errors = append(job.Errors, itemError)
+		}
+	})
+}
+
+// finish marks the job with the given ID as completed, or as failed when err is not nil.
+func (registry *jobRegistry) finish(id string, err error) {
+	registry.update(id, func(job *Job) {
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+		job.Status = JobCompleted
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+// submitJob submits a job like jobRegistry.submit and responds with an HTTP 202 Accepted along with the job
+// and its location (see getJob), an HTTP 409 Conflict along with the running job when an exclusive job of the same
+// type is already queued or running, or an HTTP 503 Service Unavailable when too many jobs are waiting.
+// It returns whether the job has been submitted.
+func submitJob(ctx context.Context, w http.ResponseWriter, jobType string, exclusive bool, run func(ctx context.Context, jobId string) error) (Job, bool) {
+	job, submitted, err := jobs.submit(ctx, jobType, exclusive, run)
+	if errors.Is(err, errJobQueueFull) {
+		handleError(w, "Failed to submit job", err, http.StatusServiceUnavailable)
+		return job, false
+	}
+	if err != nil {
+		handleError(w, "Failed to submit job", err, http.StatusInternalServerError)
+		return job, false
+	}
+	if !submitted {
+		responseJSON(w, job, http.StatusConflict)
+		return job, false
+	}
+	w.Header().Set("Location", fmt.Sprintf("/jobs/%s", job.Id))
+	responseJSON(w, job, http.StatusAccepted)
+	return job, true
+}
+
+// getJob returns the background job with the provided ID and its progress, whatever its type and the instance running it.
+func getJob(w http.ResponseWriter, r *http.Request) {
+	respondJob(requestContext(r), w, r.PathValue("id"), "")
+}
+
+// getJobOfType returns a handler responding like getJob, for the jobs of the given type only.
+func getJobOfType(jobType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondJob(requestContext(r), w, r.PathValue("id"), jobType)
+	}
+}
+
+// respondJob responds with the job with the given ID, an HTTP 404 Not Found being returned when there is no such job
+// for the tenant and namespace ctx is scoped to or when the job is not of the given type (any type when empty).
+func respondJob(ctx context.Context, w http.ResponseWriter, id string, jobType string) {
+	job, found, err := jobs.get(ctx, id)
+	if err != nil {
+		handleError(w, "Failed to retrieve job", err, http.StatusInternalServerError)
+		return
+	}
+	if !found || (jobType != "" && job.Type != jobType) {
+		handleError(w, "Job not found", withErrorCode(ErrorCodeJobNotFound, fmt.Errorf("no job found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	responseJSON(w, job, http.StatusOK)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/*
+JSON:API representation
+
+A JSON response is represented as a JSON:API document (https://jsonapi.org) as follows:
+  - an article is a resource object of type articles, its members other than id being its attributes, the relevance
+    score and the highlights of a search hit being its meta. It links to itself, and to its author, related articles
+    and revisions as relationships
+  - a page of articles (an object with an articles member) is a collection of resource objects, the other members of
+    the page (e.g. total) being the meta of the document. It links to the first, previous, next and last pages
+  - a list of articles is a collection of resource objects
+  - an error (see Problem and CustomOutput) is an error object with the status code of the response, a failed
+    validation being an error object per field which failed it, pointing at its attribute
+  - any other response is the meta of the document
+A JSON:API request body is converted back to the article (or the list of articles) of its primary data.
+*/
+
+const (
+	jsonAPIMediaType    = "application/vnd.api+json" // jsonAPIMediaType is the media type of the JSON:API representation.
+	jsonAPIVersion      = "1.1"                      // jsonAPIVersion is the version of JSON:API the documents sent comply with.
+	jsonAPIArticlesType = "articles"                 // jsonAPIArticlesType is the type of the resource objects of the articles.
+)
+
+// jsonAPIDocument is a JSON:API top-level document.
+type jsonAPIDocument struct {
+	Data    any               `json:"data,omitempty"`   // Data is the primary data, a resource object or a list of them.
+	Errors  []jsonAPIError    `json:"errors,omitempty"` // Errors holds the errors of an unsuccessful response.
+	Meta    map[string]any    `json:"meta,omitempty"`   // Meta holds the members of the response which are not resources.
+	Links   map[string]string `json:"links,omitempty"`  // Links holds the link to the document and to the other pages.
+	JSONAPI map[string]string `json:"jsonapi"`          // JSONAPI describes the implementation of JSON:API.
+}
+
+// jsonAPIResource is a JSON:API resource object.
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`                    // Type is the type of the resource, e.g. articles.
+	Id            string                         `json:"id,omitempty"`            // Id is the unique identifier of the resource.
+	Attributes    map[string]any                 `json:"attributes,omitempty"`    // Attributes holds the members of the resource.
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"` // Relationships links to the related resources.
+	Links         map[string]string              `json:"links,omitempty"`         // Links holds the link to the resource.
+	Meta          map[string]any                 `json:"meta,omitempty"`          // Meta holds the members which are not attributes.
+}
+
+// jsonAPIRelationship is a JSON:API relationship object, only made of links.
+type jsonAPIRelationship struct {
+	Links map[string]string `json:"links"` // Links holds the link to the related resources.
+}
+
+// jsonAPIError is a JSON:API error object.
+type jsonAPIError struct {
+	Status string `json:"status"`           // Status is the HTTP status code of the response.
+	Code   string `json:"code,omitempty"`   // Code is the machine-readable code of the error, see ErrorCode.
+	Title  string `json:"title,omitempty"`  // Title is the summary of the error.
+	Detail string `json:"detail,omitempty"` // Detail is the explanation of the error.
+	// Source points at the member of the request body causing the error.
+	Source map[string]string `json:"source,omitempty"`
+}
+
+// jsonToJSONAPI converts a JSON response to a JSON:API document, as described above, r being the request it answers.
+func jsonToJSONAPI(r *http.Request, statusCode int, data []byte) ([]byte, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	document := jsonAPIDocument{JSONAPI: map[string]string{"version": jsonAPIVersion}}
+	object, isObject := value.(map[string]any)
+	items, isArray := value.([]any)
+	articles, isPage := object["articles"].([]any)
+	switch {
+	case statusCode >= http.StatusBadRequest && isObject && (object["Error"] != nil || isJSONAPIProblem(object)):
+		title, _ := cmp.Or(object["title"], object["Message"]).(string)
+		detail, _ := cmp.Or(object["detail"], object["Error"]).(string)
+		code, _ := cmp.Or(object["code"], object["Code"]).(string)
+		document.Errors = []jsonAPIError{{Status: strconv.Itoa(statusCode), Code: code, Title: title, Detail: detail}}
+		fields, _ := cmp.Or(object["errors"], object["Fields"]).([]any)
+		if len(fields) > 0 {
+			document.Errors = jsonAPIFieldErrors(document.Errors[0], fields)
+		}
+	case isObject && isJSONAPIArticle(object):
+		document.Data = jsonAPIArticleResource(object)
+		document.Links = map[string]string{"self": r.URL.RequestURI()}
+	case isPage && areJSONAPIArticles(articles):
+		resources := make([]jsonAPIResource, len(articles))
+		for i, article := range articles {
+			resources[i] = jsonAPIArticleResource(article.(map[string]any))
+		}
+		delete(object, "articles")
+		document.Data, document.Meta = resources, object
+		document.Links = jsonAPIPaginationLinks(r.URL, object)
+	case isArray && areJSONAPIArticles(items):
+		resources := make([]jsonAPIResource, len(items))
+		for i, article := range items {
+			resources[i] = jsonAPIArticleResource(article.(map[string]any))
+		}
+		document.Data = resources
+		document.Links = map[string]string{"self": r.URL.RequestURI()}
+	case isObject:
+		document.Meta = object
+	default:
+		document.Meta = map[string]any{"value": value}
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// jsonAPIToJSON converts a JSON:API request body to the JSON document of its primary data, a resource object of type
+// articles being converted to an article.
+func jsonAPIToJSON(data []byte) ([]byte, error) {
+	var document struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Data) == 0 {
+		return nil, errors.New("the document has no primary data")
+	}
+
+	var resources []jsonAPIResource
+	if bytes.HasPrefix(bytes.TrimSpace(document.Data), []byte("[")) {
+		if err := json.Unmarshal(document.Data, &resources); err != nil {
+			return nil, err
+		}
+	} else {
+		var resource jsonAPIResource
+		if err := json.Unmarshal(document.Data, &resource); err != nil {
+			return nil, err
+		}
+		resources = []jsonAPIResource{resource}
+	}
+
+	articles := make([]map[string]any, len(resources))
+	for i, resource := range resources {
+		if resource.Type != jsonAPIArticlesType {
+			return nil, fmt.Errorf("resource type %q is not supported, use %s", resource.Type, jsonAPIArticlesType)
+		}
+		articles[i] = resource.Attributes
+		if articles[i] == nil {
+			articles[i] = map[string]any{}
+		}
+		if resource.Id != "" {
+			articles[i]["id"] = resource.Id
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(document.Data), []byte("[")) {
+		return json.Marshal(articles)
+	}
+	return json.Marshal(articles[0])
+}
+
+// jsonAPIFieldErrors returns an error object per field which failed the validation (see FieldError), each one pointing
+// at the attribute of the field, along with the status, code and title of the error.
+func jsonAPIFieldErrors(validationError jsonAPIError, fields []any) []jsonAPIError {
+	errorObjects := make([]jsonAPIError, 0, len(fields))
+	for _, field := range fields {
+		fieldError, _ := field.(map[string]any)
+		name, _ := fieldError["field"].(string)
+		message, _ := fieldError["message"].(string)
+		errorObject := validationError
+		errorObject.Detail = message
+		switch name {
+		case "":
+		case "id":
+			errorObject.Source = map[string]string{"pointer": "/data/id"}
+		default:
+			path := strings.NewReplacer(".", "/", "[", "/", "]", "").Replace(name)
+			errorObject.Source = map[string]string{"pointer": "/data/attributes/" + path}
+		}
+		errorObjects = append(errorObjects, errorObject)
+	}
+	return errorObjects
+}
+
+// isJSONAPIProblem reports whether a JSON object is a Problem, having a type and a numeric status.
+func isJSONAPIProblem(object map[string]any) bool {
+	_, hasType := object["type"].(string)
+	_, hasStatus := object["status"].(json.Number)
+	return hasType && hasStatus
+}
+
+// isJSONAPIArticle reports whether a JSON object is an article (or a search hit), having an id and a title.
+func isJSONAPIArticle(object map[string]any) bool {
+	_, hasId := object["id"].(string)
+	_, hasTitle := object["title"].(string)
+	return hasId && hasTitle
+}
+
+// areJSONAPIArticles reports whether all the items of a JSON array are articles.
+func areJSONAPIArticles(items []any) bool {
+	for _, item := range items {
+		if object, isObject := item.(map[string]any); !isObject || !isJSONAPIArticle(object) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonAPIArticleResource returns the resource object of an article, along with its links and relationships.
+// A trashed article has no links, as it can't be retrieved until it is restored.
+func jsonAPIArticleResource(article map[string]any) jsonAPIResource {
+	resource := jsonAPIResource{Type: jsonAPIArticlesType, Id: article["id"].(string), Attributes: article}
+	delete(article, "id")
+	for _, member := range []string{"score", "highlights"} {
+		if value, found := article[member]; found {
+			if resource.Meta == nil {
+				resource.Meta = map[string]any{}
+			}
+			resource.Meta[member] = value
+			delete(article, member)
+		}
+	}
+	if _, trashed := article["deletedAt"]; trashed {
+		return resource
+	}
+
+	self := "/article/" + url.PathEscape(resource.Id)
+	resource.Links = map[string]string{"self": self}
+	resource.Relationships = map[string]jsonAPIRelationship{
+		"related":   {Links: map[string]string{"related": self + "/related"}},
+		"revisions": {Links: map[string]string{"related": self + "/revisions"}},
+	}
+	if author, _ := article["author"].(string); author != "" {
+		resource.Relationships["author"] = jsonAPIRelationship{Links: map[string]string{"related": "/author/" + url.PathEscape(author)}}
+	}
+	return resource
+}
+
+// jsonAPIPaginationLinks returns the links to a page of articles and to the first, previous, next and last pages,
+// according to its limit, offset and total members, or to its next_cursor member in cursor mode.
+func jsonAPIPaginationLinks(requestURL *url.URL, page map[string]any) map[string]string {
+	links := map[string]string{"self": requestURL.RequestURI()}
+	pageLink := func(name string, value string) string {
+		link := *requestURL
+		queryParams := link.Query()
+		queryParams.Set(name, value)
+		link.RawQuery = queryParams.Encode()
+		return link.RequestURI()
+	}
+
+	if nextCursor, _ := page["next_cursor"].(string); nextCursor != "" {
+		links["next"] = pageLink("cursor", nextCursor)
+		return links
+	}
+	limit, limitErr := jsonAPINumber(page["limit"])
+	offset, offsetErr := jsonAPINumber(page["offset"])
+	total, totalErr := jsonAPINumber(page["total"])
+	if limitErr != nil || offsetErr != nil || totalErr != nil || limit <= 0 {
+		return links
+	}
+	offsetLink := func(offset int64) string {
+		return pageLink("offset", strconv.FormatInt(offset, 10))
+	}
+	links["first"] = offsetLink(0)
+	if offset > 0 {
+		links["prev"] = offsetLink(max(offset-limit, 0))
+	}
+	if offset+limit < total {
+		links["next"] = offsetLink(offset + limit)
+	}
+	links["last"] = offsetLink(max(total-1, 0) / limit * limit)
+	return links
+}
+
+// jsonAPINumber returns the integer of a JSON number decoded with json.Decoder.UseNumber.
+func jsonAPINumber(value any) (int64, error) {
+	number, isNumber := value.(json.Number)
+	if !isNumber {
+		return 0, errors.New("not a number")
+	}
+	return number.Int64()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"time"
+)
+
+const (
+	// kafkaBatchSize is the maximum number of events sent to Kafka at once.
+	kafkaBatchSize = 100
+	// kafkaWriteTimeout is the time given to Kafka to acknowledge a batch of events.
+	kafkaWriteTimeout = 10 * time.Second
+)
+
+// startKafkaProducer starts the background goroutine publishing the events of the event bus to the config.KafkaTopic
+// topic of the config.KafkaBrokers, when Kafka brokers are configured. Each event is published as JSON (see ArticleEvent)
+// keyed by the ID of the article, so that the events of an article are kept in order within a partition.
+// The events are sent by batches of the events waiting, up to kafkaBatchSize, a batch that can't be sent being dropped.
+func startKafkaProducer() {
+	if len(config.KafkaBrokers) == 0 {
+		return
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(config.KafkaBrokers...),
+		Topic:        config.KafkaTopic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    kafkaBatchSize,
+		BatchTimeout: time.Millisecond,
+		WriteTimeout: kafkaWriteTimeout,
+	}
+	slog.Info("Publishing article events to Kafka", "brokers", config.KafkaBrokers, "topic", config.KafkaTopic)
+
+	events, _ := articleEvents.subscribe()
+	go func() {
+		for event := range events {
+			batch := []ArticleEvent{event}
+			for waiting := true; waiting && len(batch) < kafkaBatchSize; {
+				select {
+				case next, ok := <-events:
+					if !ok {
+						waiting = false
+						break
+					}
+					batch = append(batch, next)
+				default:
+					waiting = false
+				}
+			}
+			writeKafkaEvents(writer, batch)
+		}
+	}()
+}
+
+// writeKafkaEvents publishes a batch of events to Kafka, any failure being logged.
+func writeKafkaEvents(writer *kafka.Writer, batch []ArticleEvent) {
+	messages := make([]kafka.Message, 0, len(batch))
+	for _, event := range batch {
+		value, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Unable to encode event, event not published to Kafka", "event", event.Id, "Error:", err)
+			continue
+		}
+		messages = append(messages, kafka.Message{
+			Key:     []byte(event.ArticleId),
+			Value:   value,
+			Headers: []kafka.Header{{Key: "type", Value: []byte(event.Type)}},
+			Time:    time.Unix(event.OccurredAt, 0),
+		})
+	}
+	if err := writer.WriteMessages(ctx, messages...); err != nil {
+		slog.Error("Unable to publish events to Kafka, events dropped", "events", len(messages), "topic", config.KafkaTopic, "Error:", err)
+	}
+}
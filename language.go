@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// searchLanguages maps the ISO 639-1 code of each language supported by the search engine stemming
+// to the language name expected by the search engine.
+var searchLanguages = map[string]string{
+	"ar": "arabic",
+	"hy": "armenian",
+	"eu": "basque",
+	"ca": "catalan",
+	"zh": "chinese",
+	"da": "danish",
+	"nl": "dutch",
+	"en": "english",
+	"fi": "finnish",
+	"fr": "french",
+	"de": "german",
+	"el": "greek",
+	"hi": "hindi",
+	"hu": "hungarian",
+	"id": "indonesian",
+	"ga": "irish",
+	"it": "italian",
+	"lt": "lithuanian",
+	"ne": "nepali",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sr": "serbian",
+	"es": "spanish",
+	"sv": "swedish",
+	"ta": "tamil",
+	"tr": "turkish",
+	"yi": "yiddish",
+}
+
+// searchLanguageName returns the search engine name of a language given either as an ISO 639-1 code or as a name.
+// The second value reports whether the language is supported.
+func searchLanguageName(language string) (string, bool) {
+	if name, found := searchLanguages[language]; found {
+		return name, true
+	}
+	for _, name := range searchLanguages {
+		if name == language {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// languageValidation validates if a given field is the name of a language supported by the search engine (e.g. french).
+func languageValidation(fl validator.FieldLevel) bool {
+	name, supported := searchLanguageName(fl.Field().String())
+	return supported && name == fl.Field().String()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"time"
+)
+
+const (
+	likesKeysPrefix     = "likes:article:"  // likesKeysPrefix prefixes the keys of the sets of the users liking an article.
+	bookmarksKeysPrefix = "bookmarks:user:" // bookmarksKeysPrefix prefixes the keys of the sorted sets of the articles bookmarked by a user.
+)
+
+// ArticleLikes is the number of likes of an article, as liked or unliked by a user.
+type ArticleLikes struct {
+	Id    string `json:"id"`    // Id is the ID of the article.
+	User  string `json:"user"`  // User is the ID of the user who liked or unliked the article.
+	Liked bool   `json:"liked"` // Liked reports whether the user likes the article.
+	Likes int64  `json:"likes"` // Likes is the number of users liking the article.
+}
+
+// ensureArticleExists reports whether the article with the given ID exists in the tenant ctx is scoped to, responding
+// with an HTTP 404 Not Found error when it does not (or with an HTTP 500 error when it can't be checked).
+func ensureArticleExists(ctx context.Context, w http.ResponseWriter, id string) bool {
+	exists, err := db.Exists(ctx, databaseClient, tenantKey(ctx, keysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return false
+	}
+	if exists == 0 {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// authorizeUser reports whether the actor of r may act as the user of its path, responding with an HTTP 403 Forbidden
+// error when an authenticated actor acts as another user, unless it is an admin.
+func authorizeUser(w http.ResponseWriter, r *http.Request) bool {
+	user, actor := r.PathValue("user"), requestActor(r)
+	if authenticatedSubject(r.Context()) != "" && user != actor && !isAdmin(r.Context(), actor) {
+		handleError(w, "Forbidden", fmt.Errorf("%s can't act as %s", actor, user), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// likeArticle handles PUT /users/{user}/likes/{id}, the user liking the article with the provided ID.
+// An article is liked once by a user, liking it again having no effect.
+func likeArticle(w http.ResponseWriter, r *http.Request) {
+	setArticleLike(w, r, true)
+}
+
+// unlikeArticle handles DELETE /users/{user}/likes/{id}, the user no longer liking the article with the provided ID.
+func unlikeArticle(w http.ResponseWriter, r *http.Request) {
+	setArticleLike(w, r, false)
+}
+
+// setArticleLike adds the user to the set of the users liking an article (or removes it when liked is false), then
+// stores the number of users liking the article as its likes field, so that it is returned along with the article.
+// Both are written atomically (see db.SetUpdateAndCount), so that concurrent likes can't store a stale number. The
+// number is set rather than incremented, so that it is made right by the next like when an article has been
+// concurrently replaced. An authenticated actor only likes as itself, see authorizeUser.
+func setArticleLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	ctx := requestContext(r)
+	id, user := r.PathValue("id"), r.PathValue("user")
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	likes, err := db.SetUpdateAndCount(ctx, databaseClient, tenantKey(ctx, likesKeysPrefix+id), user, liked, tenantKey(ctx, keysPrefix+id), "$.likes")
+	if errors.Is(err, db.ErrNotFound) {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleError(w, "Failed to store the like of the article", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, ArticleLikes{Id: id, User: user, Liked: liked, Likes: likes}, http.StatusOK)
+}
+
+// bookmarkArticle handles PUT /users/{user}/bookmarks/{id}, adding the article with the provided ID to the bookmarks
+// of the user, along with the time it is bookmarked. An authenticated actor only bookmarks as itself, see authorizeUser.
+func bookmarkArticle(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id, user := r.PathValue("id"), r.PathValue("user")
+	if !authorizeUser(w, r) || !ensureArticleExists(ctx, w, id) {
+		return
+	}
+	if err := db.SortedSetAdd(ctx, databaseClient, tenantKey(ctx, bookmarksKeysPrefix+user), id, float64(time.Now().Unix())); err != nil {
+		handleError(w, "Failed to store the bookmark", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("article with ID %s bookmarked by %s", id, user)}, http.StatusOK)
+}
+
+// unbookmarkArticle handles DELETE /users/{user}/bookmarks/{id}, removing the article with the provided ID from the
+// bookmarks of the user, see authorizeUser.
+func unbookmarkArticle(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id, user := r.PathValue("id"), r.PathValue("user")
+	if !authorizeUser(w, r) {
+		return
+	}
+	if err := db.SortedSetRem(ctx, databaseClient, tenantKey(ctx, bookmarksKeysPrefix+user), id); err != nil {
+		handleError(w, "Failed to delete the bookmark", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("article with ID %s no longer bookmarked by %s", id, user)}, http.StatusOK)
+}
+
+// getUserBookmarks handles GET /users/{user}/bookmarks, returning a page of the articles bookmarked by the user,
+// the most recently bookmarked first. The page is controlled by the limit and offset query parameters,
+// see parsePaginationParams. The articles deleted since they were bookmarked are left out of the page. An authenticated
+// actor only reads its own bookmarks, see authorizeUser.
+func getUserBookmarks(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	user := r.PathValue("user")
+	if !authorizeUser(w, r) {
+		return
+	}
+	queryParams := r.URL.Query()
+
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	total, err := db.SortedSetCard(ctx, databaseClient, tenantKey(ctx, bookmarksKeysPrefix+user))
+	if err != nil {
+		handleError(w, "Failed to retrieve the bookmarks from Database", err, http.StatusInternalServerError)
+		return
+	}
+	bookmarks, err := db.SortedSetRevRange(ctx, databaseClient, tenantKey(ctx, bookmarksKeysPrefix+user), int64(offset), int64(offset+limit-1))
+	if err != nil {
+		handleError(w, "Failed to retrieve the bookmarks from Database", err, http.StatusInternalServerError)
+		return
+	}
+
+	page := ArticlesPage{Articles: []Article{}, Total: int(total), Limit: limit, Offset: offset}
+	if len(bookmarks) > 0 {
+		keys := make([]string, len(bookmarks))
+		for i, bookmark := range bookmarks {
+			keys[i] = tenantKey(ctx, keysPrefix+bookmark.Member)
+		}
+		page.Articles, err = fetchArticles(ctx, keys)
+		if err != nil {
+			handleError(w, "Failed to retrieve articles from Database", err, http.StatusInternalServerError)
+			return
+		}
+	}
+	responseJSON(w, page, http.StatusOK)
+}
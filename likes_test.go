@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizeUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		roles   []string
+		user    string
+		allowed bool
+	}{
+		{"anonymous", "", nil, "alice", true},
+		{"same user", "alice", []string{roleReader}, "alice", true},
+		{"other user", "bob", []string{roleReader}, "alice", false},
+		{"admin", "carol", []string{roleAdmin}, "alice", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users/"+test.user+"/bookmarks", nil)
+			if test.subject != "" {
+				r = r.WithContext(contextWithRoles(contextWithSubject(r.Context(), test.subject), test.roles))
+			}
+			r.SetPathValue("user", test.user)
+			w := httptest.NewRecorder()
+			if allowed := authorizeUser(w, r); allowed != test.allowed {
+				t.Errorf("authorizeUser() = %v, expected %v", allowed, test.allowed)
+			}
+			if !test.allowed && w.Code != http.StatusForbidden {
+				t.Errorf("authorizeUser() responded with %d, expected %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
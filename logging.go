@@ -0,0 +1,65 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// The destinations of the logs besides a file, see Config.LogOutput.
+const (
+	stderrLogOutput = "stderr" // stderrLogOutput writes the logs to the standard error.
+	stdoutLogOutput = "stdout" // stdoutLogOutput writes the logs to the standard output.
+)
+
+// The command-line flags overriding the logging settings of the environment, see initializeLogging.
+var (
+	logLevelFlag  = flag.String("log-level", "", "level of the logs: debug, info, warn or error (overrides AS_LOG_LEVEL)")
+	logFormatFlag = flag.String("log-format", "", "format of the logs: text or json (overrides AS_LOG_FORMAT)")
+	logOutputFlag = flag.String("log-output", "", "destination of the logs: stderr, stdout or a file (overrides AS_LOG_OUTPUT)")
+)
+
+// initializeLogging sets up the default logger with the level, format and destination of Config.LogLevel,
+// Config.LogFormat and Config.LogOutput, or of their command-line flags when set. A file destination is appended to.
+// The entries report the ID of the request they are logged for, see requestIDLogHandler.
+func initializeLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cmp.Or(*logLevelFlag, config.LogLevel))); err != nil {
+		return fmt.Errorf("invalid log level: %v", err)
+	}
+	var writer io.Writer
+	switch output := cmp.Or(*logOutputFlag, config.LogOutput); output {
+	case stderrLogOutput:
+		writer = os.Stderr
+	case stdoutLogOutput:
+		writer = os.Stdout
+	default:
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("unable to open the log file: %v", err)
+		}
+		writer = file
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format := cmp.Or(*logFormatFlag, config.LogFormat); format {
+	case textLogFormat:
+		handler = slog.NewTextHandler(writer, options)
+	case jsonLogFormat:
+		handler = slog.NewJSONHandler(writer, options)
+	default:
+		return fmt.Errorf("invalid log format: %q is neither %s nor %s", format, textLogFormat, jsonLogFormat)
+	}
+	slog.SetDefault(slog.New(requestIDLogHandler{handler}))
+	return nil
+}
+
+// fatal logs an error preventing the service from running and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "Error:", err)
+	os.Exit(1)
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/stivesso/articles-search/pkg/db"
 	"io"
-	"log"
 	"log/slog"
+	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -19,21 +22,127 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Article represents the structure of an Article.
 type Article struct {
-	Id      string   `json:"id" validate:"required,validUuid"` // Id represents the unique identifier of an Article, it is a JSON field that is required and must be a valid UUID.
-	Title   string   `json:"title" validate:"required"`        // Title represents the title of an article which is a required field that must be populated.
-	Content string   `json:"content" validate:"omitempty"`     // Content represents the content of an Article, it is a JSON field that can be empty.
-	Author  string   `json:"author" validate:"omitempty"`      // Author represents the author of an Article.
-	Tags    []string `json:"tags" validate:"omitempty"`        // Tags represents the tags associated with an Article. It is a JSON field that can be empty.
+	Id      string   `json:"id" validate:"required,validUuid"`                  // Id represents the unique identifier of an Article, it is a JSON field that is required and must be a valid UUID.
+	Title   string   `json:"title" validate:"required,maxTitleLength"`          // Title represents the title of an article which is a required field that must be populated.
+	Content string   `json:"content" validate:"omitempty,maxContentSize"`       // Content represents the content of an Article, it is a JSON field that can be empty.
+	Author  string   `json:"author" validate:"omitempty"`                       // Author represents the author of an Article.
+	Tags    []string `json:"tags" validate:"omitempty,maxTags,dive,tagCharset"` // Tags represents the tags associated with an Article. It is a JSON field that can be empty.
+	// Category is the ID of the category of an Article, see Category. The articles of a category and of its
+	// descendants are listed by GET /category/{id}/articles.
+	Category string `json:"category,omitempty" search:"tag"`
+	// ContentFormat is the format of the content of an Article, either html or markdown. An empty format means html.
+	// A Markdown content is rendered as HTML by GET /article/{id}/rendered.
+	ContentFormat string `json:"contentFormat,omitempty" validate:"omitempty,oneof=html markdown" search:"-"`
+	// Language represents the language of an Article (e.g. french), used to stem its content. English is assumed when empty.
+	Language string `json:"language,omitempty" validate:"omitempty,validLanguage" search:"tag"`
+	// CreatedAt is the time an Article was created, as a Unix timestamp in seconds. It is set by the server.
+	CreatedAt int64 `json:"createdAt,omitempty" search:"-"`
+	// UpdatedAt is the last time an Article was written, as a Unix timestamp in seconds. It is set by the server.
+	UpdatedAt int64 `json:"updatedAt,omitempty" search:"-"`
+	// Status represents the publication status of an Article, either draft or published. An empty status means published.
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=draft published" search:"tag"`
+	// PublishAt is the time a draft Article is published, as a Unix timestamp in seconds. It makes the Article a draft until then.
+	PublishAt int64 `json:"publishAt,omitempty" validate:"omitempty,futureTimestamp" search:"-"`
+	// ExpiresAt is the time an Article is automatically deleted, as a Unix timestamp in seconds. It never expires when empty.
+	ExpiresAt int64 `json:"expiresAt,omitempty" validate:"omitempty,futureTimestamp" search:"-"`
+	// Version is incremented by the server on every write of an Article, the version being updated must be provided
+	// (as version or If-Match header) to update an Article so that concurrent updates are detected.
+	Version int64 `json:"version" search:"-"`
+	// DeletedAt is the time an Article was moved to the trash, as a Unix timestamp in seconds. It is set by the server.
+	DeletedAt int64 `json:"deletedAt,omitempty" search:"-"`
+	// WordCount is the number of words of the content of an Article, without its markup. It is set by the server and
+	// searched with the minWords and maxWords query parameters.
+	WordCount int `json:"wordCount,omitempty" search:"-"`
+	// ReadingTimeMinutes is the time it takes to read the content of an Article, in minutes. It is set by the server.
+	ReadingTimeMinutes int `json:"readingTimeMinutes,omitempty" search:"-"`
+	// Likes is the number of users liking an Article. It is set by the server as the Article is liked or unliked.
+	Likes int64 `json:"likes,omitempty" search:"-"`
+	// Owner is the actor who created an Article (see requestActor), searched like a tag. Only the owner, the editors
+	// and the admins (see Config.Admins) can change an Article. It is set by the server and transferred by
+	// PUT /article/{id}/acl.
+	Owner string `json:"owner,omitempty" search:"tag"`
+	// Editors lists the actors the owner allows to change an Article. It is set by PUT /article/{id}/acl.
+	Editors []string `json:"editors,omitempty" search:"-"`
 }
 
-// CustomOutput for standardized error and message responses.
+// ArticlesPage represents a single page of articles along with the paging metadata.
+type ArticlesPage struct {
+	Articles []Article `json:"articles"` // Articles holds the articles of the current page.
+	Total    int       `json:"total"`    // Total is the number of articles available across all pages.
+	Limit    int       `json:"limit"`    // Limit is the maximum number of articles returned in a page.
+	Offset   int       `json:"offset"`   // Offset is the position of the first article of the page.
+}
+
+// ArticlesCursorPage represents a page of articles retrieved in cursor mode.
+type ArticlesCursorPage struct {
+	Articles   []Article `json:"articles"`              // Articles holds the articles of the current page.
+	Limit      int       `json:"limit"`                 // Limit is the requested number of articles per page.
+	NextCursor string    `json:"next_cursor,omitempty"` // NextCursor is the token to use to get the next page, empty on the last page.
+}
+
+// CustomOutput for standardized message responses, and error responses in the legacy error format (see Problem).
 type CustomOutput struct {
-	Error   string `json:"Error,omitempty"`
-	Message string `json:"Message,omitempty"`
+	Error   string    `json:"Error,omitempty"`
+	Message string    `json:"Message,omitempty"`
+	Code    ErrorCode `json:"Code,omitempty"` // Code is the machine-readable code of an error.
+	// Fields describes the fields which failed the validation, when the error is a failed validation.
+	Fields []FieldError `json:"Fields,omitempty"`
+	// RequestId is the ID of the request the error occurred on, see withRequestID.
+	RequestId string `json:"RequestId,omitempty"`
+}
+
+// ArticleSearchHit represents an article found by a search along with its relevance score.
+type ArticleSearchHit struct {
+	Article
+	Score float64 `json:"score"` // Score is the relevance score of the article for the search.
+	// Highlights holds the matched fragments of each text field with the matched terms wrapped in <mark></mark>, only set when requested.
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// ArticlesSearchPage represents a single page of search results along with the paging metadata.
+type ArticlesSearchPage struct {
+	Articles []ArticleSearchHit `json:"articles"` // Articles holds the articles found for the current page.
+	Total    int64              `json:"total"`    // Total is the number of articles matching the search across all pages.
+	Limit    int                `json:"limit"`    // Limit is the maximum number of articles returned in a page.
+	Offset   int                `json:"offset"`   // Offset is the position of the first article of the page.
+	// Facets holds, for each requested field, the number of matching articles per value, only set when requested.
+	Facets map[string][]FacetCount `json:"facets,omitempty"`
+	// Suggestions holds, for each misspelled term, the corrected terms known by the index, only set when nothing is found.
+	Suggestions map[string][]string `json:"suggestions,omitempty"`
+}
+
+// FacetCount is the number of articles sharing a given value of a field.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ArticlesStats represents statistics computed over all the articles.
+type ArticlesStats struct {
+	Total                int64        `json:"total"`                // Total is the number of articles.
+	AverageContentLength float64      `json:"averageContentLength"` // AverageContentLength is the average number of characters of the articles content.
+	Authors              []FacetCount `json:"authors"`              // Authors holds the number of articles per author, the most prolific first.
+	Tags                 []FacetCount `json:"tags"`                 // Tags holds the number of articles per tag, the most used first.
+}
+
+// ArticleBulkError describes why a single article of a bulk operation failed.
+type ArticleBulkError struct {
+	Index int    `json:"index"`        // Index is the position of the article in the provided list.
+	Id    string `json:"id,omitempty"` // Id is the ID of the article, when provided.
+	Error string `json:"error"`        // Error is the reason of the failure.
+	// Fields describes the fields of the article which failed the validation, when it is the reason of the failure.
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// ArticlesBulkOutput is the response of a failed bulk operation, listing the failure of each article.
+type ArticlesBulkOutput struct {
+	Message string             `json:"Message"`
+	Errors  []ArticleBulkError `json:"Errors"`
 }
 
 var (
@@ -42,23 +151,119 @@ var (
 	validate        = validator.New()
 	searchIndexName = "idx_articles"
 	keysPrefix      = "article:"
+	// sortableFields lists the Article fields declared as SORTABLE in the search index
+	sortableFields = []string{"id", "title", "author", "createdAt", "updatedAt", "wordCount"}
+	// fullTextSearchParam is the query parameter used to search across all the fullTextSearchFields at once
+	fullTextSearchParam = "q"
+	// fullTextSearchFields lists the Article fields targeted by a full-text search
+	fullTextSearchFields = []string{"title", "content", "author"}
+	// searchOptionsParams lists the query parameters that tune a search without being a search criteria
+	searchOptionsParams = []string{"sortBy", "order", "fuzzy", "match", "operator", "highlight", "limit", "offset", "facets", fieldsParam, includeParam}
+	// createdRangeParams lists the query parameters filtering a search on the creation time of the articles
+	createdRangeParams = []string{"createdAfter", "createdBefore"}
+	// facetableFields lists the Article fields that can be used as facets of a search
+	facetableFields = []string{"tags", "author"}
+	// router routes the requests to their handler, see setupHTTPServer
+	router *http.ServeMux
+)
+
+const (
+	defaultPageLimit   = 20   // defaultPageLimit is the number of articles returned when no limit is provided.
+	maxPageLimit       = 100  // maxPageLimit is the maximum number of articles that can be requested in a single page.
+	maxFacetValues     = 20   // maxFacetValues is the maximum number of values returned for each facet.
+	maxStatsValues     = 1000 // maxStatsValues is the maximum number of authors and tags returned in the statistics.
+	spellcheckDistance = 1    // spellcheckDistance is the maximum Levenshtein distance of the terms suggested when a search finds nothing.
 )
 
 func main() {
 
-	// Register validate for tag validUuid
-	err := validate.RegisterValidation("validUuid", uuidValidation)
+	// Load the service settings, the logging ones being overridden by the command-line flags
+	flag.Parse()
+	var err error
+	config, err = loadConfig()
 	if err != nil {
-		log.Fatalf("Unable to register the function required to validate article data, error was: %v", err)
+		fatal("Unable to load the configuration", err)
+	}
+	if err = initializeLogging(); err != nil {
+		fatal("Unable to initialize the logging", err)
+	}
+
+	// Log the requests served, when enabled
+	initializeAccessLog()
+
+	// Register the validations of the articles
+	if err = registerValidations(); err != nil {
+		fatal("Unable to register the function required to validate article data", err)
+	}
+
+	// Load the keys verifying the bearer tokens, when the authentication is enabled.
+	err = initializeAuthentication()
+	if err != nil {
+		fatal("Failed to initialize the authentication", err)
+	}
+	if err = checkRolesAuthentication(); err != nil {
+		fatal("Failed to initialize the authentication", err)
+	}
+	if err = checkPprofAuthentication(); err != nil {
+		fatal("Failed to initialize the authentication", err)
+	}
+	if err = checkTenancyAuthentication(); err != nil {
+		fatal("Failed to initialize the authentication", err)
+	}
+
+	// Export the traces of the requests and of the Database operations, when enabled.
+	if err = initializeTracing(context.Background()); err != nil {
+		fatal("Failed to initialize the tracing", err)
 	}
 
 	// Initialize Database client.
 	err = initializeDatabase()
 	if err != nil {
-		log.Fatalf("Failed to connect to Database: %v", err)
+		fatal("Failed to connect to Database", err)
+	}
+
+	// Create the search indexes of the default tenant if needed, the ones of the other tenants and namespaces are created
+	// by their first request (see withTenancy).
+	err = initializeSearchIndex(ctx)
+	if err != nil {
+		fatal("Failed to initialize the search index", err)
+	}
+	err = initializeCommentsIndex(ctx)
+	if err != nil {
+		fatal("Failed to initialize the comments search index", err)
+	}
+
+	// Initialize the Embedder computing the articles vector.
+	err = initializeEmbedder()
+	if err != nil {
+		fatal("Failed to initialize the embedder", err)
+	}
+
+	// Build the GraphQL schema of the articles.
+	err = initializeGraphQLSchema()
+	if err != nil {
+		fatal("Failed to build the GraphQL schema", err)
+	}
+
+	// Bring the stored data up to date with the current version of the service.
+	err = runMigrations()
+	if err != nil {
+		fatal("Failed to migrate the Database", err)
+	}
+
+	// Publish the scheduled articles and compute the vectors of the written articles in the background.
+	startPublicationScheduler()
+	startEmbeddingWorker()
+	startJobWorkers(config.JobWorkers)
+	if err := startEventRelay(); err != nil {
+		fatal("Failed to relay the article events", err)
 	}
+	startEventStreamWriter()
+	startKafkaProducer()
+	startWebhookDispatcher()
 
-	// Setup HTTP server and routes.
+	// Serve the typed clients of internal services over gRPC, then setup HTTP server and routes.
+	startGRPCServer()
 	setupHTTPServer()
 }
 
@@ -79,40 +284,145 @@ func initializeDatabase() error {
 		return fmt.Errorf("unable to convert environment variable AS_DBPORT to a valid integer, the exact error was: %v", err)
 	}
 	databaseClient, err = db.NewDbClient(dbServer, dbPortInt, "", 0)
+	if err == nil && config.Tracing {
+		err = db.Instrument(databaseClient)
+	}
 	return err
 }
 
-// setupHTTPServer sets up and starts an HTTP server on address ":8080".
-// It configures route handlers for various endpoints and starts the server.
+// setupHTTPServer sets up and starts an HTTP server on config.ListenAddress, with the timeouts of the configuration.
+// It configures route handlers for various endpoints and serves them until the service is stopped, see
+// serveUntilSignaled.
 // Use mux.HandleFunc to define route handlers for each endpoint.
 func setupHTTPServer() {
 
 	mux := http.NewServeMux()
+	router = mux
 
 	// Define routes using pattern matching for IDs.
 	mux.HandleFunc("GET /articles", getAllArticles)
 	mux.HandleFunc("GET /article/{id}", getArticleByID)
-	mux.HandleFunc("POST /articles", createArticle)
+	mux.HandleFunc("POST /articles", withIdempotency(createArticle))
+	mux.HandleFunc("PUT /articles", updateArticles)
 	mux.HandleFunc("PUT /article/{id}", updateArticleByID)
+	mux.HandleFunc("PATCH /article/{id}", patchArticleByID)
 	mux.HandleFunc("DELETE /article/{id}", deleteArticleByID)
+	mux.HandleFunc("GET /articles/trash", getTrashedArticles)
+	mux.HandleFunc("DELETE /articles/trash", emptyTrash)
+	mux.HandleFunc("POST /article/{id}/restore", restoreArticle)
+	mux.HandleFunc("PUT /article/{id}/acl", updateArticleACL)
+	mux.HandleFunc("DELETE /articles/trash/{id}", purgeArticle)
+	mux.HandleFunc("GET /article/{id}/rendered", getRenderedArticle)
+	mux.HandleFunc("POST /article/{id}/view", recordArticleView)
+	mux.HandleFunc("GET /article/{id}/comments", getArticleComments)
+	mux.HandleFunc("POST /article/{id}/comments", createComment)
+	mux.HandleFunc("POST /article/{id}/comments/{commentId}/flag", flagComment)
+	mux.HandleFunc("GET /article/{id}/related", getRelatedArticles)
+	mux.HandleFunc("GET /article/{id}/revisions", getArticleRevisions)
+	mux.HandleFunc("POST /article/{id}/revisions/{n}/restore", restoreArticleRevision)
+	mux.HandleFunc("GET /article/{id}/revisions/{a}/diff/{b}", diffArticleRevisions)
 	mux.HandleFunc("GET /articles/search", searchArticles)
+	mux.HandleFunc("GET /articles/stats", getArticlesStats)
+	mux.HandleFunc("GET /articles/export", exportArticles)
+	mux.HandleFunc("GET /articles/events", streamArticleEvents)
+	mux.HandleFunc("GET /articles/subscribe", subscribeArticles)
+	mux.HandleFunc("POST /articles/import", importArticles)
+	mux.HandleFunc("GET /articles/import/{id}", getJobOfType("import"))
+	mux.HandleFunc("GET /articles/suggest", suggestArticles)
+	mux.HandleFunc("GET /articles/similar", similarArticles)
+	mux.HandleFunc("GET /articles/popular", getPopularArticles)
+	mux.HandleFunc("GET /tags", getAllTags)
+	mux.HandleFunc("GET /authors", getAllAuthors)
+	mux.HandleFunc("GET /author/{name}", getAuthorProfile)
+	mux.HandleFunc("PUT /author/{name}", updateAuthorProfile)
+	mux.HandleFunc("DELETE /author/{name}", deleteAuthorProfile)
+	mux.HandleFunc("GET /author/{name}/articles", getAuthorArticles)
+	mux.HandleFunc("GET /categories", getAllCategories)
+	mux.HandleFunc("GET /category/{id}", getCategory)
+	mux.HandleFunc("PUT /category/{id}", updateCategory)
+	mux.HandleFunc("DELETE /category/{id}", deleteCategory)
+	mux.HandleFunc("GET /category/{id}/articles", getCategoryArticles)
+	mux.HandleFunc("POST /collections", createCollection)
+	mux.HandleFunc("GET /collection/{id}", getCollection)
+	mux.HandleFunc("PUT /collection/{id}", updateCollection)
+	mux.HandleFunc("DELETE /collection/{id}", deleteCollection)
+	mux.HandleFunc("PUT /users/{user}/likes/{id}", likeArticle)
+	mux.HandleFunc("DELETE /users/{user}/likes/{id}", unlikeArticle)
+	mux.HandleFunc("GET /users/{user}/bookmarks", getUserBookmarks)
+	mux.HandleFunc("PUT /users/{user}/bookmarks/{id}", bookmarkArticle)
+	mux.HandleFunc("DELETE /users/{user}/bookmarks/{id}", unbookmarkArticle)
+
+	mux.HandleFunc("GET /jobs/{id}", getJob)
+	mux.HandleFunc("GET /usage", getUsage)
+	mux.HandleFunc("POST /graphql", executeGraphQL)
+	mux.HandleFunc("GET /openapi.json", getOpenAPIDocument)
+	mux.HandleFunc("GET /healthz", getHealth)
+	mux.HandleFunc("GET /livez", getLiveness)
+	mux.HandleFunc("GET /readyz", getReadiness)
+	if config.SwaggerUI {
+		mux.HandleFunc("GET /docs", getSwaggerUI)
+	}
 
-	serverAddress := ":8080" // HardCoded for this test
-	slog.Info(fmt.Sprintf("Starting HTTP Server on address %s\n", serverAddress))
-	if err := http.ListenAndServe(serverAddress, mux); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	// Admin routes
+	mux.HandleFunc("GET /admin/index", getIndexInfo)
+	mux.HandleFunc("POST /admin/index", recreateIndex)
+	mux.HandleFunc("PATCH /admin/index", alterIndex)
+	mux.HandleFunc("DELETE /admin/index", dropIndex)
+	mux.HandleFunc("POST /admin/reindex", startReindex)
+	mux.HandleFunc("GET /admin/reindex/{id}", getJobOfType("reindex"))
+	mux.HandleFunc("GET /admin/publications", getScheduledPublications)
+	mux.HandleFunc("GET /admin/audit", getAuditLog)
+	mux.HandleFunc("GET /admin/backup", backupArticles)
+	mux.HandleFunc("POST /admin/restore", restoreArticles)
+	mux.HandleFunc("GET /admin/restore/{id}", getJobOfType("restore"))
+	mux.HandleFunc("GET /admin/webhooks", getWebhooks)
+	mux.HandleFunc("POST /admin/webhooks", createWebhook)
+	mux.HandleFunc("GET /admin/webhooks/{id}", getWebhook)
+	mux.HandleFunc("DELETE /admin/webhooks/{id}", deleteWebhook)
+	mux.HandleFunc("GET /admin/apikeys", getAPIKeys)
+	mux.HandleFunc("POST /admin/apikeys", createAPIKey)
+	mux.HandleFunc("DELETE /admin/apikeys/{id}", revokeAPIKey)
+	mux.HandleFunc("GET /admin/comments", getModeratedComments)
+	mux.HandleFunc("PATCH /admin/comments/{commentId}", moderateComment)
+	mux.HandleFunc("DELETE /admin/comments/{commentId}", deleteComment)
+	if config.Pprof {
+		registerPprof(mux)
 	}
+
+	server := &http.Server{
+		Addr:              config.ListenAddress,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		Handler:           withRequestID(withAccessLog(withTracing(mux, withProblemDetails(withRepresentations(withIPFilter(withAuthentication(withRateLimit(withQuotas(withRoles(withTenancy(mux))))))))))),
+	}
+	if err := configureTLS(server); err != nil {
+		fatal("Failed to configure TLS", err)
+	}
+	if err := configureHTTP2(server); err != nil {
+		fatal("Failed to configure HTTP/2", err)
+	}
+	slog.Info("Starting HTTP Server", "address", config.ListenAddress, "tls", server.TLSConfig != nil)
+	serveUntilSignaled(server)
 }
 
 // responseJSON simplifies JSON response writing.
+// The response is sent in another representation when the client prefers it, see withRepresentations.
 func responseJSON(w http.ResponseWriter, v interface{}, statusCode int) {
+	responseJSONAs(w, v, jsonMediaType, statusCode)
+}
+
+// responseJSONAs writes a JSON response like responseJSON, with the given media type of JSON (e.g. application/problem+json).
+func responseJSONAs(w http.ResponseWriter, v interface{}, mediaType string, statusCode int) {
 	jsonResp, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", mediaType)
 	w.WriteHeader(statusCode)
-	w.Header().Set("Content-Type", "application/json")
 	nbrBytesWritten, err := w.Write(jsonResp)
 	if err != nil {
 		slog.Error("Unable to write the following response", "response", jsonResp, "lenght_response", nbrBytesWritten)
@@ -120,12 +430,23 @@ func responseJSON(w http.ResponseWriter, v interface{}, statusCode int) {
 }
 
 // handleError simplifies error handling and response.
+// The error is sent as a Problem, errMsg being its title and err its detail, unless config.ErrorFormat is legacy
+// in which case it is sent as a CustomOutput. Both carry the ErrorCode of err, see errorCodeOf, and the fields
+// which failed the validation when err is a failed validation, see validateStruct.
 func handleError(w http.ResponseWriter, errMsg string, err error, statusCode int) {
+	instance, requestId := problemRequest(w)
 	//Logging any 5xx error
 	if statusCode >= http.StatusInternalServerError {
-		slog.Error(errMsg, "Error:", err)
+		slog.ErrorContext(contextWithRequestID(ctx, requestId), errMsg, "Error:", err)
 	}
-	responseJSON(w, CustomOutput{Error: err.Error(), Message: errMsg}, statusCode)
+	code, fields := errorCodeOf(err, statusCode), fieldErrorsOf(err)
+	if config.ErrorFormat == legacyErrorFormat {
+		responseJSON(w, CustomOutput{Error: err.Error(), Message: errMsg, Code: code, Fields: fields, RequestId: requestId}, statusCode)
+		return
+	}
+	problem := Problem{Type: "about:blank", Title: errMsg, Status: statusCode, Detail: err.Error(), Instance: instance,
+		Code: code, Errors: fields, RequestId: requestId}
+	responseJSONAs(w, problem, problemMediaType, statusCode)
 }
 
 // isQueryParamsExpected checks if a list of query parameters are expected
@@ -138,6 +459,122 @@ func isQueryParamsExpected(queryParams url.Values, expectedParams []string) erro
 	return nil
 }
 
+// parsePaginationParams reads the limit and offset query parameters.
+// limit defaults to defaultPageLimit and can't exceed maxPageLimit, offset defaults to 0.
+// An error is returned if any of them is not a valid integer or is out of range.
+func parsePaginationParams(queryParams url.Values) (int, int, error) {
+	limit, offset := defaultPageLimit, 0
+	var err error
+	if queryParams.Has("limit") {
+		limit, err = strconv.Atoi(queryParams.Get("limit"))
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+	}
+	if queryParams.Has("offset") {
+		offset, err = strconv.Atoi(queryParams.Get("offset"))
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a positive integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// encodeCursor turns a Redis SCAN cursor into an opaque token that can be handed to clients.
+func encodeCursor(cursor uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(cursor, 10)))
+}
+
+// decodeCursor turns a token produced by encodeCursor back into a Redis SCAN cursor.
+// An empty token is the start of the iteration.
+func decodeCursor(token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	rawCursor, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("cursor is not a valid token")
+	}
+	cursor, err := strconv.ParseUint(string(rawCursor), 10, 64)
+	if err != nil {
+		return 0, errors.New("cursor is not a valid token")
+	}
+	return cursor, nil
+}
+
+// fetchArticles retrieves the articles stored at the given keys using db.JSONMGet.
+// It validates each returned element and keeps the first article of each of them.
+func fetchArticles(ctx context.Context, keys []string) ([]Article, error) {
+	articles := []Article{}
+
+	resultMget, err := db.JSONMGet(ctx, databaseClient, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Loop on each element in the array and append its first element to the result after validation
+	for _, responseRetrievedArticle := range resultMget {
+		if responseRetrievedArticle == nil {
+			// The key was removed since it was listed
+			continue
+		}
+		var resultForThisArticle []Article
+		responseArticle, isString := responseRetrievedArticle.(string)
+		if !isString {
+			return nil, errors.New("article returned in incorrect format")
+		}
+		if err := json.Unmarshal([]byte(responseArticle), &resultForThisArticle); err != nil {
+			return nil, fmt.Errorf("unable to validate the structure of returned Article: %v", err)
+		}
+		if len(resultForThisArticle) > 0 {
+			articles = append(articles, resultForThisArticle[0])
+		}
+	}
+	return articles, nil
+}
+
+// getStoredArticle retrieves the article stored at the given key, nil is returned when there is no such article.
+func getStoredArticle(ctx context.Context, key string) (*Article, error) {
+	result, err := db.JSONGet(ctx, databaseClient, key)
+	if err != nil || result == "" {
+		return nil, err
+	}
+	var article Article
+	if err := json.Unmarshal([]byte(result), &article); err != nil {
+		return nil, fmt.Errorf("unable to validate the structure of stored Article: %v", err)
+	}
+	return &article, nil
+}
+
+// setServerManagedFields sets the server managed fields of an article about to be written, ignoring the values
+// provided by the client: the creation time is kept from the stored article, if any, and the update time is now.
+// The version is the one of the stored article incremented, starting at 1, the likes, owner and editors are the ones
+// of the stored article, actor being the owner of a created article, and an article with a publishAt time
+// is a draft (see setArticlePublication).
+// A written article is never deleted, the deletion time only being set when an article is moved to the trash.
+// The HTML markup of its content is sanitized as well (see sanitizeContent), and its reading statistics are computed
+// (see setArticleReadingStats).
+func setServerManagedFields(article *Article, storedArticle *Article, actor string) {
+	now := time.Now().Unix()
+	article.Content = sanitizeContent(*article)
+	setArticleReadingStats(article)
+	setArticlePublication(article)
+	article.DeletedAt = 0
+	article.CreatedAt = now
+	article.Version = 1
+	article.Likes = 0
+	article.Owner, article.Editors = actor, nil
+	if storedArticle != nil {
+		if storedArticle.CreatedAt != 0 {
+			article.CreatedAt = storedArticle.CreatedAt
+		}
+		article.Version = storedArticle.Version + 1
+		article.Likes = storedArticle.Likes
+		article.Owner, article.Editors = storedArticle.Owner, storedArticle.Editors
+	}
+	article.UpdatedAt = now
+}
+
 // uuidValidation validates if a given field is a valid UUID format using the UUID.Parse() function.
 // It returns a boolean value indicating whether the validation succeeds or fails.
 func uuidValidation(fl validator.FieldLevel) bool {
@@ -152,7 +589,10 @@ func structFieldsJsonTags(givenStruct any) []string {
 	var listOfTags []string
 	if t.Kind() == reflect.Struct {
 		for i := 0; i < t.NumField(); i++ {
-			tag := t.Field(i).Tag.Get("json")
+			if t.Field(i).Tag.Get("search") == "-" {
+				continue // Not searchable
+			}
+			tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",") // Ignore JSON options such as omitempty
 			listOfTags = append(listOfTags, tag)
 		}
 	}
@@ -171,7 +611,10 @@ func buildSearchParams(providedParams url.Values, givenStruct any) []db.SearchPa
 			var field reflect.StructField
 			var found bool
 			for i := 0; i < givenStructType.NumField(); i++ {
-				if givenStructType.Field(i).Tag.Get("json") == param {
+				if givenStructType.Field(i).Tag.Get("search") == "-" {
+					continue // Not searchable
+				}
+				if tag, _, _ := strings.Cut(givenStructType.Field(i).Tag.Get("json"), ","); tag == param {
 					field = givenStructType.Field(i)
 					found = true
 					break
@@ -188,10 +631,14 @@ func buildSearchParams(providedParams url.Values, givenStruct any) []db.SearchPa
 
 			// Determine the type of the field
 			switch field.Type.Kind() {
-			case reflect.Slice:
-				newSearchParam.Type = db.ArrayType
 			case reflect.String:
+				// A string field indexed as a TAG (search:"tag") is searched as a whole
 				newSearchParam.Type = db.StringType
+				if field.Tag.Get("search") == "tag" {
+					newSearchParam.Type = db.TagType
+				}
+			case reflect.Slice:
+				newSearchParam.Type = db.ArrayType
 			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
 				reflect.Float32, reflect.Float64:
@@ -213,71 +660,257 @@ func buildSearchParams(providedParams url.Values, givenStruct any) []db.SearchPa
 	return searchParameters
 }
 
+// buildSearchOptions builds the db.SearchOptions from the provided query parameters.
+// sortBy must be one of the sortableFields and order must be either asc (the default) or desc.
+// operator must be either and (the default), to match all the search parameters, or or to match any of them.
+func buildSearchOptions(providedParams url.Values) (db.SearchOptions, error) {
+	var searchOptions db.SearchOptions
+
+	switch strings.ToLower(providedParams.Get("operator")) {
+	case "", "and":
+		searchOptions.MatchAny = false
+	case "or":
+		searchOptions.MatchAny = true
+	default:
+		return searchOptions, errors.New("operator must be either and or or")
+	}
+
+	if providedParams.Has("order") && !providedParams.Has("sortBy") {
+		return searchOptions, errors.New("order can only be used along with sortBy")
+	}
+
+	if providedParams.Has("sortBy") {
+		sortBy := providedParams.Get("sortBy")
+		if !slices.Contains(sortableFields, sortBy) {
+			return searchOptions, fmt.Errorf("sortBy must be one of the following fields: %v", sortableFields)
+		}
+		searchOptions.SortBy = sortBy
+
+		switch strings.ToLower(providedParams.Get("order")) {
+		case "", "asc":
+			searchOptions.SortDescending = false
+		case "desc":
+			searchOptions.SortDescending = true
+		default:
+			return searchOptions, errors.New("order must be either asc or desc")
+		}
+	}
+
+	return searchOptions, nil
+}
+
+// parseFuzziness reads the fuzzy query parameter, which is the Levenshtein distance allowed when matching text fields.
+// It defaults to 0 (exact terms) and can't exceed db.MaxFuzziness.
+func parseFuzziness(providedParams url.Values) (int, error) {
+	if !providedParams.Has("fuzzy") {
+		return 0, nil
+	}
+	fuzziness, err := strconv.Atoi(providedParams.Get("fuzzy"))
+	if err != nil || fuzziness < 0 || fuzziness > db.MaxFuzziness {
+		return 0, fmt.Errorf("fuzzy must be an integer between 0 and %d", db.MaxFuzziness)
+	}
+	return fuzziness, nil
+}
+
+// parseCreatedRange reads the createdAfter and createdBefore query parameters, both exclusive, and returns
+// the db.SearchParams restricting a search to the articles created in between, nil when none of them is provided.
+// A time is either an RFC 3339 timestamp (e.g. 2024-05-01T10:00:00Z), a date (e.g. 2024-05-01) or a Unix timestamp in seconds.
+func parseCreatedRange(providedParams url.Values) (*db.SearchParams, error) {
+	var ranges []db.NumberRange
+	for _, param := range createdRangeParams {
+		if !providedParams.Has(param) {
+			continue
+		}
+		timestamp, err := parseTimestamp(providedParams.Get(param))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp, a date (YYYY-MM-DD) or a Unix timestamp", param)
+		}
+		if param == "createdAfter" {
+			ranges = append(ranges, db.NumberRange{Min: float64(timestamp), Max: math.Inf(1), ExclusiveMin: true})
+		} else {
+			ranges = append(ranges, db.NumberRange{Min: math.Inf(-1), Max: float64(timestamp), ExclusiveMax: true})
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	return &db.SearchParams{Param: "createdAt", Type: db.NumberType, Ranges: ranges}, nil
+}
+
+// parseTimestamp parses an RFC 3339 timestamp, a date or a Unix timestamp in seconds, into a Unix timestamp in seconds.
+func parseTimestamp(value string) (int64, error) {
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return timestamp, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %s", value)
+}
+
+// parseMatchMode reads the match query parameter which is either terms (the default) or phrase.
+// It returns true when text fields should be matched as exact phrases.
+func parseMatchMode(providedParams url.Values) (bool, error) {
+	switch providedParams.Get("match") {
+	case "", "terms":
+		return false, nil
+	case "phrase":
+		return true, nil
+	default:
+		return false, errors.New("match must be either terms or phrase")
+	}
+}
+
 /*
 Handlers Functions
 */
 
-// getAllArticles retrieves all articles from the database and returns them as a JSON response.
-// It uses db.GetAllKeys to get a list of article keys and db.JSONMGet to retrieve the article details for each key.
-// The function then validates and appends the first article element to the result. Finally, it sends the result as a JSON response.
+// getAllArticles retrieves a page of articles from the database and returns them as a JSON response.
+// When the cursor query parameter is provided, the request is served by getArticlesByCursor, and when the client
+// prefers newline delimited JSON (Accept: application/x-ndjson) all the articles are streamed by streamAllArticles.
+// Otherwise, it uses db.GetAllKeys to get a list of article keys, sorts them so that pages are stable between calls,
+// and then uses fetchArticles to retrieve the article details only for the keys that fall in the requested page.
+// The page is controlled by the limit and offset query parameters, see parsePaginationParams.
+// Articles are returned as summaries, without their content unless include=content is provided, and the fields
+// query parameter restricts the fields returned for each article instead (e.g. fields=id,title), see parseListingFieldsParams.
+// Only the returned fields are retrieved from the database.
+// The result is sent as an ArticlesPage JSON response that carries the paging metadata, along with its ETag
+// so that an unchanged page is answered with an HTTP 304 Not Modified when the If-None-Match header matches it.
 func getAllArticles(w http.ResponseWriter, r *http.Request) {
-	var articles []Article
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset", "cursor", fieldsParam, includeParam}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseListingFieldsParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	if prefersNDJSON(r) {
+		if queryParams.Has("limit") || queryParams.Has("offset") || queryParams.Has("cursor") {
+			handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, errors.New("all the articles are streamed, limit, offset and cursor can't be used")), http.StatusBadRequest)
+			return
+		}
+		streamAllArticles(ctx, w, fields)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	if queryParams.Has("cursor") {
+		if queryParams.Has("offset") {
+			handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, errors.New("cursor and offset can't be used together")), http.StatusBadRequest)
+			return
+		}
+		getArticlesByCursor(w, r, queryParams.Get("cursor"), limit, fields)
+		return
+	}
+
+	page := ArticlesPage{
+		Articles: []Article{},
+		Limit:    limit,
+		Offset:   offset,
+	}
 
 	// Use Scan to efficiently iterate through keys with the specified keysPrefix.
-	keys, err := db.GetAllKeys(ctx, databaseClient, keysPrefix)
+	keys, err := db.GetAllKeys(ctx, databaseClient, tenantKey(ctx, keysPrefix))
 	if err != nil {
 		handleError(w, "Failed to retrieve article keys from Database", err, http.StatusInternalServerError)
 		return
 	}
+	page.Total = len(keys)
 
-	if len(keys) == 0 {
-		// No articles found, return an empty list with HTTP 200 OK.
-		responseJSON(w, []Article{}, http.StatusOK)
+	if offset >= len(keys) {
+		// No articles found in this page, return an empty list with HTTP 200 OK.
+		responseProjectedJSON(w, r, page, fields, http.StatusOK)
 		return
 	}
 
-	// Retrieve article details for each key
-	resultMget, err := db.JSONMGet(ctx, databaseClient, keys)
+	// Scan returns keys in no particular order, sort them to keep pages consistent
+	slices.Sort(keys)
+	keys = keys[offset:min(offset+limit, len(keys))]
+
+	// Retrieve article details for each key of the page
+	page.Articles, err = fetchArticleFields(ctx, keys, fields)
 	if err != nil {
 		handleError(w, "An Error Occurred while Getting Articles", err, http.StatusInternalServerError)
 		return
 	}
 
-	if resultMget == nil {
-		// No articles found, return an empty list with HTTP 200 OK.
-		responseJSON(w, articles, http.StatusOK)
+	responseProjectedJSON(w, r, page, fields, http.StatusOK)
+}
+
+// getArticlesByCursor serves GET /articles in cursor mode.
+// The given cursor is an opaque token previously returned as next_cursor (empty to start the iteration),
+// it is decoded back to a Redis SCAN cursor and keys are scanned from there until at least limit keys are found
+// or the iteration is complete. As SCAN COUNT is only a hint, a page can hold slightly more than limit articles.
+// The result is sent as an ArticlesCursorPage, with an empty next_cursor once all articles have been returned,
+// each article holding only the given fields (all of them when nil).
+func getArticlesByCursor(w http.ResponseWriter, r *http.Request, cursorToken string, limit int, fields []string) {
+	ctx := requestContext(r)
+	cursor, err := decodeCursor(cursorToken)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
 		return
 	}
 
-	// Loop on each element in the array and append its first element to the result after validation
-	var result []Article
-	for _, responseRetrievedArticle := range resultMget {
-		var resultForThisArticle []Article
-		responseArticle, isString := responseRetrievedArticle.(string)
-		if !isString {
-			handleError(w, "An Error Occurred while Getting Articles", fmt.Errorf("article returned in incorrect format"), http.StatusInternalServerError)
+	var keys []string
+	for {
+		var batch []string
+		batch, cursor, err = db.ScanKeys(ctx, databaseClient, tenantKey(ctx, keysPrefix), cursor, int64(limit-len(keys)))
+		if err != nil {
+			handleError(w, "Failed to retrieve article keys from Database", err, http.StatusInternalServerError)
 			return
 		}
-		err = json.Unmarshal([]byte(responseArticle), &resultForThisArticle)
+		keys = append(keys, batch...)
+		if cursor == 0 || len(keys) >= limit {
+			break
+		}
+	}
+
+	page := ArticlesCursorPage{
+		Articles: []Article{},
+		Limit:    limit,
+	}
+	if cursor != 0 {
+		page.NextCursor = encodeCursor(cursor)
+	}
+
+	if len(keys) > 0 {
+		page.Articles, err = fetchArticleFields(ctx, keys, fields)
 		if err != nil {
-			handleError(w, "Unable to validate the structure of returned Article", err, http.StatusInternalServerError)
+			handleError(w, "An Error Occurred while Getting Articles", err, http.StatusInternalServerError)
 			return
 		}
-		result = append(result, resultForThisArticle[0])
 	}
 
-	responseJSON(w, result, http.StatusOK)
+	responseProjectedJSON(w, r, page, fields, http.StatusOK)
 }
 
 // getArticleByID retrieves an article from the database using the provided ID.
 // It builds a database key using the article ID and then uses db.JSONGet to retrieve the article.
-// If the article is not found, it returns an HTTP 404 Not Found response.
-// The function then unmarshals the article JSON into an Article struct and returns it as a JSON response.
+// If the article is not found, or is a draft the actor can't read (see isHiddenDraft), it returns an HTTP 404 Not Found response.
+// The fields query parameter restricts the fields returned (e.g. fields=id,title).
+// The function then unmarshals the article JSON into an Article struct and returns it as a JSON response along with its ETag
+// and Last-Modified time, an HTTP 304 Not Modified being returned instead when the client copy is current (see responseArticleJSON).
 // If any unexpected errors occur during the process, it uses handleError to handle the errors and respond with an appropriate HTTP status code and message.
 func getArticleByID(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
 	id := r.PathValue("id")
+	fields, err := parseFieldsParam(r.URL.Query())
+	if err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
 	// Build the Database key using the article ID.
-	key := fmt.Sprintf("%s%s", keysPrefix, id)
+	key := tenantKey(ctx, keysPrefix+id)
 
 	// Retrieve the article from Database.
 	result, err := db.JSONGet(ctx, databaseClient, key)
@@ -289,7 +922,7 @@ func getArticleByID(w http.ResponseWriter, r *http.Request) {
 
 	if result == "" {
 		// Article not found, respond with HTTP 404 Not Found.
-		handleError(w, fmt.Sprintf("No article found with ID %s", id), nil, http.StatusNotFound)
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
 		return
 	}
 
@@ -299,9 +932,14 @@ func getArticleByID(w http.ResponseWriter, r *http.Request) {
 		handleError(w, "Failed to parse article data", err, http.StatusInternalServerError)
 		return
 	}
+	if isHiddenDraft(ctx, article) {
+		// A draft is only shown to the editors.
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
 
-	// Return the article as JSON.
-	responseJSON(w, article, http.StatusOK)
+	// Return the article as JSON, unless the client already holds it.
+	responseArticleJSON(w, r, article, fields, http.StatusOK)
 }
 
 // createArticle handles the creation of articles. It reads the request body and expects
@@ -316,7 +954,8 @@ func getArticleByID(w http.ResponseWriter, r *http.Request) {
 // If the JSON decoding, validation, reading, unmarshaling, or setting of articles in the
 // database fails, it returns an error with the appropriate status code.
 func createArticle(w http.ResponseWriter, r *http.Request) {
-	var articlesSetArgs []db.JSONSetArgs
+	ctx := requestContext(r)
+	var articlesSets []db.VersionedJSONSet
 	var articles []*Article
 
 	jsonDecoder := json.NewDecoder(r.Body)
@@ -324,7 +963,7 @@ func createArticle(w http.ResponseWriter, r *http.Request) {
 	// read the  first token that will help check if it's an array or a single object
 	typeChecker, err := jsonDecoder.Token()
 	if err != nil {
-		handleError(w, "Error reading JSON", err, http.StatusBadRequest)
+		handleError(w, "Error reading JSON", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
 		return
 	}
 
@@ -336,7 +975,7 @@ func createArticle(w http.ResponseWriter, r *http.Request) {
 			// decode an array value
 			err := jsonDecoder.Decode(&article)
 			if err != nil {
-				handleError(w, "Failed to decode request body", err, http.StatusBadRequest)
+				handleError(w, "Failed to decode request body", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
 				return
 			}
 			articles = append(articles, &article)
@@ -348,68 +987,54 @@ func createArticle(w http.ResponseWriter, r *http.Request) {
 		// Read the remainder of the JSON object from the decoder's buffer
 		_, err := buf.ReadFrom(jsonDecoder.Buffered())
 		if err != nil && err != io.EOF {
-			handleError(w, "Failed to read request body", err, http.StatusBadRequest)
+			handleError(w, "Failed to read request body", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
 			return
 		}
 		// Unmarshal the JSON bytes from the buffer into an article
 		var article Article
 		if err := json.Unmarshal(buf.Bytes(), &article); err != nil {
-			handleError(w, "Failed to unmarshal JSON", err, http.StatusBadRequest)
+			handleError(w, "Failed to unmarshal JSON", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
 			return
 		}
 		articles = append(articles, &article)
 	default:
-		handleError(w, "Invalid JSON format", errors.New("the Provided JSON is neither a list of articles nor an article"), http.StatusBadRequest)
+		handleError(w, "Invalid JSON format", withErrorCode(ErrorCodeInvalidBody, errors.New("the Provided JSON is neither a list of articles nor an article")), http.StatusBadRequest)
+		return
 	}
 
-	// Validate and Database Set arguments needed for Database JSONMSet
+	// Validate and prepare the writes of the articles, each of them being only written when no article has its ID
+	createdIds := make(map[string]bool, len(articles))
 	for _, article := range articles {
 		if article.Id == "" {
 			// Generate a unique UUID
 			newId := uuid.New()
 			article.Id = newId.String()
 		}
-		if validateErr := validate.Struct(article); validateErr != nil {
+		if validateErr := validateStruct(article); validateErr != nil {
 			handleError(w, fmt.Sprintf("Validation failed for article %+v", article), validateErr, http.StatusBadRequest)
 			return
 		}
-		key := fmt.Sprintf("%s%s", keysPrefix, article.Id)
-
-		// Check if the article already exists in Database
-		exists, err := db.Exists(ctx, databaseClient, key)
-		if err != nil {
-			handleError(w, "Error checking if article exists", err, http.StatusInternalServerError)
-			return
-		}
-		if exists != 0 {
-			handleError(w, fmt.Sprintf("article with ID %s found in Database", article.Id), fmt.Errorf("duplicate Article Id"), http.StatusNotFound)
+		if createdIds[article.Id] {
+			handleError(w, fmt.Sprintf("article with ID %s provided more than once", article.Id), withErrorCode(ErrorCodeDuplicateId, fmt.Errorf("duplicate Article Id")), http.StatusConflict)
 			return
 		}
+		createdIds[article.Id] = true
+		setServerManagedFields(article, nil, requestActor(r))
+		articlesSets = append(articlesSets, versionedArticleSet(ctx, tenantKey(ctx, keysPrefix+article.Id), nil, *article))
+	}
 
-		// Note: For now JSONSetArgs does not seem to marshaled back JSON
-		// Hence, we marshall this before setting as Argument
-		articleByte, errMarshall := json.Marshal(article)
-		if errMarshall != nil {
-			handleError(w, fmt.Sprintf("Creating article with ID %s in the Database failed. No Article Added", article.Id), errMarshall, http.StatusInternalServerError)
+	// Create all the articles in Database, unless one of them already exists: the check and the writes are made by a
+	// single script, so that an article created concurrently with the same ID is never overwritten
+	if err := db.JSONMSetIfVersion(ctx, databaseClient, versionPath, articlesSets); err != nil {
+		if errors.Is(err, db.ErrVersionMismatch) {
+			handleError(w, "article with the same ID found in Database", withErrorCode(ErrorCodeDuplicateId, err), http.StatusConflict)
 			return
 		}
-		articlesSetArgs = append(articlesSetArgs, db.JSONSetArgs{
-			Key:   key,
-			Path:  "$",
-			Value: articleByte,
-		})
-	}
-
-	// Set the result in Database, using JSONMSet
-	result, err := db.JSONMSetArgs(ctx, databaseClient, articlesSetArgs)
-	if err != nil {
 		handleError(w, "creating articles in the Database failed", err, http.StatusInternalServerError)
 		return
 	}
-
-	// With error from JSONMSetArgs being nil, we should not expect result to not be OK
-	if result != "OK" {
-		handleError(w, "unexpected failure while creating articles in the Database", errors.New("JSONMSetArgs returns not ok result"), http.StatusInternalServerError)
+	for _, article := range articles {
+		articleChanged(ctx, requestActor(r), nil, article)
 	}
 
 	// Output only the ID of the articles
@@ -426,91 +1051,378 @@ func createArticle(w http.ResponseWriter, r *http.Request) {
 // updateArticleByID updates an article with the provided ID in the database.
 // It decodes the JSON payload from the request body and populates the article struct.
 // Then, it validates the article struct using the validate library.
-// Next, it checks if the article exists in the database.
-// If the article does not exist, it responds with an HTTP 404 Not Found error.
+// Next, it checks if the article exists in the database and is the version designated by the If-Match header
+// (a version or an ETag) or the version field, responding with an HTTP 428 Precondition Required when no version is provided and
+// with an HTTP 409 Conflict when the version is stale.
+// If the article does not exist, it responds with an HTTP 404 Not Found error, unless the upsert query parameter
+// is set to true, in which case the article is created and an HTTP 201 Created is returned.
+// An actor who is not allowed to change the article (see canChangeArticle) gets an HTTP 403 Forbidden error.
 // Otherwise, it updates the article in the database using the key built from the ID.
-// Finally, it responds with the updated article as a JSON response.
+// Finally, it responds with the updated (or created) article as a JSON response.
 func updateArticleByID(w http.ResponseWriter, r *http.Request) {
-
+	ctx := requestContext(r)
 	id := r.PathValue("id")
 
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"upsert"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	upsert := false
+	if queryParams.Has("upsert") {
+		var err error
+		if upsert, err = strconv.ParseBool(queryParams.Get("upsert")); err != nil {
+			handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, errors.New("upsert must be a boolean")), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Decode the JSON payload directly from the request body
 	var article Article
 	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
-		handleError(w, "Invalid JSON payload", err, http.StatusBadRequest)
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
 		return
 	}
 	article.Id = id
 
 	// Validate the article struct
-	if err := validate.Struct(article); err != nil {
+	if err := validateStruct(article); err != nil {
 		handleError(w, "Validation failed for article", err, http.StatusBadRequest)
 		return
 	}
 
 	// Check if the article exists in Database
-	key := fmt.Sprintf("%s%s", keysPrefix, id)
-	exists, err := db.Exists(ctx, databaseClient, key)
+	key := tenantKey(ctx, keysPrefix+id)
+	storedArticle, err := getStoredArticle(ctx, key)
 	if err != nil {
 		handleError(w, "Error checking if article exists", err, http.StatusInternalServerError)
 		return
 	}
-	if exists == 0 {
-		handleError(w, "Article not found", fmt.Errorf("no article found with ID %s", id), http.StatusNotFound)
+	if storedArticle == nil && !upsert {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
 		return
 	}
 
-	// Update the article in Database
-	if _, err = db.JSONSet(ctx, databaseClient, key, "$", article); err != nil {
-		handleError(w, "Failed to update article in Database", err, http.StatusInternalServerError)
+	// Check that the stored article is the version being updated
+	if storedArticle != nil {
+		if !canChangeArticle(ctx, requestActor(r), storedArticle) {
+			articleForbidden(w, requestActor(r), id)
+			return
+		}
+		if !checkExpectedVersion(w, r, article.Version, storedArticle) {
+			return
+		}
+	}
+	setServerManagedFields(&article, storedArticle, requestActor(r))
+
+	// Update (or create) the article in Database, unless it has been changed concurrently
+	if err = setArticleIfVersion(ctx, key, storedArticle, article); err != nil {
+		handleVersionedWriteError(w, err)
 		return
 	}
 
 	// Respond with the updated article
-	responseJSON(w, article, http.StatusOK)
+	statusCode := http.StatusOK
+	if storedArticle == nil {
+		statusCode = http.StatusCreated
+	}
+	articleChanged(ctx, requestActor(r), storedArticle, &article)
+	responseArticleJSON(w, r, article, nil, statusCode)
+}
+
+// updateArticles updates a list of articles in the database in a single operation.
+// It decodes a JSON array of full Article objects from the request body, validates each of them and checks
+// that each article exists in the database. All the failures are gathered and reported per article
+// using ArticleBulkError with an HTTP 400 Bad Request (or 404 Not Found when only missing articles are found,
+// 409 Conflict when only stale versions are found, 403 Forbidden when only articles the actor is not allowed to change
+// are found, see canChangeArticle), in which case no article is updated.
+// Each article must hold the version it updates, see Article.Version.
+// Otherwise, all the articles are updated at once, provided that none of them has been changed concurrently,
+// and the IDs of the updated articles are returned.
+func updateArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	var articles []Article
+	if err := json.NewDecoder(r.Body).Decode(&articles); err != nil {
+		handleError(w, "Invalid JSON payload, a list of articles is expected", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	if len(articles) == 0 {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, errors.New("the list of articles to update is empty")), http.StatusBadRequest)
+		return
+	}
+
+	var versionedSets []db.VersionedJSONSet
+	var bulkErrors []ArticleBulkError
+	var previousArticles []*Article
+	notFoundOnly, conflictsOnly, forbiddenOnly := true, true, true
+	seenIds := make(map[string]bool, len(articles))
+
+	for i, article := range articles {
+		if validateErr := validateStruct(article); validateErr != nil {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id, Error: validateErr.Error(), Fields: fieldErrorsOf(validateErr)})
+			notFoundOnly, conflictsOnly, forbiddenOnly = false, false, false
+			continue
+		}
+		if seenIds[article.Id] {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id, Error: "article provided more than once"})
+			notFoundOnly, conflictsOnly, forbiddenOnly = false, false, false
+			continue
+		}
+		if article.Version == 0 {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id, Error: errVersionRequired.Error()})
+			notFoundOnly, conflictsOnly, forbiddenOnly = false, false, false
+			continue
+		}
+		seenIds[article.Id] = true
+
+		// Check if the article exists in Database
+		key := tenantKey(ctx, keysPrefix+article.Id)
+		storedArticle, err := getStoredArticle(ctx, key)
+		if err != nil {
+			handleError(w, "Error checking if article exists", err, http.StatusInternalServerError)
+			return
+		}
+		if storedArticle == nil {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id, Error: fmt.Sprintf("no article found with ID %s", article.Id)})
+			conflictsOnly, forbiddenOnly = false, false
+			continue
+		}
+		if !canChangeArticle(ctx, requestActor(r), storedArticle) {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id, Error: fmt.Sprintf("%s is not allowed to change the article", requestActor(r))})
+			notFoundOnly, conflictsOnly = false, false
+			continue
+		}
+		if article.Version != storedArticle.Version {
+			bulkErrors = append(bulkErrors, ArticleBulkError{Index: i, Id: article.Id,
+				Error: fmt.Sprintf("article is at version %d, not %d", storedArticle.Version, article.Version)})
+			notFoundOnly, forbiddenOnly = false, false
+			continue
+		}
+
+		previousArticles = append(previousArticles, storedArticle)
+		setServerManagedFields(&articles[i], storedArticle, requestActor(r))
+		versionedSets = append(versionedSets, versionedArticleSet(ctx, key, storedArticle, articles[i]))
+	}
+
+	if len(bulkErrors) > 0 {
+		statusCode := http.StatusBadRequest
+		if notFoundOnly {
+			statusCode = http.StatusNotFound
+		} else if conflictsOnly {
+			statusCode = http.StatusConflict
+		} else if forbiddenOnly {
+			statusCode = http.StatusForbidden
+		}
+		responseJSON(w, ArticlesBulkOutput{
+			Message: fmt.Sprintf("%d of %d articles failed, no article updated", len(bulkErrors), len(articles)),
+			Errors:  bulkErrors,
+		}, statusCode)
+		return
+	}
+
+	// Update all the articles in Database, unless one of them has been changed concurrently
+	if err := db.JSONMSetIfVersion(ctx, databaseClient, versionPath, versionedSets); err != nil {
+		handleVersionedWriteError(w, err)
+		return
+	}
+	for i, article := range articles {
+		articleChanged(ctx, requestActor(r), previousArticles[i], &article)
+	}
+
+	// Output only the ID of the articles
+	outputArticles := make([]struct {
+		Id string `json:"id"`
+	}, len(articles))
+
+	for i := range articles {
+		outputArticles[i].Id = articles[i].Id
+	}
+	responseJSON(w, outputArticles, http.StatusOK)
+}
+
+// patchArticleByID partially updates an article with the provided ID in the database.
+// The request body can either be a JSON Merge Patch document (RFC 7386), sent as application/merge-patch+json
+// (application/json is accepted as well), or a JSON Patch document (RFC 6902) sent as application/json-patch+json.
+// The patch is applied onto the stored article using mergePatch or applyJSONPatch respectively,
+// the result is validated like any other article and persisted using JSONSet.
+// The id of an article can't be changed through a patch.
+// The version being patched must be designated by the If-Match header (a version or an ETag), or by the patch itself (a version member of
+// a merge patch or a test operation on /version), otherwise it responds with an HTTP 428 Precondition Required.
+// If the article does not exist, it responds with an HTTP 404 Not Found error,
+// if the actor is not allowed to change it (see canChangeArticle), with an HTTP 403 Forbidden error, and
+// if a JSON Patch test operation fails, it responds with an HTTP 409 Conflict error.
+// Finally, it responds with the patched article as a JSON response.
+func patchArticleByID(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+
+	// Decode the patch document from the request body according to its format
+	var mergePatchDocument any
+	var jsonPatchDocument []jsonPatchOperation
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json", "application/json", "":
+		err = json.NewDecoder(r.Body).Decode(&mergePatchDocument)
+	case "application/json-patch+json":
+		err = json.NewDecoder(r.Body).Decode(&jsonPatchDocument)
+	default:
+		handleError(w, "Unsupported patch format",
+			fmt.Errorf("content type %s is not supported, use application/merge-patch+json or application/json-patch+json", mediaType),
+			http.StatusUnsupportedMediaType,
+		)
+		return
+	}
+	if err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+
+	// Retrieve the current article from Database
+	key := tenantKey(ctx, keysPrefix+id)
+	result, err := db.JSONGet(ctx, databaseClient, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if result == "" {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	var storedArticle any
+	if err := json.Unmarshal([]byte(result), &storedArticle); err != nil {
+		handleError(w, "Failed to parse article data", err, http.StatusInternalServerError)
+		return
+	}
+	var previousArticle Article
+	if err := json.Unmarshal([]byte(result), &previousArticle); err != nil {
+		handleError(w, "Failed to parse article data", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Apply the patch onto the stored article
+	var patchedDocument any
+	if mediaType == "application/json-patch+json" {
+		patchedDocument, err = applyJSONPatch(storedArticle, jsonPatchDocument)
+		if errors.Is(err, errPatchTestFailed) {
+			handleError(w, "Failed to apply patch", err, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			handleError(w, "Failed to apply patch", err, http.StatusBadRequest)
+			return
+		}
+	} else {
+		patchedDocument = mergePatch(storedArticle, mergePatchDocument)
+	}
+
+	// Decode the result back into an Article
+	patchedArticle, err := json.Marshal(patchedDocument)
+	if err != nil {
+		handleError(w, "Failed to apply patch", err, http.StatusInternalServerError)
+		return
+	}
+	var article Article
+	if err := json.Unmarshal(patchedArticle, &article); err != nil {
+		handleError(w, "Patched article is not a valid article", err, http.StatusBadRequest)
+		return
+	}
+	if article.Id != id {
+		handleError(w, "Patched article is not a valid article", withErrorCode(ErrorCodeValidationFailed, errors.New("the id of an article can't be changed")), http.StatusBadRequest)
+		return
+	}
+	if !canChangeArticle(ctx, requestActor(r), &previousArticle) {
+		articleForbidden(w, requestActor(r), id)
+		return
+	}
+	if !checkExpectedVersion(w, r, patchVersion(mergePatchDocument, jsonPatchDocument), &previousArticle) {
+		return
+	}
+
+	// Validate the article struct
+	if err := validateStruct(article); err != nil {
+		handleError(w, "Validation failed for article", err, http.StatusBadRequest)
+		return
+	}
+
+	// Update the article in Database
+	setServerManagedFields(&article, &previousArticle, requestActor(r))
+	if err = setArticleIfVersion(ctx, key, &previousArticle, article); err != nil {
+		handleVersionedWriteError(w, err)
+		return
+	}
+	articleChanged(ctx, requestActor(r), &previousArticle, &article)
+
+	// Respond with the patched article
+	responseArticleJSON(w, r, article, nil, http.StatusOK)
 }
 
 // deleteArticleByID deletes an article from the database using the provided ID.
 // It constructs the database key for the article by concatenating the keysPrefix and the provided ID.
 // It then checks if the article exists in the database before attempting to delete it.
-// If the article does not exist, it returns an HTTP 404 Not Found response.
+// If the article does not exist, it returns an HTTP 404 Not Found response, and if the actor is not allowed to
+// delete it (see canChangeArticle), an HTTP 403 Forbidden response.
 // If there is an error while checking if the article exists, it uses handleError to handle the error and respond with an appropriate HTTP status code and message.
+// The article is not deleted permanently but moved to the trash along with its deletion time (see trash.go),
+// which takes it out of the listings and of the search index, until it is restored or purged.
 // If there is an error while deleting the article, it uses handleError to handle the error and respond with an appropriate HTTP status code and message.
 // Finally, it responds with a success message indicating that the article has been successfully deleted.
 func deleteArticleByID(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
 	id := r.PathValue("id")
 
 	// Construct the Database key for the article
-	key := fmt.Sprintf("%s%s", keysPrefix, id)
+	key := tenantKey(ctx, keysPrefix+id)
 
 	// Check if the article exists before attempting to delete
-	exists, err := db.Exists(ctx, databaseClient, key)
+	storedArticle, err := getStoredArticle(ctx, key)
 	if err != nil {
 		handleError(w, "Error checking if article exists", err, http.StatusInternalServerError)
 		return
 	}
-	if exists == 0 {
-		handleError(w, "Article not found", fmt.Errorf("no article found with ID %s", id), http.StatusNotFound)
+	if storedArticle == nil {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	if !canChangeArticle(ctx, requestActor(r), storedArticle) {
+		articleForbidden(w, requestActor(r), id)
 		return
 	}
 
-	// Delete the article from Database
-	if _, err := db.Del(ctx, databaseClient, key); err != nil {
+	// Move the article to the trash
+	if err := db.JSONSetAndRename(ctx, databaseClient, key, "$.deletedAt", time.Now().Unix(), tenantKey(ctx, trashKeysPrefix+id)); err != nil {
 		handleError(w, "Failed to delete article from Database", err, http.StatusInternalServerError)
 		return
 	}
+	articleChanged(ctx, requestActor(r), storedArticle, nil)
 
 	// Respond to indicate successful deletion
 	responseJSON(w, CustomOutput{Message: fmt.Sprintf("article with ID %s successfully deleted", id)}, http.StatusOK)
 }
 
 // searchArticles handles the search functionality for articles based on the provided query parameters.
-// It validates the parameters, builds the search parameters, and runs the search query.
-// The search results are returned in the HTTP response.
+// Each article found is returned along with its relevance score and, when highlight=true, the fragments
+// of its text fields that matched the search, see ArticleSearchHit.
+// Results are paginated through the limit and offset parameters and returned as an ArticlesSearchPage.
+// The facets parameter (e.g. facets=tags,author) adds the number of matching articles per value of each given field.
+// The language parameter accepts either ISO 639-1 codes (e.g. language=fr) or language names (e.g. language=french).
+// When no article is found, corrected terms are suggested for the misspelled ones using db.Spellcheck.
+// Each Article field can be searched on its own, while the q parameter runs a full-text search across fullTextSearchFields.
+// The fuzzy parameter allows text fields to match terms within the given Levenshtein distance.
+// The minWords and maxWords parameters (e.g. minWords=500) restrict the search on the wordCount of the articles.
+// Prefix (e.g. title=data*) and wildcard (e.g. author=j?hn, title=*base) terms are supported on every field.
+// Exact phrases are searched either by quoting the value (e.g. title="redis search") or by setting match=phrase.
+// Within a value, alternatives are separated by | (e.g. tags=go|redis) and a leading - excludes matches (e.g. author=-smith),
+// while operator=or returns the articles matching any of the parameters instead of all of them.
+// It validates the parameters, builds the search parameters and the search options (e.g. sorting),
+// and runs the search query. The search results are returned in the HTTP response as article summaries, without their
+// content unless include=content is provided, or restricted to the Article fields listed by the fields parameter
+// when provided (e.g. fields=id,title). Only the returned fields are retrieved from the database.
 func searchArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
 
-	// Getting Expected parameters from Article JSON Tags
-	expectedParams := structFieldsJsonTags(Article{})
+	// Getting Expected parameters from Article JSON Tags, along with the free-text parameter
+	expectedParams := append(structFieldsJsonTags(Article{}), fullTextSearchParam)
 
 	providedParams := r.URL.Query()
 	invalidSearchError := "invalid search parameter"
@@ -518,27 +1430,224 @@ func searchArticles(w http.ResponseWriter, r *http.Request) {
 	if len(providedParams) == 0 {
 		handleError(w,
 			invalidSearchError,
-			fmt.Errorf("you must provide at least one of the following parameter: %v", expectedParams), http.StatusBadRequest,
+			withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("you must provide at least one of the following parameter: %v", expectedParams)), http.StatusBadRequest,
 		)
 		return
 	}
 
 	// Check that the provided parameters are in expected Parameters
-	if err := isQueryParamsExpected(providedParams, expectedParams); err != nil {
-		handleError(w, invalidSearchError, err, http.StatusBadRequest)
+	if err := isQueryParamsExpected(providedParams, slices.Concat(expectedParams, createdRangeParams, wordCountRangeParams, searchOptionsParams)); err != nil {
+		handleError(w, invalidSearchError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
 		return
 	}
 
-	// Database Search Parameter
-	searchParameters := buildSearchParams(providedParams, Article{})
+	// Database Search Parameter and Options
+	searchParameters, searchOptions, err := buildArticlesSearch(ctx, providedParams)
+	if err != nil {
+		handleError(w, invalidSearchError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseListingFieldsParams(providedParams)
+	if err != nil {
+		handleError(w, invalidSearchError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	searchOptions.Paths = articleFieldPaths(fields)
+
+	var facets []string
+	if providedParams.Has("facets") {
+		for _, facet := range strings.Split(providedParams.Get("facets"), ",") {
+			if !slices.Contains(facetableFields, facet) {
+				handleError(w, invalidSearchError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("facets must be a comma separated list of the following fields: %v", facetableFields)), http.StatusBadRequest)
+				return
+			}
+			facets = append(facets, facet)
+		}
+	}
 
 	// Run the Search Query
-	resArticles, err := db.Search[Article](ctx, databaseClient, searchIndexName, searchParameters)
+	searchOptions.WithScores = true
+	searchResult, err := db.Search[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions)
 	if err != nil {
 		genericDbErrorMsg := fmt.Sprintf("Database Error while searching with parameter: %s", providedParams.Encode())
 		handleError(w, genericDbErrorMsg, err, http.StatusInternalServerError)
 		return
 	}
 
-	responseJSON(w, resArticles, http.StatusOK)
+	page := ArticlesSearchPage{
+		Articles: make([]ArticleSearchHit, len(searchResult.Hits)),
+		Total:    searchResult.Total,
+		Limit:    searchOptions.Limit,
+		Offset:   searchOptions.Offset,
+	}
+	for i, searchHit := range searchResult.Hits {
+		page.Articles[i] = ArticleSearchHit{Article: searchHit.Item, Score: searchHit.Score, Highlights: searchHit.Highlights}
+	}
+
+	// Suggest corrected terms when nothing is found ("did you mean")
+	if searchResult.Total == 0 {
+		corrections, err := db.Spellcheck(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions, spellcheckDistance)
+		if err != nil {
+			// Suggestions are a convenience, the search results are still returned
+			slog.WarnContext(ctx, "Unable to spellcheck search", "parameters", providedParams.Encode(), "Error:", err)
+		}
+		if len(corrections) > 0 {
+			page.Suggestions = make(map[string][]string, len(corrections))
+			for term, suggestions := range corrections {
+				for _, suggestion := range suggestions {
+					page.Suggestions[term] = append(page.Suggestions[term], suggestion.Value)
+				}
+			}
+		}
+	}
+
+	// Count the matching articles per value of the requested facets
+	if len(facets) > 0 {
+		facetCounts, err := db.Facets(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions, facets, maxFacetValues)
+		if err != nil {
+			genericDbErrorMsg := fmt.Sprintf("Database Error while computing facets with parameter: %s", providedParams.Encode())
+			handleError(w, genericDbErrorMsg, err, http.StatusInternalServerError)
+			return
+		}
+		page.Facets = make(map[string][]FacetCount, len(facetCounts))
+		for field, counts := range facetCounts {
+			page.Facets[field] = make([]FacetCount, len(counts))
+			for i, count := range counts {
+				page.Facets[field][i] = FacetCount(count)
+			}
+		}
+	}
+
+	responseProjectedJSON(w, r, page, fields, http.StatusOK)
+}
+
+// buildArticlesSearch builds the parameters and the options of a search of articles from the provided query parameters
+// (see searchArticles): the Article fields, q, createdAfter, createdBefore, minWords and maxWords, along with sortBy, order, operator,
+// limit, offset, fuzzy, match and highlight. The articles the actor of ctx can't see are excluded, see visibleArticlesFilters.
+func buildArticlesSearch(ctx context.Context, providedParams url.Values) ([]db.SearchParams, db.SearchOptions, error) {
+	var queryLanguage string
+	searchParameters := buildSearchParams(providedParams, Article{})
+	for i, searchParameter := range searchParameters {
+		if searchParameter.Param != "language" {
+			continue
+		}
+		// Languages can be searched either by ISO 639-1 code (e.g. fr) or by name (e.g. french)
+		for j, language := range searchParameter.Value {
+			if name, supported := searchLanguageName(language); supported {
+				searchParameters[i].Value[j] = name
+			}
+		}
+		// Stem the query terms according to the searched language
+		if len(searchParameter.Value) == 1 {
+			queryLanguage = searchParameter.Value[0]
+		}
+	}
+	if providedParams.Has(fullTextSearchParam) {
+		searchParameters = append(searchParameters, db.SearchParams{
+			Param: strings.Join(fullTextSearchFields, "|"),
+			Type:  db.StringType,
+			Value: providedParams[fullTextSearchParam],
+		})
+	}
+	createdRange, err := parseCreatedRange(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	if createdRange != nil {
+		searchParameters = append(searchParameters, *createdRange)
+	}
+	wordCountRange, err := parseWordCountRange(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	if wordCountRange != nil {
+		searchParameters = append(searchParameters, *wordCountRange)
+	}
+	searchOptions, err := buildSearchOptions(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	searchOptions.Limit, searchOptions.Offset, err = parsePaginationParams(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	searchOptions.FieldWeights = config.SearchWeights
+	searchOptions.Language = queryLanguage
+	searchOptions.Required = visibleArticlesFilters(ctx)
+	fuzziness, err := parseFuzziness(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	exactPhrase, err := parseMatchMode(providedParams)
+	if err != nil {
+		return nil, db.SearchOptions{}, err
+	}
+	for i := range searchParameters {
+		if searchParameters[i].Type != db.ArrayType {
+			searchParameters[i].Fuzziness = fuzziness
+			searchParameters[i].ExactPhrase = exactPhrase
+		}
+	}
+
+	if providedParams.Has("highlight") {
+		highlight, err := strconv.ParseBool(providedParams.Get("highlight"))
+		if err != nil {
+			return nil, db.SearchOptions{}, errors.New("highlight must be a boolean")
+		}
+		if highlight {
+			searchOptions.Highlight = &db.HighlightOptions{
+				Fields:          fullTextSearchFields,
+				SummarizeFields: []string{"content"},
+				OpenTag:         "<mark>",
+				CloseTag:        "</mark>",
+			}
+		}
+	}
+
+	return searchParameters, searchOptions, nil
+}
+
+// getArticlesStats computes statistics over all the articles and returns them as an ArticlesStats JSON response.
+// The statistics are computed by the database using FT.AGGREGATE, through db.Aggregate and db.Facets,
+// so that articles never have to be loaded into the service.
+func getArticlesStats(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	stats := ArticlesStats{Authors: []FacetCount{}, Tags: []FacetCount{}}
+	genericDbErrorMsg := "Database Error while computing articles statistics"
+
+	// Total number of articles and average content length
+	rows, err := db.Aggregate(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), nil, db.SearchOptions{Required: visibleArticlesFilters(ctx)},
+		"LOAD", 1, "@content",
+		"APPLY", "strlen(@content)", "AS", "contentLength",
+		"GROUPBY", 0,
+		"REDUCE", "COUNT", 0, "AS", "total",
+		"REDUCE", "AVG", 1, "@contentLength", "AS", "averageContentLength",
+	)
+	if err != nil {
+		handleError(w, genericDbErrorMsg, err, http.StatusInternalServerError)
+		return
+	}
+	if len(rows) > 0 {
+		// An empty index has no total and an undefined average, both are left to 0
+		stats.Total, _ = strconv.ParseInt(rows[0]["total"], 10, 64)
+		if average, err := strconv.ParseFloat(rows[0]["averageContentLength"], 64); err == nil && !math.IsNaN(average) {
+			stats.AverageContentLength = average
+		}
+	}
+
+	// Number of articles per author and per tag
+	facetCounts, err := db.Facets(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), nil, db.SearchOptions{Required: visibleArticlesFilters(ctx)}, []string{"author", "tags"}, maxStatsValues)
+	if err != nil {
+		handleError(w, genericDbErrorMsg, err, http.StatusInternalServerError)
+		return
+	}
+	for _, count := range facetCounts["author"] {
+		stats.Authors = append(stats.Authors, FacetCount(count))
+	}
+	for _, count := range facetCounts["tags"] {
+		stats.Tags = append(stats.Tags, FacetCount(count))
+	}
+
+	responseJSON(w, stats, http.StatusOK)
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+// databaseOnce initializes the Database, the search index and the validations once for the tests needing them.
+var databaseOnce sync.Once
+
+// databaseErr is the error the initialization of the Database failed with, see requireDatabase.
+var databaseErr error
+
+// requireDatabase initializes the Database client and the search index of the default tenant, from AS_DBSERVER and
+// AS_DBPORT, skipping the test when they are not set: the Database must be a Redis Stack server.
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if os.Getenv("AS_DBSERVER") == "" {
+		t.Skip("AS_DBSERVER and AS_DBPORT must be set to a Redis Stack server")
+	}
+	databaseOnce.Do(func() {
+		if databaseErr = registerValidations(); databaseErr != nil {
+			return
+		}
+		if databaseErr = initializeDatabase(); databaseErr != nil {
+			return
+		}
+		if databaseErr = initializeEmbedder(); databaseErr != nil {
+			return
+		}
+		databaseErr = initializeSearchIndex(ctx)
+	})
+	if databaseErr != nil {
+		t.Fatalf("unable to initialize the Database: %v", databaseErr)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"net/http"
+	"strings"
+)
+
+const (
+	// htmlContentFormat and markdownContentFormat are the formats of the content of an article, see Article.ContentFormat.
+	htmlContentFormat     = "html"
+	markdownContentFormat = "markdown"
+)
+
+// markdownRenderer renders the Markdown content of the articles to HTML, following CommonMark along with the GitHub
+// Flavored Markdown extensions (tables, strikethrough, autolinks and task lists).
+// The raw HTML of the Markdown is rendered as well, having been sanitized when the article was written (see
+// sanitizeMarkdown), the rendered HTML being sanitized afterwards.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// sanitizeMarkdown returns a Markdown content whose raw HTML, the HTML blocks and the inline tags, is sanitized by
+// sanitizeHTML, the rest of the Markdown being kept as is: the markup within code spans and code blocks is text. It is
+// sanitized again until it no longer changes, so that removed tags can't join the surrounding text into new markup.
+func sanitizeMarkdown(content string) string {
+	for {
+		source := []byte(content)
+		var ranges [][2]int
+		addSegments := func(segments *text.Segments) {
+			for _, segment := range segments.Sliced(0, segments.Len()) {
+				// The consecutive lines of a block are sanitized together, their tags spanning several lines
+				if n := len(ranges); n > 0 && ranges[n-1][1] == segment.Start {
+					ranges[n-1][1] = segment.Stop
+				} else {
+					ranges = append(ranges, [2]int{segment.Start, segment.Stop})
+				}
+			}
+		}
+		document := markdownRenderer.Parser().Parse(text.NewReader(source))
+		_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch node := node.(type) {
+			case *ast.RawHTML:
+				addSegments(node.Segments)
+			case *ast.HTMLBlock:
+				addSegments(node.Lines())
+				if node.HasClosure() {
+					closure := text.NewSegments()
+					closure.Append(node.ClosureLine)
+					addSegments(closure)
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+
+		var sanitized strings.Builder
+		last := 0
+		for _, raw := range ranges {
+			sanitized.Write(source[last:raw[0]])
+			sanitized.WriteString(sanitizeHTML(string(source[raw[0]:raw[1]])))
+			last = raw[1]
+		}
+		sanitized.Write(source[last:])
+		if sanitized.String() == content {
+			return content
+		}
+		content = sanitized.String()
+	}
+}
+
+// renderArticleContent returns the content of an article as sanitized HTML (see sanitizeHTML), the Markdown content
+// being rendered first.
+func renderArticleContent(article Article) (string, error) {
+	if article.ContentFormat != markdownContentFormat {
+		return sanitizeHTML(article.Content), nil
+	}
+	var rendered bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(article.Content), &rendered); err != nil {
+		return "", fmt.Errorf("unable to render the Markdown content: %v", err)
+	}
+	return sanitizeHTML(rendered.String()), nil
+}
+
+// getRenderedArticle handles GET /article/{id}/rendered, responding with the content of an article as sanitized HTML,
+// so that it can be displayed as is.
+func getRenderedArticle(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	article, err := getStoredArticle(ctx, tenantKey(ctx, keysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if article == nil || isHiddenDraft(ctx, *article) {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+
+	rendered, err := renderArticleContent(*article)
+	if err != nil {
+		handleError(w, "Failed to render article", err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(rendered)); err != nil {
+		handleError(w, "Unable to write the rendered article", err, http.StatusInternalServerError)
+	}
+}
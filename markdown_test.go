@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"no raw HTML", "# Title\n\nSome *text*.\n", "# Title\n\nSome *text*.\n"},
+		{"script block", "Intro\n\n<script>\nalert(1)\n</script>\n\nOutro\n", "Intro\n\n\n\nOutro\n"},
+		{"inline event handler", "An <img src=\"a.png\" onerror=\"alert(1)\"> image\n", "An <img src=\"a.png\"> image\n"},
+		{"inline script", "Text <script>alert(1)</script> text\n", "Text alert(1) text\n"},
+		{"javascript link", "<a href=\"javascript:alert(1)\">link</a>\n", "<a>link</a>\n"},
+		{"allowed markup", "<p><strong>bold</strong></p>\n", "<p><strong>bold</strong></p>\n"},
+		{"code span", "Use `<script>` tags\n", "Use `<script>` tags\n"},
+		{"fenced code", "```html\n<script>alert(1)</script>\n```\n", "```html\n<script>alert(1)</script>\n```\n"},
+		{"joined markup", "<<script></script>img src=x onerror=alert(1)>\n", "<img src=\"x\">\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if sanitized := sanitizeMarkdown(test.content); sanitized != test.expected {
+				t.Errorf("sanitizeMarkdown(%q) = %q, expected %q", test.content, sanitized, test.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeContentMarkdown(t *testing.T) {
+	article := Article{ContentFormat: markdownContentFormat, Content: "# Title\n\n<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>\n"}
+	sanitized := sanitizeContent(article)
+	if strings.Contains(sanitized, "<script") || strings.Contains(sanitized, "onerror") {
+		t.Errorf("sanitizeContent(%q) = %q, expected no script", article.Content, sanitized)
+	}
+	if !strings.HasPrefix(sanitized, "# Title\n") {
+		t.Errorf("sanitizeContent(%q) = %q, expected the Markdown to be kept", article.Content, sanitized)
+	}
+}
+
+func TestCreateMarkdownArticleSanitized(t *testing.T) {
+	requireDatabase(t)
+	id := uuid.New().String()
+	body, _ := json.Marshal(Article{
+		Id: id, Title: "Markdown", ContentFormat: markdownContentFormat,
+		Content: "# Title\n\n<script>alert(1)</script>\n\nAn <img src=\"a.png\" onerror=\"alert(1)\"> image\n",
+	})
+	w := httptest.NewRecorder()
+	createArticle(w, httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /articles responded with %d: %s", w.Code, w.Body)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Del(ctx, databaseClient, keysPrefix+id)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/article/"+id, nil)
+	r.SetPathValue("id", id)
+	w = httptest.NewRecorder()
+	getArticleByID(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /article/%s responded with %d: %s", id, w.Code, w.Body)
+	}
+	var article Article
+	if err := json.Unmarshal(w.Body.Bytes(), &article); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(article.Content, "<script") || strings.Contains(article.Content, "onerror") {
+		t.Errorf("the stored content %q holds a script", article.Content)
+	}
+	if !strings.Contains(article.Content, "<img src=\"a.png\">") {
+		t.Errorf("the stored content %q lost the allowed markup", article.Content)
+	}
+}
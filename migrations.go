@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// migrationsKey is the key of the hash recording the migrations applied to the Database.
+const migrationsKey = "migrations:articles"
+
+// migrations lists the data and index migrations of the service, new migrations must be appended with a higher version.
+// A migration must be safe to run again, as a failure midway leaves it unrecorded.
+var migrations = []db.Migration{
+	{
+		Version:     1,
+		Description: "Compute the embeddings and the title suggestions of the articles stored before they were introduced",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			keys, err := db.GetAllKeys(ctx, redisClient, keysPrefix)
+			if err != nil {
+				return err
+			}
+			return refreshArticles(ctx, keys, func(int) {})
+		},
+	},
+	{
+		Version:     2,
+		Description: "Index the articles timestamps and set them to the migration time on the articles stored without them",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			if _, err := addMissingIndexFields(ctx); err != nil {
+				return err
+			}
+			keys, err := db.GetAllKeys(ctx, redisClient, keysPrefix)
+			if err != nil {
+				return err
+			}
+			now := strconv.FormatInt(time.Now().Unix(), 10)
+			for start := 0; start < len(keys); start += reindexBatchSize {
+				articles, err := fetchArticles(ctx, keys[start:min(start+reindexBatchSize, len(keys))])
+				if err != nil {
+					return err
+				}
+				var setArgs []db.JSONSetArgs
+				for _, article := range articles {
+					if article.CreatedAt != 0 {
+						continue
+					}
+					key := keysPrefix + article.Id
+					setArgs = append(setArgs,
+						db.JSONSetArgs{Key: key, Path: "$.createdAt", Value: now},
+						db.JSONSetArgs{Key: key, Path: "$.updatedAt", Value: now},
+					)
+				}
+				if len(setArgs) == 0 {
+					continue
+				}
+				if _, err := db.JSONMSetArgs(ctx, redisClient, setArgs); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "Index the articles expiration time",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			_, err := addMissingIndexFields(ctx)
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "Index the articles publication status",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			_, err := addMissingIndexFields(ctx)
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "Set the version of the articles stored without one to 1",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			keys, err := db.GetAllKeys(ctx, redisClient, keysPrefix)
+			if err != nil {
+				return err
+			}
+			for start := 0; start < len(keys); start += reindexBatchSize {
+				articles, err := fetchArticles(ctx, keys[start:min(start+reindexBatchSize, len(keys))])
+				if err != nil {
+					return err
+				}
+				var setArgs []db.JSONSetArgs
+				for _, article := range articles {
+					if article.Version == 0 {
+						setArgs = append(setArgs, db.JSONSetArgs{Key: keysPrefix + article.Id, Path: versionPath, Value: "1"})
+					}
+				}
+				if len(setArgs) == 0 {
+					continue
+				}
+				if _, err := db.JSONMSetArgs(ctx, redisClient, setArgs); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     6,
+		Description: "Index the articles word count and category",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			_, err := addMissingIndexFields(ctx)
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "Index the articles owner",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			_, err := addMissingIndexFields(ctx)
+			return err
+		},
+	},
+	{
+		Version:     8,
+		Description: "Set the word count and reading time of the articles stored without them",
+		Apply: func(ctx context.Context, redisClient *redis.Client) error {
+			keys, err := db.GetAllKeys(ctx, redisClient, keysPrefix)
+			if err != nil {
+				return err
+			}
+			for start := 0; start < len(keys); start += reindexBatchSize {
+				articles, err := fetchArticles(ctx, keys[start:min(start+reindexBatchSize, len(keys))])
+				if err != nil {
+					return err
+				}
+				var setArgs []db.JSONSetArgs
+				for _, article := range articles {
+					wordCount, readingTimeMinutes := articleReadingStats(article)
+					if wordCount == article.WordCount && readingTimeMinutes == article.ReadingTimeMinutes {
+						continue
+					}
+					key := keysPrefix + article.Id
+					setArgs = append(setArgs,
+						db.JSONSetArgs{Key: key, Path: "$.wordCount", Value: strconv.Itoa(wordCount)},
+						db.JSONSetArgs{Key: key, Path: "$.readingTimeMinutes", Value: strconv.Itoa(readingTimeMinutes)},
+					)
+				}
+				if len(setArgs) == 0 {
+					continue
+				}
+				if _, err := db.JSONMSetArgs(ctx, redisClient, setArgs); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// runMigrations applies the migrations not yet applied to the Database, at startup.
+// The migrations apply to the data of the default tenant and namespace, the indexes of the other tenants and
+// namespaces being created with the current schema by their first request, and brought up to date through PATCH /admin/index (see alterIndex).
+func runMigrations() error {
+	applied, err := db.Migrate(ctx, databaseClient, migrationsKey, migrations)
+	for _, version := range applied {
+		slog.Info("Applied Database migration", "version", version)
+	}
+	return err
+}
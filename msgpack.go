@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// jsonToMsgpack converts a JSON document to MessagePack, integers being encoded as such and the other numbers as floats.
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(msgpackValue(value)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// msgpackValue returns a JSON value decoded with json.Decoder.UseNumber with its numbers converted to int64 or float64.
+func msgpackValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		number, _ := value.Float64()
+		return number
+	case map[string]any:
+		for name, member := range value {
+			value[name] = msgpackValue(member)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = msgpackValue(item)
+		}
+	}
+	return value
+}
+
+// msgpackToJSON converts a MessagePack document to JSON. Its maps must have string keys, binary data is converted
+// to a base64 string and timestamps to RFC 3339 strings.
+func msgpackToJSON(data []byte) ([]byte, error) {
+	var value any
+	if err := msgpack.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
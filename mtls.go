@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// The policies of the client certificates, see Config.TLSClientAuth.
+const (
+	clientAuthRequire  = "require"  // clientAuthRequire refuses the TLS connections of the clients without a valid certificate.
+	clientAuthOptional = "optional" // clientAuthOptional verifies the certificate of the clients sending one.
+)
+
+// clientCertificatesEnabled reports whether the clients can authenticate with a certificate, see
+// configureClientCertificates.
+func clientCertificatesEnabled() bool {
+	return config.TLSClientCAFile != ""
+}
+
+// configureClientCertificates makes tlsConfig verify the certificates of the clients against the CA bundle of
+// Config.TLSClientCAFile, requiring one from every client unless Config.TLSClientAuth is optional.
+func configureClientCertificates(tlsConfig *tls.Config) error {
+	bundle, err := os.ReadFile(config.TLSClientCAFile)
+	if err != nil {
+		return err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(bundle) {
+		return errors.New("the client CA bundle holds no PEM certificate")
+	}
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if config.TLSClientAuth == clientAuthOptional {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return nil
+}
+
+// clientCertificateSubject returns the identity of the verified certificate r was sent with, empty when there is none:
+// its first URI name (e.g. a SPIFFE ID such as spiffe://example.org/ns/default/sa/reporting), or its common name.
+func clientCertificateSubject(r *http.Request) string {
+	if !clientCertificatesEnabled() || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	certificate := r.TLS.VerifiedChains[0][0]
+	if len(certificate.URIs) > 0 {
+		return certificate.URIs[0].String()
+	}
+	return certificate.Subject.CommonName
+}
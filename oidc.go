@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// oidcDiscoveryPath is the path of the discovery document of an OpenID Connect provider, relative to its issuer URL.
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// oidcDiscovery represents the settings of the discovery document of an OpenID Connect provider used by the
+// authentication.
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`   // Issuer is the issuer (iss claim) of the tokens of the provider.
+	JWKSURL string `json:"jwks_uri"` // JWKSURL is the URL of the JWK Set publishing the keys signing the tokens.
+}
+
+// discoverOIDC fetches the discovery document of the OpenID Connect provider of Config.OIDCIssuerURL. The issuer of the
+// document must be the issuer URL, as required by OpenID Connect Discovery.
+func discoverOIDC(httpClient *http.Client) (oidcDiscovery, error) {
+	issuerURL := strings.TrimSuffix(config.OIDCIssuerURL, "/")
+	resp, err := httpClient.Get(issuerURL + oidcDiscoveryPath)
+	if err != nil {
+		return oidcDiscovery{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return oidcDiscovery{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return oidcDiscovery{}, fmt.Errorf("invalid discovery document: %v", err)
+	}
+	switch {
+	case strings.TrimSuffix(discovery.Issuer, "/") != issuerURL:
+		return oidcDiscovery{}, fmt.Errorf("the discovery document is issued by %s, not %s", discovery.Issuer, config.OIDCIssuerURL)
+	case discovery.JWKSURL == "":
+		return oidcDiscovery{}, fmt.Errorf("the discovery document has no jwks_uri")
+	}
+	return discovery, nil
+}
+
+// lookupClaim returns the claim of a token at path, a dotted path reading a nested claim, or nil when it is missing.
+func lookupClaim(claims map[string]any, path string) any {
+	var claim any = claims
+	for _, name := range strings.Split(path, ".") {
+		object, isObject := claim.(map[string]any)
+		if !isObject {
+			return nil
+		}
+		claim = object[name]
+	}
+	return claim
+}
+
+// claimTenant returns the tenant of a token from its Config.JWTTenantClaim claim, empty for the default tenant when
+// the claim is not configured or missing, or an error when it is not a valid tenant.
+func claimTenant(claims map[string]any) (string, error) {
+	if config.JWTTenantClaim == "" {
+		return "", nil
+	}
+	switch tenant := lookupClaim(claims, config.JWTTenantClaim).(type) {
+	case nil:
+		return "", nil
+	case string:
+		if tenant != "" && !tenantPattern.MatchString(tenant) {
+			return "", fmt.Errorf("the %s claim of the token is not a valid tenant", config.JWTTenantClaim)
+		}
+		return tenant, nil
+	default:
+		return "", fmt.Errorf("the %s claim of the token is not a string", config.JWTTenantClaim)
+	}
+}
+
+// groupRoles returns the roles of the subject of a token mapped from the groups of its Config.OIDCGroupsClaim claim by
+// Config.OIDCGroupRoles, sorted. The claim is either a string or an array of strings, the groups of other types being
+// left out.
+func groupRoles(claims map[string]any) []string {
+	if len(config.OIDCGroupRoles) == 0 {
+		return nil
+	}
+	var groups []any
+	switch value := lookupClaim(claims, config.OIDCGroupsClaim).(type) {
+	case string:
+		groups = []any{value}
+	case []any:
+		groups = value
+	}
+
+	var mappedRoles []string
+	for _, group := range groups {
+		if name, isString := group.(string); isString {
+			if role, found := config.OIDCGroupRoles[name]; found {
+				mappedRoles = append(mappedRoles, role)
+			}
+		}
+	}
+	slices.Sort(mappedRoles)
+	return slices.Compact(mappedRoles)
+}
@@ -0,0 +1,988 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document served at /openapi.json complies with.
+const openAPIVersion = "3.0.3"
+
+// openAPIOperation describes a route for the OpenAPI document. The request and response bodies are given as Go values
+// whose schema is generated by reflection, or directly as a schema (a map[string]any).
+type openAPIOperation struct {
+	pattern     string                  // pattern is the route as registered on the mux, e.g. GET /article/{id}.
+	operationId string                  // operationId is the unique name of the operation, the one of its handler.
+	tag         string                  // tag groups the related operations.
+	summary     string                  // summary describes what the operation does.
+	parameters  []openAPIParameter      // parameters lists the query and header parameters, the path parameters being deduced from pattern.
+	requestBody map[string]any          // requestBody holds the request body by media type.
+	responses   map[int]openAPIResponse // responses holds the responses by status code, the errors being described by Problem.
+}
+
+// openAPIParameter describes a query or header parameter of an operation.
+type openAPIParameter struct {
+	in          string         // in is where the parameter is, either query or header.
+	name        string         // name is the name of the parameter.
+	description string         // description explains the parameter.
+	schema      map[string]any // schema is the schema of the value of the parameter.
+	required    bool           // required reports whether the parameter must be provided.
+}
+
+// openAPIResponse describes a response of an operation.
+type openAPIResponse struct {
+	description string         // description explains when the response is sent.
+	content     map[string]any // content holds the response body by media type, nil when there is none.
+}
+
+// The schemas of the usual parameters.
+var (
+	openAPIString      = map[string]any{"type": "string"}
+	openAPIInteger     = map[string]any{"type": "integer"}
+	openAPIBoolean     = map[string]any{"type": "boolean"}
+	openAPIBinary      = map[string]any{"type": "string", "format": "binary"}
+	openAPIStringArray = map[string]any{"type": "array", "items": openAPIString}
+	openAPIObject      = map[string]any{"type": "object"}
+)
+
+// The parameters shared by several operations.
+var (
+	openAPILimitParam        = openAPIParameter{in: "query", name: "limit", description: fmt.Sprintf("Maximum number of items returned, %d by default and at most %d.", defaultPageLimit, maxPageLimit), schema: openAPIInteger}
+	openAPIOffsetParam       = openAPIParameter{in: "query", name: "offset", description: "Position of the first item returned, 0 by default.", schema: openAPIInteger}
+	openAPIFieldsParam       = openAPIParameter{in: "query", name: fieldsParam, description: "Comma separated list of the article fields returned, e.g. id,title.", schema: openAPIString}
+	openAPIIncludeParam      = openAPIParameter{in: "query", name: includeParam, description: "Set to content to return the content of the articles, left out of the summaries.", schema: openAPIString}
+	openAPIIfMatchHeader     = openAPIParameter{in: "header", name: "If-Match", description: "Version or ETag of the article being updated, instead of its version field.", schema: openAPIString}
+	openAPIIfNoneMatchHeader = openAPIParameter{in: "header", name: "If-None-Match", description: "ETag of the representation held by the client, answered with a 304 when unchanged.", schema: openAPIString}
+)
+
+// openAPIPathParams describes the path parameters of the routes, by name.
+var openAPIPathParams = map[string]string{
+	"id":        "ID of the resource.",
+	"name":      "Name of the author.",
+	"n":         "Number of the revision, starting at 1.",
+	"a":         "Number of the revision compared, or current.",
+	"b":         "Number of the revision compared to, or current.",
+	"user":      "ID of the user.",
+	"commentId": "ID of the comment.",
+}
+
+// The names of the security schemes, see withAuthentication.
+const (
+	openAPIBearerAuth = "bearerAuth" // openAPIBearerAuth is the security scheme of the bearer tokens.
+	openAPIAPIKeyAuth = "apiKeyAuth" // openAPIAPIKeyAuth is the security scheme of the API keys.
+)
+
+// openAPIPathParamPattern matches the path parameters of a route pattern.
+var openAPIPathParamPattern = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// The responses shared by several operations.
+var (
+	openAPIJobResponse      = openAPIResponse{description: "The job has been submitted, its progress is reported by GET /jobs/{id}.", content: jsonContent(Job{})}
+	openAPINotModified      = openAPIResponse{description: "The representation held by the client is current."}
+	openAPIBadRequest       = errorResponse("A parameter or the request body is invalid.")
+	openAPINotFound         = errorResponse("The resource does not exist.")
+	openAPIConflict         = errorResponse("The resource has been changed or already exists.")
+	openAPIForbidden        = errorResponse("The actor is not allowed to change the article.")
+	openAPIDeleted          = openAPIResponse{description: "The resource has been deleted.", content: jsonContent(CustomOutput{})}
+	openAPIVersionRequired  = errorResponse("The version being updated has not been provided.")
+	openAPIUnsupportedMedia = errorResponse("The media type of the request body is not supported.")
+)
+
+// openAPIOperations describes every route of the API, see setupHTTPServer.
+var openAPIOperations = []openAPIOperation{
+	{
+		pattern: "GET /articles", operationId: "getAllArticles", tag: "articles",
+		summary: "List the articles as summaries, by page or by cursor, or stream all of them as newline delimited JSON.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam,
+			{in: "query", name: "cursor", description: "Token returned as next_cursor, empty to start iterating in cursor mode.", schema: openAPIString},
+			openAPIFieldsParam, openAPIIncludeParam, openAPIIfNoneMatchHeader},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of articles.", content: map[string]any{
+				jsonMediaType:   map[string]any{"oneOf": []any{ArticlesPage{}, ArticlesCursorPage{}}},
+				ndjsonMediaType: Article{},
+			}},
+			http.StatusNotModified: openAPINotModified, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /article/{id}", operationId: "getArticleByID", tag: "articles",
+		summary:    "Get an article.",
+		parameters: []openAPIParameter{openAPIFieldsParam, openAPIIfNoneMatchHeader, {in: "header", name: "If-Modified-Since", description: "Answered with a 304 when the article has not been updated since.", schema: openAPIString}},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The article.", content: jsonContent(Article{})}, http.StatusNotModified: openAPINotModified,
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /articles", operationId: "createArticle", tag: "articles",
+		summary:     "Create an article or a list of articles, an ID being generated when none is provided.",
+		parameters:  []openAPIParameter{{in: "header", name: "Idempotency-Key", description: "Unique key of the request, so that its retries are answered with the same response.", schema: openAPIString}},
+		requestBody: jsonContent(map[string]any{"oneOf": []any{Article{}, []Article{}}}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The IDs of the created articles.", content: jsonContent([]struct {
+				Id string `json:"id"`
+			}{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusConflict: openAPIConflict,
+		},
+	},
+	{
+		pattern: "PUT /articles", operationId: "updateArticles", tag: "articles",
+		summary:     "Replace a list of articles at once, each of them holding the version it updates.",
+		requestBody: jsonContent([]Article{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The IDs of the updated articles.", content: jsonContent([]struct {
+				Id string `json:"id"`
+			}{})},
+			http.StatusBadRequest: {description: "Some articles are invalid, none has been updated.", content: jsonContent(ArticlesBulkOutput{})},
+			http.StatusNotFound:   {description: "Some articles do not exist, none has been updated.", content: jsonContent(ArticlesBulkOutput{})},
+			http.StatusConflict:   {description: "Some articles have been changed, none has been updated.", content: jsonContent(ArticlesBulkOutput{})},
+			http.StatusForbidden:  {description: "The actor is not allowed to change some articles, none has been updated.", content: jsonContent(ArticlesBulkOutput{})},
+		},
+	},
+	{
+		pattern: "PUT /article/{id}", operationId: "updateArticleByID", tag: "articles",
+		summary: "Replace an article, or create it when upsert is set.",
+		parameters: []openAPIParameter{{in: "query", name: "upsert", description: "Set to true to create the article when it does not exist.", schema: openAPIBoolean},
+			openAPIIfMatchHeader},
+		requestBody: jsonContent(Article{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The updated article.", content: jsonContent(Article{})}, http.StatusCreated: {description: "The created article.", content: jsonContent(Article{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound, http.StatusConflict: openAPIConflict,
+			http.StatusForbidden: openAPIForbidden, http.StatusPreconditionRequired: openAPIVersionRequired,
+		},
+	},
+	{
+		pattern: "PATCH /article/{id}", operationId: "patchArticleByID", tag: "articles",
+		summary:    "Patch an article with a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902).",
+		parameters: []openAPIParameter{openAPIIfMatchHeader},
+		requestBody: map[string]any{
+			"application/merge-patch+json": openAPIObject,
+			"application/json-patch+json":  []jsonPatchOperation{},
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The patched article.", content: jsonContent(Article{})}, http.StatusBadRequest: openAPIBadRequest,
+			http.StatusNotFound: openAPINotFound, http.StatusConflict: openAPIConflict, http.StatusUnsupportedMediaType: openAPIUnsupportedMedia,
+			http.StatusForbidden: openAPIForbidden, http.StatusPreconditionRequired: openAPIVersionRequired,
+		},
+	},
+	{
+		pattern: "DELETE /article/{id}", operationId: "deleteArticleByID", tag: "articles",
+		summary:   "Move an article to the trash.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound, http.StatusForbidden: openAPIForbidden},
+	},
+	{
+		pattern: "PUT /article/{id}/acl", operationId: "updateArticleACL", tag: "articles",
+		summary:     "Transfer the ownership of an article and set its editors, as its owner or an admin.",
+		requestBody: jsonContent(ArticleACL{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The article with its new ACL.", content: jsonContent(Article{})}, http.StatusBadRequest: openAPIBadRequest,
+			http.StatusNotFound: openAPINotFound, http.StatusConflict: openAPIConflict,
+			http.StatusForbidden: errorResponse("The actor is neither the owner of the article nor an admin."),
+		},
+	},
+	{
+		pattern: "GET /articles/trash", operationId: "getTrashedArticles", tag: "trash",
+		summary:    "List the deleted articles along with their deletion time.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of deleted articles.", content: jsonContent(ArticlesPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "DELETE /articles/trash", operationId: "emptyTrash", tag: "trash",
+		summary: "Submit a job permanently deleting all the deleted articles.",
+		responses: map[int]openAPIResponse{
+			http.StatusAccepted: openAPIJobResponse, http.StatusConflict: {description: "The trash is already being emptied.", content: jsonContent(Job{})},
+		},
+	},
+	{
+		pattern: "POST /article/{id}/restore", operationId: "restoreArticle", tag: "trash",
+		summary: "Move a deleted article out of the trash.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The restored article.", content: jsonContent(Article{})}, http.StatusNotFound: openAPINotFound, http.StatusConflict: openAPIConflict,
+		},
+	},
+	{
+		pattern: "DELETE /articles/trash/{id}", operationId: "purgeArticle", tag: "trash",
+		summary:   "Permanently delete a deleted article along with its revisions.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /article/{id}/rendered", operationId: "getRenderedArticle", tag: "articles",
+		summary: "Get the content of an article as sanitized HTML, a Markdown content being rendered.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK:       {description: "The rendered content.", content: map[string]any{"text/html": openAPIString}},
+			http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /article/{id}/view", operationId: "recordArticleView", tag: "articles",
+		summary: "Count a view of an article.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK:       {description: "The number of views of the article.", content: jsonContent(ArticleViews{})},
+			http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "GET /article/{id}/comments", operationId: "getArticleComments", tag: "comments",
+		summary:    "List the visible comments of an article, the oldest first.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "A page of comments.", content: jsonContent(CommentsPage{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /article/{id}/comments", operationId: "createComment", tag: "comments",
+		summary:     "Comment an article, the comment being pending until approved when the comments are moderated.",
+		requestBody: jsonContent(Comment{}),
+		responses: map[int]openAPIResponse{
+			http.StatusCreated:    {description: "The comment.", content: jsonContent(Comment{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /article/{id}/comments/{commentId}/flag", operationId: "flagComment", tag: "comments",
+		summary:   "Flag a comment of an article for moderation.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The comment.", content: jsonContent(Comment{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /article/{id}/related", operationId: "getRelatedArticles", tag: "articles",
+		summary:    "Get the articles related to an article.",
+		parameters: []openAPIParameter{openAPILimitParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "The related articles, the most relevant first.", content: jsonContent([]ArticleSearchHit{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "GET /article/{id}/revisions", operationId: "getArticleRevisions", tag: "revisions",
+		summary: "List the previous versions of an article, the oldest first.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The revisions.", content: jsonContent([]ArticleRevision{})}, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /article/{id}/revisions/{n}/restore", operationId: "restoreArticleRevision", tag: "revisions",
+		summary: "Roll an article back to one of its revisions.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The restored article.", content: jsonContent(Article{})}, http.StatusBadRequest: openAPIBadRequest,
+			http.StatusNotFound: openAPINotFound, http.StatusForbidden: openAPIForbidden,
+		},
+	},
+	{
+		pattern: "GET /article/{id}/revisions/{a}/diff/{b}", operationId: "diffArticleRevisions", tag: "revisions",
+		summary: "Compare two versions of an article.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The differences.", content: jsonContent(ArticleDiff{})}, http.StatusBadRequest: openAPIBadRequest,
+			http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "GET /articles/search", operationId: "searchArticles", tag: "search",
+		summary:    "Search the articles by full text and by field, each Article field being a query parameter too (e.g. author=john).",
+		parameters: openAPISearchParameters(),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of the matching articles.", content: jsonContent(ArticlesSearchPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /articles/stats", operationId: "getArticlesStats", tag: "search",
+		summary:   "Compute statistics over all the articles.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The statistics.", content: jsonContent(ArticlesStats{})}},
+	},
+	{
+		pattern: "GET /articles/export", operationId: "exportArticles", tag: "articles",
+		summary:    "Export all the articles as CSV.",
+		parameters: []openAPIParameter{{in: "query", name: "format", description: "Format of the export, only csv is supported.", schema: map[string]any{"type": "string", "enum": []string{"csv"}}}},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The articles.", content: map[string]any{csvMediaType: openAPIString}}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /articles/events", operationId: "streamArticleEvents", tag: "events",
+		summary:    "Stream the changes made to the articles as Server-Sent Events, each data being an ArticleEvent.",
+		parameters: []openAPIParameter{{in: "query", name: "types", description: "Comma separated list of the event types streamed, all of them by default.", schema: openAPIString}},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The stream of events.", content: map[string]any{"text/event-stream": ArticleEvent{}}}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /articles/subscribe", operationId: "subscribeArticles", tag: "events",
+		summary: "Upgrade to WebSocket and push the changes made to the articles with the subscribed tags or authors, as ArticleEvent messages. " +
+			"The subscription is changed by sending SubscriptionMessage messages, each answered with a SubscriptionStatus message.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "tag", description: "Tag initially subscribed to, can be repeated.", schema: openAPIStringArray},
+			{in: "query", name: "author", description: "Author initially subscribed to, can be repeated.", schema: openAPIStringArray},
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusSwitchingProtocols: {description: "The connection is upgraded to WebSocket."}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "POST /articles/import", operationId: "importArticles", tag: "jobs",
+		summary:     "Submit a job creating the articles of a newline delimited JSON or CSV file.",
+		requestBody: map[string]any{ndjsonMediaType: Article{}, "text/csv": openAPIString},
+		responses: map[int]openAPIResponse{
+			http.StatusAccepted: openAPIJobResponse, http.StatusRequestEntityTooLarge: errorResponse("The file is too large."),
+			http.StatusUnsupportedMediaType: openAPIUnsupportedMedia,
+		},
+	},
+	{
+		pattern: "GET /articles/import/{id}", operationId: "getImportJob", tag: "jobs",
+		summary:   "Get an import job.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The job.", content: jsonContent(Job{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /articles/suggest", operationId: "suggestArticles", tag: "search",
+		summary: "Suggest article titles completing a prefix.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "prefix", description: "Beginning of the titles.", schema: openAPIString, required: true},
+			{in: "query", name: "max", description: "Maximum number of suggestions.", schema: openAPIInteger},
+			{in: "query", name: "fuzzy", description: "Set to true to tolerate a misspelled prefix.", schema: openAPIBoolean},
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The suggestions.", content: jsonContent([]TitleSuggestion{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /articles/similar", operationId: "similarArticles", tag: "search",
+		summary: "Get the articles semantically the closest to a text.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "text", description: "Text the articles are compared to.", schema: openAPIString, required: true}, openAPILimitParam,
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "The closest articles, the most similar first.", content: jsonContent([]ArticleSimilarHit{})},
+			http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /articles/popular", operationId: "getPopularArticles", tag: "search",
+		summary: "Get the most viewed articles during a window of time.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "window", description: "Number of days the views are counted over (e.g. 7d, the default), or all for the views since ever.", schema: openAPIString},
+			{in: "query", name: "limit", description: fmt.Sprintf("Maximum number of articles returned, %d by default and at most %d.", defaultPopularLimit, maxPageLimit), schema: openAPIInteger},
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "The most viewed articles, the most viewed first.", content: jsonContent([]PopularArticle{})},
+			http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /tags", operationId: "getAllTags", tag: "search",
+		summary:   "List the tags along with their number of articles.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The tags.", content: jsonContent([]TagCount{})}},
+	},
+	{
+		pattern: "GET /authors", operationId: "getAllAuthors", tag: "authors",
+		summary:   "List the authors along with their number of articles.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The authors.", content: jsonContent([]AuthorCount{})}},
+	},
+	{
+		pattern: "GET /author/{name}", operationId: "getAuthorProfile", tag: "authors",
+		summary: "Get the profile of an author.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The profile.", content: jsonContent(AuthorProfile{})}, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "PUT /author/{name}", operationId: "updateAuthorProfile", tag: "authors",
+		summary:     "Create or replace the profile of an author.",
+		requestBody: jsonContent(AuthorProfile{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The profile.", content: jsonContent(AuthorProfile{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "DELETE /author/{name}", operationId: "deleteAuthorProfile", tag: "authors",
+		summary:   "Delete the profile of an author, the articles being left untouched.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /author/{name}/articles", operationId: "getAuthorArticles", tag: "authors",
+		summary:    "List the articles of an author.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of articles.", content: jsonContent(ArticlesPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /categories", operationId: "getAllCategories", tag: "categories",
+		summary:   "Get the tree of the categories.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The top level categories along with their subcategories.", content: jsonContent([]CategoryNode{})}},
+	},
+	{
+		pattern: "GET /category/{id}", operationId: "getCategory", tag: "categories",
+		summary: "Get a category along with its subcategories.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The category.", content: jsonContent(CategoryNode{})}, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "PUT /category/{id}", operationId: "updateCategory", tag: "categories",
+		summary:     "Create or replace a category.",
+		requestBody: jsonContent(Category{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The category.", content: jsonContent(Category{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "DELETE /category/{id}", operationId: "deleteCategory", tag: "categories",
+		summary: "Delete a category without subcategories, its articles being left untouched.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound, http.StatusConflict: errorResponse("The category holds subcategories."),
+		},
+	},
+	{
+		pattern: "GET /category/{id}/articles", operationId: "getCategoryArticles", tag: "categories",
+		summary:    "List the articles of a category and of its descendants.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "A page of articles.", content: jsonContent(ArticlesPage{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "POST /collections", operationId: "createCollection", tag: "collections",
+		summary:     "Create an ordered collection of articles, such as a series, a unique ID being generated when none is provided.",
+		requestBody: jsonContent(Collection{}),
+		responses: map[int]openAPIResponse{
+			http.StatusCreated:    {description: "The collection.", content: jsonContent(Collection{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusConflict: openAPIConflict,
+		},
+	},
+	{
+		pattern: "GET /collection/{id}", operationId: "getCollection", tag: "collections",
+		summary: "Get a collection along with its articles, in order.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The collection.", content: jsonContent(CollectionArticles{})}, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "PUT /collection/{id}", operationId: "updateCollection", tag: "collections",
+		summary:     "Replace a collection.",
+		requestBody: jsonContent(Collection{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "The collection.", content: jsonContent(Collection{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "DELETE /collection/{id}", operationId: "deleteCollection", tag: "collections",
+		summary:   "Delete a collection, its articles being left untouched.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "PUT /users/{user}/likes/{id}", operationId: "likeArticle", tag: "users",
+		summary:   "Like an article.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The likes of the article.", content: jsonContent(ArticleLikes{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "DELETE /users/{user}/likes/{id}", operationId: "unlikeArticle", tag: "users",
+		summary:   "Stop liking an article.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The likes of the article.", content: jsonContent(ArticleLikes{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /users/{user}/bookmarks", operationId: "getUserBookmarks", tag: "users",
+		summary:    "List the articles bookmarked by a user, the most recently bookmarked first.",
+		parameters: []openAPIParameter{openAPILimitParam, openAPIOffsetParam},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of articles.", content: jsonContent(ArticlesPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "PUT /users/{user}/bookmarks/{id}", operationId: "bookmarkArticle", tag: "users",
+		summary:   "Bookmark an article.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The article has been bookmarked.", content: jsonContent(CustomOutput{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "DELETE /users/{user}/bookmarks/{id}", operationId: "unbookmarkArticle", tag: "users",
+		summary:   "Remove an article from the bookmarks of a user.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted},
+	},
+	{
+		pattern: "GET /jobs/{id}", operationId: "getJob", tag: "jobs",
+		summary:   "Get a background job and its progress.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The job.", content: jsonContent(Job{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /usage", operationId: "getUsage", tag: "apikeys",
+		summary: "Get the usage of the daily and monthly quotas of the API key of the request.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The usage of the quotas.", content: jsonContent(APIKeyUsage{})}, http.StatusUnauthorized: errorResponse("The request is not authenticated by an API key."),
+		},
+	},
+	{
+		pattern: "POST /graphql", operationId: "executeGraphQL", tag: "graphql",
+		summary:     "Execute a GraphQL query or mutation on the articles.",
+		requestBody: jsonContent(GraphQLRequest{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The result of the operation, along with its errors.", content: jsonContent(openAPIObject)}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /openapi.json", operationId: "getOpenAPIDocument", tag: "documentation",
+		summary:   "Get this OpenAPI document.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The OpenAPI document.", content: jsonContent(openAPIObject)}},
+	},
+	{
+		pattern: "GET /healthz", operationId: "getHealth", tag: "health",
+		summary: "Check that the Database answers and that the search index exists.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK:                 {description: "All the components are up.", content: jsonContent(Health{})},
+			http.StatusServiceUnavailable: {description: "A component is down.", content: jsonContent(Health{})},
+		},
+	},
+	{
+		pattern: "GET /livez", operationId: "getLiveness", tag: "health",
+		summary:   "Check that the process serves requests, without checking its dependencies.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The process is up.", content: jsonContent(Health{})}},
+	},
+	{
+		pattern: "GET /readyz", operationId: "getReadiness", tag: "health",
+		summary: "Check that the Database answers, that the search index exists and that the migrations have been applied.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK:                 {description: "The instance is ready to serve requests.", content: jsonContent(Health{})},
+			http.StatusServiceUnavailable: {description: "The instance can't serve requests.", content: jsonContent(Health{})},
+		},
+	},
+	{
+		pattern: "GET /admin/index", operationId: "getIndexInfo", tag: "admin",
+		summary:   "Get the information about the search index, as reported by FT.INFO.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The information.", content: jsonContent(openAPIObject)}},
+	},
+	{
+		pattern: "POST /admin/index", operationId: "recreateIndex", tag: "admin",
+		summary:   "Drop and create the search index again with the current schema.",
+		responses: map[int]openAPIResponse{http.StatusCreated: {description: "The index has been created.", content: jsonContent(CustomOutput{})}},
+	},
+	{
+		pattern: "PATCH /admin/index", operationId: "alterIndex", tag: "admin",
+		summary: "Add the fields of the current schema missing from the search index.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The fields added.", content: jsonContent(struct {
+			AddedFields []string `json:"addedFields"`
+		}{})}},
+	},
+	{
+		pattern: "DELETE /admin/index", operationId: "dropIndex", tag: "admin",
+		summary:   "Drop the search index, the articles being kept.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "POST /admin/reindex", operationId: "startReindex", tag: "jobs",
+		summary: "Submit a job rebuilding the search index from the stored articles.",
+		responses: map[int]openAPIResponse{
+			http.StatusAccepted: openAPIJobResponse, http.StatusConflict: {description: "A reindex is already running.", content: jsonContent(Job{})},
+		},
+	},
+	{
+		pattern: "GET /admin/reindex/{id}", operationId: "getReindexJob", tag: "jobs",
+		summary:   "Get a reindex job.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The job.", content: jsonContent(Job{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /admin/publications", operationId: "getScheduledPublications", tag: "admin",
+		summary:   "List the articles waiting for their publication, the next to be published first.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The scheduled publications.", content: jsonContent([]ScheduledPublication{})}},
+	},
+	{
+		pattern: "GET /admin/audit", operationId: "getAuditLog", tag: "admin",
+		summary: "List the changes made to the articles, the latest first, along with the article before and after each of them.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "articleId", description: "ID of the changed article.", schema: openAPIString},
+			{in: "query", name: "actor", description: "Actor of the changes.", schema: openAPIString},
+			{in: "query", name: "from", description: "Earliest time of the changes, as a Unix timestamp in seconds.", schema: openAPIInteger},
+			{in: "query", name: "to", description: "Latest time of the changes, as a Unix timestamp in seconds.", schema: openAPIInteger},
+			openAPILimitParam,
+			{in: "query", name: "cursor", description: "The next_cursor of the previous page.", schema: openAPIString},
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of the audit log.", content: jsonContent(AuditPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /admin/backup", operationId: "backupArticles", tag: "admin",
+		summary:   "Download a backup of the articles, their revisions and the index schema as a zip archive.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The backup.", content: map[string]any{"application/zip": openAPIBinary}}},
+	},
+	{
+		pattern: "POST /admin/restore", operationId: "restoreArticles", tag: "jobs",
+		summary:     "Submit a job restoring a backup.",
+		parameters:  []openAPIParameter{{in: "query", name: "wipe", description: "Set to true to delete the current articles beforehand.", schema: openAPIBoolean}},
+		requestBody: map[string]any{"application/zip": openAPIBinary},
+		responses: map[int]openAPIResponse{
+			http.StatusAccepted: openAPIJobResponse, http.StatusBadRequest: openAPIBadRequest, http.StatusUnsupportedMediaType: openAPIUnsupportedMedia,
+		},
+	},
+	{
+		pattern: "GET /admin/restore/{id}", operationId: "getRestoreJob", tag: "jobs",
+		summary:   "Get a restore job.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The job.", content: jsonContent(Job{})}, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /admin/webhooks", operationId: "getWebhooks", tag: "webhooks",
+		summary:   "List the webhooks, without their secret.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The webhooks.", content: jsonContent([]Webhook{})}},
+	},
+	{
+		pattern: "POST /admin/webhooks", operationId: "createWebhook", tag: "webhooks",
+		summary:     "Register a webhook notified of the article events, its secret signing the deliveries.",
+		requestBody: jsonContent(Webhook{}),
+		responses: map[int]openAPIResponse{
+			http.StatusCreated: {description: "The webhook, along with its secret.", content: jsonContent(Webhook{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "GET /admin/webhooks/{id}", operationId: "getWebhook", tag: "webhooks",
+		summary: "Get a webhook, without its secret.",
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "The webhook.", content: jsonContent(Webhook{})}, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "DELETE /admin/webhooks/{id}", operationId: "deleteWebhook", tag: "webhooks",
+		summary:   "Delete a webhook.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /admin/apikeys", operationId: "getAPIKeys", tag: "apikeys",
+		summary:   "List the API keys of the tenant, without their value.",
+		responses: map[int]openAPIResponse{http.StatusOK: {description: "The API keys.", content: jsonContent([]APIKey{})}},
+	},
+	{
+		pattern: "POST /admin/apikeys", operationId: "createAPIKey", tag: "apikeys",
+		summary:     "Create an API key of the tenant, allowed to call the routes of its scopes: read, write or admin.",
+		requestBody: jsonContent(APIKey{}),
+		responses: map[int]openAPIResponse{
+			http.StatusCreated: {description: "The API key, along with its value.", content: jsonContent(CreatedAPIKey{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "DELETE /admin/apikeys/{id}", operationId: "revokeAPIKey", tag: "apikeys",
+		summary:   "Revoke an API key of the tenant.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+	{
+		pattern: "GET /admin/comments", operationId: "getModeratedComments", tag: "comments",
+		summary: "List the comments to moderate, the most flagged first.",
+		parameters: []openAPIParameter{
+			{in: "query", name: "status", description: "Moderation status of the comments: visible, pending or hidden.", schema: openAPIString},
+			{in: "query", name: "flagged", description: "Set to true for the flagged comments only, false for the others.", schema: openAPIBoolean},
+			openAPILimitParam, openAPIOffsetParam,
+		},
+		responses: map[int]openAPIResponse{
+			http.StatusOK: {description: "A page of comments.", content: jsonContent(CommentsPage{})}, http.StatusBadRequest: openAPIBadRequest,
+		},
+	},
+	{
+		pattern: "PATCH /admin/comments/{commentId}", operationId: "moderateComment", tag: "comments",
+		summary:     "Set the moderation status of a comment.",
+		requestBody: jsonContent(CommentModeration{}),
+		responses: map[int]openAPIResponse{
+			http.StatusOK:         {description: "The comment.", content: jsonContent(Comment{})},
+			http.StatusBadRequest: openAPIBadRequest, http.StatusNotFound: openAPINotFound,
+		},
+	},
+	{
+		pattern: "DELETE /admin/comments/{commentId}", operationId: "deleteComment", tag: "comments",
+		summary:   "Delete a comment.",
+		responses: map[int]openAPIResponse{http.StatusOK: openAPIDeleted, http.StatusNotFound: openAPINotFound},
+	},
+}
+
+// openAPISearchParameters returns the parameters of GET /articles/search: the full text query, a parameter per
+// Article field, the creation range and the search options.
+func openAPISearchParameters() []openAPIParameter {
+	parameters := []openAPIParameter{{in: "query", name: fullTextSearchParam, description: "Full text query, matched against the text fields.", schema: openAPIString}}
+	for _, field := range structFieldsJsonTags(Article{}) {
+		parameters = append(parameters, openAPIParameter{in: "query", name: field, description: "Value searched in the " + field + " field.", schema: openAPIString})
+	}
+	parameters = append(parameters,
+		openAPIParameter{in: "query", name: "createdAfter", description: "Only the articles created after this time, an RFC 3339 timestamp, a date or a Unix timestamp.", schema: openAPIString},
+		openAPIParameter{in: "query", name: "createdBefore", description: "Only the articles created before this time, an RFC 3339 timestamp, a date or a Unix timestamp.", schema: openAPIString},
+		openAPIParameter{in: "query", name: "minWords", description: "Only the articles having at least this number of words.", schema: openAPIInteger},
+		openAPIParameter{in: "query", name: "maxWords", description: "Only the articles having at most this number of words.", schema: openAPIInteger},
+		openAPIParameter{in: "query", name: "sortBy", description: "Field the articles are sorted by, by relevance otherwise.", schema: map[string]any{"type": "string", "enum": sortableFields}},
+		openAPIParameter{in: "query", name: "order", description: "Sort order, along with sortBy.", schema: map[string]any{"type": "string", "enum": []string{"asc", "desc"}}},
+		openAPIParameter{in: "query", name: "fuzzy", description: fmt.Sprintf("Number of typos tolerated by the terms, from 0 to %d.", db.MaxFuzziness), schema: openAPIInteger},
+		openAPIParameter{in: "query", name: "match", description: "Whether the values are searched as terms or as exact phrases.", schema: map[string]any{"type": "string", "enum": []string{"terms", "phrase"}}},
+		openAPIParameter{in: "query", name: "operator", description: "Whether the articles must match all the parameters or any of them.", schema: map[string]any{"type": "string", "enum": []string{"and", "or"}}},
+		openAPIParameter{in: "query", name: "highlight", description: "Set to true to return the matched fragments of the text fields.", schema: openAPIBoolean},
+		openAPILimitParam, openAPIOffsetParam,
+		openAPIParameter{in: "query", name: "facets", description: "Comma separated list of the fields whose values are counted among the matching articles.", schema: openAPIString},
+		openAPIFieldsParam, openAPIIncludeParam,
+	)
+	return parameters
+}
+
+// jsonContent returns the content of a JSON body holding v.
+func jsonContent(v any) map[string]any {
+	return map[string]any{jsonMediaType: v}
+}
+
+// errorResponse returns an error response, described by Problem.
+func errorResponse(description string) openAPIResponse {
+	return openAPIResponse{description: description, content: map[string]any{problemMediaType: Problem{}}}
+}
+
+// openAPIDocument returns the OpenAPI document of the API, generated once from openAPIOperations.
+var openAPIDocument = sync.OnceValue(func() map[string]any {
+	schemas := openAPISchemas{}
+	paths := map[string]map[string]any{}
+	for _, operation := range openAPIOperations {
+		method, path, _ := strings.Cut(operation.pattern, " ")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = schemas.operation(path, operation)
+	}
+	components := map[string]any{"schemas": schemas}
+	securitySchemes := map[string]any{}
+	if authenticator != nil {
+		securitySchemes[openAPIBearerAuth] = map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+	}
+	if config.APIKeys {
+		securitySchemes[openAPIAPIKeyAuth] = map[string]any{"type": "apiKey", "in": "header", "name": apiKeyHeader}
+	}
+	if len(securitySchemes) > 0 {
+		components["securitySchemes"] = securitySchemes
+	}
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "Articles Search API",
+			"description": "Store, search and follow the changes of articles, backed by RediSearch and RedisJSON.",
+			"version":     "1.0",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+})
+
+// getOpenAPIDocument returns the OpenAPI document describing every route of the API, see openAPIOperations.
+func getOpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	responseJSON(w, openAPIDocument(), http.StatusOK)
+}
+
+// swaggerUIPage is the page of the Swagger UI exploring the OpenAPI document, its assets being loaded from a CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Articles Search API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// getSwaggerUI serves the Swagger UI, mounted when config.SwaggerUI is set.
+func getSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(swaggerUIPage)); err != nil {
+		handleError(w, "Unable to write the Swagger UI", err, http.StatusInternalServerError)
+	}
+}
+
+// openAPISchemas holds the schemas of the named types used by the operations, by name, referenced as components.
+type openAPISchemas map[string]any
+
+// operation returns the OpenAPI operation object of an operation of the given path, documenting the tenant header
+// when config.MultiTenancy is enabled and the namespace header when config.Namespaces is set. The operations changing
+// anything and the /admin operations require a bearer token or an API key when the authentication is enabled, see
+// withAuthentication.
+func (schemas openAPISchemas) operation(path string, operation openAPIOperation) map[string]any {
+	var parameters []any
+	for _, match := range openAPIPathParamPattern.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]any{
+			"in": "path", "name": match[1], "description": openAPIPathParams[match[1]], "required": true, "schema": openAPIString,
+		})
+	}
+	for _, parameter := range operation.parameters {
+		parameters = append(parameters, map[string]any{
+			"in": parameter.in, "name": parameter.name, "description": parameter.description, "required": parameter.required, "schema": parameter.schema,
+		})
+	}
+	if config.MultiTenancy {
+		parameters = append(parameters, map[string]any{
+			"in": "header", "name": config.TenantHeader, "description": "Tenant of the request, the one of its principal or the default tenant when missing.", "required": false, "schema": openAPIString,
+		})
+	}
+	if len(config.Namespaces) > 0 {
+		parameters = append(parameters, map[string]any{
+			"in": "header", "name": config.NamespaceHeader, "description": "Namespace of the request, the default namespace when missing.", "required": false, "schema": map[string]any{"type": "string", "enum": config.Namespaces},
+		})
+	}
+
+	responses := map[string]any{"default": schemas.response(errorResponse("An unexpected error occurred."))}
+	for statusCode, response := range operation.responses {
+		responses[fmt.Sprint(statusCode)] = schemas.response(response)
+	}
+	if config.RateLimit > 0 || config.APIKeys {
+		responses[fmt.Sprint(http.StatusTooManyRequests)] = schemas.response(errorResponse("The rate limit or a quota of the client is exceeded, the request can be retried after the Retry-After header."))
+	}
+	object := map[string]any{
+		"operationId": operation.operationId,
+		"summary":     operation.summary,
+		"tags":        []string{operation.tag},
+		"responses":   responses,
+	}
+	if method, _, _ := strings.Cut(operation.pattern, " "); authenticationEnabled() && (method != http.MethodGet || strings.HasPrefix(path, "/admin/")) {
+		var security []any
+		if authenticator != nil {
+			security = append(security, map[string]any{openAPIBearerAuth: []string{}})
+		}
+		if config.APIKeys {
+			security = append(security, map[string]any{openAPIAPIKeyAuth: []string{}})
+		}
+		object["security"] = security
+		responses[fmt.Sprint(http.StatusUnauthorized)] = schemas.response(errorResponse("The bearer token or the API key is missing or invalid."))
+		if _, found := responses[fmt.Sprint(http.StatusForbidden)]; !found && (config.RBAC || config.APIKeys) {
+			responses[fmt.Sprint(http.StatusForbidden)] = schemas.response(errorResponse("The actor lacks the role, or the API key the scope, of the operation."))
+		}
+	}
+	if len(parameters) > 0 {
+		object["parameters"] = parameters
+	}
+	if operation.requestBody != nil {
+		object["requestBody"] = map[string]any{"required": true, "content": schemas.content(operation.requestBody)}
+	}
+	return object
+}
+
+// response returns the OpenAPI response object of a response.
+func (schemas openAPISchemas) response(response openAPIResponse) map[string]any {
+	object := map[string]any{"description": response.description}
+	if response.content != nil {
+		object["content"] = schemas.content(response.content)
+	}
+	return object
+}
+
+// content returns the OpenAPI content of a body, by media type.
+func (schemas openAPISchemas) content(bodies map[string]any) map[string]any {
+	content := map[string]any{}
+	for mediaType, body := range bodies {
+		content[mediaType] = map[string]any{"schema": schemas.schemaOfValue(body)}
+	}
+	return content
+}
+
+// schemaOfValue returns the schema of a body, either given as a schema, a schema composed of Go values (oneOf)
+// or a Go value.
+func (schemas openAPISchemas) schemaOfValue(body any) any {
+	schema, isSchema := body.(map[string]any)
+	if !isSchema {
+		return schemas.schemaOf(reflect.TypeOf(body))
+	}
+	alternatives, isOneOf := schema["oneOf"].([]any)
+	if !isOneOf {
+		return schema
+	}
+	oneOf := make([]any, len(alternatives))
+	for i, alternative := range alternatives {
+		oneOf[i] = schemas.schemaOfValue(alternative)
+	}
+	return map[string]any{"oneOf": oneOf}
+}
+
+// schemaOf returns the schema of the JSON encoding of a Go type. The named structs are added to the schemas
+// and referenced.
+func (schemas openAPISchemas) schemaOf(t reflect.Type) map[string]any {
+	switch {
+	case t == reflect.TypeOf(json.RawMessage{}):
+		return map[string]any{}
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemas.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemas.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemas.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return schemas.structSchema(t)
+		}
+		if _, found := schemas[t.Name()]; !found {
+			// The name is taken before the fields are described, in case the struct refers to itself
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = schemas.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema returns the schema of a struct, the fields of its embedded structs being its own like with
+// encoding/json. The fields validated as required are required, and the ones validated with oneof are enumerations.
+func (schemas openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				addFields(field.Type)
+				continue
+			}
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			schema := schemas.schemaOf(field.Type)
+			for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+				if rule == "required" {
+					required = append(required, name)
+				}
+				if values, isOneOf := strings.CutPrefix(rule, "oneof="); isOneOf {
+					if items, isArray := schema["items"].(map[string]any); isArray {
+						items["enum"] = strings.Fields(values)
+					} else {
+						schema["enum"] = strings.Fields(values)
+					}
+				}
+			}
+			properties[name] = schema
+		}
+	}
+	addFields(t)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// The errors of the article operations shared by the GraphQL and gRPC APIs, wrapped along with the details.
+var (
+	errInvalidArticleRequest = errors.New("invalid request")
+	errArticleNotFound       = errors.New("article not found")
+	errArticleExists         = errors.New("article already exists")
+	errArticleVersion        = errors.New("version conflict")
+)
+
+// listArticles returns a page of the articles of the tenant ctx is scoped to sorted by ID, like GET /articles, the page
+// being controlled by the limit and offset query parameters (see parsePaginationParams).
+func listArticles(ctx context.Context, queryParams url.Values) (ArticlesPage, error) {
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		return ArticlesPage{}, fmt.Errorf("%w: %v", errInvalidArticleRequest, err)
+	}
+	page := ArticlesPage{Articles: []Article{}, Limit: limit, Offset: offset}
+	keys, err := db.GetAllKeys(ctx, databaseClient, tenantKey(ctx, keysPrefix))
+	if err != nil {
+		return page, fmt.Errorf("unable to retrieve article keys: %v", err)
+	}
+	page.Total = len(keys)
+	if offset < len(keys) {
+		slices.Sort(keys)
+		if page.Articles, err = fetchArticles(ctx, keys[offset:min(offset+limit, len(keys))]); err != nil {
+			return page, fmt.Errorf("unable to retrieve articles: %v", err)
+		}
+	}
+	return page, nil
+}
+
+// searchArticlesPage returns a page of the articles matching the search described by the query parameters of
+// GET /articles/search (see buildArticlesSearch), along with their relevance score.
+func searchArticlesPage(ctx context.Context, providedParams url.Values) (ArticlesSearchPage, error) {
+	expectedParams := append(structFieldsJsonTags(Article{}), fullTextSearchParam)
+	if err := isQueryParamsExpected(providedParams, slices.Concat(expectedParams, createdRangeParams, wordCountRangeParams, searchOptionsParams)); err != nil {
+		return ArticlesSearchPage{}, fmt.Errorf("%w: %v", errInvalidArticleRequest, err)
+	}
+	searchParameters, searchOptions, err := buildArticlesSearch(ctx, providedParams)
+	if err != nil {
+		return ArticlesSearchPage{}, fmt.Errorf("%w: %v", errInvalidArticleRequest, err)
+	}
+	searchOptions.WithScores = true
+	searchResult, err := db.Search[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions)
+	if err != nil {
+		return ArticlesSearchPage{}, fmt.Errorf("unable to search articles: %v", err)
+	}
+
+	page := ArticlesSearchPage{
+		Articles: make([]ArticleSearchHit, len(searchResult.Hits)),
+		Total:    searchResult.Total,
+		Limit:    searchOptions.Limit,
+		Offset:   searchOptions.Offset,
+	}
+	for i, searchHit := range searchResult.Hits {
+		page.Articles[i] = ArticleSearchHit{Article: searchHit.Item, Score: searchHit.Score, Highlights: searchHit.Highlights}
+	}
+	return page, nil
+}
+
+// createSingleArticle creates an article on behalf of actor, like POST /articles, and returns it as stored.
+// A unique ID is generated when none is provided.
+func createSingleArticle(ctx context.Context, actor string, article Article) (Article, error) {
+	if article.Id == "" {
+		article.Id = uuid.New().String()
+	}
+	if err := validateStruct(article); err != nil {
+		return article, fmt.Errorf("%w: %v", errInvalidArticleRequest, err)
+	}
+	setServerManagedFields(&article, nil, actor)
+
+	// The article is only written when no article has the same ID
+	key := tenantKey(ctx, keysPrefix+article.Id)
+	if err := setArticleIfVersion(ctx, key, nil, article); err != nil {
+		if errors.Is(err, db.ErrVersionMismatch) {
+			return article, fmt.Errorf("%w: article with ID %s already exists", errArticleExists, article.Id)
+		}
+		return article, fmt.Errorf("unable to create article: %v", err)
+	}
+	articleChanged(ctx, actor, nil, &article)
+	return article, nil
+}
+
+// replaceArticle replaces an article on behalf of actor, like PUT /article/{id}, and returns it as stored.
+// The version of article must be the one of the stored article, so that concurrent updates are detected, and actor
+// must be allowed to change it (see canChangeArticle).
+func replaceArticle(ctx context.Context, actor string, article Article) (Article, error) {
+	if err := validateStruct(article); err != nil {
+		return article, fmt.Errorf("%w: %v", errInvalidArticleRequest, err)
+	}
+	key := tenantKey(ctx, keysPrefix+article.Id)
+	storedArticle, err := getStoredArticle(ctx, key)
+	if err != nil {
+		return article, fmt.Errorf("unable to retrieve article: %v", err)
+	}
+	if storedArticle == nil {
+		return article, fmt.Errorf("%w: no article found with ID %s", errArticleNotFound, article.Id)
+	}
+	if !canChangeArticle(ctx, actor, storedArticle) {
+		return article, fmt.Errorf("%w: %s is not allowed to change article with ID %s", errArticleForbidden, actor, article.Id)
+	}
+	if article.Version == 0 {
+		return article, fmt.Errorf("%w: %v", errInvalidArticleRequest, errVersionRequired)
+	}
+	if article.Version != storedArticle.Version {
+		return article, fmt.Errorf("%w: article with ID %s is at version %d, not %d", errArticleVersion, article.Id, storedArticle.Version, article.Version)
+	}
+	setServerManagedFields(&article, storedArticle, actor)
+
+	if err := setArticleIfVersion(ctx, key, storedArticle, article); err != nil {
+		if errors.Is(err, db.ErrVersionMismatch) || errors.Is(err, db.ErrNotFound) {
+			return article, fmt.Errorf("%w: the article has been changed concurrently", errArticleVersion)
+		}
+		return article, fmt.Errorf("unable to update article: %v", err)
+	}
+	articleChanged(ctx, actor, storedArticle, &article)
+	return article, nil
+}
+
+// trashArticle moves the article with the given ID to the trash on behalf of actor, like DELETE /article/{id}, actor
+// being allowed to change it (see canChangeArticle).
+func trashArticle(ctx context.Context, actor string, id string) error {
+	key := tenantKey(ctx, keysPrefix+id)
+	storedArticle, err := getStoredArticle(ctx, key)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve article: %v", err)
+	}
+	if storedArticle == nil {
+		return fmt.Errorf("%w: no article found with ID %s", errArticleNotFound, id)
+	}
+	if !canChangeArticle(ctx, actor, storedArticle) {
+		return fmt.Errorf("%w: %s is not allowed to delete article with ID %s", errArticleForbidden, actor, id)
+	}
+	if err := db.JSONSetAndRename(ctx, databaseClient, key, "$.deletedAt", time.Now().Unix(), tenantKey(ctx, trashKeysPrefix+id)); err != nil {
+		return fmt.Errorf("unable to delete article: %v", err)
+	}
+	articleChanged(ctx, actor, storedArticle, nil)
+	return nil
+}
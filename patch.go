@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mergePatch applies a JSON Merge Patch (RFC 7386) onto the given target document and returns the result.
+// Both target and patch are generic JSON values as produced by json.Unmarshal into an interface{}.
+// When the patch is an object, each of its members is merged recursively into the target, a null member
+// removing the corresponding member from the target. Any other patch value replaces the target entirely.
+func mergePatch(target any, patch any) any {
+	patchObject, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+
+	targetObject, isObject := target.(map[string]any)
+	if !isObject {
+		targetObject = map[string]any{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// jsonPatchOperation represents a single operation of a JSON Patch document (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`    // Op is the operation to perform, one of add, remove, replace or test.
+	Path  string          `json:"path"`  // Path is the JSON Pointer (RFC 6901) to the location the operation applies to.
+	Value json.RawMessage `json:"value"` // Value is the value used by add, replace and test operations.
+}
+
+// errPatchTestFailed is returned by applyJSONPatch when a test operation doesn't match the document.
+var errPatchTestFailed = errors.New("test operation failed")
+
+// applyJSONPatch applies the given JSON Patch operations (RFC 6902) in order onto the document and returns the result.
+// The document is a generic JSON value as produced by json.Unmarshal into an interface{}.
+// Only add, remove, replace and test operations are supported, the patch is applied atomically in the sense that
+// an error on any operation returns an error without any result.
+func applyJSONPatch(document any, operations []jsonPatchOperation) (any, error) {
+	for i, operation := range operations {
+		var value any
+		switch operation.Op {
+		case "add", "replace", "test":
+			if len(operation.Value) == 0 {
+				return nil, fmt.Errorf("operation %d (%s) is missing a value", i, operation.Op)
+			}
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d (%s) has an invalid value: %v", i, operation.Op, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d: %q is not a supported operation, use one of add, remove, replace or test", i, operation.Op)
+		}
+
+		tokens, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %v", i, operation.Op, err)
+		}
+
+		if len(tokens) == 0 {
+			// The operation targets the whole document
+			switch operation.Op {
+			case "add", "replace":
+				document = value
+			case "remove":
+				return nil, fmt.Errorf("operation %d (remove): the whole document can't be removed", i)
+			case "test":
+				if !reflect.DeepEqual(document, value) {
+					return nil, fmt.Errorf("operation %d: %w at path %q", i, errPatchTestFailed, operation.Path)
+				}
+			}
+			continue
+		}
+
+		document, err = patchAtPointer(document, tokens, operation.Op, value)
+		if err != nil {
+			if errors.Is(err, errPatchTestFailed) {
+				return nil, fmt.Errorf("operation %d: %w at path %q", i, err, operation.Path)
+			}
+			return nil, fmt.Errorf("operation %d (%s) at path %q: %v", i, operation.Op, operation.Path, err)
+		}
+	}
+	return document, nil
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+// The empty pointer, referencing the whole document, returns no token.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q is not a valid JSON pointer", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// parseArrayIndex converts a reference token into an index of an array of the given length.
+// When allowEnd is true, the "-" token and an index equal to the length are accepted to reference the end of the array.
+func parseArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not a valid array index", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("array index %s is out of range", token)
+	}
+	return index, nil
+}
+
+// patchAtPointer walks the node following the given reference tokens and applies the operation
+// on the container holding the last token. It returns the node, which is a new value when an array was modified.
+func patchAtPointer(node any, tokens []string, op string, value any) (any, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch container := node.(type) {
+	case map[string]any:
+		child, found := container[token]
+		if !last {
+			if !found {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			newChild, err := patchAtPointer(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			container[token] = newChild
+			return container, nil
+		}
+		if !found && op != "add" {
+			return nil, fmt.Errorf("member %q does not exist", token)
+		}
+		switch op {
+		case "add", "replace":
+			container[token] = value
+		case "remove":
+			delete(container, token)
+		case "test":
+			if !reflect.DeepEqual(child, value) {
+				return nil, errPatchTestFailed
+			}
+		}
+		return container, nil
+
+	case []any:
+		index, err := parseArrayIndex(token, len(container), last && op == "add")
+		if err != nil {
+			return nil, err
+		}
+		if !last {
+			newChild, err := patchAtPointer(container[index], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			container[index] = newChild
+			return container, nil
+		}
+		switch op {
+		case "add":
+			container = slices.Insert(container, index, value)
+		case "replace":
+			container[index] = value
+		case "remove":
+			container = slices.Delete(container, index, index+1)
+		case "test":
+			if !reflect.DeepEqual(container[index], value) {
+				return nil, errPatchTestFailed
+			}
+		}
+		return container, nil
+
+	default:
+		return nil, fmt.Errorf("%q can't be referenced on a value that is neither an object nor an array", token)
+	}
+}
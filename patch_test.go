@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// unmarshalJSON returns the generic JSON value of document, failing the test when it is not valid JSON.
+func unmarshalJSON(t *testing.T, document string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("invalid JSON %s: %v", document, err)
+	}
+	return value
+}
+
+// marshalJSON returns the JSON encoding of value, its object members being sorted.
+func marshalJSON(t *testing.T, value any) string {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("unable to encode %v: %v", value, err)
+	}
+	return string(encoded)
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7386, appendix A
+	tests := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.target+" "+test.patch, func(t *testing.T) {
+			merged := marshalJSON(t, mergePatch(unmarshalJSON(t, test.target), unmarshalJSON(t, test.patch)))
+			if merged != test.expected {
+				t.Errorf("mergePatch(%s, %s) = %s, expected %s", test.target, test.patch, merged, test.expected)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		document   string
+		operations string
+		expected   string // expected is the patched document, empty when the patch fails.
+		err        error  // err is the error the patch fails with, when it is not a malformed patch.
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, nil},
+		{"add nested member", `{"a":{"b":1}}`, `[{"op":"add","path":"/a/c","value":[1]}]`, `{"a":{"b":1,"c":[1]}}`, nil},
+		{"add array item", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`, nil},
+		{"append array item", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, nil},
+		{"replace member", `{"a":1}`, `[{"op":"replace","path":"/a","value":"b"}]`, `{"a":"b"}`, nil},
+		{"replace array item", `[1,2]`, `[{"op":"replace","path":"/0","value":3}]`, `[3,2]`, nil},
+		{"replace document", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`, nil},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, nil},
+		{"remove array item", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`, nil},
+		{"escaped pointer", `{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/c~0d","value":3}]`, `{"c~d":3}`, nil},
+		{"passing test", `{"version":2}`, `[{"op":"test","path":"/version","value":2},{"op":"add","path":"/a","value":1}]`, `{"a":1,"version":2}`, nil},
+		{"failing test", `{"version":2}`, `[{"op":"test","path":"/version","value":1},{"op":"add","path":"/a","value":1}]`, "", errPatchTestFailed},
+		{"failing test of document", `{"a":1}`, `[{"op":"test","path":"","value":{}}]`, "", errPatchTestFailed},
+		{"missing value", `{"a":1}`, `[{"op":"add","path":"/b"}]`, "", nil},
+		{"unsupported operation", `{"a":1}`, `[{"op":"move","path":"/b"}]`, "", nil},
+		{"invalid pointer", `{"a":1}`, `[{"op":"remove","path":"a"}]`, "", nil},
+		{"missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, "", nil},
+		{"missing parent", `{"a":1}`, `[{"op":"add","path":"/b/c","value":2}]`, "", nil},
+		{"removed document", `{"a":1}`, `[{"op":"remove","path":""}]`, "", nil},
+		{"index out of range", `[1]`, `[{"op":"replace","path":"/1","value":2}]`, "", nil},
+		{"index with leading zero", `[1,2]`, `[{"op":"remove","path":"/01"}]`, "", nil},
+		{"end of array removed", `[1]`, `[{"op":"remove","path":"/-"}]`, "", nil},
+		{"member of a scalar", `{"a":1}`, `[{"op":"add","path":"/a/b","value":2}]`, "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var operations []jsonPatchOperation
+			if err := json.Unmarshal([]byte(test.operations), &operations); err != nil {
+				t.Fatalf("invalid JSON Patch %s: %v", test.operations, err)
+			}
+			patched, err := applyJSONPatch(unmarshalJSON(t, test.document), operations)
+			if test.expected == "" {
+				if err == nil || (test.err != nil && !errors.Is(err, test.err)) {
+					t.Errorf("applyJSONPatch(%s, %s) = %s, %v, expected an error %v", test.document, test.operations, marshalJSON(t, patched), err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyJSONPatch(%s, %s) failed: %v", test.document, test.operations, err)
+			}
+			if result := marshalJSON(t, patched); result != test.expected {
+				t.Errorf("applyJSONPatch(%s, %s) = %s, expected %s", test.document, test.operations, result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+// Package articlespb holds the gRPC ArticleService and its messages, generated from proto/articles.proto.
+package articlespb
+
+//go:generate protoc --proto_path=../../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative articles.proto
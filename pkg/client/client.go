@@ -0,0 +1,329 @@
+// Package client provides ArticlesClient, a typed client of the articles search REST API for other Go services.
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Article is an article as exchanged with the API.
+type Article struct {
+	Id        string   `json:"id,omitempty"`        // Id is the unique identifier of the article, generated by the API on creation when empty.
+	Title     string   `json:"title"`               // Title is the title of the article, required.
+	Content   string   `json:"content"`             // Content is the content of the article.
+	Author    string   `json:"author"`              // Author is the author of the article.
+	Tags      []string `json:"tags"`                // Tags are the tags of the article.
+	Language  string   `json:"language,omitempty"`  // Language is the language of the article (e.g. french), English when empty.
+	CreatedAt int64    `json:"createdAt,omitempty"` // CreatedAt is the Unix time the article was created, set by the API.
+	UpdatedAt int64    `json:"updatedAt,omitempty"` // UpdatedAt is the Unix time the article was last written, set by the API.
+	Status    string   `json:"status,omitempty"`    // Status is either draft or published, published when empty.
+	PublishAt int64    `json:"publishAt,omitempty"` // PublishAt is the Unix time a draft article is published.
+	ExpiresAt int64    `json:"expiresAt,omitempty"` // ExpiresAt is the Unix time the article is deleted, never when empty.
+	Version   int64    `json:"version"`             // Version is incremented on every write, the version updated must be provided by Update.
+	// ContentFormat is the format of the content, either html or markdown, html when empty.
+	ContentFormat string `json:"contentFormat,omitempty"`
+	// WordCount is the number of words of the content, set by the API.
+	WordCount int `json:"wordCount,omitempty"`
+	// ReadingTimeMinutes is the time it takes to read the content in minutes, set by the API.
+	ReadingTimeMinutes int `json:"readingTimeMinutes,omitempty"`
+	// Likes is the number of users liking the article, set by the API.
+	Likes int64 `json:"likes,omitempty"`
+	// Category is the ID of the category of the article.
+	Category string `json:"category,omitempty"`
+	// Owner is the actor who created the article, set by the API. Only the owner, the editors and the admins can
+	// change the article.
+	Owner string `json:"owner,omitempty"`
+	// Editors lists the actors the owner allows to change the article, set by the API.
+	Editors []string `json:"editors,omitempty"`
+}
+
+// ArticlesPage is a page of articles returned by List.
+type ArticlesPage struct {
+	Articles []Article `json:"articles"` // Articles holds the articles of the page, as summaries without their content.
+	Total    int       `json:"total"`    // Total is the number of articles available across all pages.
+	Limit    int       `json:"limit"`    // Limit is the maximum number of articles returned in a page.
+	Offset   int       `json:"offset"`   // Offset is the position of the first article of the page.
+}
+
+// SearchQuery describes a search of articles, see Search. The zero values are left to the defaults of the API.
+type SearchQuery struct {
+	Q        string     // Q is the full text query, matched against the text fields.
+	Filters  url.Values // Filters holds the values searched by Article field (JSON name), e.g. author=john.
+	Operator string     // Operator is either and (the default) or or, whether the articles must match all the filters or any of them.
+	SortBy   string     // SortBy is the field the articles are sorted by, by relevance when empty.
+	Order    string     // Order is either asc or desc, along with SortBy.
+	Fuzzy    int        // Fuzzy is the number of typos tolerated by the terms.
+	Match    string     // Match is either terms or phrase, whether the values are searched as exact phrases.
+	MinWords int        // MinWords is the minimum number of words of the articles.
+	MaxWords int        // MaxWords is the maximum number of words of the articles.
+	Limit    int        // Limit is the maximum number of articles returned.
+	Offset   int        // Offset is the position of the first article returned.
+}
+
+// SearchHit is an article found by Search along with its relevance score.
+type SearchHit struct {
+	Article
+	Score float64 `json:"score"` // Score is the relevance score of the article for the search.
+}
+
+// SearchPage is a page of the articles found by Search.
+type SearchPage struct {
+	Articles []SearchHit `json:"articles"` // Articles holds the articles found for the page, as summaries without their content.
+	Total    int64       `json:"total"`    // Total is the number of articles matching the search across all pages.
+	Limit    int         `json:"limit"`    // Limit is the maximum number of articles returned in a page.
+	Offset   int         `json:"offset"`   // Offset is the position of the first article of the page.
+}
+
+// APIError is returned when the API responds with an error status code, read from the RFC 7807 problem details
+// (or from the legacy error format) of the response.
+type APIError struct {
+	StatusCode int    // StatusCode is the HTTP status code of the response.
+	Type       string // Type is the URI reference identifying the kind of problem.
+	Title      string // Title is the summary of the error.
+	Detail     string // Detail is the explanation of the error.
+	Instance   string // Instance is the URI reference of the request the error occurred on.
+	Code       string // Code is the machine-readable code of the error, e.g. ARTICLE_NOT_FOUND or VERSION_CONFLICT.
+	// Fields describes the fields which failed the validation, when the error is a failed validation.
+	Fields []FieldError
+}
+
+// FieldError describes a field of a request which failed the validation.
+type FieldError struct {
+	Field   string `json:"field"`   // Field is the path of the field in the request body, e.g. title.
+	Rule    string `json:"rule"`    // Rule is the validation rule the field failed, e.g. required.
+	Message string `json:"message"` // Message is the explanation of the failure.
+}
+
+// Error returns the status code and the details of the error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("articles API returned status %d: %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+// readAPIError returns the APIError of an error response.
+func readAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var problem struct {
+		Type          string       `json:"type"`
+		Title         string       `json:"title"`
+		Detail        string       `json:"detail"`
+		Instance      string       `json:"instance"`
+		Code          string       `json:"code"`
+		LegacyMessage string       `json:"Message"`
+		LegacyError   string       `json:"Error"`
+		LegacyCode    string       `json:"Code"`
+		Errors        []FieldError `json:"errors"`
+		LegacyFields  []FieldError `json:"Fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err == nil {
+		apiErr.Type, apiErr.Instance = problem.Type, problem.Instance
+		apiErr.Title = cmp.Or(problem.Title, problem.LegacyMessage)
+		apiErr.Detail = cmp.Or(problem.Detail, problem.LegacyError)
+		apiErr.Code = cmp.Or(problem.Code, problem.LegacyCode)
+		apiErr.Fields = append(problem.Errors, problem.LegacyFields...)
+	}
+	return apiErr
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource (HTTP 404).
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is an APIError for a resource that has been changed or already exists (HTTP 409).
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// ArticlesClient calls the articles search REST API. Its fields can be changed before it is used.
+type ArticlesClient struct {
+	BaseURL    string        // BaseURL is the URL the API is served at, e.g. http://articles:8080.
+	HTTPClient *http.Client  // HTTPClient sends the requests.
+	Actor      string        // Deprecated: Actor is ignored, the changes being made on behalf of the authenticated subject.
+	Tenant     string        // Tenant names the tenant of the requests, sent as X-Tenant-ID header when not empty.
+	Namespace  string        // Namespace names the namespace of the requests, sent as X-Namespace header when not empty.
+	Token      string        // Token is the bearer token (a JWT) authenticating the requests, sent as Authorization header when not empty.
+	APIKey     string        // APIKey is the API key authenticating the requests, sent as X-API-Key header when not empty.
+	MaxRetries int           // MaxRetries is the number of times a request is retried after a network error or an HTTP 429, 502, 503 or 504.
+	RetryDelay time.Duration // RetryDelay is the delay before the first retry, doubled after each retry unless the API sends a Retry-After.
+}
+
+// NewArticlesClient creates a new ArticlesClient calling the API served at baseURL, with 3 retries starting after
+// 500ms. http.DefaultClient sends the requests when httpClient is nil.
+func NewArticlesClient(baseURL string, httpClient *http.Client) *ArticlesClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &ArticlesClient{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: httpClient, MaxRetries: 3, RetryDelay: 500 * time.Millisecond}
+}
+
+// Get returns the article with the given ID, an APIError satisfying IsNotFound when it does not exist.
+func (c *ArticlesClient) Get(ctx context.Context, id string) (Article, error) {
+	var article Article
+	err := c.do(ctx, http.MethodGet, "/article/"+url.PathEscape(id), nil, nil, &article)
+	return article, err
+}
+
+// List returns a page of articles sorted by ID, as summaries without their content. A limit or an offset of 0 is left
+// to the default of the API.
+func (c *ArticlesClient) List(ctx context.Context, limit int, offset int) (ArticlesPage, error) {
+	queryParams := url.Values{}
+	setIntParam(queryParams, "limit", limit)
+	setIntParam(queryParams, "offset", offset)
+	var page ArticlesPage
+	err := c.do(ctx, http.MethodGet, "/articles?"+queryParams.Encode(), nil, nil, &page)
+	return page, err
+}
+
+// Search returns a page of the articles matching the query, as summaries without their content.
+func (c *ArticlesClient) Search(ctx context.Context, query SearchQuery) (SearchPage, error) {
+	queryParams := url.Values{}
+	for field, values := range query.Filters {
+		queryParams[field] = append([]string{}, values...)
+	}
+	for name, value := range map[string]string{"q": query.Q, "operator": query.Operator, "sortBy": query.SortBy, "order": query.Order, "match": query.Match} {
+		if value != "" {
+			queryParams.Set(name, value)
+		}
+	}
+	setIntParam(queryParams, "fuzzy", query.Fuzzy)
+	setIntParam(queryParams, "minWords", query.MinWords)
+	setIntParam(queryParams, "maxWords", query.MaxWords)
+	setIntParam(queryParams, "limit", query.Limit)
+	setIntParam(queryParams, "offset", query.Offset)
+	var page SearchPage
+	err := c.do(ctx, http.MethodGet, "/articles/search?"+queryParams.Encode(), nil, nil, &page)
+	return page, err
+}
+
+// Create creates an article and returns its ID, generated by the API when the article has none. The request is sent
+// with an Idempotency-Key, so that it is safely retried: the article is created once.
+func (c *ArticlesClient) Create(ctx context.Context, article Article) (string, error) {
+	var created []struct {
+		Id string `json:"id"`
+	}
+	header := http.Header{"Idempotency-Key": []string{uuid.New().String()}}
+	if err := c.do(ctx, http.MethodPost, "/articles", header, article, &created); err != nil {
+		return "", err
+	}
+	if len(created) != 1 {
+		return "", fmt.Errorf("articles API returned %d articles created instead of 1", len(created))
+	}
+	return created[0].Id, nil
+}
+
+// Update replaces the article with the ID of the given one, which must hold the version it updates, and returns
+// the article as stored. An APIError satisfying IsConflict is returned when the article has been changed since.
+func (c *ArticlesClient) Update(ctx context.Context, article Article) (Article, error) {
+	var updated Article
+	err := c.do(ctx, http.MethodPut, "/article/"+url.PathEscape(article.Id), nil, article, &updated)
+	return updated, err
+}
+
+// Delete moves the article with the given ID to the trash.
+func (c *ArticlesClient) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/article/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// do sends a request to the API with the given headers and JSON body (none when nil), and decodes the JSON response
+// into result (unless nil). The request is retried according to MaxRetries and RetryDelay, until ctx is done.
+func (c *ArticlesClient) do(ctx context.Context, method string, path string, header http.Header, body any, result any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, header, payload, result)
+		if retryAfter < 0 || attempt >= c.MaxRetries {
+			return err
+		}
+		if retryAfter == 0 {
+			retryAfter = delay
+			delay *= 2
+		}
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// send sends a request to the API once, see do. It returns how long to wait before retrying the request along with
+// the error: -1 when the request must not be retried, 0 when the default delay applies.
+func (c *ArticlesClient) send(ctx context.Context, method string, path string, header http.Header, payload []byte, result any) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return -1, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.Tenant)
+	}
+	if c.Namespace != "" {
+		req.Header.Set("X-Namespace", c.Namespace)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := readAPIError(resp)
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			return time.Duration(max(seconds, 0)) * time.Second, apiErr
+		}
+		return -1, apiErr
+	}
+	if result == nil {
+		return -1, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return -1, fmt.Errorf("articles API response is not in the expected format: %v", err)
+	}
+	return -1, nil
+}
+
+// setIntParam sets a query parameter to a number, unless it is 0 so that the default of the API applies.
+func setIntParam(queryParams url.Values, name string, value int) {
+	if value != 0 {
+		queryParams.Set(name, strconv.Itoa(value))
+	}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// FacetCount is the number of documents sharing a given value of a field
+type FacetCount struct {
+	Value string
+	Count int64
+}
+
+// Aggregate perform a FT.AGGREGATE on the given index for the query built from the list of SearchParams,
+// followed by the given pipeline arguments (e.g. GROUPBY, REDUCE, SORTBY).
+// Each row of the result is returned as a map of its attributes, with values as strings.
+func Aggregate(ctx context.Context, redisClient *redis.Client, indexName string, filters []SearchParams, options SearchOptions, pipeline ...any) ([]map[string]string, error) {
+	queries := []any{"FT.AGGREGATE", indexName, buildQuery(filters, options)}
+	queries = append(queries, pipeline...)
+	queries = append(queries, "DIALECT", "3")
+
+	/*
+		Run query FT.AGGREGATE https://redis.io/commands/ft.aggregate/
+		Results on FT.AGGREGATE returns map[interface{}]interface{}
+		that looks like:
+		map[attributes:[] format:STRING results:[map[extra_attributes:map[author:Jane Doe count:2] values:[]]] total_results:1 warning:[]]
+	*/
+	redisAggResult, err := redisClient.Do(ctx, queries...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	topLevel, ok := redisAggResult.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("response returned when running this aggregation is not a valid map structure")
+	}
+	resultsArray, ok := topLevel["results"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("result from the aggregation is not a valid List of Interfaces")
+	}
+
+	var rows []map[string]string
+	for _, eachResult := range resultsArray {
+		res, ok := eachResult.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("database Aggregate results at first level is in invalid format")
+		}
+		resAttributes, ok := res["extra_attributes"].(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("database Aggregate result at second level is in invalid format")
+		}
+		row := make(map[string]string, len(resAttributes))
+		for name, value := range resAttributes {
+			row[fmt.Sprint(name)] = unwrapJSONValue(fmt.Sprint(value))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Facets counts, for each of the given fields, the documents matching the query built from the list of SearchParams
+// per value of the field. The maxValues most frequent values of each field are returned, the most frequent first.
+func Facets(ctx context.Context, redisClient *redis.Client, indexName string, filters []SearchParams, options SearchOptions, fields []string, maxValues int) (map[string][]FacetCount, error) {
+	facets := make(map[string][]FacetCount, len(fields))
+	for _, field := range fields {
+		rows, err := Aggregate(ctx, redisClient, indexName, filters, options,
+			"GROUPBY", 1, "@"+field,
+			"REDUCE", "COUNT", 0, "AS", "count",
+			"SORTBY", 2, "@count", "DESC",
+			"LIMIT", 0, maxValues,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		facetCounts := []FacetCount{}
+		for _, row := range rows {
+			count, err := strconv.ParseInt(row["count"], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("count of %s facet is not a valid digit", field)
+			}
+			facetCounts = append(facetCounts, FacetCount{Value: row[field], Count: count})
+		}
+		facets[field] = facetCounts
+	}
+	return facets, nil
+}
+
+// unwrapJSONValue returns the single element of a JSON array (e.g. ["Redis"]), as returned by DIALECT 3,
+// any other value is returned unchanged
+func unwrapJSONValue(value string) string {
+	var values []any
+	if err := json.Unmarshal([]byte(value), &values); err == nil && len(values) == 1 {
+		return fmt.Sprint(values[0])
+	}
+	return value
+}
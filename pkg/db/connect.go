@@ -21,3 +21,13 @@ func NewDbClient(dbHost string, dbPort int, dbPassword string, dbRedis int) (DbC
 	_, err := client.Ping(context.Background()).Result()
 	return client, err
 }
+
+// Close closes the connections of dbClient to the Redis database.
+func Close(dbClient DbClient) error {
+	return (*redis.Client)(dbClient).Close()
+}
+
+// Ping checks that the Redis database answers, using PING.
+func Ping(ctx context.Context, redisClient *redis.Client) error {
+	return redisClient.Ping(ctx).Err()
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// LimitedCounter is a counter incremented by CountersIncrWithin, up to its limit
+type LimitedCounter struct {
+	Key      string    // Key is the key of the counter
+	Limit    int64     // Limit is the maximum value of the counter, no limit applying when 0
+	ExpireAt time.Time // ExpireAt is the time the counter expires
+}
+
+// countersIncrWithinScript increments the counters stored at KEYS when none of them has reached its limit (ARGV[2i-1],
+// no limit applying when 0), each counter expiring at ARGV[2i] (a Unix timestamp in milliseconds). It returns the
+// index (starting at 1) of the first counter which has reached its limit, 0 when the counters have been incremented.
+var countersIncrWithinScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	local limit = tonumber(ARGV[2 * i - 1])
+	if limit > 0 and (tonumber(redis.call("GET", key)) or 0) >= limit then
+		return i
+	end
+end
+for i, key in ipairs(KEYS) do
+	redis.call("INCR", key)
+	redis.call("PEXPIREAT", key, ARGV[2 * i])
+end
+return 0`)
+
+// CountersIncrWithin atomically increments the given counters when none of them has reached its limit. It returns the
+// index of the first counter which has reached its limit, -1 when the counters have been incremented.
+func CountersIncrWithin(ctx context.Context, redisClient *redis.Client, counters []LimitedCounter) (int, error) {
+	keys := make([]string, len(counters))
+	args := make([]any, 0, 2*len(counters))
+	for i, counter := range counters {
+		keys[i] = counter.Key
+		args = append(args, counter.Limit, counter.ExpireAt.UnixMilli())
+	}
+	exceeded, err := countersIncrWithinScript.Run(ctx, redisClient, keys, args...).Int()
+	if err != nil {
+		return -1, err
+	}
+	return exceeded - 1, nil
+}
+
+// CountersGet returns the values of the counters stored at keys using MGET, a missing counter being 0
+func CountersGet(ctx context.Context, redisClient *redis.Client, keys []string) ([]int64, error) {
+	values, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	counters := make([]int64, len(values))
+	for i, value := range values {
+		if value, isString := value.(string); isString {
+			counters[i], _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return counters, nil
+}
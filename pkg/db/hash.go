@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// HashSet sets the value of a field of a hash using HSET
+func HashSet(ctx context.Context, redisClient *redis.Client, key string, field string, value any) error {
+	return redisClient.HSet(ctx, key, field, value).Err()
+}
+
+// HashGet returns the value of a field of a hash using HGET, an empty string is returned when there is no such field
+func HashGet(ctx context.Context, redisClient *redis.Client, key string, field string) (string, error) {
+	value, err := redisClient.HGet(ctx, key, field).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+// HashGetAll returns all the fields of a hash along with their values using HGETALL
+func HashGetAll(ctx context.Context, redisClient *redis.Client, key string) (map[string]string, error) {
+	return redisClient.HGetAll(ctx, key).Result()
+}
+
+// HashDel deletes a field of a hash using HDEL, it returns whether the field existed
+func HashDel(ctx context.Context, redisClient *redis.Client, key string, field string) (bool, error) {
+	deleted, err := redisClient.HDel(ctx, key, field).Result()
+	return deleted > 0, err
+}
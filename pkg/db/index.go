@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"strconv"
+)
+
+// IndexFieldType represents the type of a field of a search index
+type IndexFieldType string
+
+const (
+	TextField    IndexFieldType = "TEXT"
+	TagField     IndexFieldType = "TAG"
+	NumericField IndexFieldType = "NUMERIC"
+	GeoField     IndexFieldType = "GEO" // GeoField indexes "longitude,latitude" strings
+	VectorField  IndexFieldType = "VECTOR"
+)
+
+// IndexField describes a field of a search index schema
+type IndexField struct {
+	Path     string         // Path is the JSON path of the field in the documents, e.g. $.title
+	Alias    string         // Alias is the name of the field in queries, e.g. title
+	Type     IndexFieldType // Type is the type of the field
+	Sortable bool           // Sortable allows the results to be sorted by this field
+	NoStem   bool           // NoStem disables the stemming of a TextField
+	// VectorDimensions is the number of dimensions of a VectorField, stored as FLOAT32, indexed with FLAT and compared with COSINE
+	VectorDimensions int
+}
+
+// IndexSchema describes a search index over JSON documents
+type IndexSchema struct {
+	Prefixes         []string     // Prefixes are the prefixes of the keys of the documents to index
+	LanguageField    string       // LanguageField is the JSON path of the language of each document, e.g. $.language
+	Stopwords        []string     // Stopwords replace the default stopwords when not empty
+	DisableStopwords bool         // DisableStopwords indexes and searches every word, Stopwords being ignored
+	Fields           []IndexField // Fields are the fields of the documents to index
+}
+
+// IndexExists checks if a search index exists using FT._LIST
+func IndexExists(ctx context.Context, redisClient *redis.Client, indexName string) (bool, error) {
+	indexes, err := redisClient.Do(ctx, "FT._LIST").StringSlice()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(indexes, indexName), nil
+}
+
+// CreateIndex creates a search index over JSON documents with the given schema using FT.CREATE
+func CreateIndex(ctx context.Context, redisClient *redis.Client, indexName string, schema IndexSchema) error {
+	queries := []any{"FT.CREATE", indexName, "ON", "JSON"}
+	if len(schema.Prefixes) > 0 {
+		queries = append(queries, "PREFIX", len(schema.Prefixes))
+		for _, prefix := range schema.Prefixes {
+			queries = append(queries, prefix)
+		}
+	}
+	if schema.LanguageField != "" {
+		queries = append(queries, "LANGUAGE_FIELD", schema.LanguageField)
+	}
+	if schema.DisableStopwords {
+		queries = append(queries, "STOPWORDS", 0)
+	} else if len(schema.Stopwords) > 0 {
+		queries = append(queries, "STOPWORDS", len(schema.Stopwords))
+		for _, stopword := range schema.Stopwords {
+			queries = append(queries, stopword)
+		}
+	}
+
+	queries = append(queries, "SCHEMA")
+	for _, field := range schema.Fields {
+		fieldArgs, err := buildIndexFieldArgs(field)
+		if err != nil {
+			return err
+		}
+		queries = append(queries, fieldArgs...)
+	}
+
+	return redisClient.Do(ctx, queries...).Err()
+}
+
+// buildIndexFieldArgs builds the FT.CREATE SCHEMA arguments of a single IndexField
+func buildIndexFieldArgs(field IndexField) ([]any, error) {
+	args := []any{field.Path, "AS", field.Alias, string(field.Type)}
+	switch field.Type {
+	case TextField:
+		if field.NoStem {
+			args = append(args, "NOSTEM")
+		}
+	case VectorField:
+		if field.VectorDimensions <= 0 {
+			return nil, fmt.Errorf("vector field %s must have a positive number of dimensions", field.Alias)
+		}
+		args = append(args, "FLAT", 6, "TYPE", "FLOAT32", "DIM", strconv.Itoa(field.VectorDimensions), "DISTANCE_METRIC", "COSINE")
+	case GeoField:
+		if field.Sortable {
+			return nil, fmt.Errorf("geo field %s can't be sortable", field.Alias)
+		}
+	case TagField, NumericField:
+	default:
+		return nil, fmt.Errorf("%s is not a supported type for field %s", field.Type, field.Alias)
+	}
+	if field.Sortable {
+		args = append(args, "SORTABLE")
+	}
+	return args, nil
+}
+
+// IndexInfo returns the information about a search index using FT.INFO
+// The information is returned as a generic map that can be marshaled to JSON.
+func IndexInfo(ctx context.Context, redisClient *redis.Client, indexName string) (map[string]any, error) {
+	result, err := redisClient.Do(ctx, "FT.INFO", indexName).Result()
+	if err != nil {
+		return nil, err
+	}
+	info, ok := normalizeResult(result).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("response returned when getting index information is not a valid map structure")
+	}
+	return info, nil
+}
+
+// IndexAttributes returns the alias of each field of a search index, as listed by FT.INFO
+func IndexAttributes(ctx context.Context, redisClient *redis.Client, indexName string) ([]string, error) {
+	info, err := IndexInfo(ctx, redisClient, indexName)
+	if err != nil {
+		return nil, err
+	}
+	attributes, ok := info["attributes"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("attributes of the index information are not a valid List of Interfaces")
+	}
+	var aliases []string
+	for _, eachAttribute := range attributes {
+		attribute, ok := eachAttribute.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("attribute of the index information is in invalid format")
+		}
+		aliases = append(aliases, fmt.Sprint(attribute["attribute"]))
+	}
+	return aliases, nil
+}
+
+// DropIndex drops a search index using FT.DROPINDEX, the indexed documents are deleted as well when deleteDocuments is set
+func DropIndex(ctx context.Context, redisClient *redis.Client, indexName string, deleteDocuments bool) error {
+	queries := []any{"FT.DROPINDEX", indexName}
+	if deleteDocuments {
+		queries = append(queries, "DD")
+	}
+	return redisClient.Do(ctx, queries...).Err()
+}
+
+// AlterIndex adds a field to the schema of a search index using FT.ALTER
+// The documents already stored are indexed for the new field in the background.
+func AlterIndex(ctx context.Context, redisClient *redis.Client, indexName string, field IndexField) error {
+	fieldArgs, err := buildIndexFieldArgs(field)
+	if err != nil {
+		return err
+	}
+	queries := append([]any{"FT.ALTER", indexName, "SCHEMA", "ADD"}, fieldArgs...)
+	return redisClient.Do(ctx, queries...).Err()
+}
+
+// normalizeResult converts a RESP3 result so that it can be marshaled to JSON, i.e. maps keyed by strings
+func normalizeResult(result any) any {
+	switch value := result.(type) {
+	case map[any]any:
+		normalized := make(map[string]any, len(value))
+		for key, item := range value {
+			normalized[fmt.Sprint(key)] = normalizeResult(item)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(value))
+		for i, item := range value {
+			normalized[i] = normalizeResult(item)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// ListPush appends a value to a list using RPUSH, it returns the length of the list after the push
+func ListPush(ctx context.Context, redisClient *redis.Client, key string, value any) (int64, error) {
+	return redisClient.RPush(ctx, key, value).Result()
+}
+
+// ListRange returns the values of a list between the start and stop indexes (inclusive, -1 being the last) using LRANGE
+func ListRange(ctx context.Context, redisClient *redis.Client, key string, start int64, stop int64) ([]string, error) {
+	return redisClient.LRange(ctx, key, start, stop).Result()
+}
+
+// ListIndex returns the value at the given index of a list using LINDEX, an empty string is returned when there is no such value
+func ListIndex(ctx context.Context, redisClient *redis.Client, key string, index int64) (string, error) {
+	value, err := redisClient.LIndex(ctx, key, index).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Migration is a versioned change of the data or of the indexes stored in the Database
+type Migration struct {
+	Version     int                                                        // Version orders the migrations, it must be unique and positive
+	Description string                                                     // Description explains what the migration does
+	Apply       func(ctx context.Context, redisClient *redis.Client) error // Apply runs the migration, it should be safe to run again if it fails midway
+}
+
+// migrationLockTTL bounds the time the migration lock is held, should the instance holding it die
+const migrationLockTTL = 10 * time.Minute
+
+// migrationLockRetryInterval is the delay between two attempts to take the migration lock
+const migrationLockRetryInterval = time.Second
+
+// releaseLockScript deletes a lock only when it is still held by the given owner
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Migrate applies, by increasing version, the migrations not yet recorded in the hash stored at key, each applied
+// migration being recorded with the time it was applied. A lock (key:lock) ensures that only one instance migrates
+// the Database at a time, the other ones waiting for it to complete. It returns the versions applied by this call.
+func Migrate(ctx context.Context, redisClient *redis.Client, key string, migrations []Migration) ([]int, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return a.Version - b.Version })
+	for i, migration := range sorted {
+		if migration.Version <= 0 || (i > 0 && sorted[i-1].Version == migration.Version) {
+			return nil, fmt.Errorf("invalid or duplicate migration version %d", migration.Version)
+		}
+	}
+
+	lockKey := key + ":lock"
+	owner := uuid.New().String()
+	if err := acquireLock(ctx, redisClient, lockKey, owner); err != nil {
+		return nil, err
+	}
+	defer releaseLockScript.Run(context.WithoutCancel(ctx), redisClient, []string{lockKey}, owner)
+
+	applied, err := redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the applied migrations: %v", err)
+	}
+
+	var versions []int
+	for _, migration := range sorted {
+		version := strconv.Itoa(migration.Version)
+		if _, done := applied[version]; done {
+			continue
+		}
+		if err := migration.Apply(ctx, redisClient); err != nil {
+			return versions, fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Description, err)
+		}
+		if err := redisClient.HSet(ctx, key, version, time.Now().UTC().Format(time.RFC3339)).Err(); err != nil {
+			return versions, fmt.Errorf("unable to record migration %d: %v", migration.Version, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+	return versions, nil
+}
+
+// PendingMigrations returns the versions of the migrations which have not been applied yet, as recorded at key by Migrate.
+func PendingMigrations(ctx context.Context, redisClient *redis.Client, key string, migrations []Migration) ([]int, error) {
+	applied, err := redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the applied migrations: %v", err)
+	}
+	var pending []int
+	for _, migration := range migrations {
+		if _, done := applied[strconv.Itoa(migration.Version)]; !done {
+			pending = append(pending, migration.Version)
+		}
+	}
+	slices.Sort(pending)
+	return pending, nil
+}
+
+// acquireLock takes the lock stored at lockKey on behalf of owner, waiting for it to be released when already taken
+func acquireLock(ctx context.Context, redisClient *redis.Client, lockKey string, owner string) error {
+	for {
+		acquired, err := redisClient.SetNX(ctx, lockKey, owner, migrationLockTTL).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("unable to acquire the migration lock: %v", err)
+		}
+		if acquired {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to acquire the migration lock: %v", ctx.Err())
+		case <-time.After(migrationLockRetryInterval):
+		}
+	}
+}
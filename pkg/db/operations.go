@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // JSONSetArgs simply mirrors go-redis/v9 JSONSetArgs
@@ -16,10 +18,102 @@ type JSONSetArgs struct {
 }
 
 // SearchParams encapsulates the parameters used during a search
+// Param is the name of the field to search, several fields can be searched at once by separating them with | (e.g. title|content)
+// Every Value must match, within a Value alternatives separated by | (e.g. go|redis) are OR'ed
+// and a leading - (e.g. -smith) excludes the documents matching the alternative.
+// Each Value can hold prefix (e.g. data*) or wildcard (e.g. *base, d?ta) terms.
+// A text Value surrounded by double quotes (e.g. "redis search") is matched as an exact phrase,
+// ExactPhrase can be set to match every text Value as an exact phrase.
+// Fuzziness is the Levenshtein distance (up to MaxFuzziness) allowed when matching text terms, 0 meaning exact terms
+// A NumberType field is searched with Ranges instead of Value, the field value must be within every range
+// A GeoType field is searched with GeoAreas instead of Value, the field location must be within every area
+// Exclude matches the documents not matching the SearchParams instead, including the ones missing the field
 type SearchParams struct {
-	Param string
-	Type  JSONDataType
-	Value []string
+	Param       string
+	Type        JSONDataType
+	Value       []string
+	Fuzziness   int
+	ExactPhrase bool
+	Ranges      []NumberRange
+	GeoAreas    []GeoRadius
+	Exclude     bool
+}
+
+// NumberRange is a range of values of a NUMERIC field, searched with @field:[min max]
+// Min and Max are inclusive unless ExclusiveMin and ExclusiveMax are set, math.Inf(-1) and math.Inf(1) standing for no bound
+type NumberRange struct {
+	Min          float64
+	Max          float64
+	ExclusiveMin bool
+	ExclusiveMax bool
+}
+
+// GeoUnit is the unit of the radius of a GeoRadius
+type GeoUnit string
+
+const (
+	Meters     GeoUnit = "m"
+	Kilometers GeoUnit = "km"
+	Miles      GeoUnit = "mi"
+	Feet       GeoUnit = "ft"
+)
+
+// GeoRadius is the area within Radius (in Unit) of a location, searched on a GEO field with @field:[lon lat radius unit]
+type GeoRadius struct {
+	Longitude float64
+	Latitude  float64
+	Radius    float64
+	Unit      GeoUnit
+}
+
+// MaxFuzziness is the maximum Levenshtein distance supported by RediSearch fuzzy matching
+const MaxFuzziness = 3
+
+// SearchOptions encapsulates the options that tune how a search is run and how its results are returned
+type SearchOptions struct {
+	SortBy         string // SortBy is the SORTABLE field used to sort the results, no sorting when empty
+	SortDescending bool   // SortDescending sorts the results in descending order instead of ascending
+	MatchAny       bool   // MatchAny returns the documents matching any of the filters instead of all of them
+	WithScores     bool   // WithScores returns the relevance score of each result
+	Language       string // Language is the language used to stem the query terms (e.g. french), the index default when empty
+	Offset         int    // Offset is the number of results to skip
+	Limit          int    // Limit is the maximum number of results returned, RediSearch default (10) is used when 0
+	// Highlight, when set, returns the matched fragments of some fields with markup along with each result
+	Highlight *HighlightOptions
+	// distanceField is the attribute holding the vector distance of each result, set by KNNSearch
+	distanceField string
+	// FieldWeights holds the weight applied to matches on a given field when scoring results, fields missing have a weight of 1
+	FieldWeights map[string]float64
+	// Required holds filters that must always match on top of the searched ones, whatever MatchAny
+	Required []SearchParams
+	// Paths restricts the returned documents to the values at the given top level JSON paths (e.g. $.title),
+	// the whole documents being returned when empty
+	Paths []string
+}
+
+// HighlightOptions configures the HIGHLIGHT and SUMMARIZE FT.SEARCH options
+type HighlightOptions struct {
+	Fields          []string // Fields are the TEXT fields whose matched terms are highlighted
+	SummarizeFields []string // SummarizeFields are the TEXT fields reduced to the fragments around the matched terms
+	OpenTag         string   // OpenTag is inserted before each matched term, e.g. <mark>
+	CloseTag        string   // CloseTag is inserted after each matched term, e.g. </mark>
+}
+
+// SearchResult holds the results of a search
+type SearchResult[T any] struct {
+	Total int64          // Total is the number of documents matching the search, regardless of Offset and Limit
+	Hits  []SearchHit[T] // Hits are the documents returned for the requested Offset and Limit
+}
+
+// SearchHit is a single result of a search
+type SearchHit[T any] struct {
+	Key   string  // Key is the Redis key of the document
+	Score float64 // Score is the relevance score of the document, only set when SearchOptions.WithScores is requested
+	Item  T       // Item is the document itself
+	// Highlights holds the highlighted (and possibly summarized) value of each field, only set when SearchOptions.Highlight is requested
+	Highlights map[string]string
+	// Distance is the distance between the document vector and the searched vector, only set by KNNSearch
+	Distance float64
 }
 
 // JSONDataType represents the different JSON Data Type
@@ -31,6 +125,8 @@ const (
 	StringType  JSONDataType = "String"
 	BooleanType JSONDataType = "Boolean"
 	ArrayType   JSONDataType = "Array"
+	TagType     JSONDataType = "Tag" // TagType is a String indexed as a TAG, it is searched the same way as an ArrayType
+	GeoType     JSONDataType = "Geo" // GeoType is a String holding a "longitude,latitude" location indexed as a GEO field
 	ObjectType  JSONDataType = "Hash"
 	NullType    JSONDataType = "Null"
 )
@@ -50,6 +146,12 @@ func GetAllKeys(ctx context.Context, redisClient *redis.Client, keysPrefix strin
 	return keys, nil
 }
 
+// ScanKeys runs a single SCAN iteration from the given cursor for keys matching a certain prefix.
+// It returns the keys found along with the cursor to use for the next iteration, 0 meaning the iteration is complete.
+func ScanKeys(ctx context.Context, redisClient *redis.Client, keysPrefix string, cursor uint64, count int64) ([]string, uint64, error) {
+	return redisClient.Scan(ctx, cursor, keysPrefix+"*", count).Result()
+}
+
 // JSONGet returns results from go-redis/v9 JSONGet
 func JSONGet(ctx context.Context, redisClient *redis.Client, key string) (string, error) {
 	result, err := redisClient.JSONGet(ctx, key).Result()
@@ -68,11 +170,88 @@ func JSONMGet(ctx context.Context, redisClient *redis.Client, keys []string) ([]
 	return result, err
 }
 
+// JSONMGetPaths retrieves only the values at the given top level JSON paths (e.g. $.title) of the documents stored
+// at the given keys, pipelining a JSON.GET per key, so that the rest of the documents is never sent by the database.
+// Each document is built back from its values and unmarshalled to T, the keys that do not exist being skipped.
+func JSONMGetPaths[T any](ctx context.Context, redisClient *redis.Client, keys []string, paths []string) ([]T, error) {
+	pipe := redisClient.Pipeline()
+	cmds := make([]*redis.JSONCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.JSONGet(ctx, key, paths...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	items := make([]T, 0, len(keys))
+	for _, cmd := range cmds {
+		result, err := cmd.Result()
+		if err == redis.Nil || result == "" {
+			// The key was removed since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// JSON.GET returns the matches of a single path as is, and an object holding the matches of each path otherwise
+		values := make(map[string]json.RawMessage)
+		if len(paths) == 1 {
+			values[paths[0]] = json.RawMessage(result)
+		} else if err := json.Unmarshal([]byte(result), &values); err != nil {
+			return nil, fmt.Errorf("database result not on expected format, error %v", err)
+		}
+		var item T
+		if err := unmarshalPathValues(values, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// unmarshalPathValues builds back a document from the matches of some of its top level JSON paths (e.g. $.title),
+// each given as the JSON array of its matches, and unmarshals it to v. The paths without any match are left out.
+func unmarshalPathValues(values map[string]json.RawMessage, v any) error {
+	document := make(map[string]json.RawMessage, len(values))
+	for path, value := range values {
+		var matches []json.RawMessage
+		if err := json.Unmarshal(value, &matches); err != nil {
+			return fmt.Errorf("database result not on expected format, error %v", err)
+		}
+		if len(matches) > 0 {
+			document[strings.TrimPrefix(path, "$.")] = matches[0]
+		}
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
 // JSONSet returns results from go-redis/v9 JSONSet
 func JSONSet(ctx context.Context, redisClient *redis.Client, key string, path string, value any) (string, error) {
 	return redisClient.JSONSet(ctx, key, path, value).Result()
 }
 
+// JSONNumIncrBy increments the number at the given path of a JSON document using JSON.NUMINCRBY,
+// and returns the incremented number
+func JSONNumIncrBy(ctx context.Context, redisClient *redis.Client, key string, path string, increment float64) (float64, error) {
+	result, err := redisClient.JSONNumIncrBy(ctx, key, path, increment).Result()
+	if err != nil {
+		return 0, err
+	}
+	// A JSONPath returns the incremented number of every matching value as an array
+	var numbers []float64
+	if err := json.Unmarshal([]byte(result), &numbers); err != nil {
+		return 0, err
+	}
+	if len(numbers) == 0 {
+		return 0, fmt.Errorf("no number found at %s of %s", path, key)
+	}
+	return numbers[0], nil
+}
+
 // JSONMSetArgs returns  results from go-redis/v9 JSONMSetArgs
 func JSONMSetArgs(ctx context.Context, redisClient *redis.Client, setArgs []JSONSetArgs) (string, error) {
 	var redisSetArgs []redis.JSONSetArgs
@@ -82,42 +261,177 @@ func JSONMSetArgs(ctx context.Context, redisClient *redis.Client, setArgs []JSON
 	return redisClient.JSONMSetArgs(ctx, redisSetArgs).Result()
 }
 
+// JSONMSetArgsExpireAt sets the given JSON values like JSONMSetArgs and sets the expiration of their keys in the same
+// transaction (MULTI): each key expires at its time in expireAt, as a Unix timestamp in seconds, using EXPIREAT, the keys
+// without time being made persistent using PERSIST.
+func JSONMSetArgsExpireAt(ctx context.Context, redisClient *redis.Client, setArgs []JSONSetArgs, expireAt map[string]int64) error {
+	var redisSetArgs []redis.JSONSetArgs
+	for _, setArg := range setArgs {
+		redisSetArgs = append(redisSetArgs, redis.JSONSetArgs(setArg))
+	}
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.JSONMSetArgs(ctx, redisSetArgs)
+		for _, setArg := range setArgs {
+			if expireAt[setArg.Key] > 0 {
+				pipe.ExpireAt(ctx, setArg.Key, time.Unix(expireAt[setArg.Key], 0))
+			} else {
+				pipe.Persist(ctx, setArg.Key)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
 // Exists return results from go-redis/v9 Exists
 func Exists(ctx context.Context, redisClient *redis.Client, key string) (int64, error) {
 	return redisClient.Exists(ctx, key).Result()
 }
 
 // Del return results from go-redis/v9 Del
-func Del(ctx context.Context, redisClient *redis.Client, key string) (int64, error) {
-	return redisClient.Del(ctx, key).Result()
+func Del(ctx context.Context, redisClient *redis.Client, keys ...string) (int64, error) {
+	return redisClient.Del(ctx, keys...).Result()
+}
+
+// DelByPrefix deletes all the keys with the given prefix, scanning and unlinking them in batches of count keys,
+// and returns the number of keys deleted
+func DelByPrefix(ctx context.Context, redisClient *redis.Client, keysPrefix string, count int64) (int64, error) {
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, nextCursor, err := ScanKeys(ctx, redisClient, keysPrefix, cursor, count)
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			unlinked, err := redisClient.Unlink(ctx, keys...).Result()
+			deleted += unlinked
+			if err != nil {
+				return deleted, err
+			}
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// Get returns the string value of a key using GET, an empty string is returned when the key does not exist
+func Get(ctx context.Context, redisClient *redis.Client, key string) (string, error) {
+	value, err := redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+// Set sets the string value of a key, expiring after ttl (no expiration when 0), using SET
+func Set(ctx context.Context, redisClient *redis.Client, key string, value any, ttl time.Duration) error {
+	return redisClient.Set(ctx, key, value, ttl).Err()
+}
+
+// SetNX sets the string value of a key unless it already exists, expiring after ttl (no expiration when 0), using SET NX
+// It returns true when the value has been set
+func SetNX(ctx context.Context, redisClient *redis.Client, key string, value any, ttl time.Duration) (bool, error) {
+	return redisClient.SetNX(ctx, key, value, ttl).Result()
+}
+
+// DelIfEquals deletes a key only when it holds the given string value (e.g. a lock still held by its owner), atomically
+// It returns true when the key has been deleted
+func DelIfEquals(ctx context.Context, redisClient *redis.Client, key string, value string) (bool, error) {
+	deleted, err := releaseLockScript.Run(ctx, redisClient, []string{key}, value).Int64()
+	return deleted == 1, err
+}
+
+// JSONDel return results from go-redis/v9 JSONDel
+func JSONDel(ctx context.Context, redisClient *redis.Client, key string, path string) (int64, error) {
+	return redisClient.JSONDel(ctx, key, path).Result()
+}
+
+// ExpireAt return results from go-redis/v9 ExpireAt
+func ExpireAt(ctx context.Context, redisClient *redis.Client, key string, tm time.Time) (bool, error) {
+	return redisClient.ExpireAt(ctx, key, tm).Result()
+}
+
+// Persist return results from go-redis/v9 Persist
+func Persist(ctx context.Context, redisClient *redis.Client, key string) (bool, error) {
+	return redisClient.Persist(ctx, key).Result()
+}
+
+// JSONSetAndRename sets the value at path in the JSON document stored at key, then renames key to newKey
+// (replacing any document stored at newKey), atomically using MULTI/EXEC
+func JSONSetAndRename(ctx context.Context, redisClient *redis.Client, key string, path string, value any, newKey string) error {
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.JSONSet(ctx, key, path, value)
+		pipe.Rename(ctx, key, newKey)
+		return nil
+	})
+	return err
+}
+
+// RenameNXAndJSONDel renames key to newKey unless newKey already exists, then deletes the value at path in the
+// JSON document stored at newKey, atomically using MULTI/EXEC. It returns false when newKey already exists,
+// in which case nothing is renamed (the document stored at newKey is expected not to hold any value at path)
+func RenameNXAndJSONDel(ctx context.Context, redisClient *redis.Client, key string, newKey string, path string) (bool, error) {
+	var renamed *redis.BoolCmd
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		renamed = pipe.RenameNX(ctx, key, newKey)
+		pipe.JSONDel(ctx, newKey, path)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return renamed.Val(), nil
 }
 
 // Search perform a FT.SEARCH on the given index using the parameter provided on a list of SearchParams
-func Search[T any](ctx context.Context, redisClient *redis.Client, indexName string, filters []SearchParams) ([]T, error) {
+// The given SearchOptions are translated to their FT.SEARCH counterpart (e.g. SORTBY, WITHSCORES)
+func Search[T any](ctx context.Context, redisClient *redis.Client, indexName string, filters []SearchParams, options SearchOptions) (SearchResult[T], error) {
+	return runSearch[T](ctx, redisClient, indexName, buildQuery(filters, options), options)
+}
+
+// runSearch runs FT.SEARCH on the given index for the given query string (extra arguments such as PARAMS are appended
+// after the SearchOptions arguments) and gathers the results.
+func runSearch[T any](ctx context.Context, redisClient *redis.Client, indexName string, query string, options SearchOptions, extraArgs ...any) (SearchResult[T], error) {
 
 	var queries []any
-	var result []T
+	var result SearchResult[T]
 
 	// Build the Search Query
 	queries = append(queries, "FT.SEARCH", indexName)
-	var args []string
-	for _, searchParam := range filters {
-		var fieldSearch string
-		if searchParam.Type == ArrayType {
-			fieldSearch = fmt.Sprintf("@%s:{%s}", searchParam.Param, strings.Join(searchParam.Value, " "))
-		} else {
-			fieldSearch = fmt.Sprintf("@%s:%s", searchParam.Param, strings.Join(searchParam.Value, " "))
+	queries = append(queries, query)
+	if options.SortBy != "" {
+		sortOrder := "ASC"
+		if options.SortDescending {
+			sortOrder = "DESC"
 		}
-		args = append(args, fieldSearch)
+		queries = append(queries, "SORTBY", options.SortBy, sortOrder)
+	}
+	if options.WithScores {
+		queries = append(queries, "WITHSCORES")
 	}
-	queries = append(queries, strings.Join(args, " "))
+	if options.Highlight != nil {
+		queries = append(queries, buildHighlightArgs(*options.Highlight, options.Paths)...)
+	} else if len(options.Paths) > 0 {
+		queries = append(queries, buildReturnArgs(options.Paths, nil)...)
+	}
+	if options.Limit > 0 {
+		queries = append(queries, "LIMIT", options.Offset, options.Limit)
+	}
+	if options.Language != "" {
+		queries = append(queries, "LANGUAGE", options.Language)
+	}
+	queries = append(queries, extraArgs...)
 	queries = append(queries, "DIALECT", "3")
 
 	/*
 		Run query FT.SEARCH https://redis.io/commands/ft.search/
 		Results on FT.SEARCH returns map[interface{}]interface{}
 		that looks like:
-		map[attributes:[] format:STRING results:[map[extra_attributes:map[$:{"id":1,"title"...}] id:articleKey:1 values:[]]] total_results:1 warning:[]]
+		map[attributes:[] format:STRING results:[map[extra_attributes:map[$:{"id":1,"title"...}] id:articleKey:1 score:1.5 values:[]]] total_results:1 warning:[]]
+		with score only being part of each result when WITHSCORES is requested
 	*/
 
 	redisFtResult, err := redisClient.Do(ctx, queries...).Result()
@@ -137,6 +451,7 @@ func Search[T any](ctx context.Context, redisClient *redis.Client, indexName str
 		return result, fmt.Errorf("total Results is not a valid digit")
 	}
 
+	result.Total = totalResults
 	if totalResults <= 0 {
 		return result, nil
 	}
@@ -160,15 +475,44 @@ func Search[T any](ctx context.Context, redisClient *redis.Client, indexName str
 		if !ok {
 			return result, fmt.Errorf("database Search result at second level is in invalid format")
 		}
+		key, _ := res["id"].(string)
+		var score float64
+		if options.WithScores {
+			if score, ok = res["score"].(float64); !ok {
+				return result, fmt.Errorf("database Search result score is in invalid format")
+			}
+		}
+
+		var distance float64
+		if options.distanceField != "" {
+			distance, _ = strconv.ParseFloat(fmt.Sprint(resAttributes[options.distanceField]), 64)
+		}
+
+		var highlights map[string]string
+		if options.Highlight != nil {
+			highlights = parseHighlights(resAttributes, *options.Highlight)
+		}
 
-		for _, resultItem := range resAttributes {
-			if jsonString, ok := resultItem.(string); ok {
-				var newItems []T // Use a slice to handle multiple Items
-				err = json.Unmarshal([]byte(jsonString), &newItems)
-				if err != nil {
-					return result, fmt.Errorf("database result not on expected format, error %v", err)
+		if len(options.Paths) > 0 {
+			values := make(map[string]json.RawMessage, len(options.Paths))
+			for _, path := range options.Paths {
+				if jsonString, ok := resAttributes[path].(string); ok {
+					values[path] = json.RawMessage(jsonString)
 				}
-				result = append(result, newItems...)
+			}
+			var newItem T
+			if err := unmarshalPathValues(values, &newItem); err != nil {
+				return result, err
+			}
+			result.Hits = append(result.Hits, SearchHit[T]{Key: key, Score: score, Distance: distance, Item: newItem, Highlights: highlights})
+		} else if jsonString, ok := resAttributes["$"].(string); ok {
+			var newItems []T // Use a slice to handle multiple Items
+			err = json.Unmarshal([]byte(jsonString), &newItems)
+			if err != nil {
+				return result, fmt.Errorf("database result not on expected format, error %v", err)
+			}
+			for _, newItem := range newItems {
+				result.Hits = append(result.Hits, SearchHit[T]{Key: key, Score: score, Distance: distance, Item: newItem, Highlights: highlights})
 			}
 		}
 	}
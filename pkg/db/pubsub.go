@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// Publish posts a message to a channel using PUBLISH, it returns the number of clients that received it
+func Publish(ctx context.Context, redisClient *redis.Client, channel string, message any) (int64, error) {
+	return redisClient.Publish(ctx, channel, message).Result()
+}
+
+// Subscribe subscribes to a channel using SUBSCRIBE, waiting for the subscription to be confirmed, and returns the
+// messages published to the channel from then on, along with the function ending the subscription. The subscription is
+// restored whenever the connection is lost, the messages published meanwhile being missed.
+func Subscribe(ctx context.Context, redisClient *redis.Client, channel string) (<-chan string, func() error, error) {
+	pubSub := redisClient.Subscribe(ctx, channel)
+	if _, err := pubSub.Receive(ctx); err != nil {
+		_ = pubSub.Close()
+		return nil, nil, err
+	}
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for message := range pubSub.Channel() {
+			messages <- message.Payload
+		}
+	}()
+	return messages, pubSub.Close, nil
+}
@@ -0,0 +1,316 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// buildQuery builds the FT.SEARCH query string from a list of SearchParams.
+// The filters are all required to match, unless options.MatchAny is set in which case any of them is enough,
+// the options.Required filters being required to match anyway.
+// No filter means all the documents in the index.
+func buildQuery(filters []SearchParams, options SearchOptions) string {
+	var args []string
+	for _, searchParam := range filters {
+		fieldQuery := buildFilterQuery(searchParam, options.FieldWeights)
+		if fieldQuery == "" {
+			continue
+		}
+		if options.MatchAny {
+			fieldQuery = "(" + fieldQuery + ")"
+		}
+		args = append(args, fieldQuery)
+	}
+
+	var required []string
+	for _, searchParam := range options.Required {
+		if fieldQuery := buildFilterQuery(searchParam, options.FieldWeights); fieldQuery != "" {
+			required = append(required, fieldQuery)
+		}
+	}
+
+	if len(args) == 0 {
+		if len(required) > 0 {
+			return strings.Join(required, " ")
+		}
+		return "*"
+	}
+	query := strings.Join(args, " ")
+	if options.MatchAny {
+		query = strings.Join(args, " | ")
+		if len(required) > 0 {
+			query = "(" + query + ")"
+		}
+	}
+	return strings.Join(append([]string{query}, required...), " ")
+}
+
+// buildFilterQuery builds the FT.SEARCH query part for a single SearchParams, negated when Exclude is set
+func buildFilterQuery(searchParam SearchParams, fieldWeights map[string]float64) string {
+	fieldQuery := buildFieldQuery(searchParam, fieldWeights)
+	if fieldQuery == "" || !searchParam.Exclude {
+		return fieldQuery
+	}
+	return "-(" + fieldQuery + ")"
+}
+
+// buildFieldQuery builds the FT.SEARCH query part for a single SearchParams
+// A TEXT field with a weight other than 1 in fieldWeights gets a $weight attribute, when several TEXT fields
+// are searched at once, each of them is queried on its own so that it can be weighted separately.
+// It returns an empty string when the SearchParams has no value to search for.
+func buildFieldQuery(searchParam SearchParams, fieldWeights map[string]float64) string {
+	if searchParam.Type == NumberType {
+		return buildRangeQuery(searchParam.Param, searchParam.Ranges)
+	}
+	if searchParam.Type == GeoType {
+		return buildGeoQuery(searchParam.Param, searchParam.GeoAreas)
+	}
+
+	var parts []string
+	for _, value := range searchParam.Value {
+		var part string
+		if searchParam.Type == ArrayType || searchParam.Type == TagType {
+			part = buildTagQuery(searchParam.Param, value)
+		} else {
+			part = buildTextQuery(value, searchParam.Fuzziness, searchParam.ExactPhrase)
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	if searchParam.Type == ArrayType || searchParam.Type == TagType {
+		return strings.Join(parts, " ")
+	}
+	fields := strings.Split(searchParam.Param, "|")
+	if !slices.ContainsFunc(fields, func(field string) bool { return hasWeight(fieldWeights, field) }) {
+		// Parentheses keep all the terms bound to the field(s), Param can target several fields separated by |
+		return fmt.Sprintf("@%s:(%s)", searchParam.Param, strings.Join(parts, " "))
+	}
+
+	var fieldQueries []string
+	for _, field := range fields {
+		fieldQuery := fmt.Sprintf("@%s:(%s)", field, strings.Join(parts, " "))
+		if hasWeight(fieldWeights, field) {
+			fieldQuery = fmt.Sprintf("(%s) => { $weight: %s; }", fieldQuery, strconv.FormatFloat(fieldWeights[field], 'f', -1, 64))
+		}
+		fieldQueries = append(fieldQueries, fieldQuery)
+	}
+	if len(fieldQueries) == 1 {
+		return fieldQueries[0]
+	}
+	return "(" + strings.Join(fieldQueries, " | ") + ")"
+}
+
+// buildRangeQuery builds the query matching the values of a NUMERIC field within all the given ranges,
+// e.g. @createdAt:[(1700000000 +inf]
+func buildRangeQuery(field string, ranges []NumberRange) string {
+	var parts []string
+	for _, numberRange := range ranges {
+		parts = append(parts, fmt.Sprintf("@%s:[%s %s]", field,
+			formatRangeBound(numberRange.Min, numberRange.ExclusiveMin),
+			formatRangeBound(numberRange.Max, numberRange.ExclusiveMax),
+		))
+	}
+	return strings.Join(parts, " ")
+}
+
+// buildGeoQuery builds the query matching the locations of a GEO field within all the given areas,
+// e.g. @location:[2.3522 48.8566 10 km]. Areas with an unknown unit or a negative radius are ignored.
+func buildGeoQuery(field string, areas []GeoRadius) string {
+	var parts []string
+	for _, area := range areas {
+		if !slices.Contains([]GeoUnit{Meters, Kilometers, Miles, Feet}, area.Unit) || area.Radius < 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("@%s:[%s %s %s %s]", field,
+			strconv.FormatFloat(area.Longitude, 'f', -1, 64),
+			strconv.FormatFloat(area.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(area.Radius, 'f', -1, 64),
+			area.Unit,
+		))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatRangeBound formats a bound of a numeric range, an exclusive bound being prefixed by (
+func formatRangeBound(bound float64, exclusive bool) string {
+	if math.IsInf(bound, 0) {
+		return strings.ToLower(strconv.FormatFloat(bound, 'f', -1, 64)) // -inf or +inf
+	}
+	formatted := strconv.FormatFloat(bound, 'f', -1, 64)
+	if exclusive {
+		formatted = "(" + formatted
+	}
+	return formatted
+}
+
+// hasWeight reports whether a weight other than the default 1 is set for the field
+func hasWeight(fieldWeights map[string]float64, field string) bool {
+	weight, found := fieldWeights[field]
+	return found && weight > 0 && weight != 1
+}
+
+// buildTagQuery builds the query matching a single value of a TAG field, e.g. @tags:{go | redis}
+// The alternatives separated by | are OR'ed and the whole value is negated when it starts with -.
+func buildTagQuery(field string, value string) string {
+	value, negated := strings.CutPrefix(strings.TrimSpace(value), "-")
+	var tags []string
+	for _, tag := range strings.Split(value, "|") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, formatTerm(tag, 0))
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	query := fmt.Sprintf("@%s:{%s}", field, strings.Join(tags, " | "))
+	if negated {
+		query = "-" + query
+	}
+	return query
+}
+
+// buildTextQuery builds the query matching a single value of a TEXT field, e.g. (redis|-(json search))
+// The alternatives separated by | are OR'ed and each alternative starting with - is negated.
+// An alternative surrounded by double quotes (or any alternative when exactPhrase is set) is matched as an exact phrase.
+func buildTextQuery(value string, fuzziness int, exactPhrase bool) string {
+	var alternatives []string
+	for _, alternative := range strings.Split(value, "|") {
+		alternative, negated := strings.CutPrefix(strings.TrimSpace(alternative), "-")
+
+		var terms []string
+		phrase, isQuoted := strings.CutPrefix(alternative, `"`)
+		phrase, hasClosingQuote := strings.CutSuffix(phrase, `"`)
+		if exactPhrase || (isQuoted && hasClosingQuote) {
+			// An exact phrase is kept as a single quoted term, e.g. "redis search"
+			var words []string
+			for _, word := range strings.Fields(strings.ReplaceAll(phrase, `"`, " ")) {
+				words = append(words, escapeQueryTerm(word, ""))
+			}
+			if len(words) > 0 {
+				terms = append(terms, fmt.Sprintf(`"%s"`, strings.Join(words, " ")))
+			}
+		} else {
+			for _, term := range strings.Fields(alternative) {
+				terms = append(terms, formatTerm(term, fuzziness))
+			}
+		}
+		if len(terms) == 0 {
+			continue
+		}
+
+		expression := strings.Join(terms, " ")
+		if len(terms) > 1 {
+			expression = "(" + expression + ")"
+		}
+		if negated {
+			expression = "-" + expression
+		}
+		alternatives = append(alternatives, expression)
+	}
+	if len(alternatives) > 1 {
+		return "(" + strings.Join(alternatives, " | ") + ")"
+	}
+	return strings.Join(alternatives, "")
+}
+
+// formatTerm formats a single search term according to its kind:
+// a term ending with * (e.g. data*) is a prefix query,
+// a term with * or ? anywhere else (e.g. *base or d?ta) is turned into a wildcard query (e.g. w'*base'),
+// any other term is surrounded by as many % as the allowed Levenshtein distance when fuzziness is set (e.g. %%term%%)
+// The term is escaped so that it can't alter the structure of the query.
+func formatTerm(term string, fuzziness int) string {
+	prefix, isPrefix := strings.CutSuffix(term, "*")
+	switch {
+	case isPrefix && prefix != "" && !strings.ContainsAny(prefix, "*?"):
+		return escapeQueryTerm(prefix, "") + "*"
+	case strings.ContainsAny(term, "*?"):
+		return fmt.Sprintf("w'%s'", escapeQueryTerm(term, "*?"))
+	case fuzziness > 0:
+		fuzzyMarker := strings.Repeat("%", min(fuzziness, MaxFuzziness))
+		return fuzzyMarker + escapeQueryTerm(term, "") + fuzzyMarker
+	default:
+		return escapeQueryTerm(term, "")
+	}
+}
+
+// escapeQueryTerm escapes with a backslash every character of the term that has a meaning in the RediSearch
+// query syntax, i.e. everything that is not a letter, a digit or an underscore, except the characters listed in keep.
+func escapeQueryTerm(term string, keep string) string {
+	var escaped strings.Builder
+	for _, r := range term {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && !strings.ContainsRune(keep, r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// buildHighlightArgs builds the FT.SEARCH arguments returning the document ($), or the values at the given paths, along with
+// the highlighted and summarized fields described by the HighlightOptions
+func buildHighlightArgs(highlight HighlightOptions, paths []string) []any {
+	returnedFields := slices.Clone(highlight.Fields)
+	for _, field := range highlight.SummarizeFields {
+		if !slices.Contains(returnedFields, field) {
+			returnedFields = append(returnedFields, field)
+		}
+	}
+
+	args := buildReturnArgs(paths, returnedFields)
+	if len(highlight.SummarizeFields) > 0 {
+		args = append(args, "SUMMARIZE", "FIELDS", len(highlight.SummarizeFields))
+		for _, field := range highlight.SummarizeFields {
+			args = append(args, field)
+		}
+	}
+	if len(highlight.Fields) > 0 {
+		args = append(args, "HIGHLIGHT", "FIELDS", len(highlight.Fields))
+		for _, field := range highlight.Fields {
+			args = append(args, field)
+		}
+		if highlight.OpenTag != "" || highlight.CloseTag != "" {
+			args = append(args, "TAGS", highlight.OpenTag, highlight.CloseTag)
+		}
+	}
+	return args
+}
+
+// buildReturnArgs builds the RETURN FT.SEARCH option returning the values at the given JSON paths, or the whole
+// documents ($) when there is none, along with the given fields
+func buildReturnArgs(paths []string, fields []string) []any {
+	if len(paths) == 0 {
+		paths = []string{"$"}
+	}
+	args := []any{"RETURN", len(paths) + len(fields)}
+	for _, returned := range slices.Concat(paths, fields) {
+		args = append(args, returned)
+	}
+	return args
+}
+
+// parseHighlights gathers the highlighted fields from the extra_attributes of a search result
+// With DIALECT 3 each value is returned as a JSON array, its elements are joined back into a single string.
+func parseHighlights(attributes map[any]any, highlight HighlightOptions) map[string]string {
+	highlights := make(map[string]string)
+	for _, field := range slices.Concat(highlight.Fields, highlight.SummarizeFields) {
+		value, ok := attributes[field].(string)
+		if !ok {
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal([]byte(value), &values); err == nil {
+			value = strings.Join(values, " ")
+		}
+		highlights[field] = value
+	}
+	return highlights
+}
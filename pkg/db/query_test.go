@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestEscapeQueryTerm(t *testing.T) {
+	tests := []struct {
+		name     string
+		term     string
+		keep     string
+		expected string
+	}{
+		{"empty", "", "", ""},
+		{"word", "redis", "", "redis"},
+		{"letters and digits", "Go_1_22", "", "Go_1_22"},
+		{"non ASCII letters", "été", "", "été"},
+		{"space", "full text", "", `full\ text`},
+		{"dash", "foo-bar", "", `foo\-bar`},
+		{"field query", "@title:x", "", `\@title\:x`},
+		{"tag query", "{a|b}", "", `\{a\|b\}`},
+		{"quote", `"quoted"`, "", `\"quoted\"`},
+		{"backslash", `a\b`, "", `a\\b`},
+		{"kept wildcard", "pre*", "*", "pre*"},
+		{"escaped wildcard", "pre*", "", `pre\*`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if escaped := escapeQueryTerm(test.term, test.keep); escaped != test.expected {
+				t.Errorf("escapeQueryTerm(%q, %q) = %q, expected %q", test.term, test.keep, escaped, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"strconv"
+	"time"
+)
+
+// TokenBucket is the state of a token bucket after a request took a token from it, see TokenBucketTake
+type TokenBucket struct {
+	Allowed    bool          // Allowed reports whether the bucket held a token for the request
+	Remaining  int           // Remaining is the number of whole tokens left in the bucket
+	RetryAfter time.Duration // RetryAfter is the time until the bucket holds a token again, 0 when it still holds one
+	ResetAfter time.Duration // ResetAfter is the time until the bucket is full again
+}
+
+// tokenBucketScript takes a token from the bucket stored as a hash at KEYS[1], holding up to ARGV[1] tokens and refilled
+// with ARGV[2] tokens per second, the bucket being full when missing. The clock of Redis is used, so that the buckets
+// are shared by instances whose clocks drift. The bucket expires once it would be full again. It returns 1 when a token
+// has been taken (0 otherwise) and the tokens left, as a string not to be truncated to an integer.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(bucket[1]) or capacity
+local at = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - at) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}`)
+
+// TokenBucketTake atomically takes a token from the token bucket stored at key, holding up to capacity tokens and
+// refilled with rate tokens per second, and returns its state.
+func TokenBucketTake(ctx context.Context, redisClient *redis.Client, key string, capacity int, rate float64) (TokenBucket, error) {
+	result, err := tokenBucketScript.Run(ctx, redisClient, []string{key}, capacity, strconv.FormatFloat(rate, 'f', -1, 64)).Slice()
+	if err != nil {
+		return TokenBucket{}, err
+	}
+	allowed, _ := result[0].(int64)
+	tokensString, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(tokensString, 64)
+	if err != nil {
+		return TokenBucket{}, err
+	}
+
+	bucket := TokenBucket{
+		Allowed:    allowed == 1,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(capacity) - tokens) / rate * float64(time.Second)),
+	}
+	if !bucket.Allowed {
+		bucket.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return bucket, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// A scheduled record is stored in a hash, by ID, while its ID is held by a sorted set scored by the time it is due,
+// so that the due records can be claimed by any instance of a service (see SortedSetClaimByScore).
+
+// scheduleNXScript stores ARGV[2] at the field ARGV[1] of the hash KEYS[1] unless it exists, and then adds the field
+// to the sorted set KEYS[2] with the score ARGV[3]. It returns 1 when the record has been stored, 0 otherwise.
+var scheduleNXScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[1])
+return 1`)
+
+// ScheduleRecordNX atomically stores a record at the field id of the hash key, unless there is already one, and
+// schedules it at score in the sorted set scheduleKey. It returns whether the record has been stored, so that the same
+// record can be scheduled by several callers and is only scheduled once.
+func ScheduleRecordNX(ctx context.Context, redisClient *redis.Client, key string, scheduleKey string, id string, record any, score float64) (bool, error) {
+	stored, err := scheduleNXScript.Run(ctx, redisClient, []string{key, scheduleKey}, id, record, strconv.FormatFloat(score, 'f', -1, 64)).Int()
+	return stored == 1, err
+}
+
+// ScheduleRecord stores a record at the field id of the hash key and schedules it at score in the sorted set
+// scheduleKey, in a transaction.
+func ScheduleRecord(ctx context.Context, redisClient *redis.Client, key string, scheduleKey string, id string, record any, score float64) error {
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, id, record)
+		pipe.ZAdd(ctx, scheduleKey, redis.Z{Score: score, Member: id})
+		return nil
+	})
+	return err
+}
+
+// UnscheduleRecord deletes the record at the field id of the hash key along with its schedule in the sorted set
+// scheduleKey, in a transaction.
+func UnscheduleRecord(ctx context.Context, redisClient *redis.Client, key string, scheduleKey string, id string) error {
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, key, id)
+		pipe.ZRem(ctx, scheduleKey, id)
+		return nil
+	})
+	return err
+}
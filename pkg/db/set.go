@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+// SetAdd adds a member to a set using SADD, it returns whether the member was added (false when already there)
+func SetAdd(ctx context.Context, redisClient *redis.Client, key string, member string) (bool, error) {
+	added, err := redisClient.SAdd(ctx, key, member).Result()
+	return added > 0, err
+}
+
+// SetRem removes a member from a set using SREM, it returns whether the member was there
+func SetRem(ctx context.Context, redisClient *redis.Client, key string, member string) (bool, error) {
+	removed, err := redisClient.SRem(ctx, key, member).Result()
+	return removed > 0, err
+}
+
+// SetMembers returns the members of a set using SMEMBERS
+func SetMembers(ctx context.Context, redisClient *redis.Client, key string) ([]string, error) {
+	return redisClient.SMembers(ctx, key).Result()
+}
+
+// SetIsMember reports whether member is in a set using SISMEMBER
+func SetIsMember(ctx context.Context, redisClient *redis.Client, key string, member string) (bool, error) {
+	return redisClient.SIsMember(ctx, key, member).Result()
+}
+
+// SetCard returns the number of members of a set using SCARD, 0 when the set does not exist
+func SetCard(ctx context.Context, redisClient *redis.Client, key string) (int64, error) {
+	return redisClient.SCard(ctx, key).Result()
+}
+
+// setMemberCountScript adds ARGV[2] to the set KEYS[1] (or removes it when ARGV[1] is 0), then stores the number of
+// members of the set at the path ARGV[3] of the JSON document KEYS[2], provided that the document exists. It returns the
+// number of members, -1 when the document does not exist.
+var setMemberCountScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	return -1
+end
+if ARGV[1] == "1" then
+	redis.call("SADD", KEYS[1], ARGV[2])
+else
+	redis.call("SREM", KEYS[1], ARGV[2])
+end
+local count = redis.call("SCARD", KEYS[1])
+redis.call("JSON.SET", KEYS[2], ARGV[3], tostring(count))
+return count`)
+
+// SetUpdateAndCount adds a member to a set (or removes it when add is false) and stores the number of members of the
+// set at countPath (e.g. $.likes) of the JSON document documentKey, atomically using a Lua script, so that concurrent
+// updates can't store a stale count. It returns the number of members, or an error wrapping ErrNotFound when the
+// document does not exist, the set being left unchanged.
+func SetUpdateAndCount(ctx context.Context, redisClient *redis.Client, key string, member string, add bool, documentKey string, countPath string) (int64, error) {
+	addArg := 0
+	if add {
+		addArg = 1
+	}
+	count, err := setMemberCountScript.Run(ctx, redisClient, []string{key, documentKey}, addArg, member, countPath).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, documentKey)
+	}
+	return count, nil
+}
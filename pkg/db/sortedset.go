@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// ScoredMember is a member of a sorted set along with its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// popByScoreScript removes and returns up to ARGV[2] members of the sorted set KEYS[1] whose score is at most ARGV[1]
+var popByScoreScript = redis.NewScript(`
+local members = redis.call("ZRANGE", KEYS[1], "-inf", ARGV[1], "BYSCORE", "LIMIT", 0, ARGV[2])
+if #members > 0 then
+	redis.call("ZREM", KEYS[1], unpack(members))
+end
+return members`)
+
+// claimByScoreScript returns up to ARGV[2] members of the sorted set KEYS[1] whose score is at most ARGV[1], setting
+// their score to ARGV[3]
+var claimByScoreScript = redis.NewScript(`
+local members = redis.call("ZRANGE", KEYS[1], "-inf", ARGV[1], "BYSCORE", "LIMIT", 0, ARGV[2])
+for _, member in ipairs(members) do
+	redis.call("ZADD", KEYS[1], "XX", ARGV[3], member)
+end
+return members`)
+
+// SortedSetAdd adds a member with the given score to a sorted set, or updates its score, using ZADD
+func SortedSetAdd(ctx context.Context, redisClient *redis.Client, key string, member string, score float64) error {
+	return redisClient.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+// SortedSetRem removes a member from a sorted set using ZREM
+func SortedSetRem(ctx context.Context, redisClient *redis.Client, key string, member string) error {
+	return redisClient.ZRem(ctx, key, member).Err()
+}
+
+// SortedSetRange returns the members of a sorted set, along with their score, by increasing score using ZRANGE WITHSCORES
+func SortedSetRange(ctx context.Context, redisClient *redis.Client, key string) ([]ScoredMember, error) {
+	result, err := redisClient.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ScoredMember, 0, len(result))
+	for _, z := range result {
+		member, _ := z.Member.(string)
+		members = append(members, ScoredMember{Member: member, Score: z.Score})
+	}
+	return members, nil
+}
+
+// SortedSetPopByScore atomically removes and returns up to count members of a sorted set whose score is at most maxScore,
+// by increasing score. As the members are removed, each of them is returned to a single caller.
+func SortedSetPopByScore(ctx context.Context, redisClient *redis.Client, key string, maxScore float64, count int) ([]string, error) {
+	return popByScoreScript.Run(ctx, redisClient, []string{key}, strconv.FormatFloat(maxScore, 'f', -1, 64), count).StringSlice()
+}
+
+// SortedSetClaimByScore atomically returns up to count members of a sorted set whose score is at most maxScore, by
+// increasing score, setting their score to leaseScore. Unlike SortedSetPopByScore, the members are kept: a member
+// claimed by a caller is returned again once leaseScore is reached, unless the caller removes it or changes its score
+// meanwhile, so that a member is never lost when its caller dies.
+func SortedSetClaimByScore(ctx context.Context, redisClient *redis.Client, key string, maxScore float64, count int, leaseScore float64) ([]string, error) {
+	return claimByScoreScript.Run(ctx, redisClient, []string{key}, strconv.FormatFloat(maxScore, 'f', -1, 64), count, strconv.FormatFloat(leaseScore, 'f', -1, 64)).StringSlice()
+}
+
+// SortedSetIncrBy increments the score of a member of a sorted set using ZINCRBY, the member being added when missing,
+// and returns its new score. The sorted set expires after ttl (no expiration when 0), the expiration being renewed.
+func SortedSetIncrBy(ctx context.Context, redisClient *redis.Client, key string, member string, increment float64, ttl time.Duration) (float64, error) {
+	var score *redis.FloatCmd
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		score = pipe.ZIncrBy(ctx, key, increment, member)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return score.Val(), nil
+}
+
+// SortedSetUnionTop returns the count members with the highest scores of the union of sorted sets, their scores being
+// summed, by decreasing score. The union is stored at destination using ZUNIONSTORE while it is ranked, then deleted.
+func SortedSetUnionTop(ctx context.Context, redisClient *redis.Client, keys []string, destination string, count int) ([]ScoredMember, error) {
+	var top *redis.ZSliceCmd
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, destination, &redis.ZStore{Keys: keys, Aggregate: "SUM"})
+		top = pipe.ZRevRangeWithScores(ctx, destination, 0, int64(count-1))
+		pipe.Del(ctx, destination)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ScoredMember, 0, len(top.Val()))
+	for _, z := range top.Val() {
+		member, _ := z.Member.(string)
+		members = append(members, ScoredMember{Member: member, Score: z.Score})
+	}
+	return members, nil
+}
+
+// SortedSetRevRange returns the members of a sorted set from start to stop (both inclusive, -1 being the last one),
+// along with their score, by decreasing score using ZRANGE REV WITHSCORES
+func SortedSetRevRange(ctx context.Context, redisClient *redis.Client, key string, start int64, stop int64) ([]ScoredMember, error) {
+	result, err := redisClient.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ScoredMember, 0, len(result))
+	for _, z := range result {
+		member, _ := z.Member.(string)
+		members = append(members, ScoredMember{Member: member, Score: z.Score})
+	}
+	return members, nil
+}
+
+// SortedSetCard returns the number of members of a sorted set using ZCARD, 0 when the sorted set does not exist
+func SortedSetCard(ctx context.Context, redisClient *redis.Client, key string) (int64, error) {
+	return redisClient.ZCard(ctx, key).Result()
+}
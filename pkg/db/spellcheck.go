@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sort"
+)
+
+// Spellcheck perform a FT.SPELLCHECK on the given index for the query built from the list of SearchParams.
+// It returns, for each misspelled term of the query, the corrections found in the index within the given
+// Levenshtein distance (1 to 4), the most relevant first.
+func Spellcheck(ctx context.Context, redisClient *redis.Client, indexName string, filters []SearchParams, options SearchOptions, distance int) (map[string][]Suggestion, error) {
+	queries := []any{"FT.SPELLCHECK", indexName, buildQuery(filters, options), "DISTANCE", distance, "DIALECT", "3"}
+
+	/*
+		Run query FT.SPELLCHECK https://redis.io/commands/ft.spellcheck/
+		Results on FT.SPELLCHECK returns map[interface{}]interface{}
+		that looks like:
+		map[results:map[reids:[map[redis:0.5] map[reds:0.25]]]]
+	*/
+	redisSpellResult, err := redisClient.Do(ctx, queries...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	topLevel, ok := redisSpellResult.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("response returned when running this spellcheck is not a valid map structure")
+	}
+	results, ok := topLevel["results"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("result from the spellcheck is not a valid map structure")
+	}
+
+	corrections := make(map[string][]Suggestion, len(results))
+	for term, termSuggestions := range results {
+		suggestionsList, ok := termSuggestions.([]any)
+		if !ok {
+			return nil, fmt.Errorf("spellcheck suggestions of %v are not a valid List of Interfaces", term)
+		}
+		var suggestions []Suggestion
+		for _, eachSuggestion := range suggestionsList {
+			suggestion, ok := eachSuggestion.(map[interface{}]interface{})
+			if !ok {
+				return nil, fmt.Errorf("spellcheck suggestion of %v is in invalid format", term)
+			}
+			for value, score := range suggestion {
+				scoreFloat, _ := score.(float64)
+				suggestions = append(suggestions, Suggestion{Value: fmt.Sprint(value), Score: scoreFloat})
+			}
+		}
+		sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+		if len(suggestions) > 0 {
+			corrections[fmt.Sprint(term)] = suggestions
+		}
+	}
+	return corrections, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamAdd appends an entry made of the given fields to a stream using XADD, the stream being trimmed to about maxLen
+// entries (the oldest ones being evicted, no trimming when maxLen is 0). It returns the ID of the entry.
+func StreamAdd(ctx context.Context, redisClient *redis.Client, stream string, maxLen int64, values map[string]any) (string, error) {
+	return redisClient.XAdd(ctx, &redis.XAddArgs{Stream: stream, MaxLen: maxLen, Approx: true, Values: values}).Result()
+}
+
+// StreamEntry is an entry of a stream along with its ID
+type StreamEntry struct {
+	Id     string
+	Values map[string]string
+}
+
+// StreamRevRange returns up to count entries of a stream whose ID is between start and end (both included, - and +
+// meaning the first and the last entries), the latest first, using XREVRANGE
+func StreamRevRange(ctx context.Context, redisClient *redis.Client, stream string, end string, start string, count int64) ([]StreamEntry, error) {
+	messages, err := redisClient.XRevRangeN(ctx, stream, end, start, count).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StreamEntry, 0, len(messages))
+	for _, message := range messages {
+		values := make(map[string]string, len(message.Values))
+		for field, value := range message.Values {
+			values[field], _ = value.(string)
+		}
+		entries = append(entries, StreamEntry{Id: message.ID, Values: values})
+	}
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// Suggestion is an entry of a suggestions dictionary along with its score
+type Suggestion struct {
+	Value string
+	Score float64
+}
+
+// SuggestionAdd adds a value to the given suggestions dictionary using FT.SUGADD
+// It returns the size of the dictionary after the addition.
+func SuggestionAdd(ctx context.Context, redisClient *redis.Client, dictionary string, value string, score float64) (int64, error) {
+	return redisClient.Do(ctx, "FT.SUGADD", dictionary, value, score).Int64()
+}
+
+// SuggestionDel removes a value from the given suggestions dictionary using FT.SUGDEL
+// It returns true when the value was part of the dictionary.
+func SuggestionDel(ctx context.Context, redisClient *redis.Client, dictionary string, value string) (bool, error) {
+	deleted, err := redisClient.Do(ctx, "FT.SUGDEL", dictionary, value).Int64()
+	return deleted == 1, err
+}
+
+// SuggestionGet returns up to max values of the given suggestions dictionary completing the prefix, using FT.SUGGET
+// The values are ranked by score, the best first. When fuzzy is set, prefixes within a Levenshtein distance of 1 are matched as well.
+func SuggestionGet(ctx context.Context, redisClient *redis.Client, dictionary string, prefix string, fuzzy bool, max int) ([]Suggestion, error) {
+	queries := []any{"FT.SUGGET", dictionary, prefix}
+	if fuzzy {
+		queries = append(queries, "FUZZY")
+	}
+	queries = append(queries, "WITHSCORES", "MAX", max)
+
+	// FT.SUGGET WITHSCORES returns a flat list alternating each value and its score
+	result, err := redisClient.Do(ctx, queries...).Slice()
+	if err == redis.Nil {
+		return []Suggestion{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result)%2 != 0 {
+		return nil, fmt.Errorf("suggestions returned are not a list of value and score")
+	}
+
+	suggestions := make([]Suggestion, 0, len(result)/2)
+	for i := 0; i < len(result); i += 2 {
+		score, err := strconv.ParseFloat(fmt.Sprint(result[i+1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("score of suggestion %v is not a valid number", result[i])
+		}
+		suggestions = append(suggestions, Suggestion{Value: fmt.Sprint(result[i]), Score: score})
+	}
+	return suggestions, nil
+}
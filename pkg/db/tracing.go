@@ -0,0 +1,14 @@
+package db
+
+import (
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// Instrument traces every command sent to Redis by dbClient (including the raw ones such as FT.SEARCH) as a client
+// span named after the command, within the span of the context of the command, with the tracer provider registered
+// with OpenTelemetry. A pipeline or transaction is traced as a single span. The arguments of the commands are left out
+// of the spans, as they hold the content of the articles.
+func Instrument(dbClient DbClient) error {
+	return redisotel.InstrumentTracing((*redis.Client)(dbClient), redisotel.WithDBStatement(false))
+}
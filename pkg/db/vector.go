@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+// vectorDistanceField is the name given to the distance of each result of a KNNSearch
+const vectorDistanceField = "__vector_distance"
+
+// KNNSearch perform a FT.SEARCH returning the k documents whose vector field is the closest to the given vector blob
+// (e.g. little-endian FLOAT32 values), among the documents matching the list of SearchParams.
+// The results are sorted by Distance, the closest first, SortBy and Offset/Limit of the SearchOptions being ignored.
+func KNNSearch[T any](ctx context.Context, redisClient *redis.Client, indexName string, vectorField string, vector []byte, k int, filters []SearchParams, options SearchOptions) (SearchResult[T], error) {
+	filterQuery := buildQuery(filters, options)
+	if filterQuery != "*" {
+		filterQuery = "(" + filterQuery + ")"
+	}
+	query := fmt.Sprintf("%s=>[KNN %d @%s $vector AS %s]", filterQuery, k, vectorField, vectorDistanceField)
+
+	options.SortBy = vectorDistanceField
+	options.SortDescending = false
+	options.Offset, options.Limit = 0, k
+	options.distanceField = vectorDistanceField
+	return runSearch[T](ctx, redisClient, indexName, query, options, "PARAMS", 2, "vector", vector)
+}
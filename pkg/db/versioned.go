@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrNotFound is returned when a key expected to exist does not
+	ErrNotFound = errors.New("key not found")
+	// ErrVersionMismatch is returned when the version stored in a JSON document is not the expected one
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+// VersionedJSONSet is a JSON document to set at the root of Key, provided the version it holds is Version,
+// a Version of 0 meaning that Key must not exist (or hold a document without version).
+// When HistoryKey is set, History is appended as JSON to the list at HistoryKey along with the document
+// (e.g. the version of the document being replaced).
+// Key expires at ExpireAt, as a Unix timestamp in seconds, or is made persistent when ExpireAt is 0.
+type VersionedJSONSet struct {
+	Key        string
+	Version    int64
+	Value      any
+	HistoryKey string
+	History    any
+	ExpireAt   int64
+}
+
+// versionedSetScript sets the JSON documents of the first half of KEYS when all of them hold their expected version at
+// the version path ARGV[1], ARGV holding then the expected version, the document, the history entry and the expiration
+// time of each key. The history entry of a document, unless empty, is appended to the list of the second half of KEYS
+// at the same position, and the key expires at its expiration time with EXPIREAT, or is made persistent when it is 0.
+// It returns the status (1 when the documents are set, 0 on a version mismatch, -1 on a missing key) along with the
+// position of the failing key.
+var versionedSetScript = redis.NewScript(`
+local count = #KEYS / 2
+for i = 1, count do
+	local expected = tonumber(ARGV[i * 4 - 2])
+	local current = redis.call("JSON.GET", KEYS[i], ARGV[1])
+	if not current then
+		if expected ~= 0 then
+			return {-1, i}
+		end
+	elseif (cjson.decode(current)[1] or 0) ~= expected then
+		return {0, i}
+	end
+end
+for i = 1, count do
+	redis.call("JSON.SET", KEYS[i], "$", ARGV[i * 4 - 1])
+	if ARGV[i * 4] ~= "" then
+		redis.call("RPUSH", KEYS[count + i], ARGV[i * 4])
+	end
+	if tonumber(ARGV[i * 4 + 1]) > 0 then
+		redis.call("EXPIREAT", KEYS[i], ARGV[i * 4 + 1])
+	else
+		redis.call("PERSIST", KEYS[i])
+	end
+end
+return {1, 0}`)
+
+// JSONMSetIfVersion sets all the given JSON documents provided that each of them holds its expected version at versionPath
+// (e.g. $.version), atomically using a Lua script: either all the documents are set, along with their history entries
+// and their expiration, or none of them. The returned error wraps ErrNotFound or ErrVersionMismatch, along with the failing key, when a check fails.
+func JSONMSetIfVersion(ctx context.Context, redisClient *redis.Client, versionPath string, sets []VersionedJSONSet) error {
+	keys := make([]string, 0, len(sets))
+	historyKeys := make([]string, 0, len(sets))
+	args := []any{versionPath}
+	for _, set := range sets {
+		value, err := json.Marshal(set.Value)
+		if err != nil {
+			return err
+		}
+		var history []byte
+		historyKey := set.Key
+		if set.HistoryKey != "" {
+			if history, err = json.Marshal(set.History); err != nil {
+				return err
+			}
+			historyKey = set.HistoryKey
+		}
+		keys = append(keys, set.Key)
+		historyKeys = append(historyKeys, historyKey)
+		args = append(args, set.Version, value, history, set.ExpireAt)
+	}
+
+	result, err := versionedSetScript.Run(ctx, redisClient, append(keys, historyKeys...), args...).Int64Slice()
+	if err != nil {
+		return err
+	}
+	if len(result) != 2 {
+		return fmt.Errorf("unexpected result %v", result)
+	}
+	switch result[0] {
+	case 1:
+		return nil
+	case -1:
+		return fmt.Errorf("%w: %s", ErrNotFound, keys[result[1]-1])
+	default:
+		return fmt.Errorf("%w: %s", ErrVersionMismatch, keys[result[1]-1])
+	}
+}
+
+// JSONSetIfVersion sets a JSON document provided that it holds the expected version at versionPath, see JSONMSetIfVersion
+func JSONSetIfVersion(ctx context.Context, redisClient *redis.Client, versionPath string, key string, version int64, value any) error {
+	return JSONMSetIfVersion(ctx, redisClient, versionPath, []VersionedJSONSet{{Key: key, Version: version, Value: value}})
+}
@@ -0,0 +1,81 @@
+// Package embedding provides primitives for turning texts into vectors (embeddings) used by semantic searches
+package embedding
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns a text into a vector of a fixed number of dimensions, texts with close meanings having close vectors.
+type Embedder interface {
+	// Embed returns the vector of the given text.
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// Dimensions returns the number of dimensions of the vectors returned by Embed.
+	Dimensions() int
+}
+
+// HashingEmbedder is an Embedder that needs no external service: it hashes each word of a text into one of the
+// dimensions of the vector (feature hashing). Texts sharing words get close vectors, which makes it a lexical
+// rather than a truly semantic Embedder, suitable as a default or for testing.
+type HashingEmbedder struct {
+	dimensions int
+}
+
+// NewHashingEmbedder creates a new HashingEmbedder returning vectors of the given number of dimensions.
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+// Dimensions returns the number of dimensions of the vectors returned by Embed.
+func (e *HashingEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Embed returns the L2 normalized vector of the given text, each word adding (or subtracting, depending on its hash)
+// one to the dimension it is hashed to.
+func (e *HashingEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(word))
+		sum := hash.Sum64()
+		if sum>>63 == 1 {
+			vector[sum%uint64(e.dimensions)]--
+		} else {
+			vector[sum%uint64(e.dimensions)]++
+		}
+	}
+	return Normalize(vector), nil
+}
+
+// Normalize scales the vector in place to a length of 1 and returns it, a zero vector is returned unchanged.
+func Normalize(vector []float32) []float32 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// ToBytes encodes the vector as the little-endian FLOAT32 blob expected by Redis vector fields.
+func ToBytes(vector []float32) []byte {
+	blob := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(value))
+	}
+	return blob
+}
@@ -0,0 +1,71 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HTTPEmbedder is an Embedder delegating to an external embeddings API compatible with the OpenAI embeddings endpoint,
+// i.e. a POST of {"model": ..., "input": ...} answered with {"data": [{"embedding": [...]}]}.
+type HTTPEmbedder struct {
+	url        string
+	model      string
+	apiKey     string
+	dimensions int
+	httpClient *http.Client
+}
+
+// NewHTTPEmbedder creates a new HTTPEmbedder calling the given URL with the given model.
+// The apiKey, when not empty, is sent as a bearer token. dimensions must match the vectors returned by the model.
+func NewHTTPEmbedder(url string, model string, apiKey string, dimensions int, httpClient *http.Client) *HTTPEmbedder {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &HTTPEmbedder{url: url, model: model, apiKey: apiKey, dimensions: dimensions, httpClient: httpClient}
+}
+
+// Dimensions returns the number of dimensions of the vectors returned by Embed.
+func (e *HTTPEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Embed returns the vector of the given text as computed by the embeddings API.
+func (e *HTTPEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(map[string]string{"model": e.model, "input": text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API returned status %s", resp.Status)
+	}
+
+	var embeddings struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("embeddings API response is not in the expected format: %v", err)
+	}
+	if len(embeddings.Data) == 0 || len(embeddings.Data[0].Embedding) != e.dimensions {
+		return nil, fmt.Errorf("embeddings API did not return a vector of %d dimensions", e.dimensions)
+	}
+	return embeddings.Data[0].Embedding, nil
+}
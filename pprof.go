@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/pprof"
+)
+
+// pprofPath is the path of the index of the profiles of the service, mounted when Config.Pprof is enabled. Being an
+// /admin route, it requires the admin role or scope (see requiredRole).
+const pprofPath = "/admin/debug/pprof/"
+
+// checkPprofAuthentication returns an error when Config.Pprof is enabled without authentication, the profiles exposing
+// the internals of the service to anyone otherwise.
+func checkPprofAuthentication() error {
+	if config.Pprof && !authenticationEnabled() {
+		return errors.New("the profiling endpoints require the authentication, with bearer tokens or API keys")
+	}
+	return nil
+}
+
+// registerPprof mounts the net/http/pprof handlers under pprofPath, e.g. /admin/debug/pprof/profile?seconds=30 for a
+// CPU profile or /admin/debug/pprof/heap for a heap profile, to be read with go tool pprof.
+func registerPprof(mux *http.ServeMux) {
+	// The handlers expect their /debug/pprof/ path, the profiles being named after it.
+	withoutAdmin := func(handler http.HandlerFunc) http.Handler {
+		return http.StripPrefix("/admin", handler)
+	}
+	mux.Handle("GET "+pprofPath, withoutAdmin(pprof.Index))
+	mux.Handle("GET "+pprofPath+"cmdline", withoutAdmin(pprof.Cmdline))
+	mux.Handle("GET "+pprofPath+"profile", withoutAdmin(pprof.Profile))
+	mux.Handle("GET "+pprofPath+"symbol", withoutAdmin(pprof.Symbol))
+	mux.Handle("POST "+pprofPath+"symbol", withoutAdmin(pprof.Symbol))
+	mux.Handle("GET "+pprofPath+"trace", withoutAdmin(pprof.Trace))
+}
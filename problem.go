@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+const (
+	// problemMediaType is the media type of the error responses, see Problem.
+	problemMediaType = "application/problem+json"
+	// problemErrorFormat and legacyErrorFormat are the formats of the error responses, see Config.ErrorFormat.
+	problemErrorFormat = "problem"
+	legacyErrorFormat  = "legacy"
+)
+
+// Problem is an error response following RFC 7807 (Problem Details for HTTP APIs), sent as application/problem+json.
+type Problem struct {
+	Type     string    `json:"type"`               // Type is a URI reference identifying the kind of problem, about:blank when it is only described by Status.
+	Title    string    `json:"title"`              // Title is the summary of the problem.
+	Status   int       `json:"status"`             // Status is the HTTP status code of the response.
+	Detail   string    `json:"detail,omitempty"`   // Detail is the explanation specific to this occurrence of the problem.
+	Instance string    `json:"instance,omitempty"` // Instance is the URI reference of the request the problem occurred on.
+	Code     ErrorCode `json:"code"`               // Code is the machine-readable code of the problem (an extension member), see ErrorCode.
+	// RequestId is the ID of the request the problem occurred on (an extension member), see withRequestID.
+	RequestId string `json:"requestId,omitempty"`
+	// Errors describes the fields which failed the validation (an extension member), when the problem is a failed validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// problemWriter is an http.ResponseWriter knowing the request it responds to, so that the errors can tell their instance.
+type problemWriter struct {
+	http.ResponseWriter
+	instance  string // instance is the URI reference of the request.
+	requestId string // requestId is the ID of the request, see withRequestID.
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (pw *problemWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// Hijack lets the handler take over the connection (e.g. for WebSocket), through the underlying http.ResponseWriter.
+func (pw *problemWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(pw.ResponseWriter).Hijack()
+}
+
+// withProblemDetails lets handleError report the request URI as the instance of the problems it responds with, along
+// with the ID of the request.
+func withProblemDetails(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(&problemWriter{ResponseWriter: w, instance: r.URL.RequestURI(), requestId: requestIDOf(r.Context())}, r)
+	})
+}
+
+// problemRequest returns the URI reference and the ID of the request w responds to, as known by the problemWriter it
+// wraps, empty when there is none.
+func problemRequest(w http.ResponseWriter) (string, string) {
+	for {
+		switch writer := w.(type) {
+		case *problemWriter:
+			return writer.instance, writer.requestId
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return "", ""
+		}
+	}
+}
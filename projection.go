@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// fieldsParam is the query parameter listing the Article fields to return, e.g. fields=id,title,tags.
+	fieldsParam = "fields"
+	// includeParam is the query parameter listing the heavy Article fields to add to the summaries of a listing, e.g. include=content.
+	includeParam = "include"
+)
+
+// heavyFields lists the Article fields left out of the article summaries returned by listings unless included.
+var heavyFields = []string{"content"}
+
+// articleFieldNames returns the JSON names of all the Article fields.
+func articleFieldNames() []string {
+	var names []string
+	articleType := reflect.TypeOf(Article{})
+	for i := 0; i < articleType.NumField(); i++ {
+		name, _, _ := strings.Cut(articleType.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseFieldsParam reads the fields query parameter, a comma separated list of Article fields.
+// It returns nil when the parameter is not provided, meaning that all the fields are returned.
+func parseFieldsParam(queryParams url.Values) ([]string, error) {
+	if !queryParams.Has(fieldsParam) {
+		return nil, nil
+	}
+	names := articleFieldNames()
+	var fields []string
+	for _, field := range strings.Split(queryParams.Get(fieldsParam), ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(names, field) {
+			return nil, fmt.Errorf("fields must be a comma separated list of the following fields: %v", names)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parseListingFieldsParams reads the fields and include query parameters of a listing and returns the Article fields
+// to return for each article: the fields parameter when provided, otherwise the summary of the articles (all the fields
+// but the heavyFields) along with the heavy fields listed by the include parameter. nil means that all the fields are returned.
+func parseListingFieldsParams(queryParams url.Values) ([]string, error) {
+	fields, err := parseFieldsParam(queryParams)
+	if err != nil || fields != nil {
+		return fields, err
+	}
+	var included []string
+	if queryParams.Has(includeParam) {
+		for _, field := range strings.Split(queryParams.Get(includeParam), ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(heavyFields, field) {
+				return nil, fmt.Errorf("include must be a comma separated list of the following fields: %v", heavyFields)
+			}
+			included = append(included, field)
+		}
+	}
+	if len(included) == len(heavyFields) {
+		return nil, nil
+	}
+	return slices.DeleteFunc(articleFieldNames(), func(name string) bool {
+		return slices.Contains(heavyFields, name) && !slices.Contains(included, name)
+	}), nil
+}
+
+// articleFieldPaths returns the JSON paths of the given Article fields in the stored articles, nil when fields is nil.
+func articleFieldPaths(fields []string) []string {
+	var paths []string
+	for _, field := range fields {
+		paths = append(paths, "$."+field)
+	}
+	return paths
+}
+
+// fetchArticleFields retrieves the articles stored at the given keys like fetchArticles, but only with the given
+// Article fields, retrieved with db.JSONMGetPaths so that the other fields are never sent by the database.
+func fetchArticleFields(ctx context.Context, keys []string, fields []string) ([]Article, error) {
+	if fields == nil {
+		return fetchArticles(ctx, keys)
+	}
+	return db.JSONMGetPaths[Article](ctx, databaseClient, keys, articleFieldPaths(fields))
+}
+
+// projectFields returns v, an article or a page of articles, with only the given Article fields kept in each article.
+// Members which are not Article fields (e.g. the score of a search hit or the paging metadata) are kept as is.
+// v is returned unchanged when fields is nil.
+func projectFields(v any, fields []string) (any, error) {
+	if fields == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	excluded := slices.DeleteFunc(articleFieldNames(), func(name string) bool { return slices.Contains(fields, name) })
+	articles, isPage := document["articles"].([]any)
+	if !isPage {
+		articles = []any{document}
+	}
+	for _, article := range articles {
+		if article, isObject := article.(map[string]any); isObject {
+			for _, name := range excluded {
+				delete(article, name)
+			}
+		}
+	}
+	return document, nil
+}
+
+// responseProjectedJSON sends v like responseJSONWithETag, with only the given Article fields (see projectFields).
+func responseProjectedJSON(w http.ResponseWriter, r *http.Request, v any, fields []string, statusCode int) {
+	projected, err := projectFields(v, fields)
+	if err != nil {
+		handleError(w, "Failed to select the requested fields", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSONWithETag(w, r, projected, statusCode)
+}
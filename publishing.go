@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// The statuses of an Article, an Article without status being published.
+const (
+	statusDraft     = "draft"
+	statusPublished = "published"
+)
+
+const (
+	// publicationScheduleKey is the key of the sorted set of the articles IDs scheduled for publication, scored by publishAt.
+	publicationScheduleKey = "schedule:articles:publication"
+	// publicationCheckInterval is the interval at which the scheduler publishes the articles whose time has come.
+	publicationCheckInterval = 10 * time.Second
+	// publicationBatchSize is the maximum number of articles published at once by the scheduler.
+	publicationBatchSize = 100
+)
+
+// ScheduledPublication represents an article waiting for its publication.
+type ScheduledPublication struct {
+	Id        string `json:"id"`        // Id is the ID of the article to publish.
+	PublishAt int64  `json:"publishAt"` // PublishAt is the time the article is published, as a Unix timestamp in seconds.
+}
+
+// setArticlePublication makes an article with a publishAt time a draft until it is published by the scheduler.
+func setArticlePublication(article *Article) {
+	if article.PublishAt != 0 {
+		article.Status = statusDraft
+	}
+}
+
+// canReadDrafts reports whether the actor ctx is authenticated as is allowed to read the drafts, an editor.
+func canReadDrafts(ctx context.Context) bool {
+	actor := authenticatedSubject(ctx)
+	return actor != "" && hasRole(ctx, actor, roleEditor)
+}
+
+// isHiddenDraft reports whether article is a draft the actor of ctx isn't allowed to read (see canReadDrafts), which is
+// then reported as not found.
+func isHiddenDraft(ctx context.Context, article Article) bool {
+	return article.Status == statusDraft && !canReadDrafts(ctx)
+}
+
+// visibleArticlesFilters returns the filters excluding from a search the articles the actor of ctx can't see: the
+// expired ones (see notExpiredFilters) and the drafts, unless the actor can read them (see canReadDrafts).
+func visibleArticlesFilters(ctx context.Context) []db.SearchParams {
+	filters := notExpiredFilters()
+	if !canReadDrafts(ctx) {
+		filters = append(filters, db.SearchParams{Param: "status", Type: db.TagType, Value: []string{statusDraft}, Exclude: true})
+	}
+	return filters
+}
+
+// refreshPublicationSchedule keeps the publication schedule in sync with an article that has been written (or deleted
+// when current is nil), any failure being logged as the schedule can be fixed by writing the article again.
+// ctx is scoped to the tenant of the article.
+func refreshPublicationSchedule(ctx context.Context, id string, current *Article) {
+	var err error
+	if current != nil && current.PublishAt != 0 {
+		err = db.SortedSetAdd(ctx, databaseClient, tenantKey(ctx, publicationScheduleKey), id, float64(current.PublishAt))
+	} else {
+		err = db.SortedSetRem(ctx, databaseClient, tenantKey(ctx, publicationScheduleKey), id)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to update the publication schedule", "id", id, "Error:", err)
+	}
+}
+
+// startPublicationScheduler starts the background goroutine publishing the scheduled articles of every tenant once
+// their time has come.
+func startPublicationScheduler() {
+	go func() {
+		ticker := time.NewTicker(publicationCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, ctx := range tenantContexts() {
+				publishDueArticles(ctx)
+			}
+		}
+	}()
+}
+
+// publishDueArticles publishes the articles of the tenant ctx is scoped to whose publishAt time has come. Each due
+// article is taken off the schedule atomically beforehand, so that it is published once even when several instances of
+// the service are running.
+func publishDueArticles(ctx context.Context) {
+	for {
+		now := time.Now().Unix()
+		ids, err := db.SortedSetPopByScore(ctx, databaseClient, tenantKey(ctx, publicationScheduleKey), float64(now), publicationBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to retrieve the articles to publish", "Error:", err)
+			return
+		}
+		for _, id := range ids {
+			if err := publishArticle(ctx, id, now); err != nil {
+				slog.ErrorContext(ctx, "Unable to publish article, it is scheduled again", "id", id, "Error:", err)
+				if err := db.SortedSetAdd(ctx, databaseClient, tenantKey(ctx, publicationScheduleKey), id, float64(now)); err != nil {
+					slog.ErrorContext(ctx, "Unable to schedule article again", "id", id, "Error:", err)
+				}
+			}
+		}
+		if len(ids) < publicationBatchSize {
+			return
+		}
+	}
+}
+
+// publishArticle flips the draft with the given ID to published, unless it no longer exists or has been rescheduled.
+func publishArticle(ctx context.Context, id string, now int64) error {
+	key := tenantKey(ctx, keysPrefix+id)
+	storedArticle, err := getStoredArticle(ctx, key)
+	if err != nil || storedArticle == nil || storedArticle.PublishAt == 0 || storedArticle.PublishAt > now {
+		return err
+	}
+
+	article := *storedArticle
+	article.Status = statusPublished
+	article.PublishAt = 0
+	setServerManagedFields(&article, storedArticle, schedulerActor)
+	if err := setArticleIfVersion(ctx, key, storedArticle, article); err != nil {
+		return err
+	}
+	articleChanged(ctx, schedulerActor, storedArticle, &article)
+	slog.InfoContext(ctx, "Published scheduled article", "id", id)
+	return nil
+}
+
+// getScheduledPublications returns the articles waiting for their publication, the next to be published first.
+func getScheduledPublications(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	members, err := db.SortedSetRange(ctx, databaseClient, tenantKey(ctx, publicationScheduleKey))
+	if err != nil {
+		handleError(w, "Failed to retrieve the scheduled publications", err, http.StatusInternalServerError)
+		return
+	}
+	publications := make([]ScheduledPublication, 0, len(members))
+	for _, member := range members {
+		publications = append(publications, ScheduledPublication{Id: member.Member, PublishAt: int64(member.Score)})
+	}
+	responseJSON(w, publications, http.StatusOK)
+}
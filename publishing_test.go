@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"github.com/stivesso/articles-search/pkg/db"
+	"slices"
+	"testing"
+)
+
+func TestIsHiddenDraft(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		roles   []string
+		status  string
+		hidden  bool
+	}{
+		{"published to anonymous", "", nil, statusPublished, false},
+		{"without status to anonymous", "", nil, "", false},
+		{"draft to anonymous", "", nil, statusDraft, true},
+		{"draft to reader", "alice", []string{roleReader}, statusDraft, true},
+		{"draft to editor", "bob", []string{roleEditor}, statusDraft, false},
+		{"draft to admin", "carol", []string{roleAdmin}, statusDraft, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.subject != "" {
+				ctx = contextWithRoles(contextWithSubject(ctx, test.subject), test.roles)
+			}
+			if hidden := isHiddenDraft(ctx, Article{Status: test.status}); hidden != test.hidden {
+				t.Errorf("isHiddenDraft() = %v, expected %v", hidden, test.hidden)
+			}
+			excludesDrafts := slices.ContainsFunc(visibleArticlesFilters(ctx), func(filter db.SearchParams) bool {
+				return filter.Param == "status" && filter.Exclude && slices.Contains(filter.Value, statusDraft)
+			})
+			if test.status == statusDraft && excludesDrafts != test.hidden {
+				t.Errorf("visibleArticlesFilters() excludes the drafts = %v, expected %v", excludesDrafts, test.hidden)
+			}
+		})
+	}
+}
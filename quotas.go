@@ -0,0 +1,168 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// quotaKeysPrefix prefixes the keys of the counters of the quotas, shared by all the tenants, followed by the API key,
+// the kind of quota and its period (e.g. quota:<id>:writes:2024-05).
+const quotaKeysPrefix = "quota:"
+
+// usagePath is the path of the usage of the quotas, which is not counted so that it can be checked once they are
+// exhausted.
+const usagePath = "/usage"
+
+// The kinds of requests counted by the quotas.
+const (
+	quotaKindRequests = "requests" // quotaKindRequests counts all the requests.
+	quotaKindWrites   = "writes"   // quotaKindWrites counts the requests changing anything (any method but GET, HEAD and OPTIONS).
+)
+
+// The periods of the quotas, in UTC.
+const (
+	quotaPeriodDay   = "day"   // quotaPeriodDay is the period of the daily quotas.
+	quotaPeriodMonth = "month" // quotaPeriodMonth is the period of the monthly quotas.
+)
+
+// APIKeyQuotas holds the quotas of an API key, each of them being the one of the configuration when 0 (see
+// Config.QuotaDailyRequests).
+type APIKeyQuotas struct {
+	DailyRequests   int `json:"dailyRequests,omitempty" validate:"min=0"`   // DailyRequests is the number of requests allowed per day.
+	MonthlyRequests int `json:"monthlyRequests,omitempty" validate:"min=0"` // MonthlyRequests is the number of requests allowed per month.
+	DailyWrites     int `json:"dailyWrites,omitempty" validate:"min=0"`     // DailyWrites is the number of requests changing anything allowed per day.
+	MonthlyWrites   int `json:"monthlyWrites,omitempty" validate:"min=0"`   // MonthlyWrites is the number of requests changing anything allowed per month.
+}
+
+// QuotaUsage is the usage of a quota of an API key during its current period.
+type QuotaUsage struct {
+	Kind      string `json:"kind"`                // Kind is the kind of requests counted, requests or writes.
+	Period    string `json:"period"`              // Period is the period of the quota, day or month.
+	Limit     int    `json:"limit"`               // Limit is the number of requests allowed per period, no limit applying when 0.
+	Used      int64  `json:"used"`                // Used is the number of requests made during the current period.
+	Remaining *int64 `json:"remaining,omitempty"` // Remaining is the number of requests left for the current period, missing when there is no limit.
+	ResetAt   int64  `json:"resetAt"`             // ResetAt is the time the current period ends, as a Unix timestamp in seconds.
+}
+
+// APIKeyUsage is the usage of the quotas of an API key.
+type APIKeyUsage struct {
+	Id     string       `json:"id"`     // Id is the ID of the API key, empty for the bootstrap key.
+	Name   string       `json:"name"`   // Name is the name of the API key.
+	Quotas []QuotaUsage `json:"quotas"` // Quotas lists the usage of the quotas of the key, the daily ones first.
+}
+
+// quota is a quota of an API key during its current period.
+type quota struct {
+	kind   string    // kind is the kind of requests counted, see quotaKindRequests.
+	period string    // period is the period of the quota, see quotaPeriodDay.
+	limit  int       // limit is the number of requests allowed per period, no limit applying when 0.
+	key    string    // key is the key of the counter of the current period.
+	endsAt time.Time // endsAt is the time the current period ends.
+}
+
+// apiKeyQuotas returns the quotas of apiKey during the periods of now, the daily ones first: its own quotas, or the
+// ones of the configuration.
+func apiKeyQuotas(apiKey APIKey, now time.Time) []quota {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	prefix := quotaKeysPrefix + apiKey.clientId() + ":"
+	dayKey, monthKey := ":"+day.Format(time.DateOnly), ":"+month.Format("2006-01")
+	dayEnd, monthEnd := day.AddDate(0, 0, 1), month.AddDate(0, 1, 0)
+
+	var own APIKeyQuotas
+	if apiKey.Quotas != nil {
+		own = *apiKey.Quotas
+	}
+	return []quota{
+		{quotaKindRequests, quotaPeriodDay, cmp.Or(own.DailyRequests, config.QuotaDailyRequests), prefix + quotaKindRequests + dayKey, dayEnd},
+		{quotaKindWrites, quotaPeriodDay, cmp.Or(own.DailyWrites, config.QuotaDailyWrites), prefix + quotaKindWrites + dayKey, dayEnd},
+		{quotaKindRequests, quotaPeriodMonth, cmp.Or(own.MonthlyRequests, config.QuotaMonthlyRequests), prefix + quotaKindRequests + monthKey, monthEnd},
+		{quotaKindWrites, quotaPeriodMonth, cmp.Or(own.MonthlyWrites, config.QuotaMonthlyWrites), prefix + quotaKindWrites + monthKey, monthEnd},
+	}
+}
+
+// withQuotas enforces the quotas of the API keys (see apiKeyQuotas) on the requests authenticated by one, counting
+// them in the Database so that the quotas are shared by the instances. A request exceeding a quota is answered with
+// an HTTP 429 Too Many Requests error along with a Retry-After header, until the end of the period of the quota, and is
+// not counted. The requests are let through when the Database can't be reached.
+func withQuotas(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, found := authenticatedAPIKey(r.Context())
+		if !found || r.URL.Path == usagePath {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		now := time.Now()
+		var quotas []quota
+		var counters []db.LimitedCounter
+		for _, apiKeyQuota := range apiKeyQuotas(apiKey, now) {
+			if apiKeyQuota.kind == quotaKindWrites && isReadMethod(r.Method) && requiredRole(r) == roleReader {
+				continue
+			}
+			quotas = append(quotas, apiKeyQuota)
+			counters = append(counters, db.LimitedCounter{Key: apiKeyQuota.key, Limit: int64(apiKeyQuota.limit), ExpireAt: apiKeyQuota.endsAt})
+		}
+		exceeded, err := db.CountersIncrWithin(r.Context(), databaseClient, counters)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Unable to check the quotas of the API key, the request is let through", "apiKey", apiKey.clientId(), "Error:", err)
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if exceeded >= 0 {
+			exceededQuota := quotas[exceeded]
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(exceededQuota.endsAt.Sub(now)), 1)))
+			err := fmt.Errorf("the quota of %d %s per %s of the API key is exhausted", exceededQuota.limit, exceededQuota.kind, exceededQuota.period)
+			handleError(w, "Quota exceeded", withErrorCode(ErrorCodeQuotaExceeded, err), http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// getUsage handles GET /usage, returning the usage of the quotas of the API key authenticating the request during
+// their current period. A request which is not authenticated by an API key is answered with an HTTP 401 Unauthorized
+// error.
+func getUsage(w http.ResponseWriter, r *http.Request) {
+	apiKey, found := authenticatedAPIKey(r.Context())
+	if !found {
+		handleError(w, "Authentication required", fmt.Errorf("the usage is the one of the API key of the %s header", apiKeyHeader), http.StatusUnauthorized)
+		return
+	}
+	usage, err := apiKeyUsage(requestContext(r), apiKey)
+	if err != nil {
+		handleError(w, "Failed to retrieve the usage of the API key", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, usage, http.StatusOK)
+}
+
+// apiKeyUsage returns the usage of the quotas of apiKey during their current period.
+func apiKeyUsage(ctx context.Context, apiKey APIKey) (APIKeyUsage, error) {
+	quotas := apiKeyQuotas(apiKey, time.Now())
+	keys := make([]string, len(quotas))
+	for i, apiKeyQuota := range quotas {
+		keys[i] = apiKeyQuota.key
+	}
+	counters, err := db.CountersGet(ctx, databaseClient, keys)
+	if err != nil {
+		return APIKeyUsage{}, err
+	}
+
+	usage := APIKeyUsage{Id: apiKey.Id, Name: apiKey.Name, Quotas: make([]QuotaUsage, len(quotas))}
+	for i, apiKeyQuota := range quotas {
+		quotaUsage := QuotaUsage{Kind: apiKeyQuota.kind, Period: apiKeyQuota.period, Limit: apiKeyQuota.limit, Used: counters[i], ResetAt: apiKeyQuota.endsAt.Unix()}
+		if apiKeyQuota.limit > 0 {
+			remaining := max(int64(apiKeyQuota.limit)-counters[i], 0)
+			quotaUsage.Remaining = &remaining
+		}
+		usage.Quotas[i] = quotaUsage
+	}
+	return usage, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitKeysPrefix prefixes the keys of the token buckets of the rate limiter, shared by all the tenants, followed
+// by the kind of client (key or ip) and its identifier.
+const rateLimitKeysPrefix = "ratelimit:"
+
+// rateLimitKey returns the key of the token bucket of the client of r: its API key when it is authenticated by one (see
+// withAuthentication), its IP address otherwise.
+func rateLimitKey(r *http.Request) string {
+	if apiKey, found := authenticatedAPIKey(r.Context()); found {
+		return rateLimitKeysPrefix + "key:" + apiKey.clientId()
+	}
+	return rateLimitKeysPrefix + "ip:" + clientIP(r)
+}
+
+// withRateLimit limits the requests of each client (see rateLimitKey) to Config.RateLimit per Config.RateLimitWindow
+// when Config.RateLimit is set, with a token bucket stored in the Database so that the limit is shared by the
+// instances. The bucket holds up to Config.RateLimit tokens, each request taking one, and is refilled continuously.
+// The responses hold the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and a
+// request finding the bucket empty is answered with an HTTP 429 Too Many Requests error along with a Retry-After
+// header. The requests are let through when the Database can't be reached.
+func withRateLimit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.RateLimit == 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		rate := float64(config.RateLimit) / config.RateLimitWindow.Seconds()
+		bucket, err := db.TokenBucketTake(r.Context(), databaseClient, rateLimitKey(r), config.RateLimit, rate)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Unable to check the rate limit, the request is let through", "Error:", err)
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(config.RateLimit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(bucket.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(bucket.ResetAfter)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", config.RateLimit, ceilSeconds(config.RateLimitWindow)))
+		if !bucket.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(bucket.RetryAfter), 1)))
+			handleError(w, "Too many requests", fmt.Errorf("the rate limit of %d requests per %s is exceeded", config.RateLimit, config.RateLimitWindow), http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds returns d as a number of seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"golang.org/x/net/html"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// wordCountRangeParams lists the query parameters filtering a search on the number of words of the articles.
+var wordCountRangeParams = []string{"minWords", "maxWords"}
+
+// articleReadingStats returns the number of words of the content of an article, without its markup, and the time it
+// takes to read it in minutes at config.ReadingWordsPerMinute, rounded up.
+func articleReadingStats(article Article) (wordCount int, readingTimeMinutes int) {
+	content := article.Content
+	if article.ContentFormat == markdownContentFormat {
+		if rendered, err := renderArticleContent(article); err == nil {
+			content = rendered
+		}
+	}
+	wordCount = len(strings.Fields(htmlText(content)))
+	readingTimeMinutes = int(math.Ceil(float64(wordCount) / float64(config.ReadingWordsPerMinute)))
+	return wordCount, readingTimeMinutes
+}
+
+// htmlText returns the text of an HTML fragment, the elements being replaced by spaces so that they separate words.
+func htmlText(fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return fragment
+	}
+	var text strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.String()
+		case html.TextToken:
+			text.Write(tokenizer.Text())
+		default:
+			text.WriteByte(' ')
+		}
+	}
+}
+
+// setArticleReadingStats sets the wordCount and readingTimeMinutes of an article about to be written.
+func setArticleReadingStats(article *Article) {
+	article.WordCount, article.ReadingTimeMinutes = articleReadingStats(*article)
+}
+
+// parseWordCountRange reads the minWords and maxWords query parameters, both inclusive, and returns the
+// db.SearchParams restricting a search to the articles having a number of words in between, nil when none of them
+// is provided.
+func parseWordCountRange(providedParams url.Values) (*db.SearchParams, error) {
+	wordCountRange := db.NumberRange{Min: math.Inf(-1), Max: math.Inf(1)}
+	for _, param := range wordCountRangeParams {
+		if !providedParams.Has(param) {
+			continue
+		}
+		words, err := strconv.Atoi(providedParams.Get(param))
+		if err != nil || words < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer", param)
+		}
+		if param == "minWords" {
+			wordCountRange.Min = float64(words)
+		} else {
+			wordCountRange.Max = float64(words)
+		}
+	}
+	if math.IsInf(wordCountRange.Min, -1) && math.IsInf(wordCountRange.Max, 1) {
+		return nil, nil
+	}
+	return &db.SearchParams{Param: "wordCount", Type: db.NumberType, Ranges: []db.NumberRange{wordCountRange}}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultRelatedLimit  = 5 // defaultRelatedLimit is the number of related articles returned when no limit is provided.
+	minSignificantLength = 3 // minSignificantLength is the minimum number of characters of a title term to be significant.
+)
+
+// significantTitleTerms returns the terms of a title that are long enough to characterize an article.
+func significantTitleTerms(title string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(title, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if utf8.RuneCountInString(term) >= minSignificantLength {
+			terms = append(terms, strings.ToLower(term))
+		}
+	}
+	return terms
+}
+
+// getRelatedArticles returns the articles related to the article with the provided ID.
+// It runs a more-like-this query matching any of the article tags, or any of its significant title terms
+// in the title and content of the other articles, ranked by relevance score.
+// The source article is excluded from the results and the number of articles returned is controlled by
+// the limit query parameter (defaultRelatedLimit by default, up to maxPageLimit).
+func getRelatedArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	queryParams := r.URL.Query()
+	invalidRelatedError := "invalid related articles parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"limit"}); err != nil {
+		handleError(w, invalidRelatedError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit := defaultRelatedLimit
+	if queryParams.Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(queryParams.Get("limit"))
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			handleError(w, invalidRelatedError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Retrieve the source article
+	key := tenantKey(ctx, keysPrefix+id)
+	article, err := getStoredArticle(ctx, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if article == nil || isHiddenDraft(ctx, *article) {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+
+	// Build the more-like-this query, any of the tags or title terms being enough to be related
+	var searchParameters []db.SearchParams
+	if len(article.Tags) > 0 {
+		searchParameters = append(searchParameters, db.SearchParams{
+			Param: "tags",
+			Type:  db.ArrayType,
+			Value: []string{strings.Join(article.Tags, "|")},
+		})
+	}
+	if titleTerms := significantTitleTerms(article.Title); len(titleTerms) > 0 {
+		searchParameters = append(searchParameters, db.SearchParams{
+			Param: "title|content",
+			Type:  db.StringType,
+			Value: []string{strings.Join(titleTerms, "|")},
+		})
+	}
+	if len(searchParameters) == 0 {
+		// Nothing characterizes the article, nothing can be related to it
+		responseJSON(w, []ArticleSearchHit{}, http.StatusOK)
+		return
+	}
+
+	// One more article is requested as the source article is most likely part of the results
+	searchOptions := db.SearchOptions{
+		MatchAny:     true,
+		WithScores:   true,
+		Limit:        limit + 1,
+		FieldWeights: config.SearchWeights,
+		Required:     visibleArticlesFilters(ctx),
+	}
+	searchResult, err := db.Search[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), searchParameters, searchOptions)
+	if err != nil {
+		handleError(w, fmt.Sprintf("Database Error while searching articles related to %s", id), err, http.StatusInternalServerError)
+		return
+	}
+
+	relatedArticles := []ArticleSearchHit{}
+	for _, searchHit := range searchResult.Hits {
+		if searchHit.Item.Id == id || len(relatedArticles) == limit {
+			continue
+		}
+		relatedArticles = append(relatedArticles, ArticleSearchHit{Article: searchHit.Item, Score: searchHit.Score})
+	}
+	responseJSON(w, relatedArticles, http.StatusOK)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// jsonMediaType is the media type of the JSON representation, the one used by the handlers.
+const jsonMediaType = "application/json"
+
+// representation is a format articles can be exchanged in besides JSON. The handlers only deal with JSON,
+// the request bodies and the responses being converted from and to the other representations by withRepresentations.
+type representation struct {
+	mediaTypes []string                          // mediaTypes are the media types of the representation, the first one being sent.
+	fromJSON   func(data []byte) ([]byte, error) // fromJSON converts a JSON document to the representation.
+	toJSON     func(data []byte) ([]byte, error) // toJSON converts a document in the representation to JSON.
+	// fromJSONResponse, when set, is used instead of fromJSON to convert a JSON response given the request it answers
+	// and its status code, e.g. to link to the other pages.
+	fromJSONResponse func(r *http.Request, statusCode int, data []byte) ([]byte, error)
+}
+
+// representations lists the supported representations besides JSON.
+var representations = []*representation{
+	{mediaTypes: []string{"application/xml", "text/xml"}, fromJSON: jsonToXML, toJSON: xmlToJSON},
+	{mediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, fromJSON: jsonToYAML, toJSON: yamlToJSON},
+	{mediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, fromJSON: jsonToMsgpack, toJSON: msgpackToJSON},
+	{mediaTypes: []string{jsonAPIMediaType}, fromJSONResponse: jsonToJSONAPI, toJSON: jsonAPIToJSON},
+}
+
+// findRepresentation returns the representation with the given media type, nil for JSON or an unsupported media type.
+func findRepresentation(mediaType string) *representation {
+	for _, candidate := range representations {
+		for _, candidateMediaType := range candidate.mediaTypes {
+			if strings.EqualFold(candidateMediaType, mediaType) {
+				return candidate
+			}
+		}
+	}
+	return nil
+}
+
+// negotiateRepresentation returns the representation preferred by an Accept header, nil for JSON.
+// JSON is preferred on equal quality, and is used whenever none of the accepted media types is supported.
+func negotiateRepresentation(accept string) *representation {
+	var preferred *representation
+	preferredQuality := acceptQuality(accept, jsonMediaType)
+	for _, candidate := range representations {
+		for _, mediaType := range candidate.mediaTypes {
+			if quality := acceptQuality(accept, mediaType); quality > preferredQuality {
+				preferred, preferredQuality = candidate, quality
+			}
+		}
+	}
+	return preferred
+}
+
+// acceptQuality returns the quality given by an Accept header to a media type, according to the most specific media range
+// matching it, 0 when the media type is not acceptable. Any media type is acceptable when there is no Accept header.
+func acceptQuality(accept string, mediaType string) float64 {
+	if strings.TrimSpace(accept) == "" {
+		return 1
+	}
+	quality, specificity := 0.0, -1
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	for _, mediaRange := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		var rangeSpecificity int
+		switch {
+		case strings.EqualFold(rangeType, mediaType):
+			rangeSpecificity = 2
+		case strings.EqualFold(rangeType, mainType+"/*"):
+			rangeSpecificity = 1
+		case rangeType == "*/*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+		specificity, quality = rangeSpecificity, 1
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+	}
+	return quality
+}
+
+// isJSONMediaType reports whether a Content-Type header value designates JSON, including the problems (see Problem).
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == jsonMediaType || mediaType == problemMediaType)
+}
+
+// representationWriter is an http.ResponseWriter converting the JSON responses it is given to a representation.
+// The other responses (e.g. without body) are written through as is.
+type representationWriter struct {
+	http.ResponseWriter
+	representation *representation
+	request        *http.Request
+	statusCode     int
+	wroteHeader    bool
+	converting     bool
+	body           bytes.Buffer
+}
+
+// WriteHeader holds back the status code of a JSON response until it is converted, and sends the others right away.
+func (rw *representationWriter) WriteHeader(statusCode int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	if isJSONMediaType(rw.Header().Get("Content-Type")) {
+		rw.statusCode = statusCode
+		rw.converting = true
+		return
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write buffers the body of a JSON response until it is converted, and sends the others right away.
+func (rw *representationWriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.converting {
+		return rw.body.Write(data)
+	}
+	return rw.ResponseWriter.Write(data)
+}
+
+// Flush sends the data written so far to the client, unless the response is being converted.
+func (rw *representationWriter) Flush() {
+	if !rw.converting {
+		_ = http.NewResponseController(rw.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (rw *representationWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack lets the handler take over the connection (e.g. for WebSocket), through the underlying http.ResponseWriter.
+func (rw *representationWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// finish converts and sends the JSON response held back, the JSON response being sent as is when it can't be converted.
+func (rw *representationWriter) finish() {
+	if !rw.converting {
+		return
+	}
+	body := rw.body.Bytes()
+	var converted []byte
+	var err error
+	if rw.representation.fromJSONResponse != nil {
+		converted, err = rw.representation.fromJSONResponse(rw.request, rw.statusCode, body)
+	} else {
+		converted, err = rw.representation.fromJSON(body)
+	}
+	if err != nil {
+		slog.Error("Unable to convert the response", "mediaType", rw.representation.mediaTypes[0], "Error:", err)
+	} else {
+		body = converted
+		rw.Header().Set("Content-Type", rw.representation.mediaTypes[0])
+	}
+	rw.Header().Del("Content-Length")
+	rw.ResponseWriter.WriteHeader(rw.statusCode)
+	if _, err := rw.ResponseWriter.Write(body); err != nil {
+		slog.Error("Unable to write the converted response", "Error:", err)
+	}
+}
+
+// withRepresentations lets the clients exchange articles in any of the supported representations, the handlers
+// only dealing with JSON: a request body sent in another representation (according to its Content-Type) is converted
+// to JSON beforehand, and a JSON response is converted to the representation preferred by the Accept header, if any.
+func withRepresentations(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if accepted := negotiateRepresentation(r.Header.Get("Accept")); accepted != nil {
+			rw := &representationWriter{ResponseWriter: w, representation: accepted, request: r}
+			defer rw.finish()
+			w = rw
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if received := findRepresentation(mediaType); received != nil && r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				handleError(w, "Failed to read request body", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+				return
+			}
+			if body, err = received.toJSON(body); err != nil {
+				handleError(w, "Invalid "+mediaType+" payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Type", jsonMediaType)
+			r.Header.Del("Content-Length")
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"regexp"
+)
+
+// requestIDHeader is the header holding the ID of a request, honored when sent by the client and sent back along with
+// the response.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern is the pattern of a request ID sent by a client, a new ID being generated for the others.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// requestIDContextKey is the context key of the ID of a request, see withRequestID.
+type requestIDContextKey struct{}
+
+// contextWithRequestID returns a copy of parent tagged with the given request ID.
+func contextWithRequestID(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, requestIDContextKey{}, requestID)
+}
+
+// requestIDOf returns the ID of the request ctx serves, empty when it does not serve a request.
+func requestIDOf(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// newRequestID returns the ID of a request: the one sent by the client when it is valid, a new UUID otherwise.
+func newRequestID(sent string) string {
+	if requestIDPattern.MatchString(sent) {
+		return sent
+	}
+	return uuid.New().String()
+}
+
+// withRequestID tags the requests with their X-Request-ID header, or a new ID when it is missing or invalid, and sends
+// the ID back as X-Request-ID header of the response. The ID is carried by the context of the request down to the
+// Database operations, and reported by the log entries (see requestIDLogHandler) and the error responses.
+func withRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := newRequestID(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID)
+		handler.ServeHTTP(w, r.WithContext(contextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// requestIDLogHandler is a slog.Handler adding the ID of the request the context of a log entry serves to the entry,
+// as requestId attribute, along with the ID of its trace as traceId attribute when it is traced (see withTracing).
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+// Handle adds the ID of the request ctx serves and of its trace to the record, if any, and passes it to the underlying
+// handler.
+func (handler requestIDLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := requestIDOf(ctx); requestID != "" {
+		record.AddAttrs(slog.String("requestId", requestID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && sc.IsSampled() {
+		record.AddAttrs(slog.String("traceId", sc.TraceID().String()))
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a requestIDLogHandler whose underlying handler has the given attributes.
+func (handler requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDLogHandler{handler.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a requestIDLogHandler whose underlying handler has the given group.
+func (handler requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return requestIDLogHandler{handler.Handler.WithGroup(name)}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// restoreBatchSize is the number of articles written at once by a restore job.
+const restoreBatchSize = 100
+
+// restoreArticles starts a background job restoring the articles of a backup archive produced by backupArticles,
+// uploaded as application/zip, and responds with an HTTP 202 Accepted along with the job, whose progress is reported
+// by getJob. The articles of the archive overwrite the stored articles with the same ID, and the wipe query
+// parameter set to true deletes beforehand all the articles, along with the articles in the trash, their revisions,
+// the title suggestions and the publication schedule. The search index is then rebuilt (see reindex).
+// If a restore job is already queued or running, it responds with an HTTP 409 Conflict along with that job (see submitJob).
+func restoreArticles(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"wipe"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	wipe := false
+	if queryParams.Has("wipe") {
+		var err error
+		if wipe, err = strconv.ParseBool(queryParams.Get("wipe")); err != nil {
+			handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, errors.New("wipe must be a boolean")), http.StatusBadRequest)
+			return
+		}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/zip" {
+		handleError(w, "Unsupported backup format",
+			fmt.Errorf("content type %s is not supported, use application/zip", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// The archive is kept in a temporary file, its files being read in any order
+	archiveFile, err := os.CreateTemp("", "articles-restore-*.zip")
+	if err != nil {
+		handleError(w, "Failed to store the backup archive", err, http.StatusInternalServerError)
+		return
+	}
+	removeArchive := func() {
+		archiveFile.Close()
+		if err := os.Remove(archiveFile.Name()); err != nil {
+			slog.WarnContext(r.Context(), "Unable to remove the backup archive", "file", archiveFile.Name(), "Error:", err)
+		}
+	}
+	size, err := io.Copy(archiveFile, r.Body)
+	if err != nil {
+		removeArchive()
+		handleError(w, "Failed to read request body", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	archive, manifest, err := openBackupArchive(archiveFile, size)
+	if err != nil {
+		removeArchive()
+		handleError(w, "Invalid backup archive", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+
+	_, submitted := submitJob(requestContext(r), w, "restore", true, func(ctx context.Context, jobId string) error {
+		defer removeArchive()
+		jobs.update(jobId, func(job *Job) { job.Total = manifest.Articles + manifest.TrashedArticles })
+		return restore(ctx, jobId, archive, wipe)
+	})
+	if !submitted {
+		removeArchive()
+	}
+}
+
+// openBackupArchive opens a backup archive and returns it along with its manifest.
+// An archive without manifest, being incomplete, or with a layout more recent than the supported one is rejected.
+func openBackupArchive(file io.ReaderAt, size int64) (*zip.Reader, BackupManifest, error) {
+	var manifest BackupManifest
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, manifest, err
+	}
+	manifestFile, err := archive.Open(backupManifestFile)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("the archive has no %s, it is not a complete backup", backupManifestFile)
+	}
+	defer manifestFile.Close()
+	if err := json.NewDecoder(manifestFile).Decode(&manifest); err != nil {
+		return nil, manifest, fmt.Errorf("invalid %s: %v", backupManifestFile, err)
+	}
+	if manifest.Version < 1 || manifest.Version > backupFormatVersion {
+		return nil, manifest, fmt.Errorf("backup version %d is not supported, the latest supported version is %d", manifest.Version, backupFormatVersion)
+	}
+	return archive, manifest, nil
+}
+
+// restore restores the articles of a backup archive in the tenant ctx is scoped to for the job with the given ID, after
+// wiping the stored ones when requested, then rebuilds the search index, the job step and progress being updated along
+// the way.
+func restore(ctx context.Context, jobId string, archive *zip.Reader, wipe bool) error {
+	if wipe {
+		jobs.update(jobId, func(job *Job) { job.Step = "wipe" })
+		for _, prefix := range []string{keysPrefix, trashKeysPrefix, revisionsKeysPrefix} {
+			if _, err := db.DelByPrefix(ctx, databaseClient, tenantKey(ctx, prefix), restoreBatchSize); err != nil {
+				return fmt.Errorf("unable to delete the keys with prefix %s: %v", prefix, err)
+			}
+		}
+		for _, key := range []string{suggestionsDictionary, publicationScheduleKey} {
+			if _, err := db.Del(ctx, databaseClient, tenantKey(ctx, key)); err != nil {
+				return fmt.Errorf("unable to delete %s: %v", key, err)
+			}
+		}
+	}
+
+	jobs.update(jobId, func(job *Job) { job.Step = "restore" })
+	for _, file := range []struct{ name, prefix string }{{backupArticlesFile, keysPrefix}, {backupTrashFile, trashKeysPrefix}} {
+		if err := restoreBackupArticles(ctx, jobId, archive, file.name, file.prefix); err != nil {
+			return err
+		}
+	}
+
+	jobs.update(jobId, func(job *Job) {
+		job.Step = "reindex"
+		job.Processed = 0
+	})
+	return reindex(ctx, jobId)
+}
+
+// restoreBackupArticles writes the articles of a newline delimited JSON file of a backup archive at keys with the given prefix
+// in the tenant ctx is scoped to, by batches of restoreBatchSize articles. The articles are written as they were backed up,
+// their expiration being set again.
+func restoreBackupArticles(ctx context.Context, jobId string, archive *zip.Reader, name string, prefix string) error {
+	prefix = tenantKey(ctx, prefix)
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %v", name, err)
+	}
+	defer file.Close()
+
+	var articles []Article
+	writeBatch := func() error {
+		if len(articles) == 0 {
+			return nil
+		}
+		setArgs := make([]db.JSONSetArgs, len(articles))
+		expireAt := make(map[string]int64, len(articles))
+		for i, article := range articles {
+			articleByte, err := json.Marshal(article)
+			if err != nil {
+				return err
+			}
+			setArgs[i] = db.JSONSetArgs{Key: prefix + article.Id, Path: "$", Value: articleByte}
+			expireAt[prefix+article.Id] = article.ExpiresAt
+		}
+		if err := db.JSONMSetArgsExpireAt(ctx, databaseClient, setArgs, expireAt); err != nil {
+			return fmt.Errorf("unable to restore articles: %v", err)
+		}
+		jobs.update(jobId, func(job *Job) { job.Processed += len(articles) })
+		articles = articles[:0]
+		return nil
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var article Article
+		err := decoder.Decode(&article)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid article in %s: %v", name, err)
+		}
+		if article.Id == "" {
+			return fmt.Errorf("invalid article in %s: article without id", name)
+		}
+		articles = append(articles, article)
+		if len(articles) == restoreBatchSize {
+			if err := writeBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	return writeBatch()
+}
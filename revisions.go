@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"strconv"
+)
+
+// revisionsKeysPrefix is the prefix of the keys of the lists holding the previous versions of each article,
+// kept out of keysPrefix so that they are neither listed nor indexed as articles.
+const revisionsKeysPrefix = "revisions:article:"
+
+// ArticleRevision represents a previous version of an article.
+type ArticleRevision struct {
+	Revision  int64   `json:"revision"`  // Revision is the number of the revision, starting at 1 for the first version of an article.
+	RevisedAt int64   `json:"revisedAt"` // RevisedAt is the time this version was replaced, as a Unix timestamp in seconds.
+	Article   Article `json:"article"`   // Article is the article as it was before being replaced.
+}
+
+// getArticleRevisions returns the previous versions of the article with the provided ID, the oldest first.
+// If the article does not exist, or is a draft the actor can't read (see isHiddenDraft), it responds with an HTTP 404
+// Not Found error.
+func getArticleRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+
+	storedArticle, err := getStoredArticle(ctx, tenantKey(ctx, keysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if storedArticle == nil || isHiddenDraft(ctx, *storedArticle) {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+
+	values, err := db.ListRange(ctx, databaseClient, tenantKey(ctx, revisionsKeysPrefix+id), 0, -1)
+	if err != nil {
+		handleError(w, "Failed to retrieve article revisions from Database", err, http.StatusInternalServerError)
+		return
+	}
+	revisions := make([]ArticleRevision, 0, len(values))
+	for i, value := range values {
+		var revision ArticleRevision
+		if err := json.Unmarshal([]byte(value), &revision); err != nil {
+			handleError(w, "Failed to parse article revision", err, http.StatusInternalServerError)
+			return
+		}
+		revision.Revision = int64(i + 1)
+		revisions = append(revisions, revision)
+	}
+	responseJSON(w, revisions, http.StatusOK)
+}
+
+// getRevision retrieves the given revision of the article with the provided ID, nil is returned when there is no such revision.
+func getRevision(ctx context.Context, id string, number int64) (*ArticleRevision, error) {
+	if number < 1 {
+		return nil, nil
+	}
+	value, err := db.ListIndex(ctx, databaseClient, tenantKey(ctx, revisionsKeysPrefix+id), number-1)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var revision ArticleRevision
+	if err := json.Unmarshal([]byte(value), &revision); err != nil {
+		return nil, err
+	}
+	revision.Revision = number
+	return &revision, nil
+}
+
+// restoreArticleRevision rolls the article with the provided ID back to the given revision.
+// The revision is validated like any other article and written as the current version, the version it replaces
+// being recorded as a new revision. If the article or the revision does not exist, it responds with an HTTP 404
+// Not Found error, and if the actor is not allowed to change the article (see canChangeArticle), with an HTTP 403
+// Forbidden error. Finally, it responds with the restored article as a JSON response.
+func restoreArticleRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	number, err := strconv.ParseInt(r.PathValue("n"), 10, 64)
+	if err != nil {
+		handleError(w, "Invalid revision", withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("revision must be an integer, got %s", r.PathValue("n"))), http.StatusBadRequest)
+		return
+	}
+
+	key := tenantKey(ctx, keysPrefix+id)
+	storedArticle, err := getStoredArticle(ctx, key)
+	if err != nil {
+		handleError(w, "Failed to retrieve article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if storedArticle == nil {
+		handleError(w, "Article not found", withErrorCode(ErrorCodeArticleNotFound, fmt.Errorf("no article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	if !canChangeArticle(ctx, requestActor(r), storedArticle) {
+		articleForbidden(w, requestActor(r), id)
+		return
+	}
+	revision, err := getRevision(ctx, id, number)
+	if err != nil {
+		handleError(w, "Failed to retrieve article revision from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if revision == nil {
+		handleError(w, "Revision not found", withErrorCode(ErrorCodeRevisionNotFound, fmt.Errorf("no revision %d found for article with ID %s", number, id)), http.StatusNotFound)
+		return
+	}
+
+	article := revision.Article
+	article.Id = id
+	if err := validateStruct(article); err != nil {
+		handleError(w, "Validation failed for the revision", err, http.StatusBadRequest)
+		return
+	}
+	setServerManagedFields(&article, storedArticle, requestActor(r))
+
+	if err = setArticleIfVersion(ctx, key, storedArticle, article); err != nil {
+		handleVersionedWriteError(w, err)
+		return
+	}
+	articleChanged(ctx, requestActor(r), storedArticle, &article)
+
+	responseArticleJSON(w, r, article, nil, http.StatusOK)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"testing"
+)
+
+func TestSetArticleIfVersionRecordsRevision(t *testing.T) {
+	requireDatabase(t)
+	id := uuid.New().String()
+	key := keysPrefix + id
+	t.Cleanup(func() {
+		_, _ = db.Del(ctx, databaseClient, key, revisionsKeysPrefix+id)
+	})
+
+	created := Article{Id: id, Title: "First", Content: "First version", Author: "alice", Version: 1}
+	if err := setArticleIfVersion(ctx, key, nil, created); err != nil {
+		t.Fatalf("setArticleIfVersion() failed to create the article: %v", err)
+	}
+	updated := created
+	updated.Title, updated.Version = "Second", 2
+	if err := setArticleIfVersion(ctx, key, &created, updated); err != nil {
+		t.Fatalf("setArticleIfVersion() failed to update the article: %v", err)
+	}
+	stale := updated
+	stale.Title, stale.Version = "Stale", 2
+	if err := setArticleIfVersion(ctx, key, &created, stale); !errors.Is(err, db.ErrVersionMismatch) {
+		t.Fatalf("setArticleIfVersion() = %v, expected %v", err, db.ErrVersionMismatch)
+	}
+
+	revisions, err := db.ListRange(ctx, databaseClient, revisionsKeysPrefix+id, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("%d revisions recorded, expected 1", len(revisions))
+	}
+	revision, err := getRevision(ctx, id, 1)
+	if err != nil || revision == nil || revision.Article.Title != "First" {
+		t.Errorf("getRevision() = %v, %v, expected the first version", revision, err)
+	}
+}
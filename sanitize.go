@@ -0,0 +1,110 @@
+package main
+
+import (
+	"golang.org/x/net/html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultHTMLAllowedTags lists the HTML elements kept in the content of the articles when AS_HTML_ALLOWED_TAGS is not set.
+var defaultHTMLAllowedTags = []string{
+	"a", "b", "blockquote", "br", "code", "del", "div", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img", "li",
+	"ol", "p", "pre", "s", "span", "strong", "table", "tbody", "td", "th", "thead", "tr", "u", "ul",
+}
+
+// defaultHTMLAllowedAttributes lists the HTML attributes kept in the content of the articles when
+// AS_HTML_ALLOWED_ATTRIBUTES is not set, as element:attribute, * standing for any element.
+var defaultHTMLAllowedAttributes = []string{"a:href", "a:title", "img:src", "img:alt", "img:title", "*:lang"}
+
+// htmlDroppedContentTags lists the HTML elements removed along with their content when they are not allowed,
+// their content not being meant to be displayed.
+var htmlDroppedContentTags = []string{"iframe", "noscript", "object", "script", "style", "template", "textarea", "title"}
+
+// htmlURLAttributes lists the HTML attributes holding a URL, only kept when the URL is relative or uses one of the
+// htmlAllowedURLSchemes.
+var htmlURLAttributes = []string{"action", "background", "cite", "formaction", "href", "poster", "src"}
+
+// htmlAllowedURLSchemes lists the schemes of the URLs kept in the content of the articles.
+var htmlAllowedURLSchemes = []string{"http", "https", "mailto"}
+
+// sanitizeContent returns the content of an article about to be written sanitized by sanitizeHTML, unless
+// config.HTMLSanitization is disabled. Only the raw HTML of a Markdown content is sanitized, see sanitizeMarkdown.
+func sanitizeContent(article Article) string {
+	if !config.HTMLSanitization {
+		return article.Content
+	}
+	if article.ContentFormat == markdownContentFormat {
+		return sanitizeMarkdown(article.Content)
+	}
+	return sanitizeHTML(article.Content)
+}
+
+// sanitizeHTML returns an HTML fragment without the elements and attributes which are not allowed by
+// config.HTMLAllowedTags and config.HTMLAllowedAttributes, nor the comments, so that it can't carry a script to
+// the applications rendering it. A content without any markup is returned as is.
+func sanitizeHTML(content string) string {
+	if !strings.Contains(content, "<") {
+		return content
+	}
+
+	var sanitized strings.Builder
+	droppedContentDepth := 0
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return sanitized.String()
+		}
+		token := tokenizer.Token()
+		allowed := slices.Contains(config.HTMLAllowedTags, token.Data)
+		switch tokenType {
+		case html.TextToken:
+			if droppedContentDepth == 0 {
+				// The text is escaped again, so that removed elements can't join the remaining text into markup
+				sanitized.WriteString(html.EscapeString(token.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if !allowed && slices.Contains(htmlDroppedContentTags, token.Data) && tokenType == html.StartTagToken {
+				droppedContentDepth++
+			}
+			if allowed && droppedContentDepth == 0 {
+				token.Attr = sanitizeHTMLAttributes(token.Data, token.Attr)
+				sanitized.WriteString(token.String())
+			}
+		case html.EndTagToken:
+			if !allowed && slices.Contains(htmlDroppedContentTags, token.Data) && droppedContentDepth > 0 {
+				droppedContentDepth--
+			} else if allowed && droppedContentDepth == 0 {
+				sanitized.WriteString(token.String())
+			}
+		}
+	}
+}
+
+// sanitizeHTMLAttributes returns the attributes of an element allowed by config.HTMLAllowedAttributes, the ones
+// holding a URL with a scheme other than the htmlAllowedURLSchemes being removed.
+func sanitizeHTMLAttributes(tag string, attributes []html.Attribute) []html.Attribute {
+	var allowedAttributes []html.Attribute
+	for _, attribute := range attributes {
+		if attribute.Namespace != "" ||
+			!slices.Contains(config.HTMLAllowedAttributes, tag+":"+attribute.Key) &&
+				!slices.Contains(config.HTMLAllowedAttributes, "*:"+attribute.Key) {
+			continue
+		}
+		if slices.Contains(htmlURLAttributes, attribute.Key) && !isAllowedHTMLURL(attribute.Val) {
+			continue
+		}
+		allowedAttributes = append(allowedAttributes, attribute)
+	}
+	return allowedAttributes
+}
+
+// isAllowedHTMLURL reports whether a URL is relative or uses one of the htmlAllowedURLSchemes.
+func isAllowedHTMLURL(value string) bool {
+	parsedURL, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	return parsedURL.Scheme == "" || slices.Contains(htmlAllowedURLSchemes, strings.ToLower(parsedURL.Scheme))
+}
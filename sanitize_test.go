@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"no markup", "Fish & chips", "Fish & chips"},
+		{"allowed markup", "<p><strong>bold</strong> text</p>", "<p><strong>bold</strong> text</p>"},
+		{"script", "<p>Hi</p><script>alert(1)</script>", "<p>Hi</p>"},
+		{"nested dropped content", "<p>a<iframe><p>b</p></iframe>c</p>", "<p>ac</p>"},
+		{"unknown element", "<form><p>text</p></form>", "<p>text</p>"},
+		{"comment", "a<!-- <script>alert(1)</script> -->b", "ab"},
+		{"event handler", `<img src="a.png" onerror="alert(1)">`, `<img src="a.png">`},
+		{"attribute of any element", `<p lang="en" style="color: red">text</p>`, `<p lang="en">text</p>`},
+		{"attribute of another element", `<p href="/a">text</p>`, `<p>text</p>`},
+		{"javascript link", `<a href="javascript:alert(1)">link</a>`, `<a>link</a>`},
+		{"javascript link with spaces", `<a href=" JavaScript:alert(1)">link</a>`, `<a>link</a>`},
+		{"data image", `<img src="data:image/svg+xml,<svg onload=alert(1)>">`, `<img>`},
+		{"relative link", `<a href="/articles?tag=go">link</a>`, `<a href="/articles?tag=go">link</a>`},
+		{"https link", `<a href="https://example.com" title="Example">link</a>`, `<a href="https://example.com" title="Example">link</a>`},
+		{"joined markup", "<<script></script>img src=x onerror=alert(1)>", "&lt;img src=x onerror=alert(1)&gt;"},
+		{"escaped text", "<p>&lt;script&gt;</p>", "<p>&lt;script&gt;</p>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if sanitized := sanitizeHTML(test.content); sanitized != test.expected {
+				t.Errorf("sanitizeHTML(%q) = %q, expected %q", test.content, sanitized, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serverStopping is closed once the service starts shutting down, so that the long-lived streams (Server-Sent Events
+// and WebSocket subscriptions) end, and that GET /readyz reports the instance as not ready.
+var serverStopping = make(chan struct{})
+
+// stoppingHealthCheck checks that the service is not shutting down, see serverStopping.
+var stoppingHealthCheck = healthCheck{name: "server", check: func(ctx context.Context) error {
+	select {
+	case <-serverStopping:
+		return errors.New("the server is shutting down")
+	default:
+		return nil
+	}
+}}
+
+// serveUntilSignaled serves HTTP requests with server, over TLS when it has a TLS configuration (see configureTLS),
+// until the process receives SIGINT or SIGTERM, then shuts the service down gracefully: the listeners are closed and
+// the requests in flight are given Config.ShutdownTimeout to complete, along with the gRPC calls, before the traces
+// are flushed and the Database client is closed. A second signal exits right away.
+func serveUntilSignaled(server *http.Server) {
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			served <- server.ListenAndServeTLS("", "")
+			return
+		}
+		served <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-served:
+		fatal("Failed to start HTTP server", err)
+	case <-signalCtx.Done():
+		stop()
+	}
+
+	start := time.Now()
+	slog.Info("Shutting down, draining the requests in flight", "timeout", config.ShutdownTimeout)
+	close(serverStopping)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Unable to drain all the requests in flight, closing their connections", "Error:", err)
+		_ = server.Close()
+	}
+	stopGRPCServer(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Unable to export all the traces", "Error:", err)
+	}
+	if err := db.Close(databaseClient); err != nil {
+		slog.Warn("Unable to close the Database client", "Error:", err)
+	}
+	slog.Info("Server stopped", "after", time.Since(start))
+}
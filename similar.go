@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"github.com/stivesso/articles-search/pkg/embedding"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ArticleSimilarHit represents an article found by a semantic search along with its similarity to the searched text.
+type ArticleSimilarHit struct {
+	Article
+	Similarity float64 `json:"similarity"` // Similarity is the cosine similarity between the article and the searched text, 1 being identical.
+}
+
+// embeddingField is the index field (and JSON path) holding the vector of each article.
+const embeddingField = "embedding"
+
+const (
+	// embeddingScheduleKey is the key of the sorted set of the IDs of the articles whose vector has to be computed,
+	// scored by the time it is due.
+	embeddingScheduleKey = "schedule:articles:embedding"
+	// embeddingCheckInterval is the interval at which the embedding worker computes the vectors which are due.
+	embeddingCheckInterval = time.Second
+	// embeddingBatchSize is the maximum number of articles taken off the schedule at once by the embedding worker.
+	embeddingBatchSize = 100
+	// embeddingRetryDelay is the time waited before computing the vector of an article again, when it has failed.
+	embeddingRetryDelay = time.Minute
+)
+
+// embedder computes the vector of the articles, it is set at startup by initializeEmbedder.
+var embedder embedding.Embedder
+
+// initializeEmbedder initializes the embedder according to the configuration:
+// the http embedder relies on an external embeddings API while the default hashing embedder needs nothing.
+func initializeEmbedder() error {
+	switch config.Embedder {
+	case "hashing":
+		embedder = embedding.NewHashingEmbedder(config.EmbeddingDimensions)
+	case "http":
+		if config.EmbedderURL == "" {
+			return errors.New("AS_EMBEDDER_URL needs to be set when using the http embedder")
+		}
+		embedder = embedding.NewHTTPEmbedder(config.EmbedderURL, config.EmbedderModel, config.EmbedderAPIKey, config.EmbeddingDimensions, nil)
+	default:
+		return fmt.Errorf("%s is not a supported embedder, use either hashing or http", config.Embedder)
+	}
+	return nil
+}
+
+// embeddingText returns the text of an article that its vector is computed from.
+func embeddingText(article Article) string {
+	return article.Title + "\n" + article.Content
+}
+
+// scheduleEmbedding schedules the computation of the vector of an article by the embedding worker (see
+// startEmbeddingWorker), so that the writes never wait for the embedder. The vector is secondary data, failures are
+// logged rather than returned. ctx is scoped to the tenant of the article.
+func scheduleEmbedding(ctx context.Context, article Article) {
+	if err := db.SortedSetAdd(ctx, databaseClient, tenantKey(ctx, embeddingScheduleKey), article.Id, float64(time.Now().Unix())); err != nil {
+		slog.WarnContext(ctx, "Unable to schedule article embedding", "id", article.Id, "Error:", err)
+	}
+}
+
+// startEmbeddingWorker starts the background goroutine computing the vectors of the articles of every tenant scheduled
+// by scheduleEmbedding.
+func startEmbeddingWorker() {
+	go func() {
+		ticker := time.NewTicker(embeddingCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, ctx := range tenantContexts() {
+				embedDueArticles(ctx)
+			}
+		}
+	}()
+}
+
+// embedDueArticles computes the vectors of the articles of the tenant ctx is scoped to which are due. Each article is
+// taken off the schedule atomically beforehand, so that its vector is computed once even when several instances of the
+// service are running, and scheduled again embeddingRetryDelay later when it fails.
+func embedDueArticles(ctx context.Context) {
+	for {
+		ids, err := db.SortedSetPopByScore(ctx, databaseClient, tenantKey(ctx, embeddingScheduleKey), float64(time.Now().Unix()), embeddingBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to retrieve the articles to embed", "Error:", err)
+			return
+		}
+		for _, id := range ids {
+			if err := embedArticle(ctx, id); err != nil {
+				slog.WarnContext(ctx, "Unable to compute article embedding, it is scheduled again", "id", id, "Error:", err)
+				if err := db.SortedSetAdd(ctx, databaseClient, tenantKey(ctx, embeddingScheduleKey), id, float64(time.Now().Add(embeddingRetryDelay).Unix())); err != nil {
+					slog.ErrorContext(ctx, "Unable to schedule article embedding again", "id", id, "Error:", err)
+				}
+			}
+		}
+		if len(ids) < embeddingBatchSize {
+			return
+		}
+	}
+}
+
+// embedArticle computes the vector of the article with the given ID as currently stored, unless it no longer exists,
+// and stores it along with the article. An article changed meanwhile is scheduled again by its change.
+func embedArticle(ctx context.Context, id string) error {
+	key := tenantKey(ctx, keysPrefix+id)
+	article, err := getStoredArticle(ctx, key)
+	if err != nil || article == nil {
+		return err
+	}
+	vector, err := embedder.Embed(ctx, embeddingText(*article))
+	if err != nil {
+		return err
+	}
+	_, err = db.JSONSet(ctx, databaseClient, key, "$."+embeddingField, vector)
+	return err
+}
+
+// similarArticles returns the articles semantically the closest to the text query parameter.
+// The vector of the text is computed by the embedder and the closest articles are found with db.KNNSearch.
+// The number of articles returned is controlled by the limit query parameter.
+func similarArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	invalidSimilarError := "invalid similar search parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"text", "limit"}); err != nil {
+		handleError(w, invalidSimilarError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	text := queryParams.Get("text")
+	if text == "" {
+		handleError(w, invalidSimilarError, withErrorCode(ErrorCodeInvalidParameter, errors.New("text must be provided")), http.StatusBadRequest)
+		return
+	}
+	limit, _, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, invalidSimilarError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	vector, err := embedder.Embed(ctx, text)
+	if err != nil {
+		handleError(w, "Unable to compute the embedding of the text", err, http.StatusInternalServerError)
+		return
+	}
+
+	searchResult, err := db.KNNSearch[Article](ctx, databaseClient, tenantIndexName(ctx, searchIndexName), embeddingField, embedding.ToBytes(vector), limit, nil, db.SearchOptions{Required: visibleArticlesFilters(ctx)})
+	if err != nil {
+		handleError(w, "Database Error while searching similar articles", err, http.StatusInternalServerError)
+		return
+	}
+
+	resArticles := make([]ArticleSimilarHit, len(searchResult.Hits))
+	for i, searchHit := range searchResult.Hits {
+		// The index uses the COSINE distance metric, which is 1 - cosine similarity
+		resArticles[i] = ArticleSimilarHit{Article: searchHit.Item, Similarity: 1 - searchHit.Distance}
+	}
+	responseJSON(w, resArticles, http.StatusOK)
+}
+
+// parseEmbeddingDimensions converts the number of dimensions of the articles vector.
+func parseEmbeddingDimensions(value string) (int, error) {
+	dimensions, err := strconv.Atoi(value)
+	if err != nil || dimensions < 1 {
+		return 0, errors.New("the number of dimensions must be a positive integer")
+	}
+	return dimensions, nil
+}
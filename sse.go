@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sseKeepAliveInterval is the interval at which a comment is sent to the idle event streams, so that proxies don't close them.
+const sseKeepAliveInterval = 15 * time.Second
+
+// streamArticleEvents streams the changes made to the articles as Server-Sent Events (text/event-stream) until the client
+// disconnects, each event being sent with its ID, its type (e.g. article.updated) and the ArticleEvent as JSON data.
+// Only the changes of the articles of the tenant and namespace of the request are sent, and the types query parameter, a comma
+// separated list of event types, restricts them further.
+// Only the events that occur after the connection are sent: the changes missed while disconnected can be read
+// from the Redis Stream of the changes (see startEventStreamWriter).
+func streamArticleEvents(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"types"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	types, err := parseArticleEventTypes(queryParams.Get("types"))
+	if err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	events, unsubscribe := clusterArticleEvents.subscribe()
+	defer unsubscribe()
+
+	// The stream lasts as long as the client stays, beyond config.WriteTimeout
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		slog.ErrorContext(r.Context(), "Unable to stream events, the response can't be flushed", "Error:", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-serverStopping:
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			if !event.scopedTo(r.Context()) || (len(types) > 0 && !slices.Contains(types, event.Type)) {
+				continue
+			}
+			err = writeServerSentEvent(w, event)
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			// The client is gone
+			return
+		}
+	}
+}
+
+// writeServerSentEvent writes an event in the text/event-stream format.
+func writeServerSentEvent(w http.ResponseWriter, event ArticleEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
+
+// parseArticleEventTypes parses a comma separated list of event types, an empty list being returned for an empty value.
+func parseArticleEventTypes(value string) ([]ArticleEventType, error) {
+	var types []ArticleEventType
+	if value == "" {
+		return types, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		eventType := ArticleEventType(strings.TrimSpace(name))
+		if !slices.Contains(articleEventTypes, eventType) {
+			return nil, fmt.Errorf("types must be a comma separated list of the following event types: %v", articleEventTypes)
+		}
+		types = append(types, eventType)
+	}
+	return types, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// ndjsonMediaType is the media type of newline delimited JSON, one JSON document per line.
+	ndjsonMediaType = "application/x-ndjson"
+	// streamBatchSize is the number of articles retrieved at once while streaming articles.
+	streamBatchSize = 100
+)
+
+// prefersNDJSON reports whether the Accept header of a request prefers newline delimited JSON to JSON.
+func prefersNDJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return acceptQuality(accept, ndjsonMediaType) > acceptQuality(accept, jsonMediaType)
+}
+
+// streamAllArticles sends all the articles as newline delimited JSON, each article holding only the given fields
+// (all of them when nil), see streamArticles.
+func streamAllArticles(ctx context.Context, w http.ResponseWriter, fields []string) {
+	encoder := json.NewEncoder(w)
+	streamArticles(ctx, w, ndjsonMediaType, fields, nil, func(articles []Article) error {
+		return writeNDJSONArticles(encoder, articles, fields)
+	})
+}
+
+// streamArticles sends all the articles in a response of the given content type, each article holding only the given
+// fields (all of them when nil). The response starts with begin, when provided, then articles are passed to write
+// in batches as they are retrieved by scanArticles, so that the whole collection is never held in memory.
+// Once the first articles are sent, a failure can only be logged and ends the response.
+func streamArticles(ctx context.Context, w http.ResponseWriter, contentType string, fields []string, begin func() error, write func([]Article) error) {
+	controller := http.NewResponseController(w)
+	started := false
+	start := func() error {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		started = true
+		if begin != nil {
+			return begin()
+		}
+		return nil
+	}
+
+	err := scanArticles(ctx, keysPrefix, fields, func(articles []Article) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := write(articles); err != nil {
+			return err
+		}
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(ctx, "Unable to flush streamed articles", "Error:", err)
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		if !started {
+			handleError(w, "An Error Occurred while Getting Articles", err, http.StatusInternalServerError)
+			return
+		}
+		slog.ErrorContext(ctx, "Unable to stream articles, the response is incomplete", "Error:", err)
+	}
+}
+
+// scanArticles retrieves all the articles of the tenant ctx is scoped to stored at keys with the given prefix, with only the given fields (all of
+// them when nil), and passes them to handle in batches of streamBatchSize as the keys are scanned, stopping on the
+// first error. As with SCAN, an article changed during the iteration may be handled twice or not at all.
+func scanArticles(ctx context.Context, prefix string, fields []string, handle func(articles []Article) error) error {
+	var cursor uint64
+	for {
+		keys, nextCursor, err := db.ScanKeys(ctx, databaseClient, tenantKey(ctx, prefix), cursor, streamBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			articles, err := fetchArticleFields(ctx, keys, fields)
+			if err != nil {
+				return err
+			}
+			if err := handle(articles); err != nil {
+				return err
+			}
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// writeNDJSONArticles writes each article on its own line, with only the given fields (see projectFields).
+func writeNDJSONArticles(encoder *json.Encoder, articles []Article, fields []string) error {
+	for _, article := range articles {
+		projected, err := projectFields(article, fields)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(projected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
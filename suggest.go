@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// TitleSuggestion represents a title completion along with its score.
+type TitleSuggestion struct {
+	Title string  `json:"title"` // Title is the completed title.
+	Score float64 `json:"score"` // Score ranks the suggestion, the higher the better.
+}
+
+const (
+	defaultSuggestionsMax = 5  // defaultSuggestionsMax is the number of suggestions returned when no max is provided.
+	maxSuggestionsMax     = 20 // maxSuggestionsMax is the maximum number of suggestions that can be requested.
+)
+
+// suggestionsDictionary is the Database key of the suggestions dictionary holding the articles title.
+var suggestionsDictionary = "suggest:articles:title"
+
+// refreshTitleSuggestion keeps the suggestions dictionary in sync with an article title.
+// previousTitle is the title being replaced (empty for a new article) and title is the new title (empty for a deleted article).
+// The suggestions dictionary is secondary data, failures are logged rather than returned.
+// Note that articles sharing the same title share the same suggestion.
+func refreshTitleSuggestion(ctx context.Context, previousTitle string, title string) {
+	if previousTitle == title {
+		return
+	}
+	if previousTitle != "" {
+		if _, err := db.SuggestionDel(ctx, databaseClient, tenantKey(ctx, suggestionsDictionary), previousTitle); err != nil {
+			slog.WarnContext(ctx, "Unable to remove title from suggestions", "title", previousTitle, "Error:", err)
+		}
+	}
+	if title != "" {
+		if _, err := db.SuggestionAdd(ctx, databaseClient, tenantKey(ctx, suggestionsDictionary), title, 1); err != nil {
+			slog.WarnContext(ctx, "Unable to add title to suggestions", "title", title, "Error:", err)
+		}
+	}
+}
+
+// suggestArticles returns ranked title completions for the prefix query parameter using db.SuggestionGet.
+// The number of suggestions is controlled by the max query parameter (up to maxSuggestionsMax)
+// and fuzzy=true also completes prefixes with a typo.
+func suggestArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	invalidSuggestError := "invalid suggestion parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"prefix", "max", "fuzzy"}); err != nil {
+		handleError(w, invalidSuggestError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	prefix := queryParams.Get("prefix")
+	if prefix == "" {
+		handleError(w, invalidSuggestError, withErrorCode(ErrorCodeInvalidParameter, errors.New("prefix must be provided")), http.StatusBadRequest)
+		return
+	}
+
+	maxSuggestions := defaultSuggestionsMax
+	if queryParams.Has("max") {
+		var err error
+		maxSuggestions, err = strconv.Atoi(queryParams.Get("max"))
+		if err != nil || maxSuggestions < 1 || maxSuggestions > maxSuggestionsMax {
+			handleError(w, invalidSuggestError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("max must be an integer between 1 and %d", maxSuggestionsMax)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	fuzzy := false
+	if queryParams.Has("fuzzy") {
+		var err error
+		if fuzzy, err = strconv.ParseBool(queryParams.Get("fuzzy")); err != nil {
+			handleError(w, invalidSuggestError, withErrorCode(ErrorCodeInvalidParameter, errors.New("fuzzy must be a boolean")), http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := db.SuggestionGet(ctx, databaseClient, tenantKey(ctx, suggestionsDictionary), prefix, fuzzy, maxSuggestions)
+	if err != nil {
+		handleError(w, "Database Error while getting suggestions", err, http.StatusInternalServerError)
+		return
+	}
+
+	titleSuggestions := make([]TitleSuggestion, len(suggestions))
+	for i, suggestion := range suggestions {
+		titleSuggestions[i] = TitleSuggestion{Title: suggestion.Value, Score: suggestion.Score}
+	}
+	responseJSON(w, titleSuggestions, http.StatusOK)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+)
+
+// TagCount represents a tag along with the number of articles it is associated with.
+type TagCount struct {
+	Tag   string `json:"tag"`   // Tag is the tag itself.
+	Count int64  `json:"count"` // Count is the number of articles associated with the tag.
+}
+
+// getAllTags returns all the distinct tags along with their number of articles, the most used first.
+// The counts are computed by the database using FT.AGGREGATE, through db.Facets, up to maxStatsValues tags.
+func getAllTags(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	if err := isQueryParamsExpected(r.URL.Query(), nil); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	facetCounts, err := db.Facets(ctx, databaseClient, tenantIndexName(ctx, searchIndexName), nil, db.SearchOptions{Required: visibleArticlesFilters(ctx)}, []string{"tags"}, maxStatsValues)
+	if err != nil {
+		handleError(w, "Database Error while counting tags", err, http.StatusInternalServerError)
+		return
+	}
+
+	tags := make([]TagCount, len(facetCounts["tags"]))
+	for i, count := range facetCounts["tags"] {
+		tags[i] = TagCount{Tag: count.Value, Count: count.Count}
+	}
+	responseJSON(w, tags, http.StatusOK)
+}
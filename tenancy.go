@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+const (
+	// tenantKeysPrefix prefixes the keys of the Database scoped to a tenant, followed by the tenant and a colon
+	// (e.g. tenant:acme:article:<id>). The keys of the default tenant are not prefixed.
+	tenantKeysPrefix = "tenant:"
+	// tenantsKey is the key of the set of the tenants which have been served, so that the background tasks
+	// (e.g. the publication scheduler) run for each of them.
+	tenantsKey = "tenants"
+	// namespaceKeysPrefix prefixes the keys of the Database scoped to a namespace within a tenant, followed by the
+	// namespace and a colon (e.g. namespace:staging:article:<id>). The keys of the default namespace are not prefixed.
+	namespaceKeysPrefix = "namespace:"
+)
+
+// tenantPattern is the pattern of a valid tenant or namespace identifier.
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// The errors of resolveTenant and initializeTenant.
+var (
+	// errTenantUnauthenticated is returned when a request which is not authenticated names a tenant.
+	errTenantUnauthenticated = errors.New("the requests naming a tenant must be authenticated")
+	// errTenantForbidden is returned when a request names another tenant than the one of its principal.
+	errTenantForbidden = errors.New("forbidden tenant")
+	// errUnknownTenant is returned for a tenant which is neither in config.Tenants nor recorded in tenantsKey.
+	errUnknownTenant = errors.New("unknown tenant")
+)
+
+// tenantContextKey is the context key of the tenant of a request, see withTenancy.
+type tenantContextKey struct{}
+
+// namespaceContextKey is the context key of the namespace of a request, see withTenancy.
+type namespaceContextKey struct{}
+
+// principalTenantContextKey is the context key of the tenant of the principal authenticating a request, see
+// contextWithPrincipalTenant.
+type principalTenantContextKey struct{}
+
+// initializedTenants records the tenants and namespaces whose search indexes have been checked by this instance, by
+// their tenantKey prefix, so that they are only created once.
+var initializedTenants sync.Map
+
+// contextWithTenant returns a copy of parent scoped to the given tenant, the default tenant when empty.
+func contextWithTenant(parent context.Context, tenant string) context.Context {
+	return context.WithValue(parent, tenantContextKey{}, tenant)
+}
+
+// tenantOf returns the tenant ctx is scoped to, empty for the default tenant.
+func tenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// contextWithPrincipalTenant returns a copy of parent authenticated as a principal of the given tenant, the default
+// tenant when empty, which is the only tenant its requests may be scoped to (see resolveTenant).
+func contextWithPrincipalTenant(parent context.Context, tenant string) context.Context {
+	return context.WithValue(parent, principalTenantContextKey{}, tenant)
+}
+
+// principalTenant returns the tenant of the principal ctx is authenticated as, along with false when ctx is not
+// authenticated or when its principal may act on any tenant (e.g. the bootstrap API key).
+func principalTenant(ctx context.Context) (string, bool) {
+	tenant, found := ctx.Value(principalTenantContextKey{}).(string)
+	return tenant, found
+}
+
+// checkTenancyAuthentication returns an error when Config.MultiTenancy is enabled without authentication, anyone
+// being able to act on any tenant by naming it in the TenantHeader otherwise.
+func checkTenancyAuthentication() error {
+	if config.MultiTenancy && !authenticationEnabled() {
+		return errors.New("the multi-tenancy requires the authentication, with bearer tokens, API keys or client certificates")
+	}
+	return nil
+}
+
+// resolveTenant returns the tenant of a request naming the tenant sent, empty for the default tenant, when
+// config.MultiTenancy is enabled, which requires the authentication (see checkTenancyAuthentication). The tenant is the
+// one of the principal of the request (see principalTenant) whatever the tenant sent, a different one being refused
+// with errTenantForbidden, and a request which is not authenticated can't name a tenant.
+func resolveTenant(ctx context.Context, sent string) (string, error) {
+	if !config.MultiTenancy {
+		return "", nil
+	}
+	bound, restricted := principalTenant(ctx)
+	switch {
+	case restricted && sent == "":
+		return bound, nil
+	case restricted && sent != bound:
+		return "", fmt.Errorf("%w: the principal of the request can't act on tenant %s", errTenantForbidden, sent)
+	case sent != "" && authenticatedSubject(ctx) == "":
+		return "", errTenantUnauthenticated
+	}
+	return sent, nil
+}
+
+// contextWithNamespace returns a copy of parent scoped to the given namespace, the default namespace when empty.
+func contextWithNamespace(parent context.Context, namespace string) context.Context {
+	return context.WithValue(parent, namespaceContextKey{}, namespace)
+}
+
+// namespaceOf returns the namespace ctx is scoped to, empty for the default namespace.
+func namespaceOf(ctx context.Context) string {
+	namespace, _ := ctx.Value(namespaceContextKey{}).(string)
+	return namespace
+}
+
+// tenantKey returns the Database key of the tenant and namespace ctx is scoped to, the key itself for the default
+// tenant and namespace. Every key holding the data of the tenants (articles, revisions, comments...) goes through
+// tenantKey.
+func tenantKey(ctx context.Context, key string) string {
+	if namespace := namespaceOf(ctx); namespace != "" {
+		key = namespaceKeysPrefix + namespace + ":" + key
+	}
+	if tenant := tenantOf(ctx); tenant != "" {
+		key = tenantKeysPrefix + tenant + ":" + key
+	}
+	return key
+}
+
+// tenantIndexName returns the name of a search index of the tenant and namespace ctx is scoped to
+// (e.g. idx_articles:acme or idx_articles:acme:ns:staging), the name itself for the default tenant and namespace.
+func tenantIndexName(ctx context.Context, indexName string) string {
+	if tenant := tenantOf(ctx); tenant != "" {
+		indexName += ":" + tenant
+	}
+	if namespace := namespaceOf(ctx); namespace != "" {
+		indexName += ":ns:" + namespace
+	}
+	return indexName
+}
+
+// requestContext returns the context of the Database operations made to serve r, scoped to its tenant.
+// The context is not canceled along with the request, so that a write is not left halfway by a client going away.
+func requestContext(r *http.Request) context.Context {
+	return context.WithoutCancel(r.Context())
+}
+
+// withTenancy scopes the requests to their tenant when config.MultiTenancy is enabled: the one of their principal or
+// the one named by their config.TenantHeader header (see resolveTenant), and to the namespace named by their
+// config.NamespaceHeader header when it is one of config.Namespaces. The requests without them are served from the
+// default tenant and namespace. The search indexes of a tenant or namespace are created by its first request.
+// An invalid or unknown tenant (see initializeTenant) or a namespace which is not allowed is answered with an HTTP 400
+// Bad Request error, a tenant named by a request which is not authenticated with an HTTP 401 Unauthorized error, and a
+// tenant other than the one of the principal with an HTTP 403 Forbidden error.
+func withTenancy(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := resolveTenant(r.Context(), r.Header.Get(config.TenantHeader))
+		switch {
+		case errors.Is(err, errTenantUnauthenticated):
+			handleError(w, "Authentication required", err, http.StatusUnauthorized)
+			return
+		case err != nil:
+			handleError(w, "Forbidden", err, http.StatusForbidden)
+			return
+		}
+		namespace := r.Header.Get(config.NamespaceHeader)
+		if len(config.Namespaces) == 0 {
+			namespace = ""
+		}
+		if tenant == "" && namespace == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if tenant != "" && !tenantPattern.MatchString(tenant) {
+			err := withErrorCode(ErrorCodeInvalidTenant, fmt.Errorf("%s must only hold letters, digits, - and _, up to 64 of them", config.TenantHeader))
+			handleError(w, "Invalid tenant", err, http.StatusBadRequest)
+			return
+		}
+		if namespace != "" && !slices.Contains(config.Namespaces, namespace) {
+			err := withErrorCode(ErrorCodeInvalidNamespace, fmt.Errorf("%s must be one of the following namespaces: %v", config.NamespaceHeader, config.Namespaces))
+			handleError(w, "Invalid namespace", err, http.StatusBadRequest)
+			return
+		}
+		scopedCtx := contextWithNamespace(contextWithTenant(r.Context(), tenant), namespace)
+		if err := initializeTenant(scopedCtx); errors.Is(err, errUnknownTenant) {
+			handleError(w, "Invalid tenant", withErrorCode(ErrorCodeInvalidTenant, err), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			handleError(w, "Failed to initialize the tenant", err, http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r.WithContext(scopedCtx))
+	})
+}
+
+// initializeTenant creates the search indexes of the tenant and namespace ctx is scoped to when they do not exist yet,
+// and records the tenant in tenantsKey. It is only done once per tenant and namespace by this instance. Only the
+// tenants of config.Tenants and the ones already recorded are initialized, errUnknownTenant being returned for the
+// others so that a client can't create search indexes at will.
+func initializeTenant(ctx context.Context) error {
+	tenant, scope := tenantOf(ctx), tenantKey(ctx, "")
+	if _, initialized := initializedTenants.Load(scope); initialized {
+		return nil
+	}
+	if tenant != "" && !slices.Contains(config.Tenants, tenant) {
+		recorded, err := db.SetIsMember(ctx, databaseClient, tenantsKey, tenant)
+		if err != nil {
+			return fmt.Errorf("unable to check tenant %s: %v", tenant, err)
+		}
+		if !recorded {
+			return fmt.Errorf("%w: %s is not one of the tenants served", errUnknownTenant, tenant)
+		}
+	}
+	if err := initializeSearchIndex(ctx); err != nil {
+		return fmt.Errorf("unable to initialize the search index of %s: %v", tenantIndexName(ctx, searchIndexName), err)
+	}
+	if err := initializeCommentsIndex(ctx); err != nil {
+		return fmt.Errorf("unable to initialize the comments search index of %s: %v", tenantIndexName(ctx, commentsIndexName), err)
+	}
+	if tenant != "" {
+		if _, err := db.SetAdd(ctx, databaseClient, tenantsKey, tenant); err != nil {
+			return fmt.Errorf("unable to record tenant %s: %v", tenant, err)
+		}
+	}
+	initializedTenants.Store(scope, true)
+	return nil
+}
+
+// tenantContexts returns a context scoped to each tenant served so far and to each of config.Namespaces within them,
+// the default tenant and namespace first, for the background tasks to run for each of them. The search indexes of the
+// namespaces are created if needed, a namespace whose indexes can't be created being left out.
+func tenantContexts() []context.Context {
+	perTenant := []context.Context{ctx}
+	if config.MultiTenancy {
+		tenants, err := db.SetMembers(ctx, databaseClient, tenantsKey)
+		if err != nil {
+			slog.Warn("Unable to list the tenants, only the default tenant is processed", "Error:", err)
+		}
+		for _, tenant := range tenants {
+			perTenant = append(perTenant, contextWithTenant(ctx, tenant))
+		}
+	}
+
+	contexts := slices.Clone(perTenant)
+	for _, tenantCtx := range perTenant {
+		for _, namespace := range config.Namespaces {
+			namespaceCtx := contextWithNamespace(tenantCtx, namespace)
+			if err := initializeTenant(namespaceCtx); err != nil {
+				slog.Warn("Unable to initialize namespace, it is not processed", "namespace", namespace, "tenant", tenantOf(tenantCtx), "Error:", err)
+				continue
+			}
+			contexts = append(contexts, namespaceCtx)
+		}
+	}
+	return contexts
+}
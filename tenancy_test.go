@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestResolveTenant(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.MultiTenancy = true
+	config.APIKeys = true
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		sent   string
+		tenant string
+		err    error
+	}{
+		{"anonymous default tenant", context.Background(), "", "", nil},
+		{"anonymous naming a tenant", context.Background(), "acme", "", errTenantUnauthenticated},
+		{"principal of the tenant", contextWithPrincipalTenant(contextWithSubject(context.Background(), "alice"), "acme"), "", "acme", nil},
+		{"principal naming its tenant", contextWithPrincipalTenant(contextWithSubject(context.Background(), "alice"), "acme"), "acme", "acme", nil},
+		{"principal naming another tenant", contextWithPrincipalTenant(contextWithSubject(context.Background(), "alice"), "acme"), "other", "", errTenantForbidden},
+		{"bootstrap key naming a tenant", contextWithSubject(context.Background(), "bootstrap"), "other", "other", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tenant, err := resolveTenant(test.ctx, test.sent)
+			if !errors.Is(err, test.err) || tenant != test.tenant {
+				t.Errorf("resolveTenant() = %q, %v, expected %q, %v", tenant, err, test.tenant, test.err)
+			}
+		})
+	}
+}
+
+func TestCheckTenancyAuthentication(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.MultiTenancy = true
+	if err := checkTenancyAuthentication(); err == nil {
+		t.Error("checkTenancyAuthentication() accepted the multi-tenancy without authentication")
+	}
+	config.APIKeys = true
+	if err := checkTenancyAuthentication(); err != nil {
+		t.Errorf("checkTenancyAuthentication() failed: %v", err)
+	}
+}
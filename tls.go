@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateReloader holds the certificate of the server as loaded from Config.TLSCertFile and Config.TLSKeyFile,
+// loading it again when the files change so that a rotated certificate is served without restarting.
+type certificateReloader struct {
+	certFile string // certFile is the path of the PEM certificate chain.
+	keyFile  string // keyFile is the path of the PEM private key.
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate // certificate is the certificate currently served.
+	modTimes    [2]time.Time     // modTimes holds the modification times of the files the certificate was loaded from.
+}
+
+// newCertificateReloader returns a certificateReloader serving the certificate of the given files, or an error when
+// they can't be loaded.
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// reload loads the certificate again when its files have changed since it was loaded, reporting whether it did.
+// The certificate being served is kept when the new one can't be loaded, e.g. while its files are being written.
+func (reloader *certificateReloader) reload() (bool, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{reloader.certFile, reloader.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	reloader.mu.RLock()
+	unchanged := reloader.certificate != nil && modTimes == reloader.modTimes
+	reloader.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to load the TLS certificate: %v", err)
+	}
+	reloader.mu.Lock()
+	reloader.certificate, reloader.modTimes = &certificate, modTimes
+	reloader.mu.Unlock()
+	return true, nil
+}
+
+// getCertificate returns the certificate currently served, for tls.Config.GetCertificate.
+func (reloader *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	return reloader.certificate, nil
+}
+
+// watch reloads the certificate every interval until the service shuts down, see reload.
+func (reloader *certificateReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-serverStopping:
+			return
+		case <-ticker.C:
+			reloaded, err := reloader.reload()
+			if err != nil {
+				slog.Warn("Unable to reload the TLS certificate, the current one is still served", "Error:", err)
+			} else if reloaded {
+				slog.Info("Reloaded the TLS certificate", "certFile", reloader.certFile)
+			}
+		}
+	}
+}
+
+// configureTLS makes server serve HTTPS with the certificate of Config.TLSCertFile and Config.TLSKeyFile, when set,
+// checking every Config.TLSReloadInterval whether they have been rotated. TLS 1.2 is the minimum version accepted.
+// The certificates of the clients are verified when Config.TLSClientCAFile is set, see configureClientCertificates.
+func configureTLS(server *http.Server) error {
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		if clientCertificatesEnabled() {
+			return errors.New("the client certificates require HTTPS, with AS_TLS_CERT_FILE and AS_TLS_KEY_FILE")
+		}
+		return nil
+	}
+	if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+		return errors.New("both AS_TLS_CERT_FILE and AS_TLS_KEY_FILE must be set to serve HTTPS")
+	}
+	reloader, err := newCertificateReloader(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+	if config.TLSReloadInterval > 0 {
+		go reloader.watch(config.TLSReloadInterval)
+	}
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.getCertificate}
+	if clientCertificatesEnabled() {
+		if err := configureClientCertificates(server.TLSConfig); err != nil {
+			return fmt.Errorf("unable to load the client CA bundle: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// The OTLP protocols the traces can be exported with, see Config.OTLPProtocol.
+const (
+	otlpProtocolHTTP = "http/protobuf" // otlpProtocolHTTP exports the traces over OTLP/HTTP, with protobuf payloads.
+	otlpProtocolGRPC = "grpc"          // otlpProtocolGRPC exports the traces over OTLP/gRPC.
+)
+
+// tracerProvider records the spans of the requests and of the Database operations and exports them, nil when the
+// tracing is disabled, see initializeTracing.
+var tracerProvider *sdktrace.TracerProvider
+
+// initializeTracing registers the OpenTelemetry tracer provider exporting the traces over OTLP when Config.Tracing is
+// enabled, along with the W3C Trace Context and Baggage propagators. The standard OTEL_* environment variables (e.g.
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_RESOURCE_ATTRIBUTES or OTEL_TRACES_SAMPLER) are honoured. The service is described
+// by its name along with the attributes detected from its host, process and container.
+func initializeTracing(ctx context.Context) error {
+	if !config.Tracing {
+		return nil
+	}
+	var exporter *otlptrace.Exporter
+	var err error
+	switch config.OTLPProtocol {
+	case otlpProtocolGRPC:
+		var options []otlptracegrpc.Option
+		if config.OTLPEndpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpointURL(config.OTLPEndpoint))
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		var options []otlptracehttp.Option
+		if config.OTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.OTLPEndpoint, "/")+"/v1/traces"))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	}
+	if err != nil {
+		return err
+	}
+	serviceResource, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithFromEnv(), resource.WithTelemetrySDK(), resource.WithHost(), resource.WithOS(), resource.WithProcess(), resource.WithContainer(),
+		resource.WithAttributes(semconv.ServiceName(config.TracingServiceName)),
+	)
+	if errors.Is(err, resource.ErrPartialResource) || errors.Is(err, resource.ErrSchemaURLConflict) {
+		slog.Warn("Unable to detect all the attributes of the service", "Error:", err)
+	} else if err != nil {
+		return err
+	}
+	options := []sdktrace.TracerProviderOption{sdktrace.WithBatcher(exporter), sdktrace.WithResource(serviceResource)}
+	// The sampler of OTEL_TRACES_SAMPLER is used when set, the SDK reading it when none is given.
+	if _, found := os.LookupEnv("OTEL_TRACES_SAMPLER"); !found {
+		options = append(options, sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.TracingSampleRatio))))
+	}
+	tracerProvider = sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("Unable to export the traces", "Error:", err)
+	}))
+	slog.Info("Exporting the traces", "protocol", config.OTLPProtocol, "endpoint", config.OTLPEndpoint, "sampleRatio", config.TracingSampleRatio)
+	return nil
+}
+
+// shutdownTracing exports the spans not exported yet and stops the tracer provider, when the tracing is enabled.
+func shutdownTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	return tracerProvider.Shutdown(ctx)
+}
+
+// withTracing traces each request as a server span named after its route in routes (e.g. GET /article/{id}),
+// continuing the trace of its traceparent header when sent, with otelhttp. The Database operations made to serve the
+// request are traced as child spans (see db.Instrument), so that the latency of a request can be attributed to Redis
+// or to the service. A request answered with a 5xx status code is reported as failed.
+func withTracing(routes *http.ServeMux, handler http.Handler) http.Handler {
+	if tracerProvider == nil {
+		return handler
+	}
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(attribute.String("http.request.id", requestIDOf(r.Context())))
+		if _, pattern := routes.Handler(r); pattern != "" {
+			_, route, _ := strings.Cut(pattern, " ")
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		handler.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(routed, "", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		if _, pattern := routes.Handler(r); pattern != "" {
+			return pattern
+		}
+		return r.Method
+	}))
+}
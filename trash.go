@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// trashKeysPrefix is the prefix of the keys of the deleted articles, kept out of keysPrefix so that they are neither
+// listed nor indexed until they are restored.
+const trashKeysPrefix = "trash:article:"
+
+// getTrashedArticles retrieves the deleted articles, along with their deletion time, paginated with the limit
+// and offset query parameters like GET /articles, and responds with an ArticlesPage.
+func getTrashedArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"limit", "offset"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaginationParams(queryParams)
+	if err != nil {
+		handleError(w, "invalid pagination parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+
+	page := ArticlesPage{
+		Articles: []Article{},
+		Limit:    limit,
+		Offset:   offset,
+	}
+
+	keys, err := db.GetAllKeys(ctx, databaseClient, tenantKey(ctx, trashKeysPrefix))
+	if err != nil {
+		handleError(w, "Failed to retrieve deleted article keys from Database", err, http.StatusInternalServerError)
+		return
+	}
+	page.Total = len(keys)
+	if offset >= len(keys) {
+		responseJSON(w, page, http.StatusOK)
+		return
+	}
+
+	slices.Sort(keys)
+	page.Articles, err = fetchArticles(ctx, keys[offset:min(offset+limit, len(keys))])
+	if err != nil {
+		handleError(w, "An Error Occurred while Getting deleted Articles", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, page, http.StatusOK)
+}
+
+// emptyTrash submits a background job permanently deleting all the deleted articles along with their revisions,
+// and responds with an HTTP 202 Accepted along with the job, whose progress is reported by getJob.
+// If the trash is already being emptied, it responds with an HTTP 409 Conflict along with that job (see submitJob).
+func emptyTrash(w http.ResponseWriter, r *http.Request) {
+	submitJob(requestContext(r), w, "purge", true, purgeTrash)
+}
+
+// purgeTrash permanently deletes all the deleted articles of the tenant ctx is scoped to along with their revisions, likes
+// and comments for the job with the given ID, by batches of keys as they are scanned, the job progress being updated
+// along the way.
+func purgeTrash(ctx context.Context, jobId string) error {
+	trashPrefix := tenantKey(ctx, trashKeysPrefix)
+	var cursor uint64
+	for {
+		keys, nextCursor, err := db.ScanKeys(ctx, databaseClient, trashPrefix, cursor, streamBatchSize)
+		if err != nil {
+			return fmt.Errorf("unable to list deleted articles: %v", err)
+		}
+		if len(keys) > 0 {
+			ids, revisionsKeys, likesKeys := make([]string, len(keys)), make([]string, len(keys)), make([]string, len(keys))
+			for i, key := range keys {
+				ids[i] = strings.TrimPrefix(key, trashPrefix)
+				revisionsKeys[i] = tenantKey(ctx, revisionsKeysPrefix+ids[i])
+				likesKeys[i] = tenantKey(ctx, likesKeysPrefix+ids[i])
+			}
+			if _, err := db.Del(ctx, databaseClient, slices.Concat(keys, revisionsKeys, likesKeys)...); err != nil {
+				return fmt.Errorf("unable to purge deleted articles: %v", err)
+			}
+			if err := deleteArticlesComments(ctx, ids); err != nil {
+				return fmt.Errorf("unable to purge deleted articles comments: %v", err)
+			}
+			jobs.update(jobId, func(job *Job) { job.Processed += len(keys) })
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// restoreArticle moves the deleted article with the provided ID out of the trash and responds with the restored article.
+// If there is no such deleted article, it responds with an HTTP 404 Not Found error and if an article with the same ID
+// has been created since the deletion, it responds with an HTTP 409 Conflict error.
+func restoreArticle(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	trashKey := tenantKey(ctx, trashKeysPrefix+id)
+
+	article, err := getStoredArticle(ctx, trashKey)
+	if err != nil {
+		handleError(w, "Failed to retrieve deleted article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if article == nil {
+		handleError(w, "Deleted article not found", withErrorCode(ErrorCodeTrashedArticleNotFound, fmt.Errorf("no deleted article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+
+	restored, err := db.RenameNXAndJSONDel(ctx, databaseClient, trashKey, tenantKey(ctx, keysPrefix+id), "$.deletedAt")
+	if err != nil {
+		handleError(w, "Failed to restore article", err, http.StatusInternalServerError)
+		return
+	}
+	if !restored {
+		handleError(w, "Failed to restore article", withErrorCode(ErrorCodeDuplicateId, errors.New("an article with the same ID already exists")), http.StatusConflict)
+		return
+	}
+	article.DeletedAt = 0
+	articleChanged(ctx, requestActor(r), nil, article)
+
+	responseArticleJSON(w, r, *article, nil, http.StatusOK)
+}
+
+// purgeArticle permanently deletes the deleted article with the provided ID, along with its revisions, likes and comments.
+// If there is no such deleted article, it responds with an HTTP 404 Not Found error.
+func purgeArticle(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+
+	deleted, err := db.Del(ctx, databaseClient, tenantKey(ctx, trashKeysPrefix+id))
+	if err != nil {
+		handleError(w, "Failed to purge article from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		handleError(w, "Deleted article not found", withErrorCode(ErrorCodeTrashedArticleNotFound, fmt.Errorf("no deleted article found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	if _, err := db.Del(ctx, databaseClient, tenantKey(ctx, revisionsKeysPrefix+id), tenantKey(ctx, likesKeysPrefix+id)); err != nil {
+		handleError(w, "Failed to purge article revisions from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if err := deleteArticlesComments(ctx, []string{id}); err != nil {
+		handleError(w, "Failed to purge article comments from Database", err, http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("article with ID %s permanently deleted", id)}, http.StatusOK)
+}
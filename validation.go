@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentPolicyValidations holds the validations enforcing the limits of the configuration on the articles, by tag.
+var contentPolicyValidations = map[string]validator.Func{
+	"maxTitleLength": maxTitleLengthValidation,
+	"maxContentSize": maxContentSizeValidation,
+	"maxTags":        maxTagsValidation,
+	"tagCharset":     tagCharsetValidation,
+}
+
+// registerValidations registers the validations of the tags of the articles with validate, the fields failing them
+// being named by their JSON name.
+func registerValidations() error {
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validations := map[string]validator.Func{
+		"validUuid":       uuidValidation,
+		"validLanguage":   languageValidation,
+		"futureTimestamp": futureTimestampValidation,
+	}
+	// Register validate for the content policies of the configuration
+	for tag, validation := range contentPolicyValidations {
+		validations[tag] = validation
+	}
+	for tag, validation := range validations {
+		if err := validate.RegisterValidation(tag, validation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxTitleLengthValidation validates if a given field has at most config.MaxTitleLength characters.
+func maxTitleLengthValidation(fl validator.FieldLevel) bool {
+	return config.MaxTitleLength == 0 || utf8.RuneCountInString(fl.Field().String()) <= config.MaxTitleLength
+}
+
+// maxContentSizeValidation validates if a given field has at most config.MaxContentSize bytes.
+func maxContentSizeValidation(fl validator.FieldLevel) bool {
+	return config.MaxContentSize == 0 || len(fl.Field().String()) <= config.MaxContentSize
+}
+
+// maxTagsValidation validates if a given list has at most config.MaxTags items.
+func maxTagsValidation(fl validator.FieldLevel) bool {
+	return config.MaxTags == 0 || fl.Field().Len() <= config.MaxTags
+}
+
+// tagCharsetValidation validates if a given field fully matches config.TagPattern.
+func tagCharsetValidation(fl validator.FieldLevel) bool {
+	return config.TagPattern == nil || config.TagPattern.MatchString(fl.Field().String())
+}
+
+// FieldError describes a field which failed the validation of a request, so that clients can point at it.
+type FieldError struct {
+	Field   string `json:"field"`   // Field is the path of the field in the request body, e.g. title or events[0].
+	Rule    string `json:"rule"`    // Rule is the validation rule the field failed, e.g. required or oneof.
+	Message string `json:"message"` // Message is the explanation of the failure, e.g. "title is required".
+}
+
+// validationError is a failed validation, described field by field. It wraps the validator.ValidationErrors
+// it is built from.
+type validationError struct {
+	fields []FieldError
+	err    validator.ValidationErrors
+}
+
+// Error returns the messages of the fields which failed the validation.
+func (e *validationError) Error() string {
+	messages := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the underlying validator.ValidationErrors.
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// validateStruct validates a struct with validate, the fields which fail the validation being described by the
+// returned error, see fieldErrorsOf.
+func validateStruct(value any) error {
+	err := validate.Struct(value)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+	fields := make([]FieldError, len(validationErrors))
+	for i, fieldError := range validationErrors {
+		fields[i] = FieldError{
+			Field:   fieldErrorPath(fieldError),
+			Rule:    fieldError.Tag(),
+			Message: fieldErrorMessage(fieldError),
+		}
+	}
+	return &validationError{fields: fields, err: validationErrors}
+}
+
+// fieldErrorsOf returns the fields which failed the validation reported by err, nil if err is not a validation error.
+func fieldErrorsOf(err error) []FieldError {
+	var validationErr *validationError
+	if errors.As(err, &validationErr) {
+		return validationErr.fields
+	}
+	return nil
+}
+
+// jsonFieldName returns the JSON name of a struct field, for validate to name the fields as the requests do.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldErrorPath returns the path of a field which failed the validation, without the name of the validated struct.
+func fieldErrorPath(fieldError validator.FieldError) string {
+	_, path, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+	return path
+}
+
+// fieldErrorMessage returns the explanation of a validation failure.
+func fieldErrorMessage(fieldError validator.FieldError) string {
+	field := fieldErrorPath(fieldError)
+	switch fieldError.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(fieldError.Param()), ", "))
+	case "unique":
+		return fmt.Sprintf("%s must not hold the same value twice", field)
+	case "validUuid":
+		return fmt.Sprintf("%s must be a valid UUID", field)
+	case "validLanguage":
+		return fmt.Sprintf("%s must be the name of a language supported by the search engine", field)
+	case "futureTimestamp":
+		return fmt.Sprintf("%s must be a Unix timestamp in the future", field)
+	case "maxTitleLength":
+		return fmt.Sprintf("%s must be at most %d characters long", field, config.MaxTitleLength)
+	case "maxContentSize":
+		return fmt.Sprintf("%s must be at most %d bytes long", field, config.MaxContentSize)
+	case "maxTags":
+		return fmt.Sprintf("%s must have at most %d items", field, config.MaxTags)
+	case "tagCharset":
+		return fmt.Sprintf("%s must match the pattern %s", field, config.TagPattern)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, fieldError.Tag())
+	}
+}
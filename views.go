@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	viewsKeyPrefix      = "views:"      // viewsKeyPrefix prefixes the keys of the sorted sets counting the views of the articles.
+	totalViewsKey       = "views:total" // totalViewsKey is the sorted set counting the views of the articles since ever.
+	allTimeViewsWindow  = "all"         // allTimeViewsWindow is the window of GET /articles/popular counting all the views.
+	defaultViewsWindow  = "7d"          // defaultViewsWindow is the window of GET /articles/popular when none is provided.
+	defaultPopularLimit = 10            // defaultPopularLimit is the number of popular articles returned when no limit is provided.
+	viewsBucketLayout   = time.DateOnly // viewsBucketLayout formats the day of a bucket in its key, e.g. views:2024-05-01.
+)
+
+// ArticleViews is the number of views of an article.
+type ArticleViews struct {
+	Id    string `json:"id"`    // Id is the ID of the article.
+	Views int64  `json:"views"` // Views is the number of times the article has been viewed since ever.
+}
+
+// PopularArticle is an article along with its number of views during the window of GET /articles/popular.
+type PopularArticle struct {
+	Article
+	Views int64 `json:"views"` // Views is the number of times the article has been viewed during the window.
+}
+
+// viewsBucketKey returns the key of the sorted set counting the views of the articles of the tenant ctx is scoped to
+// during the day of t, in UTC.
+func viewsBucketKey(ctx context.Context, t time.Time) string {
+	return tenantKey(ctx, viewsKeyPrefix+t.UTC().Format(viewsBucketLayout))
+}
+
+// recordArticleView handles POST /article/{id}/view, counting a view of an article. The view is counted in the bucket
+// of the current day, kept config.ViewsRetentionDays days, and in the total views of the article.
+func recordArticleView(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	if !ensureArticleExists(ctx, w, id) {
+		return
+	}
+
+	retention := time.Duration(config.ViewsRetentionDays+1) * 24 * time.Hour
+	if _, err := db.SortedSetIncrBy(ctx, databaseClient, viewsBucketKey(ctx, time.Now()), id, 1, retention); err != nil {
+		handleError(w, "Failed to record the view of the article", err, http.StatusInternalServerError)
+		return
+	}
+	views, err := db.SortedSetIncrBy(ctx, databaseClient, tenantKey(ctx, totalViewsKey), id, 1, 0)
+	if err != nil {
+		handleError(w, "Failed to record the view of the article", err, http.StatusInternalServerError)
+		return
+	}
+	responseJSON(w, ArticleViews{Id: id, Views: int64(views)}, http.StatusOK)
+}
+
+// parseViewsWindow reads the window query parameter of GET /articles/popular, a number of days (e.g. 7d) up to
+// config.ViewsRetentionDays, or all for the views since ever. It returns the keys of the sorted sets counting the
+// views of the window in the tenant ctx is scoped to.
+func parseViewsWindow(ctx context.Context, window string) ([]string, error) {
+	if window == allTimeViewsWindow {
+		return []string{tenantKey(ctx, totalViewsKey)}, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+	if err != nil || !strings.HasSuffix(window, "d") || days < 1 || days > config.ViewsRetentionDays {
+		return nil, fmt.Errorf("window must be a number of days between 1d and %dd, or %s", config.ViewsRetentionDays, allTimeViewsWindow)
+	}
+	now := time.Now()
+	keys := make([]string, days)
+	for day := range keys {
+		keys[day] = viewsBucketKey(ctx, now.AddDate(0, 0, -day))
+	}
+	return keys, nil
+}
+
+// getPopularArticles handles GET /articles/popular, returning the most viewed articles during the window query
+// parameter (defaultViewsWindow by default, see parseViewsWindow), the most viewed first.
+// The number of articles returned is controlled by the limit query parameter (defaultPopularLimit by default, up to
+// maxPageLimit). The articles deleted since they were viewed are left out.
+func getPopularArticles(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	queryParams := r.URL.Query()
+	invalidPopularError := "invalid popular articles parameter"
+
+	if err := isQueryParamsExpected(queryParams, []string{"window", "limit"}); err != nil {
+		handleError(w, invalidPopularError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	window := defaultViewsWindow
+	if queryParams.Has("window") {
+		window = queryParams.Get("window")
+	}
+	keys, err := parseViewsWindow(ctx, window)
+	if err != nil {
+		handleError(w, invalidPopularError, withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	limit := defaultPopularLimit
+	if queryParams.Has("limit") {
+		limit, err = strconv.Atoi(queryParams.Get("limit"))
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			handleError(w, invalidPopularError, withErrorCode(ErrorCodeInvalidParameter, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mostViewed, err := db.SortedSetUnionTop(ctx, databaseClient, keys, tenantKey(ctx, viewsKeyPrefix+"popular:"+uuid.New().String()), limit)
+	if err != nil {
+		handleError(w, "Failed to retrieve the views of the articles", err, http.StatusInternalServerError)
+		return
+	}
+	popularArticles := []PopularArticle{}
+	if len(mostViewed) == 0 {
+		responseJSON(w, popularArticles, http.StatusOK)
+		return
+	}
+	articleKeys := make([]string, len(mostViewed))
+	for i, viewed := range mostViewed {
+		articleKeys[i] = tenantKey(ctx, keysPrefix+viewed.Member)
+	}
+	articles, err := fetchArticles(ctx, articleKeys)
+	if err != nil {
+		handleError(w, "Failed to retrieve articles from Database", err, http.StatusInternalServerError)
+		return
+	}
+	articlesById := make(map[string]Article, len(articles))
+	for _, article := range articles {
+		articlesById[article.Id] = article
+	}
+	for _, viewed := range mostViewed {
+		if article, found := articlesById[viewed.Member]; found {
+			popularArticles = append(popularArticles, PopularArticle{Article: article, Views: int64(viewed.Score)})
+		}
+	}
+	responseJSON(w, popularArticles, http.StatusOK)
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stivesso/articles-search/pkg/db"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Webhook represents a URL notified of the changes made to the articles.
+type Webhook struct {
+	Id  string `json:"id"`                          // Id is the unique identifier of the webhook.
+	URL string `json:"url" validate:"required,url"` // URL is the http or https URL the events are posted to.
+	// Secret is the key signing the notifications (see postWebhook), generated when not provided.
+	// It is only returned when the webhook is registered.
+	Secret string `json:"secret,omitempty"`
+	// Events lists the types of the events notified, all of them when empty.
+	Events    []ArticleEventType `json:"events,omitempty" validate:"dive,oneof=article.created article.updated article.deleted"`
+	CreatedAt int64              `json:"createdAt"` // CreatedAt is the time the webhook was registered, as a Unix timestamp in seconds.
+}
+
+const (
+	// webhooksKey is the key of the hash holding the registered webhooks, by ID.
+	webhooksKey = "webhooks"
+	// webhookTimeout is the time given to a webhook to respond to a notification.
+	webhookTimeout = 10 * time.Second
+	// webhookMaxAttempts is the number of times a notification is sent before giving up, when a webhook fails.
+	webhookMaxAttempts = 5
+	// webhookRetryDelay is the time waited before sending a notification again, doubled after each failed attempt.
+	webhookRetryDelay = 2 * time.Second
+	// webhookDeliveriesKey is the key of the hash holding the deliveries waiting to be made, by ID.
+	webhookDeliveriesKey = "webhooks:deliveries"
+	// webhookScheduleKey is the key of the sorted set of the IDs of the deliveries waiting to be made, scored by the
+	// time of their next attempt.
+	webhookScheduleKey = "schedule:webhooks:deliveries"
+	// webhookCheckInterval is the interval at which the deliveries whose time has come are made.
+	webhookCheckInterval = time.Second
+	// webhookBatchSize is the maximum number of deliveries claimed at once by an instance of the service.
+	webhookBatchSize = 100
+	// webhookLease is the time a claimed delivery is left to the instance of the service which claimed it, before
+	// being claimed again.
+	webhookLease = 3 * webhookTimeout
+	// The headers of the notifications.
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookDelivery is a notification of an event to a webhook waiting to be made, stored in webhookDeliveriesKey and
+// scheduled in webhookScheduleKey until it is made or given up, see deliverWebhook.
+type webhookDelivery struct {
+	Id        string           `json:"id"`                  // Id is the ID of the delivery, made of the IDs of the event and of the webhook.
+	WebhookId string           `json:"webhookId"`           // WebhookId is the ID of the notified webhook.
+	EventId   string           `json:"eventId"`             // EventId is the ID of the notified event.
+	EventType ArticleEventType `json:"eventType"`           // EventType is the type of the notified event.
+	Payload   json.RawMessage  `json:"payload"`             // Payload is the event as JSON, the body of the notifications.
+	Attempts  int              `json:"attempts"`            // Attempts is the number of attempts which have failed so far.
+	LastError string           `json:"lastError,omitempty"` // LastError is why the last attempt failed.
+}
+
+// errWebhookAddressForbidden is returned when a webhook resolves to an address it is not allowed to be notified on,
+// see checkWebhookAddress.
+var errWebhookAddressForbidden = errors.New("the webhook address is not allowed")
+
+// sharedAddressSpace is the shared address space of the carriers (RFC 6598), see checkWebhookAddress.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// webhookClient is the HTTP client sending the notifications. It doesn't follow the redirects, which are reported as
+// refused notifications, and only connects to the addresses allowed by checkWebhookAddress, the address being checked
+// once resolved so that a webhook can't reach a forbidden address through its host name. No proxy is used.
+var webhookClient = newWebhookClient()
+
+// newWebhookClient returns the HTTP client sending the notifications, see webhookClient.
+func newWebhookClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: webhookTimeout, Control: checkWebhookAddress}).DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// createWebhook registers a webhook, responding with an HTTP 201 Created along with the webhook and its secret,
+// which is not returned afterward.
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	var webhook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		handleError(w, "Invalid JSON payload", withErrorCode(ErrorCodeInvalidBody, err), http.StatusBadRequest)
+		return
+	}
+	if err := validateStruct(webhook); err != nil {
+		handleError(w, "Validation failed for webhook", err, http.StatusBadRequest)
+		return
+	}
+	if parsedURL, err := url.Parse(webhook.URL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		handleError(w, "Validation failed for webhook", withErrorCode(ErrorCodeValidationFailed, errors.New("url must be an http or https URL")), http.StatusBadRequest)
+		return
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			handleError(w, "Failed to generate webhook secret", err, http.StatusInternalServerError)
+			return
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	webhook.Id = uuid.New().String()
+	webhook.CreatedAt = time.Now().Unix()
+
+	record, err := json.Marshal(webhook)
+	if err != nil {
+		handleError(w, "Failed to encode webhook", err, http.StatusInternalServerError)
+		return
+	}
+	if err := db.HashSet(ctx, databaseClient, tenantKey(ctx, webhooksKey), webhook.Id, record); err != nil {
+		handleError(w, "Failed to store webhook in Database", err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/admin/webhooks/%s", webhook.Id))
+	responseJSON(w, webhook, http.StatusCreated)
+}
+
+// getWebhooks returns the registered webhooks, the oldest first, without their secret.
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := fetchWebhooks(requestContext(r))
+	if err != nil {
+		handleError(w, "Failed to retrieve webhooks from Database", err, http.StatusInternalServerError)
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	responseJSON(w, webhooks, http.StatusOK)
+}
+
+// getWebhook returns the webhook with the provided ID, without its secret.
+// If there is no such webhook, it returns an HTTP 404 Not Found response.
+func getWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	webhook, found, err := fetchWebhook(ctx, id)
+	if err != nil {
+		handleError(w, "Failed to retrieve webhook from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		handleError(w, "Webhook not found", withErrorCode(ErrorCodeWebhookNotFound, fmt.Errorf("no webhook found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	webhook.Secret = ""
+	responseJSON(w, webhook, http.StatusOK)
+}
+
+// deleteWebhook unregisters the webhook with the provided ID, the notifications waiting to be sent being dropped.
+// If there is no such webhook, it returns an HTTP 404 Not Found response.
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	id := r.PathValue("id")
+	deleted, err := db.HashDel(ctx, databaseClient, tenantKey(ctx, webhooksKey), id)
+	if err != nil {
+		handleError(w, "Failed to delete webhook from Database", err, http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		handleError(w, "Webhook not found", withErrorCode(ErrorCodeWebhookNotFound, fmt.Errorf("no webhook found with ID %s", id)), http.StatusNotFound)
+		return
+	}
+	responseJSON(w, CustomOutput{Message: fmt.Sprintf("webhook %s successfully deleted", id)}, http.StatusOK)
+}
+
+// fetchWebhook returns the webhook with the given ID registered by the tenant ctx is scoped to, the second value
+// reports whether there is such a webhook.
+func fetchWebhook(ctx context.Context, id string) (Webhook, bool, error) {
+	record, err := db.HashGet(ctx, databaseClient, tenantKey(ctx, webhooksKey), id)
+	if err != nil || record == "" {
+		return Webhook{}, false, err
+	}
+	var webhook Webhook
+	if err := json.Unmarshal([]byte(record), &webhook); err != nil {
+		return Webhook{}, false, fmt.Errorf("unable to validate the structure of stored Webhook %s: %v", id, err)
+	}
+	return webhook, true, nil
+}
+
+// fetchWebhooks returns the webhooks registered by the tenant ctx is scoped to, the oldest first.
+func fetchWebhooks(ctx context.Context) ([]Webhook, error) {
+	records, err := db.HashGetAll(ctx, databaseClient, tenantKey(ctx, webhooksKey))
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]Webhook, 0, len(records))
+	for id, record := range records {
+		var webhook Webhook
+		if err := json.Unmarshal([]byte(record), &webhook); err != nil {
+			return nil, fmt.Errorf("unable to validate the structure of stored Webhook %s: %v", id, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt != webhooks[j].CreatedAt {
+			return webhooks[i].CreatedAt < webhooks[j].CreatedAt
+		}
+		return webhooks[i].Id < webhooks[j].Id
+	})
+	return webhooks, nil
+}
+
+// startWebhookDispatcher starts the background goroutines notifying the registered webhooks of the events of the event
+// bus, the webhooks of a tenant or namespace being only notified of the events of its own articles. The notifications
+// are scheduled as deliveries stored in the Database (see scheduleWebhookDeliveries), which are then delivered by any
+// instance of the service (see deliverDueWebhooks), so that they are retried until they succeed or are given up even
+// when the instance which scheduled them stops.
+func startWebhookDispatcher() {
+	events, _ := articleEvents.subscribe()
+	go func() {
+		for event := range events {
+			scheduleWebhookDeliveries(event.context(ctx), event)
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(webhookCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, ctx := range tenantContexts() {
+				deliverDueWebhooks(ctx)
+			}
+		}
+	}()
+}
+
+// scheduleWebhookDeliveries schedules a delivery of an event to each webhook notified of its type, registered by the
+// tenant ctx is scoped to, to be made right away. A delivery is only scheduled once, whatever the number of instances
+// of the service scheduling it.
+func scheduleWebhookDeliveries(ctx context.Context, event ArticleEvent) {
+	webhooks, err := fetchWebhooks(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to retrieve the webhooks, event not notified", "event", event.Id, "Error:", err)
+		return
+	}
+	var payload []byte
+	for _, webhook := range webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				slog.ErrorContext(ctx, "Unable to encode event, event not notified", "event", event.Id, "Error:", err)
+				return
+			}
+		}
+		delivery := webhookDelivery{Id: event.Id + ":" + webhook.Id, WebhookId: webhook.Id, EventId: event.Id, EventType: event.Type, Payload: payload}
+		record, err := json.Marshal(delivery)
+		if err == nil {
+			_, err = db.ScheduleRecordNX(ctx, databaseClient, tenantKey(ctx, webhookDeliveriesKey), tenantKey(ctx, webhookScheduleKey), delivery.Id, record, float64(time.Now().Unix()))
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to schedule webhook delivery, event not notified", "webhook", webhook.Id, "event", event.Id, "Error:", err)
+		}
+	}
+}
+
+// deliverDueWebhooks makes the deliveries of the tenant ctx is scoped to whose time has come, each of them on its own
+// goroutine so that a slow webhook doesn't delay the others. Each due delivery is claimed for webhookLease beforehand,
+// so that it is made by a single instance of the service, and made again should that instance stop meanwhile.
+func deliverDueWebhooks(ctx context.Context) {
+	now := time.Now()
+	ids, err := db.SortedSetClaimByScore(ctx, databaseClient, tenantKey(ctx, webhookScheduleKey), float64(now.Unix()), webhookBatchSize, float64(now.Add(webhookLease).Unix()))
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to retrieve the webhook deliveries to make", "Error:", err)
+		return
+	}
+	for _, id := range ids {
+		go deliverWebhook(ctx, id)
+	}
+}
+
+// deliverWebhook makes an attempt of the delivery with the given ID, claimed by deliverDueWebhooks. The delivery is
+// deleted once the webhook has been notified, or once it has failed webhookMaxAttempts times (it can't be reached, or
+// responds with an HTTP 429 or 5xx status), and scheduled again otherwise, waiting longer after each attempt.
+// The deliveries of a deleted webhook are dropped.
+func deliverWebhook(ctx context.Context, id string) {
+	record, err := db.HashGet(ctx, databaseClient, tenantKey(ctx, webhookDeliveriesKey), id)
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to retrieve webhook delivery, it is made later", "delivery", id, "Error:", err)
+		return
+	}
+	if record == "" {
+		// The delivery has been made meanwhile
+		unscheduleWebhookDelivery(ctx, id)
+		return
+	}
+	var delivery webhookDelivery
+	if err := json.Unmarshal([]byte(record), &delivery); err != nil {
+		slog.ErrorContext(ctx, "Invalid webhook delivery, it is dropped", "delivery", id, "Error:", err)
+		unscheduleWebhookDelivery(ctx, id)
+		return
+	}
+	webhook, found, err := fetchWebhook(ctx, delivery.WebhookId)
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to retrieve webhook, the delivery is made later", "webhook", delivery.WebhookId, "delivery", id, "Error:", err)
+		return
+	}
+	if !found {
+		unscheduleWebhookDelivery(ctx, id)
+		return
+	}
+
+	err = postWebhook(ctx, webhook, delivery)
+	if err == nil {
+		unscheduleWebhookDelivery(ctx, id)
+		return
+	}
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= webhookMaxAttempts {
+		slog.ErrorContext(ctx, "Unable to notify webhook, giving up", "webhook", webhook.Id, "event", delivery.EventId, "attempts", delivery.Attempts, "Error:", err)
+		unscheduleWebhookDelivery(ctx, id)
+		return
+	}
+	slog.WarnContext(ctx, "Unable to notify webhook, retrying", "webhook", webhook.Id, "event", delivery.EventId, "attempt", delivery.Attempts, "Error:", err)
+	retryAt := time.Now().Add(webhookRetryDelay << (delivery.Attempts - 1))
+	updated, err := json.Marshal(delivery)
+	if err == nil {
+		err = db.ScheduleRecord(ctx, databaseClient, tenantKey(ctx, webhookDeliveriesKey), tenantKey(ctx, webhookScheduleKey), id, updated, float64(retryAt.Unix()))
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to record webhook delivery attempt, it is made again", "delivery", id, "Error:", err)
+	}
+}
+
+// unscheduleWebhookDelivery deletes the delivery with the given ID, a failure being logged as the delivery is then
+// made again.
+func unscheduleWebhookDelivery(ctx context.Context, id string) {
+	if err := db.UnscheduleRecord(ctx, databaseClient, tenantKey(ctx, webhookDeliveriesKey), tenantKey(ctx, webhookScheduleKey), id); err != nil {
+		slog.WarnContext(ctx, "Unable to delete webhook delivery", "delivery", id, "Error:", err)
+	}
+}
+
+// postWebhook sends a single notification of an event to a webhook, see deliverWebhook.
+//
+// Along with the event as JSON, the notification holds the following headers:
+//   - X-Webhook-Event, the type of the event
+//   - X-Webhook-Delivery, the ID of the event, the same for all the attempts
+//   - X-Webhook-Timestamp, the time of the attempt as a Unix timestamp in seconds
+//   - X-Webhook-Signature, sha256= followed by the hex encoded HMAC-SHA256, keyed by the secret of the webhook,
+//     of the timestamp, a dot and the body, so that the webhook can check the origin and the freshness of the notification
+func postWebhook(ctx context.Context, webhook Webhook, delivery webhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", jsonMediaType)
+	request.Header.Set(webhookEventHeader, string(delivery.EventType))
+	request.Header.Set(webhookDeliveryHeader, delivery.EventId)
+	request.Header.Set(webhookTimestampHeader, timestamp)
+	request.Header.Set(webhookSignatureHeader, "sha256="+signWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	if response.StatusCode >= 300 {
+		// The notification is refused by the webhook (or redirected, see webhookClient), sending it again would not help
+		slog.WarnContext(ctx, "Webhook refused notification", "webhook", webhook.Id, "event", delivery.EventId, "status", response.StatusCode)
+	}
+	return nil
+}
+
+// checkWebhookAddress refuses to connect to a loopback, private, link-local, multicast or unspecified address, or to
+// an address of the shared address space of the carriers (100.64.0.0/10), unless Config.WebhookPrivateTargets is set.
+// It is called by the dialer of webhookClient with the address resolved from the host of the webhook.
+func checkWebhookAddress(network string, address string, _ syscall.RawConn) error {
+	if config.WebhookPrivateTargets {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", errWebhookAddressForbidden, ip)
+	}
+	return nil
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of the timestamp, a dot and the payload, keyed by secret.
+func signWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckWebhookAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.215.14:443", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+		{"224.0.0.1:80", false},
+	}
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			err := checkWebhookAddress("tcp", test.address, nil)
+			if allowed := err == nil; allowed != test.allowed {
+				t.Errorf("checkWebhookAddress() = %v, expected allowed = %v", err, test.allowed)
+			}
+			if err != nil && !errors.Is(err, errWebhookAddressForbidden) {
+				t.Errorf("checkWebhookAddress() = %v, expected %v", err, errWebhookAddressForbidden)
+			}
+		})
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+	if _, err := webhookClient.Do(request); !errors.Is(err, errWebhookAddressForbidden) {
+		t.Errorf("webhookClient.Do() = %v, expected %v", err, errWebhookAddressForbidden)
+	}
+
+	config.WebhookPrivateTargets = true
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		t.Fatalf("webhookClient.Do() failed: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusTemporaryRedirect || redirected {
+		t.Errorf("webhookClient.Do() responded with %d, expected the redirect not to be followed", response.StatusCode)
+	}
+}
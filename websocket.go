@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// websocketPingInterval is the interval at which the subscribers are pinged, so that dead connections are detected.
+	websocketPingInterval = 30 * time.Second
+	// websocketPongTimeout is the time given to a subscriber to answer a ping, or to send any message.
+	websocketPongTimeout = 2 * websocketPingInterval
+	// websocketWriteTimeout is the time given to a subscriber to receive a message.
+	websocketWriteTimeout = 10 * time.Second
+	// websocketMaxMessageSize is the maximum size of the messages sent by a subscriber, in bytes.
+	websocketMaxMessageSize = 64 << 10
+)
+
+// websocketUpgrader upgrades the subscription requests to WebSocket, only from pages served by the same host.
+var websocketUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// SubscriptionMessage is a message sent by a WebSocket subscriber to change the articles it is notified of.
+type SubscriptionMessage struct {
+	Action  string   `json:"action" validate:"oneof=subscribe unsubscribe"` // Action is either subscribe or unsubscribe.
+	Tags    []string `json:"tags,omitempty"`                                // Tags are the tags to subscribe to or to unsubscribe from.
+	Authors []string `json:"authors,omitempty"`                             // Authors are the authors to subscribe to or to unsubscribe from.
+}
+
+// SubscriptionStatus is the message sent to a WebSocket subscriber in response to a SubscriptionMessage.
+type SubscriptionStatus struct {
+	Tags    []string `json:"tags"`            // Tags are the tags subscribed to.
+	Authors []string `json:"authors"`         // Authors are the authors subscribed to.
+	Error   string   `json:"error,omitempty"` // Error is the reason why the SubscriptionMessage has been rejected.
+}
+
+// articleSubscription holds the tags and the authors a WebSocket subscriber is notified of.
+type articleSubscription struct {
+	mu      sync.Mutex
+	tags    []string
+	authors []string
+}
+
+// matches reports whether an event is about an article with one of the subscribed tags or authors,
+// any event matching when nothing is subscribed.
+func (subscription *articleSubscription) matches(event ArticleEvent) bool {
+	subscription.mu.Lock()
+	defer subscription.mu.Unlock()
+	if len(subscription.tags) == 0 && len(subscription.authors) == 0 {
+		return true
+	}
+	if slices.ContainsFunc(subscription.authors, func(author string) bool { return strings.EqualFold(author, event.Article.Author) }) {
+		return true
+	}
+	return slices.ContainsFunc(event.Article.Tags, func(tag string) bool { return slices.Contains(subscription.tags, tag) })
+}
+
+// apply subscribes to or unsubscribes from the tags and the authors of a message, and returns the resulting status.
+func (subscription *articleSubscription) apply(message SubscriptionMessage) SubscriptionStatus {
+	subscription.mu.Lock()
+	defer subscription.mu.Unlock()
+	for _, values := range []struct {
+		current *[]string
+		changed []string
+	}{{&subscription.tags, message.Tags}, {&subscription.authors, message.Authors}} {
+		for _, value := range values.changed {
+			value = strings.TrimSpace(value)
+			index := slices.Index(*values.current, value)
+			if message.Action == "subscribe" && index < 0 && value != "" {
+				*values.current = append(*values.current, value)
+			}
+			if message.Action == "unsubscribe" && index >= 0 {
+				*values.current = slices.Delete(*values.current, index, index+1)
+			}
+		}
+	}
+	return subscription.status()
+}
+
+// status returns the tags and the authors subscribed to, subscription.mu being held.
+func (subscription *articleSubscription) status() SubscriptionStatus {
+	return SubscriptionStatus{Tags: append([]string{}, subscription.tags...), Authors: append([]string{}, subscription.authors...)}
+}
+
+// subscribeArticles upgrades the connection to WebSocket and pushes the changes made to the articles of the tenant and
+// namespace of the request with the subscribed tags or authors, as ArticleEvent JSON messages, until the client disconnects. The articles are matched as they are
+// after the change (or before it, for a deletion), all the changes being pushed while nothing is subscribed.
+// The tag and author query parameters, which can be repeated, set the initial subscription. It is then changed by the
+// SubscriptionMessage sent by the client, e.g. {"action": "subscribe", "tags": ["go"]}, each of them being answered
+// with a SubscriptionStatus message.
+func subscribeArticles(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	if err := isQueryParamsExpected(queryParams, []string{"tag", "author"}); err != nil {
+		handleError(w, "invalid query parameter", withErrorCode(ErrorCodeInvalidParameter, err), http.StatusBadRequest)
+		return
+	}
+	subscription := &articleSubscription{}
+	subscription.apply(SubscriptionMessage{Action: "subscribe", Tags: queryParams["tag"], Authors: queryParams["author"]})
+
+	// The upgrader responds to the invalid requests
+	conn, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := clusterArticleEvents.subscribe()
+	defer unsubscribe()
+
+	// Only this goroutine writes to the connection, the statuses being handed over by the reading one
+	statuses := make(chan SubscriptionStatus)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer close(done)
+		readSubscriptionMessages(conn, subscription, statuses, stopped)
+	}()
+
+	ping := time.NewTicker(websocketPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-serverStopping:
+			closing := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			_ = conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(websocketWriteTimeout))
+			return
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTimeout))
+		case status := <-statuses:
+			err = writeWebsocketJSON(conn, status)
+		case event := <-events:
+			if event.scopedTo(r.Context()) && subscription.matches(event) {
+				err = writeWebsocketJSON(conn, event)
+			}
+		}
+		if err != nil {
+			// The client is gone
+			return
+		}
+	}
+}
+
+// readSubscriptionMessages applies the SubscriptionMessage sent by a subscriber until it disconnects, handing over
+// the resulting statuses to be sent until stopped is closed.
+func readSubscriptionMessages(conn *websocket.Conn, subscription *articleSubscription, statuses chan<- SubscriptionStatus, stopped <-chan struct{}) {
+	conn.SetReadLimit(websocketMaxMessageSize)
+	extendReadDeadline := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(websocketPongTimeout))
+	}
+	conn.SetPongHandler(extendReadDeadline)
+
+	for {
+		if err := extendReadDeadline(""); err != nil {
+			return
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Warn("WebSocket subscriber disconnected", "Error:", err)
+			}
+			return
+		}
+
+		var message SubscriptionMessage
+		var status SubscriptionStatus
+		err = json.Unmarshal(data, &message)
+		if err == nil {
+			err = validateStruct(message)
+		}
+		if err == nil {
+			status = subscription.apply(message)
+		} else {
+			subscription.mu.Lock()
+			status = subscription.status()
+			subscription.mu.Unlock()
+			status.Error = err.Error()
+		}
+
+		select {
+		case statuses <- status:
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// writeWebsocketJSON sends v as a JSON message.
+func writeWebsocketJSON(conn *websocket.Conn, v any) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(v)
+}
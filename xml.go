@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+XML representation
+
+A JSON document is represented in XML as follows, so that it can be converted back to the same JSON document:
+  - the root element is named response, the members of an object are elements named after them, and the items
+    of an array are elements named item, e.g. <response><tags type="array"><item>go</item></tags></response>
+  - a member whose name is not a valid XML name is an element named member with a name attribute
+  - strings are text, numbers, booleans, arrays and empty objects are marked with a type attribute (number,
+    boolean, array or object) and null with a nil="true" attribute
+*/
+
+const (
+	xmlRootElement   = "response" // xmlRootElement is the name of the root element of the XML documents sent.
+	xmlItemElement   = "item"     // xmlItemElement is the name of the elements of the items of an array.
+	xmlMemberElement = "member"   // xmlMemberElement is the name of the elements of the members whose name is not a valid XML name.
+)
+
+// jsonToXML converts a JSON document to XML.
+func jsonToXML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buffer)
+	encoder.Indent("", "  ")
+	if err := encodeXMLValue(decoder, encoder, xmlRootElement); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeXMLValue encodes the next JSON value read from decoder as an XML element with the given name.
+func encodeXMLValue(decoder *json.Decoder, encoder *xml.Encoder, name string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start.Name.Local = xmlMemberElement
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "name"}, Value: name})
+	}
+	setType := func(valueType string) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: valueType})
+	}
+
+	var text string
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '[' {
+			setType("array")
+		} else if !decoder.More() {
+			setType("object")
+		}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for decoder.More() {
+			childName := xmlItemElement
+			if value == '{' {
+				memberToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				childName, _ = memberToken.(string)
+			}
+			if err := encodeXMLValue(decoder, encoder, childName); err != nil {
+				return err
+			}
+		}
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+		return encoder.EncodeToken(start.End())
+	case string:
+		text = value
+	case json.Number:
+		setType("number")
+		text = value.String()
+	case bool:
+		setType("boolean")
+		text = strconv.FormatBool(value)
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := encoder.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// isXMLName reports whether name can be used as is as the name of an XML element.
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.')) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// xmlElement is an XML element read by xmlToJSON.
+type xmlElement struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlElement
+	text     strings.Builder
+}
+
+// xmlToJSON converts an XML document, following the representation described above, to JSON.
+// The name of the root element is not significant. An element without type attribute is an object when
+// it has child elements, a string otherwise.
+func xmlToJSON(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlElement
+	var open []*xmlElement
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: token.Name.Local, attrs: make(map[string]string)}
+			for _, attr := range token.Attr {
+				element.attrs[attr.Name.Local] = attr.Value
+			}
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, element)
+			} else if root != nil {
+				return nil, errors.New("XML document must have a single root element")
+			} else {
+				root = element
+			}
+			open = append(open, element)
+		case xml.EndElement:
+			open = open[:len(open)-1]
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].text.Write(token)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("XML document is empty")
+	}
+
+	value, err := root.value()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// value returns the JSON value represented by the element.
+func (element *xmlElement) value() (any, error) {
+	if element.attrs["nil"] == "true" {
+		return nil, nil
+	}
+	text := strings.TrimSpace(element.text.String())
+	switch valueType := element.attrs["type"]; valueType {
+	case "array":
+		items := make([]any, 0, len(element.children))
+		for _, child := range element.children {
+			item, err := child.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case "number":
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("element %s is not a valid number", element.name)
+		}
+		return json.Number(text), nil
+	case "boolean":
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("element %s is not a valid boolean", element.name)
+		}
+		return value, nil
+	case "object", "":
+		if valueType == "" && len(element.children) == 0 {
+			return element.text.String(), nil
+		}
+		members := make(map[string]any, len(element.children))
+		for _, child := range element.children {
+			name := child.name
+			if memberName, ok := child.attrs["name"]; ok && name == xmlMemberElement {
+				name = memberName
+			}
+			member, err := child.value()
+			if err != nil {
+				return nil, err
+			}
+			members[name] = member
+		}
+		return members, nil
+	default:
+		return nil, fmt.Errorf("element %s has an unknown type %s", element.name, valueType)
+	}
+}